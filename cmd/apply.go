@@ -0,0 +1,120 @@
+/*
+Copyright © 2025 Matt Krueger <mkrueger@rstms.net>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+ 1. Redistributions of source code must retain the above copyright notice,
+    this list of conditions and the following disclaimer.
+
+ 2. Redistributions in binary form must reproduce the above copyright notice,
+    this list of conditions and the following disclaimer in the documentation
+    and/or other materials provided with the distribution.
+
+ 3. Neither the name of the copyright holder nor the names of its contributors
+    may be used to endorse or promote products derived from this software
+    without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rstms/vmx/ws"
+	"github.com/spf13/cobra"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply -f SPEC_FILE",
+	Short: "create or update an instance from a spec file",
+	Long: `
+Read a YAML or JSON instance spec and bring the instance in line with it.
+If the named instance does not exist, it is created.  Otherwise the spec is
+compared with the instance configuration and only the differing sections
+are modified; the instance must be powered off for changes to be applied.
+Use 'vmx plan' to view the changes without applying them, and 'vmx
+export-spec' to generate a spec from an existing instance.  SPEC_FILE may
+be '-' to read from stdin.
+
+Spec keys:
+  name, guest_os, cpu, memory, disk, preallocated, single_file, efi,
+  time_sync, timezone, clipboard, mac (auto|none|MAC),
+  nics: [{index, remove, connection, device, mac, start_connected}],
+  iso: {file, boot_connected, ca, cert, key},
+  serial: {pipe, client, v2v}, vnc: {enabled, port},
+  share: {enabled, host_path, guest_path}, guestinfo: {KEY: VALUE}
+
+The guest_os, disk, preallocated and single_file keys are used only when
+the instance is created.  Each nics entry configures adapter ethernetINDEX;
+adapters not listed are left unchanged.  mac is shorthand for a nics entry
+setting the MAC address of ethernet0.
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		InitController(ctx)
+		spec, err := readSpecFile(ViperGetString("apply.file"))
+		exitOnError(err)
+		plan, err := vmx.ApplySpec(ctx, spec, ws.ApplyOptions{Wait: ViperGetBool("wait")})
+		exitOnError(err)
+		outputPlan(plan)
+	},
+}
+
+func readSpecFile(filename string) (*ws.Spec, error) {
+	if filename == "" {
+		return nil, Fatalf("missing spec file")
+	}
+	var data []byte
+	var err error
+	if filename == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(filename)
+	}
+	if err != nil {
+		return nil, Fatal(err)
+	}
+	spec, err := ws.ParseSpec(data)
+	if err != nil {
+		return nil, Fatal(err)
+	}
+	return spec, nil
+}
+
+func outputPlan(plan *ws.SpecPlan) {
+	if OutputJSON {
+		fmt.Println(FormatJSON(plan))
+		return
+	}
+	switch {
+	case plan.Create:
+		fmt.Printf("[%s] create\n", plan.Name)
+	case len(plan.Changes) == 0:
+		fmt.Printf("[%s] no changes\n", plan.Name)
+	default:
+		for _, change := range plan.Changes {
+			fmt.Printf("[%s] %s: '%s' -> '%s'\n", plan.Name, change.Section, change.Have, change.Want)
+		}
+	}
+}
+
+func init() {
+	CobraAddCommand(rootCmd, rootCmd, applyCmd)
+	OptionString(applyCmd, "file", "f", "", "spec file")
+}
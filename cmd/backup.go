@@ -0,0 +1,158 @@
+/*
+Copyright © 2025 Matt Krueger <mkrueger@rstms.net>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+ 1. Redistributions of source code must retain the above copyright notice,
+    this list of conditions and the following disclaimer.
+
+ 2. Redistributions in binary form must reproduce the above copyright notice,
+    this list of conditions and the following disclaimer in the documentation
+    and/or other materials provided with the distribution.
+
+ 3. Neither the name of the copyright holder nor the names of its contributors
+    may be used to endorse or promote products derived from this software
+    without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/rstms/vmx/ws"
+	"github.com/spf13/cobra"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup VID [DEST]",
+	Short: "write an instance backup archive or repository snapshot",
+	Long: `
+Write the files in the instance directory to a local tar archive with a
+JSON manifest listing each file's size and sha256 checksum and the VMX
+configuration.  The archive is compressed according to the DEST suffix:
+.tar.zst, .tar.gz or .tar.  If DEST is a directory, the archive is written
+there as NAME-TIMESTAMP.tar.zst.  The instance must be off or suspended.
+The archive is written under a '.partial' name and renamed when complete.
+
+With --repo, DEST is omitted and a snapshot is added to the local backup
+repository DIR, which is created if it does not exist.  Files are stored
+as deduplicated, compressed content-defined chunks.  On linux and macos
+hosts using the ssh or sshclient shell, each disk is compared with the
+previous snapshot in 4 MiB blocks on the host, and only changed blocks are
+transferred.
+`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		repo := ViperGetString("backup.repo")
+		if repo == "" && len(args) != 2 {
+			exitOnError(Fatalf("DEST is required without --repo"))
+		}
+		if repo != "" && len(args) != 1 {
+			exitOnError(Fatalf("DEST is not used with --repo"))
+		}
+		ctx := cmd.Context()
+		InitController(ctx)
+		vm, err := vmx.Get(ctx, args[0])
+		exitOnError(err)
+		var result string
+		if repo != "" {
+			result, err = vmx.BackupRepo(ctx, vm.Name, repo)
+		} else {
+			result, err = vmx.Backup(ctx, vm.Name, args[1])
+		}
+		exitOnError(err)
+		if ViperGetBool("verbose") {
+			fmt.Printf("[%s] %s\n", vm.Name, result)
+		}
+	},
+}
+
+var backupPruneCmd = &cobra.Command{
+	Use:   "prune [NAME]",
+	Short: "remove backup repository snapshots",
+	Long: `
+Remove the snapshots of instance NAME, or of all instances, not kept by the
+retention policy, then remove chunks no longer referenced by any snapshot.
+--keep-daily N keeps the latest snapshot of each of the N most recent days
+with snapshots, and --keep-weekly N does the same for ISO weeks.
+`,
+	Args: cobra.RangeArgs(0, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := ""
+		if len(args) > 0 {
+			name = args[0]
+		}
+		repo, err := ws.OpenRepository(requireRepo("prune.repo"), false)
+		exitOnError(err)
+		defer repo.Close()
+		policy := ws.RetentionPolicy{
+			KeepDaily:  ViperGetInt("prune.keep_daily"),
+			KeepWeekly: ViperGetInt("prune.keep_weekly"),
+		}
+		result, err := repo.Prune(name, policy)
+		exitOnError(err)
+		if ViperGetBool("verbose") {
+			fmt.Println(result)
+		}
+	},
+}
+
+var backupSnapshotsCmd = &cobra.Command{
+	Use:   "snapshots [NAME]",
+	Short: "list backup repository snapshots",
+	Long: `
+List the snapshot IDs of instance NAME, or of all instances, in the backup
+repository.  An ID may be passed to 'vmx restore --snapshot'.
+`,
+	Args: cobra.RangeArgs(0, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := ws.OpenRepository(requireRepo("snapshots.repo"), false)
+		exitOnError(err)
+		defer repo.Close()
+		names := args
+		if len(names) == 0 {
+			names, err = repo.Instances()
+			exitOnError(err)
+		}
+		for _, name := range names {
+			ids, err := repo.SnapshotIDs(name)
+			exitOnError(err)
+			for _, id := range ids {
+				fmt.Printf("%s %s\n", name, id)
+			}
+		}
+	},
+}
+
+func requireRepo(key string) string {
+	repo := ViperGetString(key)
+	if repo == "" {
+		exitOnError(Fatalf("--repo is required"))
+	}
+	return repo
+}
+
+func init() {
+	CobraAddCommand(rootCmd, rootCmd, backupCmd)
+	OptionString(backupCmd, "repo", "", "", "backup repository directory")
+	CobraAddCommand(rootCmd, backupCmd, backupPruneCmd)
+	OptionString(backupPruneCmd, "repo", "", "", "backup repository directory")
+	OptionInt(backupPruneCmd, "keep-daily", "", 0, "number of daily snapshots to keep")
+	OptionInt(backupPruneCmd, "keep-weekly", "", 0, "number of weekly snapshots to keep")
+	CobraAddCommand(rootCmd, backupCmd, backupSnapshotsCmd)
+	OptionString(backupSnapshotsCmd, "repo", "", "", "backup repository directory")
+}
@@ -0,0 +1,104 @@
+/*
+Copyright © 2025 Matt Krueger <mkrueger@rstms.net>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+ 1. Redistributions of source code must retain the above copyright notice,
+    this list of conditions and the following disclaimer.
+
+ 2. Redistributions in binary form must reproduce the above copyright notice,
+    this list of conditions and the following disclaimer in the documentation
+    and/or other materials provided with the distribution.
+
+ 3. Neither the name of the copyright holder nor the names of its contributors
+    may be used to endorse or promote products derived from this software
+    without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var boxCmd = &cobra.Command{
+	Use:   "box",
+	Short: "Vagrant box operations",
+	Long: `
+Import and package Vagrant boxes for the vmware_desktop provider.  A box is
+a tar or gzip compressed tar archive containing metadata.json, a VMX file
+and the VMDK disks it references.
+`,
+}
+
+var boxImportCmd = &cobra.Command{
+	Use:   "import FILE NAME",
+	Short: "create an instance from a Vagrant box",
+	Long: `
+Create instance NAME in the first vmware_roots directory from the local
+vmware_desktop box FILE.  The VMX display name is set to NAME, the network
+adapters are given auto-generated MAC addresses, a new BIOS UUID is set and
+disk paths are reduced to filenames in the instance directory.
+`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if !IsFile(args[0]) {
+			exitOnError(Fatalf("box not found: %s", args[0]))
+		}
+		ctx := cmd.Context()
+		InitController(ctx)
+		result, err := vmx.ImportBox(ctx, args[0], args[1])
+		exitOnError(err)
+		if ViperGetBool("verbose") {
+			fmt.Println(result)
+		}
+	},
+}
+
+var boxPackageCmd = &cobra.Command{
+	Use:   "package VID",
+	Short: "write an instance as a Vagrant box",
+	Long: `
+Write the instance VMX, VMDK disks and nvram to a gzip compressed
+vmware_desktop box.  The default --output filename is NAME.box in the
+current directory.  The instance must be powered off.
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		InitController(ctx)
+		vm, err := vmx.Get(ctx, args[0])
+		exitOnError(err)
+		filename := ViperGetString("package.output")
+		if filename == "" {
+			filename = vm.Name + ".box"
+		}
+		result, err := vmx.PackageBox(ctx, vm.Name, filename)
+		exitOnError(err)
+		if ViperGetBool("verbose") {
+			fmt.Printf("[%s] %s\n", vm.Name, result)
+		}
+	},
+}
+
+func init() {
+	CobraAddCommand(rootCmd, rootCmd, boxCmd)
+	CobraAddCommand(rootCmd, boxCmd, boxImportCmd)
+	CobraAddCommand(rootCmd, boxCmd, boxPackageCmd)
+	OptionString(boxPackageCmd, "output", "", "", "box filename")
+}
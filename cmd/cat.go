@@ -45,10 +45,11 @@ Write the content of the instance's VMX file to stdout
 `,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		InitController()
+		ctx := cmd.Context()
+		InitController(ctx)
 		vid := args[0]
-		content, err := vmx.GetProperty(vid, "vmx")
-		cobra.CheckErr(err)
+		content, err := vmx.GetProperty(ctx, vid, "vmx")
+		exitOnError(err)
 		content = strings.TrimSpace(content)
 		fmt.Println(content)
 	},
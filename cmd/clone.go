@@ -0,0 +1,74 @@
+/*
+Copyright © 2025 Matt Krueger <mkrueger@rstms.net>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+ 1. Redistributions of source code must retain the above copyright notice,
+    this list of conditions and the following disclaimer.
+
+ 2. Redistributions in binary form must reproduce the above copyright notice,
+    this list of conditions and the following disclaimer in the documentation
+    and/or other materials provided with the distribution.
+
+ 3. Neither the name of the copyright holder nor the names of its contributors
+    may be used to endorse or promote products derived from this software
+    without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+package cmd
+
+import (
+	"github.com/rstms/vmx/ws"
+	"github.com/spf13/cobra"
+)
+
+var cloneCmd = &cobra.Command{
+	Use:   "clone SRC_VID NAME",
+	Short: "clone a VM instance",
+	Long: `
+Clone the selected instance to a new instance NAME in the first configured
+vmware_roots directory using the vmrun utility on the host.
+
+A full clone copies the virtual disks.  A linked clone shares the parent's
+disks and requires a snapshot.  Use --snapshot to clone from a named 
+snapshot.
+
+The new instance is given a new display name, an auto-generated MAC address
+and a new BIOS UUID.
+`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		InitController(ctx)
+		src := args[0]
+		dst := args[1]
+		options := ws.CloneOptions{
+			Linked:   ViperGetBool("clone.linked"),
+			Snapshot: ViperGetString("clone.snapshot"),
+		}
+		result, err := vmx.Clone(ctx, src, dst, options)
+		exitOnError(err)
+		if OutputJSON && ViperGetBool("status") {
+			OutputInstanceState(ctx, dst, result)
+		}
+	},
+}
+
+func init() {
+	CobraAddCommand(rootCmd, rootCmd, cloneCmd)
+	OptionSwitch(cloneCmd, "linked", "", "create a linked clone")
+	OptionString(cloneCmd, "snapshot", "", "", "clone from the named snapshot")
+}
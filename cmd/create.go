@@ -45,7 +45,8 @@ unless specified with option flags.
 `,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		InitController()
+		ctx := cmd.Context()
+		InitController(ctx)
 		name := args[0]
 
 		options := ws.NewCreateOptions()
@@ -60,7 +61,14 @@ unless specified with option flags.
 		options.HostTimeSync = ViperGetBool("time_sync")
 		options.GuestTimeZone = ViperGetString("timezone")
 		options.ClipboardEnabled = ViperGetBool("clipboard")
-		options.MacAddress = ViperGetString("mac")
+		options.NIC.Connection = ViperGetString("create.nic_type")
+		options.NIC.Device = ViperGetString("create.nic_device")
+		switch mac := ViperGetString("mac"); mac {
+		case "", "none":
+			options.NIC = ws.NICOptions{Remove: true}
+		default:
+			options.NIC.MacAddress = mac
+		}
 
 		switch {
 		case ViperGetBool("openbsd"):
@@ -75,13 +83,16 @@ unless specified with option flags.
 			options.GuestOS = "other-64"
 		}
 
+		err := initGuestInfoOptions(options, "create")
+		exitOnError(err)
+
 		isoOptions, err := InitIsoOptions()
-		cobra.CheckErr(err)
+		exitOnError(err)
 
-		result, err := vmx.Create(name, *options, *isoOptions)
-		cobra.CheckErr(err)
+		result, err := vmx.Create(ctx, name, *options, *isoOptions)
+		exitOnError(err)
 		if OutputJSON && options.Wait && ViperGetBool("status") {
-			OutputInstanceState(name, result)
+			OutputInstanceState(ctx, name, result)
 		}
 	},
 }
@@ -92,7 +103,9 @@ func init() {
 	OptionString(createCmd, "ram", "", "2G", "memory size")
 	OptionString(createCmd, "disk", "", "16G", "disk size")
 	OptionString(createCmd, "timezone", "", "UTC", "guest time zone")
-	OptionString(createCmd, "mac", "", "auto", "MAC address")
+	OptionString(createCmd, "mac", "", "auto", "MAC address [auto|none|MAC]")
+	OptionString(createCmd, "nic-type", "", "", "ethernet connection [nat|bridged|hostonly|vmnetN|segment:NAME]")
+	OptionString(createCmd, "nic-device", "", "", "ethernet device [e1000|e1000e|vmxnet3]")
 	OptionSwitch(createCmd, "efi", "", "EFI boot")
 	OptionSwitch(createCmd, "time-sync", "", "enable time sync with host")
 	OptionSwitch(createCmd, "clipboard", "", "enable clipboard sharing with host")
@@ -102,4 +115,8 @@ func init() {
 	OptionSwitch(createCmd, "debian", "", "Debian guest")
 	OptionSwitch(createCmd, "ubuntu", "", "Ubuntu guest")
 	OptionSwitch(createCmd, "windows", "", "Windows guest")
+	OptionString(createCmd, "guestinfo", "", "", "set guestinfo variables [format: 'key=value,...']")
+	OptionString(createCmd, "metadata", "", "", "set guestinfo.metadata from file [base64]")
+	OptionString(createCmd, "userdata", "", "", "set guestinfo.userdata from file [base64]")
+	OptionString(createCmd, "ignition", "", "", "set guestinfo.ignition.config.data from file [base64]")
 }
@@ -51,16 +51,17 @@ This application is a tool to generate the needed files
 to quickly create a Cobra application.`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		InitController()
+		ctx := cmd.Context()
+		InitController(ctx)
 		vid := args[0]
-		vm, err := vmx.Get(vid)
-		cobra.CheckErr(err)
+		vm, err := vmx.Get(ctx, vid)
+		exitOnError(err)
 		if confirm(fmt.Sprintf("Confirm IRRECOVERABLE DESTRUCTION of VM instance '%s'", vm.Name)) {
 			options := ws.DestroyOptions{
 				Force: ViperGetBool("kill"),
 			}
-			err := vmx.Destroy(vm.Id, options)
-			cobra.CheckErr(err)
+			err := vmx.Destroy(ctx, vm.Id, options)
+			exitOnError(err)
 
 			if OutputJSON && ViperGetBool("status") {
 				// we can't call OutputInstanceState, so build the status here
@@ -79,7 +80,7 @@ func confirm(prompt string) bool {
 	for {
 		fmt.Printf("%s [y/N]: ", prompt)
 		response, err := reader.ReadString('\n')
-		cobra.CheckErr(err)
+		exitOnError(err)
 		response = strings.ToLower(strings.TrimSpace(response))
 		if response == "y" || response == "yes" {
 			return true
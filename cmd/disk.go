@@ -0,0 +1,300 @@
+/*
+Copyright © 2025 Matt Krueger <mkrueger@rstms.net>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+ 1. Redistributions of source code must retain the above copyright notice,
+    this list of conditions and the following disclaimer.
+
+ 2. Redistributions in binary form must reproduce the above copyright notice,
+    this list of conditions and the following disclaimer in the documentation
+    and/or other materials provided with the distribution.
+
+ 3. Neither the name of the copyright holder nor the names of its contributors
+    may be used to endorse or promote products derived from this software
+    without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rstms/vmx/ws"
+	"github.com/spf13/cobra"
+)
+
+var diskCmd = &cobra.Command{
+	Use:   "disk",
+	Short: "virtual disk operations",
+	Long: `
+List, add, import and remove instance VMDK disks.  Disks are attached to a
+slot on an nvme, scsi, sata or ide controller.  A slot is named by
+controller, bus and unit, for example 'nvme0:1', 'scsi0:0' or 'sata1:2'.
+The instance must be powered off to add, import or remove a disk.
+
+The expand, shrink, defrag and convert subcommands run vmware-vdiskmanager
+on the host.  DISK is a slot or VMDK filename.  The instance must be powered
+off and have no snapshots.
+`,
+}
+
+var diskListCmd = &cobra.Command{
+	Use:     "list VID",
+	Aliases: []string{"ls"},
+	Short:   "list instance disks",
+	Long: `
+List the VMDK disks attached to the instance with their slot and capacity.
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		InitController(ctx)
+		vm, err := vmx.Get(ctx, args[0])
+		exitOnError(err)
+		disks, err := vmx.ListDisks(ctx, vm.Name)
+		exitOnError(err)
+		if OutputJSON {
+			output := make(map[string]any)
+			output[vm.Name] = disks
+			fmt.Println(FormatJSON(output))
+		} else {
+			for _, disk := range disks {
+				fmt.Printf("%s\t%s\t%s\n", disk.Device, disk.Size, disk.File)
+			}
+		}
+	},
+}
+
+var diskAddCmd = &cobra.Command{
+	Use:   "add VID",
+	Short: "add a disk",
+	Long: `
+Create a VMDK disk in the instance directory and attach it.  The disk is
+attached to the first free slot on the --controller, or to --slot.  The
+default filename is derived from the instance name and slot.  Set --attach
+to attach an existing VMDK --file instead of creating one.  For scsi disks,
+--adapter selects the controller type.
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		InitController(ctx)
+		vm, err := vmx.Get(ctx, args[0])
+		exitOnError(err)
+		options := ws.DiskOptions{
+			Controller:   ViperGetString("add.controller"),
+			Slot:         ViperGetString("add.slot"),
+			Adapter:      ViperGetString("add.adapter"),
+			File:         ViperGetString("add.file"),
+			Size:         ViperGetString("add.size"),
+			SingleFile:   ViperGetBool("add.single_file"),
+			Preallocated: ViperGetBool("add.preallocated"),
+			Attach:       ViperGetBool("add.attach"),
+		}
+		if options.Attach && options.File == "" {
+			exitOnError(Fatalf("--attach requires --file"))
+		}
+		result, err := vmx.AddDisk(ctx, vm.Name, options)
+		exitOnError(err)
+		if ViperGetBool("verbose") {
+			fmt.Printf("[%s] %s\n", vm.Name, result)
+		}
+	},
+}
+
+var diskRemoveCmd = &cobra.Command{
+	Use:     "remove VID SLOT",
+	Aliases: []string{"rm"},
+	Short:   "remove a disk",
+	Long: `
+Detach the disk at SLOT.  The VMDK files are left in the instance directory
+unless --delete is set.
+`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		InitController(ctx)
+		vm, err := vmx.Get(ctx, args[0])
+		exitOnError(err)
+		options := ws.DiskOptions{Delete: ViperGetBool("remove.delete")}
+		result, err := vmx.RemoveDisk(ctx, vm.Name, args[1], options)
+		exitOnError(err)
+		if ViperGetBool("verbose") {
+			fmt.Printf("[%s] %s\n", vm.Name, result)
+		}
+	},
+}
+
+var diskImportCmd = &cobra.Command{
+	Use:   "import VID IMAGE",
+	Short: "add a disk from a raw or qcow2 image",
+	Long: `
+Convert the local raw, qcow2 or sparse VMDK IMAGE to a VMDK disk, upload it
+to the instance directory and attach it.  Slot and filename selection are
+the same as 'disk add'.  The disk capacity is the image size, or --size if
+larger.  With --stream-optimized, a compressed streamOptimized VMDK is
+uploaded and converted to a growable disk on the host.  qcow2 images with
+backing files or encryption are not supported.
+`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		InitController(ctx)
+		vm, err := vmx.Get(ctx, args[0])
+		exitOnError(err)
+		if !IsFile(args[1]) {
+			exitOnError(Fatalf("image not found: %s", args[1]))
+		}
+		options := ws.DiskOptions{
+			Controller: ViperGetString("import.controller"),
+			Slot:       ViperGetString("import.slot"),
+			Adapter:    ViperGetString("import.adapter"),
+			File:       ViperGetString("import.file"),
+			Size:       ViperGetString("import.size"),
+			Image:      args[1],
+			Stream:     ViperGetBool("import.stream_optimized"),
+		}
+		result, err := vmx.AddDisk(ctx, vm.Name, options)
+		exitOnError(err)
+		if ViperGetBool("verbose") {
+			fmt.Printf("[%s] %s\n", vm.Name, result)
+		}
+	},
+}
+
+var diskExpandCmd = &cobra.Command{
+	Use:   "expand VID DISK",
+	Short: "grow a disk",
+	Long: `
+Increase the capacity of DISK to --size.  Partitions and filesystems in the
+guest must be grown separately.
+`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		size := ViperGetString("expand.size")
+		if size == "" {
+			exitOnError(Fatalf("missing --size"))
+		}
+		ctx := cmd.Context()
+		runDiskManager(ctx, args, func(vid, disk string) (*ws.VMDisk, error) {
+			return vmx.ExpandDisk(ctx, vid, disk, size)
+		})
+	},
+}
+
+var diskShrinkCmd = &cobra.Command{
+	Use:   "shrink VID DISK",
+	Short: "reclaim unused disk space",
+	Long: `
+Shrink a growable DISK, returning unused space to the host.  Zero the free
+space in the guest first for the best result.
+`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		runDiskManager(ctx, args, func(vid, disk string) (*ws.VMDisk, error) {
+			return vmx.ShrinkDisk(ctx, vid, disk)
+		})
+	},
+}
+
+var diskDefragCmd = &cobra.Command{
+	Use:   "defrag VID DISK",
+	Short: "defragment a disk",
+	Long: `
+Defragment the VMDK files of a growable DISK.
+`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		runDiskManager(ctx, args, func(vid, disk string) (*ws.VMDisk, error) {
+			return vmx.DefragDisk(ctx, vid, disk)
+		})
+	},
+}
+
+var diskConvertCmd = &cobra.Command{
+	Use:   "convert VID DISK",
+	Short: "convert a disk to another type",
+	Long: `
+Convert DISK to the disk --type, given by name or number:
+
+  0 single_file_growable
+  1 multiple_file_growable
+  2 single_file_preallocated
+  3 multiple_file_preallocated
+  4 preallocated_ESX
+  5 compressed_streaming_optimized
+  6 thin_provisioned
+
+The converted copy replaces the original VMDK files.
+`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		diskType, err := ws.ParseVDiskType(ViperGetString("convert.type"))
+		exitOnError(err)
+		ctx := cmd.Context()
+		runDiskManager(ctx, args, func(vid, disk string) (*ws.VMDisk, error) {
+			return vmx.ConvertDisk(ctx, vid, disk, diskType)
+		})
+	},
+}
+
+func runDiskManager(ctx context.Context, args []string, operation func(string, string) (*ws.VMDisk, error)) {
+	InitController(ctx)
+	vm, err := vmx.Get(ctx, args[0])
+	exitOnError(err)
+	disk, err := operation(vm.Name, args[1])
+	exitOnError(err)
+	if OutputJSON {
+		output := make(map[string]any)
+		output[vm.Name] = disk
+		fmt.Println(FormatJSON(output))
+	} else if ViperGetBool("verbose") {
+		fmt.Printf("[%s] %s\t%s\t%s\n", vm.Name, disk.Device, disk.Size, disk.File)
+	}
+}
+
+func init() {
+	CobraAddCommand(rootCmd, rootCmd, diskCmd)
+	CobraAddCommand(rootCmd, diskCmd, diskListCmd)
+	CobraAddCommand(rootCmd, diskCmd, diskAddCmd)
+	CobraAddCommand(rootCmd, diskCmd, diskRemoveCmd)
+	OptionString(diskAddCmd, "controller", "", "", "disk controller [nvme|scsi|sata|ide; default: nvme]")
+	OptionString(diskAddCmd, "slot", "", "", "disk slot [example: scsi0:1]")
+	OptionString(diskAddCmd, "adapter", "", "", "scsi controller type [lsilogic|pvscsi]")
+	OptionString(diskAddCmd, "file", "", "", "VMDK filename")
+	OptionString(diskAddCmd, "size", "", "16G", "disk size")
+	OptionSwitch(diskAddCmd, "single-file", "", "create single-file VMDK disk")
+	OptionSwitch(diskAddCmd, "preallocated", "", "pre-allocate VMDK disk")
+	OptionSwitch(diskAddCmd, "attach", "", "attach existing VMDK file")
+	OptionSwitch(diskRemoveCmd, "delete", "", "delete VMDK files")
+	CobraAddCommand(rootCmd, diskCmd, diskImportCmd)
+	OptionString(diskImportCmd, "controller", "", "", "disk controller [nvme|scsi|sata|ide; default: nvme]")
+	OptionString(diskImportCmd, "slot", "", "", "disk slot [example: scsi0:1]")
+	OptionString(diskImportCmd, "adapter", "", "", "scsi controller type [lsilogic|pvscsi]")
+	OptionString(diskImportCmd, "file", "", "", "VMDK filename")
+	OptionString(diskImportCmd, "size", "", "", "minimum disk size")
+	OptionSwitch(diskImportCmd, "stream-optimized", "", "upload a compressed streamOptimized VMDK")
+	CobraAddCommand(rootCmd, diskCmd, diskExpandCmd)
+	CobraAddCommand(rootCmd, diskCmd, diskShrinkCmd)
+	CobraAddCommand(rootCmd, diskCmd, diskDefragCmd)
+	CobraAddCommand(rootCmd, diskCmd, diskConvertCmd)
+	OptionString(diskExpandCmd, "size", "", "", "new disk size")
+	OptionString(diskConvertCmd, "type", "", "", "disk type [name or number]")
+}
@@ -43,10 +43,11 @@ Read the VMX file of the selected instance, then read and decode the
 VMDK file.  Output the disk details in JSON format
 `,
 	Run: func(cmd *cobra.Command, args []string) {
-		InitController()
+		ctx := cmd.Context()
+		InitController(ctx)
 		vid := args[0]
-		disks, err := vmx.GetProperty(vid, "disks")
-		cobra.CheckErr(err)
+		disks, err := vmx.GetProperty(ctx, vid, "disks")
+		exitOnError(err)
 		fmt.Println(disks)
 	},
 }
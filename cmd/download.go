@@ -52,7 +52,8 @@ TARGET/HOST_FILENAME
 `,
 	Args: cobra.RangeArgs(2, 3),
 	Run: func(cmd *cobra.Command, args []string) {
-		InitController()
+		ctx := cmd.Context()
+		InitController(ctx)
 
 		vid := args[0]
 
@@ -67,8 +68,8 @@ TARGET/HOST_FILENAME
 			target = filepath.Join(target, hostFile)
 		}
 
-		err := vmx.Download(vid, target, hostFile)
-		cobra.CheckErr(err)
+		err := vmx.Download(ctx, vid, target, hostFile)
+		exitOnError(err)
 	},
 }
 
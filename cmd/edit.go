@@ -38,6 +38,7 @@ import (
 	"path/filepath"
 	"runtime"
 
+	"github.com/rstms/vmx/ws"
 	"github.com/spf13/cobra"
 )
 
@@ -54,13 +55,17 @@ The instance must be in the 'poweredOff' state.
 
 The original content of the file is saved in a backup file in the current
 directory.
+
+The edited file is checked with 'vmx lint' before it is uploaded.  If errors
+are found, the file is not uploaded unless --force is set.
 `,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		InitController()
+		ctx := cmd.Context()
+		InitController(ctx)
 		vid := args[0]
-		vm, err := vmx.Get(vid)
-		cobra.CheckErr(err)
+		vm, err := vmx.Get(ctx, vid)
+		exitOnError(err)
 
 		backupDir := ViperGetString("backup_dir")
 		if backupDir == "" {
@@ -69,33 +74,33 @@ directory.
 		log.Printf("backupDir=%s\n", backupDir)
 		if !IsDir(backupDir) {
 			err := os.MkdirAll(backupDir, 0700)
-			cobra.CheckErr(err)
+			exitOnError(err)
 		}
 
 		// verify VM is powered off
-		powerState, err := vmx.GetProperty(vm.Id, "power")
-		cobra.CheckErr(err)
+		powerState, err := vmx.GetProperty(ctx, vm.Id, "power")
+		exitOnError(err)
 		if powerState != "off" {
 			err := Fatalf("cannot edit in power state: %s", powerState)
-			cobra.CheckErr(err)
+			exitOnError(err)
 		}
 
 		// read VMX data
-		vmxData, err := vmx.GetProperty(vm.Id, "vmx")
-		cobra.CheckErr(err)
+		vmxData, err := vmx.GetProperty(ctx, vm.Id, "vmx")
+		exitOnError(err)
 		log.Printf("read vmxData: %d bytes\n", len(vmxData))
 
 		// write data to edit file
 		vmxFile := filepath.Join(backupDir, fmt.Sprintf("%s.vmx", vm.Name))
 		err = os.WriteFile(vmxFile, []byte(vmxData), 0600)
-		cobra.CheckErr(err)
+		exitOnError(err)
 		log.Printf("wrote vmxFile: %s\n", vmxFile)
 
 		// write data to backup file
 		backupFile, err := backupFilename(vmxFile)
-		cobra.CheckErr(err)
+		exitOnError(err)
 		err = os.WriteFile(backupFile, []byte(vmxData), 0600)
-		cobra.CheckErr(err)
+		exitOnError(err)
 		log.Printf("wrote backupFile: %s\n", backupFile)
 
 		// edit file
@@ -122,18 +127,26 @@ directory.
 			fmt.Fprintf(os.Stderr, "editor exited: %d, not uploading result\n", editor.ProcessState.ExitCode())
 			return
 		default:
-			cobra.CheckErr(err)
+			exitOnError(err)
 		}
 		log.Printf("editor exited %d\n", editor.ProcessState.ExitCode())
 		// upload result to host
 		editedData, err := os.ReadFile(vmxFile)
-		cobra.CheckErr(err)
+		exitOnError(err)
 		log.Printf("read editedData: %d bytes\n", len(editedData))
 		if string(editedData) == string(vmxData) {
 			fmt.Println("no changes")
 		} else {
-			err = vmx.SetProperty(vm.Id, "vmx", string(editedData))
-			cobra.CheckErr(err)
+			issues, err := vmx.Lint(ctx, vm.Id, editedData)
+			exitOnError(err)
+			for _, issue := range issues {
+				fmt.Fprintf(os.Stderr, "[%s] %s\n", vm.Name, issue)
+			}
+			if ws.LintHasErrors(issues) && !ViperGetBool("edit.force") {
+				exitOnError(Fatalf("lint errors; not uploading %s (use --force to override)", vmxFile))
+			}
+			err = vmx.SetProperty(ctx, vm.Id, "vmx", string(editedData))
+			exitOnError(err)
 		}
 	},
 }
@@ -158,4 +171,5 @@ func backupFilename(pathname string) (string, error) {
 
 func init() {
 	CobraAddCommand(rootCmd, rootCmd, editCmd)
+	OptionSwitch(editCmd, "force", "", "upload despite lint errors")
 }
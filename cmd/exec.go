@@ -0,0 +1,88 @@
+/*
+Copyright © 2025 Matt Krueger <mkrueger@rstms.net>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+ 1. Redistributions of source code must retain the above copyright notice,
+    this list of conditions and the following disclaimer.
+
+ 2. Redistributions in binary form must reproduce the above copyright notice,
+    this list of conditions and the following disclaimer in the documentation
+    and/or other materials provided with the distribution.
+
+ 3. Neither the name of the copyright holder nor the names of its contributors
+    may be used to endorse or promote products derived from this software
+    without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/rstms/vmx/ws"
+	"github.com/spf13/cobra"
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec VID -- COMMAND [ARGS...]",
+	Short: "run a program in the guest",
+	Long: `
+Run COMMAND inside the guest of a running instance using VMware Tools.
+The guest does not need network access.
+
+Guest credentials are read from the --guest-user and --guest-password 
+options, the guest_user and guest_password config values, or the 
+VMX_GUEST_USER and VMX_GUEST_PASSWORD environment variables.
+
+COMMAND must be a full pathname in the guest.  With --script, COMMAND and
+ARGS are joined and run as a script by the INTERPRETER program.
+
+The exit code of the guest program is output and used as the exit code.
+With --no-wait, the command returns as soon as the program has started.
+`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		InitController(ctx)
+		vid := args[0]
+		options := ws.GuestExecOptions{
+			Username:     ViperGetString("guest_user"),
+			Password:     ViperGetString("guest_password"),
+			Wait:         ViperGetBool("wait"),
+			Interactive:  ViperGetBool("exec.interactive"),
+			ActiveWindow: ViperGetBool("exec.active_window"),
+			Interpreter:  ViperGetString("exec.script"),
+		}
+		result, err := vmx.GuestExec(ctx, vid, args[1:], options)
+		exitOnError(err)
+		if OutputJSON {
+			fmt.Println(FormatJSON(result))
+		} else {
+			fmt.Println(result.Result)
+		}
+		if result.ExitCode != 0 {
+			ExitCode = &result.ExitCode
+		}
+	},
+}
+
+func init() {
+	CobraAddCommand(rootCmd, rootCmd, execCmd)
+	OptionSwitch(execCmd, "interactive", "", "run in the interactive guest session")
+	OptionSwitch(execCmd, "active-window", "", "run in an active window (Windows guests)")
+	OptionString(execCmd, "script", "", "", "run COMMAND as script text with INTERPRETER")
+}
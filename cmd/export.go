@@ -0,0 +1,76 @@
+/*
+Copyright © 2025 Matt Krueger <mkrueger@rstms.net>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+ 1. Redistributions of source code must retain the above copyright notice,
+    this list of conditions and the following disclaimer.
+
+ 2. Redistributions in binary form must reproduce the above copyright notice,
+    this list of conditions and the following disclaimer in the documentation
+    and/or other materials provided with the distribution.
+
+ 3. Neither the name of the copyright holder nor the names of its contributors
+    may be used to endorse or promote products derived from this software
+    without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export VID --ova FILE",
+	Short: "export an instance as an OVA or OVF package",
+	Long: `
+Write an OVA archive containing an OVF descriptor, a manifest and
+streamOptimized VMDK disks converted from the instance disks.  The
+descriptor describes the cpu count, memory size, guest OS, disks and network
+adapters.  If the --ova filename ends with '.ovf', the descriptor, manifest
+and disks are written as separate files in its directory.  The instance must
+be powered off.
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		filename := ViperGetString("export.ova")
+		if filename == "" {
+			exitOnError(Fatalf("missing --ova"))
+		}
+		lower := strings.ToLower(filename)
+		if !strings.HasSuffix(lower, ".ova") && !strings.HasSuffix(lower, ".ovf") {
+			exitOnError(Fatalf("expected .ova or .ovf filename: %s", filename))
+		}
+		ctx := cmd.Context()
+		InitController(ctx)
+		vm, err := vmx.Get(ctx, args[0])
+		exitOnError(err)
+		result, err := vmx.ExportOVF(ctx, vm.Name, filename)
+		exitOnError(err)
+		if ViperGetBool("verbose") {
+			fmt.Printf("[%s] %s\n", vm.Name, result)
+		}
+	},
+}
+
+func init() {
+	CobraAddCommand(rootCmd, rootCmd, exportCmd)
+	OptionString(exportCmd, "ova", "", "", "output OVA or OVF filename")
+}
@@ -57,7 +57,8 @@ PREFIX may be provided with the 'vmx' property to filter the vmx file output
 `,
 	Args: cobra.RangeArgs(1, 3),
 	Run: func(cmd *cobra.Command, args []string) {
-		InitController()
+		ctx := cmd.Context()
+		InitController(ctx)
 		vid := args[0]
 		var property string
 		var prefix string
@@ -70,8 +71,8 @@ PREFIX may be provided with the 'vmx' property to filter the vmx file output
 				prefix = strings.ToLower(args[2])
 			}
 		}
-		value, err := vmx.GetProperty(vid, property)
-		cobra.CheckErr(err)
+		value, err := vmx.GetProperty(ctx, vid, property)
+		exitOnError(err)
 		if prefix != "" {
 			for _, line := range strings.Split(value, "\n") {
 				if strings.HasPrefix(strings.ToLower(line), prefix) {
@@ -0,0 +1,165 @@
+/*
+Copyright © 2025 Matt Krueger <mkrueger@rstms.net>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+ 1. Redistributions of source code must retain the above copyright notice,
+    this list of conditions and the following disclaimer.
+
+ 2. Redistributions in binary form must reproduce the above copyright notice,
+    this list of conditions and the following disclaimer in the documentation
+    and/or other materials provided with the distribution.
+
+ 3. Neither the name of the copyright holder nor the names of its contributors
+    may be used to endorse or promote products derived from this software
+    without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/rstms/vmx/ws"
+	"github.com/spf13/cobra"
+)
+
+var guestCmd = &cobra.Command{
+	Use:   "guest",
+	Short: "guest file, directory and process operations",
+	Long: `
+Operate on files, directories and processes inside the guest of a running
+instance using VMware Tools.  Guest credentials are read as described in 
+'vmx exec --help'.
+`,
+}
+
+var guestLsCmd = &cobra.Command{
+	Use:   "ls VID DIRECTORY",
+	Short: "list a guest directory",
+	Long: `
+List the files in a guest DIRECTORY.
+`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		InitController(ctx)
+		vid := args[0]
+		dir := args[1]
+		files, err := vmx.GuestListDirectory(ctx, vid, dir, initGuestOptions())
+		exitOnError(err)
+		if OutputJSON {
+			output := make(map[string]any)
+			output[dir] = files
+			fmt.Println(FormatJSON(output))
+		} else {
+			for _, file := range files {
+				fmt.Println(file)
+			}
+		}
+	},
+}
+
+var guestMkdirCmd = &cobra.Command{
+	Use:   "mkdir VID DIRECTORY",
+	Short: "create a guest directory",
+	Long: `
+Create DIRECTORY in the guest.
+`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		InitController(ctx)
+		err := vmx.GuestMkdir(ctx, args[0], args[1], initGuestOptions())
+		exitOnError(err)
+	},
+}
+
+var guestRmCmd = &cobra.Command{
+	Use:   "rm VID PATHNAME",
+	Short: "delete a guest file",
+	Long: `
+Delete the file PATHNAME in the guest.
+`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		InitController(ctx)
+		err := vmx.GuestRemove(ctx, args[0], args[1], initGuestOptions())
+		exitOnError(err)
+	},
+}
+
+var guestPsCmd = &cobra.Command{
+	Use:   "ps VID",
+	Short: "list guest processes",
+	Long: `
+List the processes running in the guest.  Each process is output as a JSON
+record with pid, owner and cmd.
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		InitController(ctx)
+		vm, err := vmx.Get(ctx, args[0])
+		exitOnError(err)
+		processes, err := vmx.GuestListProcesses(ctx, vm.Name, initGuestOptions())
+		exitOnError(err)
+		if OutputJSON {
+			output := make(map[string]any)
+			output[vm.Name] = processes
+			fmt.Println(FormatJSON(output))
+		} else {
+			for _, process := range processes {
+				fmt.Printf("%d\t%s\t%s\n", process.Pid, process.Owner, process.Command)
+			}
+		}
+	},
+}
+
+var guestKillCmd = &cobra.Command{
+	Use:   "kill VID PID",
+	Short: "kill a guest process",
+	Long: `
+Terminate the guest process PID.
+`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		InitController(ctx)
+		pid, err := strconv.Atoi(args[1])
+		exitOnError(err)
+		err = vmx.GuestKillProcess(ctx, args[0], pid, initGuestOptions())
+		exitOnError(err)
+	},
+}
+
+func initGuestOptions() ws.GuestOptions {
+	return ws.GuestOptions{
+		Username: ViperGetString("guest_user"),
+		Password: ViperGetString("guest_password"),
+	}
+}
+
+func init() {
+	CobraAddCommand(rootCmd, rootCmd, guestCmd)
+	CobraAddCommand(rootCmd, guestCmd, guestLsCmd)
+	CobraAddCommand(rootCmd, guestCmd, guestMkdirCmd)
+	CobraAddCommand(rootCmd, guestCmd, guestRmCmd)
+	CobraAddCommand(rootCmd, guestCmd, guestPsCmd)
+	CobraAddCommand(rootCmd, guestCmd, guestKillCmd)
+}
@@ -0,0 +1,94 @@
+/*
+Copyright © 2025 Matt Krueger <mkrueger@rstms.net>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+ 1. Redistributions of source code must retain the above copyright notice,
+    this list of conditions and the following disclaimer.
+
+ 2. Redistributions in binary form must reproduce the above copyright notice,
+    this list of conditions and the following disclaimer in the documentation
+    and/or other materials provided with the distribution.
+
+ 3. Neither the name of the copyright holder nor the names of its contributors
+    may be used to endorse or promote products derived from this software
+    without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var guestcpCmd = &cobra.Command{
+	Use:   "guest-cp SOURCE DEST",
+	Short: "copy a file to or from the guest",
+	Long: `
+Copy a file between the local system and the guest of a running instance
+using VMware Tools.  The guest side is written as VID:PATHNAME.
+
+Examples:
+vmx guest-cp ./setup.sh testvm:/tmp/setup.sh
+vmx guest-cp testvm:/var/log/messages ./messages
+
+If the configured Workstation host is remote, the file is staged in the 
+instance directory on the host and transferred with scp or winexec.
+
+If DEST is a local directory, the guest filename is used.  Guest 
+credentials are read as described in 'vmx exec --help'.
+`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		InitController(ctx)
+		srcVID, srcPath, srcGuest := parseGuestPath(args[0])
+		dstVID, dstPath, dstGuest := parseGuestPath(args[1])
+		options := initGuestOptions()
+		switch {
+		case srcGuest && dstGuest:
+			exitOnError(Fatalf("guest to guest copy is not supported"))
+		case dstGuest:
+			err := vmx.GuestUpload(ctx, dstVID, srcPath, dstPath, options)
+			exitOnError(err)
+		case srcGuest:
+			if IsDir(dstPath) {
+				_, filename := filepath.Split(strings.ReplaceAll(srcPath, "\\", "/"))
+				dstPath = filepath.Join(dstPath, filename)
+			}
+			err := vmx.GuestDownload(ctx, srcVID, srcPath, dstPath, options)
+			exitOnError(err)
+		default:
+			exitOnError(Fatalf("SOURCE or DEST must be a guest path [VID:PATHNAME]"))
+		}
+	},
+}
+
+// split VID:PATHNAME; a single-letter prefix is a local drive letter
+func parseGuestPath(arg string) (string, string, bool) {
+	vid, pathname, ok := strings.Cut(arg, ":")
+	if !ok || len(vid) < 2 {
+		return "", arg, false
+	}
+	return vid, pathname, true
+}
+
+func init() {
+	CobraAddCommand(rootCmd, rootCmd, guestcpCmd)
+}
@@ -0,0 +1,141 @@
+/*
+Copyright © 2025 Matt Krueger <mkrueger@rstms.net>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+ 1. Redistributions of source code must retain the above copyright notice,
+    this list of conditions and the following disclaimer.
+
+ 2. Redistributions in binary form must reproduce the above copyright notice,
+    this list of conditions and the following disclaimer in the documentation
+    and/or other materials provided with the distribution.
+
+ 3. Neither the name of the copyright holder nor the names of its contributors
+    may be used to endorse or promote products derived from this software
+    without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rstms/vmx/ws"
+	"github.com/spf13/cobra"
+)
+
+var guestinfoCmd = &cobra.Command{
+	Use:   "guestinfo",
+	Short: "guestinfo variable operations",
+	Long: `
+Read and write instance guestinfo variables.  A running guest reads these
+with VMware Tools ('vmtoolsd --cmd "info-get guestinfo.KEY"'), which is how
+the cloud-init VMware datasource and Ignition obtain their configuration.
+
+KEY may be given with or without the 'guestinfo.' prefix.  When the instance
+is powered off, get and set operate on the VMX file.  Use 'vmx modify' with
+--guestinfo, --metadata, --userdata or --ignition to set multiple keys.
+`,
+}
+
+var guestinfoGetCmd = &cobra.Command{
+	Use:   "get VID KEY",
+	Short: "read a guestinfo variable",
+	Long: `
+Output the value of the guestinfo variable KEY.  For a running instance the
+value is read as a guest variable, or from the runtime configuration if
+--runtime-config is set.
+`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		InitController(ctx)
+		options := ws.GuestInfoOptions{RuntimeConfig: ViperGetBool("get.runtime_config")}
+		value, err := vmx.GetGuestInfo(ctx, args[0], args[1], options)
+		exitOnError(err)
+		if OutputJSON {
+			output := make(map[string]any)
+			output[ws.GuestInfoKey(args[1])] = value
+			fmt.Println(FormatJSON(output))
+		} else {
+			fmt.Println(value)
+		}
+	},
+}
+
+var guestinfoSetCmd = &cobra.Command{
+	Use:   "set VID KEY VALUE",
+	Short: "write a guestinfo variable",
+	Long: `
+Set the guestinfo variable KEY to VALUE.  For a running instance the value
+is written as a guest variable, which is lost at power off, or into the
+runtime configuration if --runtime-config is set.  For a powered off
+instance the value is written into the VMX file; an empty VALUE removes KEY.
+`,
+	Args: cobra.ExactArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		InitController(ctx)
+		options := ws.GuestInfoOptions{RuntimeConfig: ViperGetBool("set.runtime_config")}
+		result, err := vmx.SetGuestInfo(ctx, args[0], args[1], args[2], options)
+		exitOnError(err)
+		if ViperGetBool("verbose") {
+			fmt.Println(result)
+		}
+	},
+}
+
+var guestinfoListCmd = &cobra.Command{
+	Use:     "list VID",
+	Aliases: []string{"ls"},
+	Short:   "list guestinfo variables",
+	Long: `
+List the guestinfo variables stored in the instance VMX file.
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		InitController(ctx)
+		vm, err := vmx.Get(ctx, args[0])
+		exitOnError(err)
+		values, err := vmx.ListGuestInfo(ctx, vm.Name)
+		exitOnError(err)
+		if OutputJSON {
+			output := make(map[string]any)
+			output[vm.Name] = values
+			fmt.Println(FormatJSON(output))
+		} else {
+			keys := []string{}
+			for key := range values {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fmt.Printf("%s = %s\n", key, values[key])
+			}
+		}
+	},
+}
+
+func init() {
+	CobraAddCommand(rootCmd, rootCmd, guestinfoCmd)
+	CobraAddCommand(rootCmd, guestinfoCmd, guestinfoGetCmd)
+	CobraAddCommand(rootCmd, guestinfoCmd, guestinfoSetCmd)
+	CobraAddCommand(rootCmd, guestinfoCmd, guestinfoListCmd)
+	OptionSwitch(guestinfoGetCmd, "runtime-config", "", "read the runtime configuration")
+	OptionSwitch(guestinfoSetCmd, "runtime-config", "", "write the runtime configuration")
+}
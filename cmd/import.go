@@ -0,0 +1,76 @@
+/*
+Copyright © 2025 Matt Krueger <mkrueger@rstms.net>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+ 1. Redistributions of source code must retain the above copyright notice,
+    this list of conditions and the following disclaimer.
+
+ 2. Redistributions in binary form must reproduce the above copyright notice,
+    this list of conditions and the following disclaimer in the documentation
+    and/or other materials provided with the distribution.
+
+ 3. Neither the name of the copyright holder nor the names of its contributors
+    may be used to endorse or promote products derived from this software
+    without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/rstms/vmx/ws"
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import FILE [NAME]",
+	Short: "create an instance from an OVA or OVF package",
+	Long: `
+Create instance NAME from a local OVA archive or OVF descriptor.  The cpu
+count, memory size, guest OS, firmware and network adapters are read from
+the descriptor, and each disk is converted and attached at the slot of its
+OVF controller.  NAME defaults to the OVF virtual system name.  Manifest
+digests are verified when a manifest is present.
+
+Network adapters on an OVF network named nat, bridged, hostonly, vmnetN or
+segment:NAME use that connection; other networks use --network.
+`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if !IsFile(args[0]) {
+			exitOnError(Fatalf("file not found: %s", args[0]))
+		}
+		name := ""
+		if len(args) > 1 {
+			name = args[1]
+		}
+		ctx := cmd.Context()
+		InitController(ctx)
+		options := ws.OVFOptions{Network: ViperGetString("import.network")}
+		result, err := vmx.ImportOVF(ctx, args[0], name, options)
+		exitOnError(err)
+		if ViperGetBool("verbose") {
+			fmt.Println(result)
+		}
+	},
+}
+
+func init() {
+	CobraAddCommand(rootCmd, rootCmd, importCmd)
+	OptionString(importCmd, "network", "", "nat", "connection for unrecognized OVF networks [nat|bridged|hostonly|vmnetN|segment:NAME]")
+}
@@ -43,16 +43,17 @@ var killCmd = &cobra.Command{
 	Args:    cobra.ExactArgs(1),
 	Aliases: []string{"poweroff"},
 	Run: func(cmd *cobra.Command, args []string) {
-		InitController()
+		ctx := cmd.Context()
+		InitController(ctx)
 		vid := args[0]
 		options := ws.StopOptions{
 			Wait:     ViperGetBool("wait"),
 			PowerOff: true,
 		}
-		result, err := vmx.Stop(vid, options)
-		cobra.CheckErr(err)
+		result, err := vmx.Stop(ctx, vid, options)
+		exitOnError(err)
 		if OutputJSON && ViperGetBool("status") {
-			OutputInstanceState(vid, result)
+			OutputInstanceState(ctx, vid, result)
 		}
 	},
 }
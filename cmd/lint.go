@@ -0,0 +1,99 @@
+/*
+Copyright © 2025 Matt Krueger <mkrueger@rstms.net>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+ 1. Redistributions of source code must retain the above copyright notice,
+    this list of conditions and the following disclaimer.
+
+ 2. Redistributions in binary form must reproduce the above copyright notice,
+    this list of conditions and the following disclaimer in the documentation
+    and/or other materials provided with the distribution.
+
+ 3. Neither the name of the copyright holder nor the names of its contributors
+    may be used to endorse or promote products derived from this software
+    without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rstms/vmx/ws"
+	"github.com/spf13/cobra"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint VID|FILE",
+	Short: "check a VMX configuration for problems",
+	Long: `
+Parse the VMX file of the selected instance, or a local VMX FILE, and report
+problems: unparsed lines, duplicate keys, devices which are not present but
+have leftover settings, missing disk files, unknown guestOS values, malformed
+MAC addresses and invalid USB device IDs.  Disk files referenced by a local
+FILE are checked relative to its directory.  The exit code is 1 if any
+errors are found.
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var name string
+		var issues []ws.LintIssue
+		if IsFile(args[0]) {
+			name = args[0]
+			data, err := os.ReadFile(name)
+			exitOnError(err)
+			dir := filepath.Dir(name)
+			issues = ws.LintVMX(data, func(filename string) bool {
+				if filepath.IsAbs(filename) {
+					return IsFile(filename)
+				}
+				return IsFile(filepath.Join(dir, filename))
+			})
+		} else {
+			ctx := cmd.Context()
+			InitController(ctx)
+			vm, err := vmx.Get(ctx, args[0])
+			exitOnError(err)
+			name = vm.Name
+			issues, err = vmx.Lint(ctx, vm.Name, nil)
+			exitOnError(err)
+		}
+		outputLintIssues(name, issues)
+		if ws.LintHasErrors(issues) {
+			exitCode := 1
+			ExitCode = &exitCode
+		}
+	},
+}
+
+func outputLintIssues(name string, issues []ws.LintIssue) {
+	if OutputJSON {
+		output := make(map[string]any)
+		output[name] = issues
+		fmt.Println(FormatJSON(output))
+		return
+	}
+	for _, issue := range issues {
+		fmt.Printf("[%s] %s\n", name, issue)
+	}
+}
+
+func init() {
+	CobraAddCommand(rootCmd, rootCmd, lintCmd)
+}
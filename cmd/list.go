@@ -51,20 +51,21 @@ more detailed listing.
 	Aliases: []string{"ls"},
 	Args:    cobra.RangeArgs(0, 1),
 	Run: func(cmd *cobra.Command, args []string) {
-		InitController()
+		ctx := cmd.Context()
+		InitController(ctx)
 		var vid string
 		if len(args) > 0 {
 			vid = args[0]
 		}
 		iso, err := ws.IsIsoPath(vid)
-		cobra.CheckErr(err)
+		exitOnError(err)
 		options := ws.FilesOptions{
 			Detail: ViperGetBool("list.detail"),
 			All:    ViperGetBool("list.all"),
 			Iso:    iso,
 		}
-		lines, err := vmx.Files(vid, options)
-		cobra.CheckErr(err)
+		lines, err := vmx.Files(ctx, vid, options)
+		exitOnError(err)
 		if OutputJSON {
 			result := make(map[string]any)
 			var label string
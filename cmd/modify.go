@@ -31,7 +31,9 @@ POSSIBILITY OF SUCH DAMAGE.
 package cmd
 
 import (
+	"encoding/base64"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/rstms/vmx/ws"
@@ -45,48 +47,57 @@ var modifyCmd = &cobra.Command{
 
 vnc modify [FLAGS] VID
 
-Change instance NIC, ISO, TTY, VNC, EFI configuration parameters.  
+Change instance NIC, ISO, TTY, VNC, EFI and guestinfo configuration parameters.  
 The instance must be powered off.
 
+Ethernet flags apply to the adapter selected with --nic; adapters 0 through
+9 are supported.  An adapter is added if it is not present.  The connection
+may be nat, bridged, hostonly, a custom network (vmnetN) or a LAN segment
+(segment:NAME).  Instances using the same segment NAME share the segment.
+
 See the flags and options help for descriptions of the available settings.
 Changes can be specified for multiple categories in a single command.
 `,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		InitController()
-		vm, err := vmx.Get(args[0])
-		cobra.CheckErr(err)
+		ctx := cmd.Context()
+		InitController(ctx)
+		vm, err := vmx.Get(ctx, args[0])
+		exitOnError(err)
 
 		options := ws.CreateOptions{}
 
 		// initX functions depend on zero-values in CreateOptions
 
 		err = initETHOptions(&options)
-		cobra.CheckErr(err)
+		exitOnError(err)
 
 		err = initTTYOptions(&options)
-		cobra.CheckErr(err)
+		exitOnError(err)
 
 		err = initVNCOptions(&options)
-		cobra.CheckErr(err)
+		exitOnError(err)
 
 		err = initEFIOptions(&options)
-		cobra.CheckErr(err)
+		exitOnError(err)
 
 		err = initShareOptions(&options)
-		cobra.CheckErr(err)
+		exitOnError(err)
 
 		err = initClipboardOptions(&options)
-		cobra.CheckErr(err)
+		exitOnError(err)
 
 		isoOptions, err := InitIsoOptions()
-		cobra.CheckErr(err)
+		exitOnError(err)
 
 		err = initUSBOptions(&options)
-		cobra.CheckErr(err)
+		exitOnError(err)
+
+		err = initGuestInfoOptions(&options, "modify")
+		exitOnError(err)
 
-		actions, err := vmx.Modify(vm.Name, options, *isoOptions)
-		cobra.CheckErr(err)
+		actions, err := vmx.Modify(ctx, vm.Name, options, *isoOptions)
+		exitOnError(err)
 		if OutputJSON {
 			output := make(map[string]any)
 			output[vm.Name] = actions
@@ -113,16 +124,39 @@ func initETHOptions(options *ws.CreateOptions) error {
 		if disable {
 			return Fatalf("conflict: eth_mac/eth_disable")
 		}
+	} else if enable {
+		address = "auto"
+	}
+	index, err := ws.ParseNICIndex(ViperGetString("modify.nic"))
+	if err != nil {
+		return Fatal(err)
+	}
+	connect := ViperGetBool("modify.nic_connect")
+	disconnect := ViperGetBool("modify.nic_disconnect")
+	if connect && disconnect {
+		return Fatalf("conflict: nic_connect/nic_disconnect")
+	}
+	nic := ws.NICOptions{
+		Index:                index,
+		Connection:           ViperGetString("modify.nic_type"),
+		Device:               ViperGetString("modify.nic_device"),
+		MacAddress:           address,
+		ModifyStartConnected: connect || disconnect,
+		StartConnected:       connect,
+	}
+	if nic.Connection != "" || nic.Device != "" || nic.ModifyStartConnected {
+		if disable {
+			return Fatalf("conflict: nic settings/eth_disable")
+		}
+		enable = true
 	}
 	switch {
 	case enable:
 		options.ModifyNIC = true
-		if address == "" {
-			address = "auto"
-		}
-		options.MacAddress = address
+		options.NIC = nic
 	case disable:
 		options.ModifyNIC = true
+		options.NIC = ws.NICOptions{Index: index, Remove: true}
 	}
 	return nil
 }
@@ -266,11 +300,54 @@ func initUSBOptions(options *ws.CreateOptions) error {
 	return nil
 }
 
+// guestinfo keys set from base64-encoded local files
+var guestInfoFileKeys = map[string]string{
+	"metadata": "guestinfo.metadata",
+	"userdata": "guestinfo.userdata",
+	"ignition": "guestinfo.ignition.config.data",
+}
+
+func initGuestInfoOptions(options *ws.CreateOptions, cmdName string) error {
+	values := make(map[string]string)
+	guestInfo := ViperGetString(cmdName + ".guestinfo")
+	if guestInfo != "" {
+		for _, item := range strings.Split(guestInfo, ",") {
+			key, value, ok := strings.Cut(item, "=")
+			if !ok || key == "" {
+				return Fatalf("failed parsing guestinfo: '%s'", item)
+			}
+			values[ws.GuestInfoKey(key)] = value
+		}
+	}
+	for option, key := range guestInfoFileKeys {
+		filename := ViperGetString(cmdName + "." + option)
+		if filename == "" {
+			continue
+		}
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return Fatal(err)
+		}
+		values[key] = base64.StdEncoding.EncodeToString(data)
+		values[key+".encoding"] = "base64"
+	}
+	if len(values) > 0 {
+		options.ModifyGuestInfo = true
+		options.GuestInfo = values
+	}
+	return nil
+}
+
 func init() {
 	CobraAddCommand(rootCmd, rootCmd, modifyCmd)
+	OptionString(modifyCmd, "nic", "", "0", "select ethernet adapter [0-9]")
 	OptionSwitch(modifyCmd, "eth-enable", "", "enable ethernet [auto-generated MAC]")
 	OptionString(modifyCmd, "eth-mac", "", "", "enable ethernet [user-defined MAC]")
 	OptionSwitch(modifyCmd, "eth-disable", "", "remove ethernet device")
+	OptionString(modifyCmd, "nic-type", "", "", "ethernet connection [nat|bridged|hostonly|vmnetN|segment:NAME]")
+	OptionString(modifyCmd, "nic-device", "", "", "ethernet device [e1000|e1000e|vmxnet3]")
+	OptionSwitch(modifyCmd, "nic-connect", "", "connect ethernet at power on")
+	OptionSwitch(modifyCmd, "nic-disconnect", "", "do not connect ethernet at power on")
 
 	OptionSwitch(modifyCmd, "vnc-enable", "", "enable instance VNC server")
 	OptionString(modifyCmd, "vnc-port", "", "5900", "VNC listen port")
@@ -299,6 +376,11 @@ func init() {
 	OptionString(modifyCmd, "usb1", "", "", "set USB device1 VID:PID")
 	OptionSwitch(modifyCmd, "no-usb1", "", "clear USB device1")
 
+	OptionString(modifyCmd, "guestinfo", "", "", "set guestinfo variables [format: 'key=value,...'; empty value removes]")
+	OptionString(modifyCmd, "metadata", "", "", "set guestinfo.metadata from file [base64]")
+	OptionString(modifyCmd, "userdata", "", "", "set guestinfo.userdata from file [base64]")
+	OptionString(modifyCmd, "ignition", "", "", "set guestinfo.ignition.config.data from file [base64]")
+
 	modifyCmd.MarkFlagsMutuallyExclusive("usb-allow-hid", "no-usb-allow-hid")
 	modifyCmd.MarkFlagsMutuallyExclusive("usb-allow-ccid", "no-usb-allow-ccid")
 	modifyCmd.MarkFlagsMutuallyExclusive("usb0", "no-usb0")
@@ -0,0 +1,71 @@
+/*
+Copyright © 2025 Matt Krueger <mkrueger@rstms.net>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+ 1. Redistributions of source code must retain the above copyright notice,
+    this list of conditions and the following disclaimer.
+
+ 2. Redistributions in binary form must reproduce the above copyright notice,
+    this list of conditions and the following disclaimer in the documentation
+    and/or other materials provided with the distribution.
+
+ 3. Neither the name of the copyright holder nor the names of its contributors
+    may be used to endorse or promote products derived from this software
+    without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+package cmd
+
+import (
+	"github.com/rstms/vmx/ws"
+	"github.com/spf13/cobra"
+)
+
+var rebootCmd = &cobra.Command{
+	Use:   "reboot VID",
+	Short: "reboot a VM instance",
+	Long: `
+Reset a running VM instance.  By default a soft reset is requested, which 
+requires VMware Tools in the guest.  Use --hard to reset without notifying
+the guest, or --escalate to fall back to a hard reset if the soft reset
+fails.  The power state is unchanged by a reset, so --wait awaits VMware
+Tools stopping and then running again in the guest; it requires VMware
+Tools to be running when the reset is requested.
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		InitController(ctx)
+		vid := args[0]
+		options := ws.StopOptions{
+			Wait:     ViperGetBool("wait"),
+			PowerOff: ViperGetBool("reboot.hard"),
+			Escalate: ViperGetBool("reboot.escalate"),
+		}
+		result, err := vmx.Reboot(ctx, vid, options)
+		exitOnError(err)
+		if OutputJSON && ViperGetBool("status") {
+			OutputInstanceState(ctx, vid, result)
+		}
+	},
+}
+
+func init() {
+	CobraAddCommand(rootCmd, rootCmd, rebootCmd)
+	OptionSwitch(rebootCmd, "hard", "", "reset without notifying the guest")
+	OptionSwitch(rebootCmd, "escalate", "", "hard reset if the soft reset fails")
+}
@@ -0,0 +1,89 @@
+/*
+Copyright © 2025 Matt Krueger <mkrueger@rstms.net>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+ 1. Redistributions of source code must retain the above copyright notice,
+    this list of conditions and the following disclaimer.
+
+ 2. Redistributions in binary form must reproduce the above copyright notice,
+    this list of conditions and the following disclaimer in the documentation
+    and/or other materials provided with the distribution.
+
+ 3. Neither the name of the copyright holder nor the names of its contributors
+    may be used to endorse or promote products derived from this software
+    without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/rstms/vmx/ws"
+	"github.com/spf13/cobra"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore ARCHIVE|VID [NAME]",
+	Short: "create an instance from a backup archive or repository snapshot",
+	Long: `
+Verify the file sizes and checksums of a backup archive written by 'vmx
+backup', then create the instance in the --root vmware_roots directory,
+which defaults to the first configured root.  NAME defaults to the name of
+the backed up instance.  An instance restored under a new name is given a
+new display name, auto-generated MAC addresses and a new BIOS UUID.
+
+With --repo, the first argument is the name of the backed up instance and
+the --snapshot ID, or the latest snapshot, is restored from the backup
+repository.
+`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		repo := ViperGetString("restore.repo")
+		if repo == "" && !IsFile(args[0]) {
+			exitOnError(Fatalf("archive not found: %s", args[0]))
+		}
+		name := ""
+		if len(args) > 1 {
+			name = args[1]
+		}
+		ctx := cmd.Context()
+		InitController(ctx)
+		options := ws.RestoreOptions{
+			Root:     ViperGetString("restore.root"),
+			Snapshot: ViperGetString("restore.snapshot"),
+		}
+		var result string
+		var err error
+		if repo != "" {
+			result, err = vmx.RestoreRepo(ctx, repo, args[0], name, options)
+		} else {
+			result, err = vmx.Restore(ctx, args[0], name, options)
+		}
+		exitOnError(err)
+		if ViperGetBool("verbose") {
+			fmt.Println(result)
+		}
+	},
+}
+
+func init() {
+	CobraAddCommand(rootCmd, rootCmd, restoreCmd)
+	OptionString(restoreCmd, "root", "", "", "vmware_roots directory for the restored instance")
+	OptionString(restoreCmd, "repo", "", "", "backup repository directory")
+	OptionString(restoreCmd, "snapshot", "", "", "backup repository snapshot ID")
+}
@@ -31,9 +31,12 @@ POSSIBILITY OF SUCH DAMAGE.
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 
 	"github.com/rstms/vmx/ws"
 	"github.com/spf13/cobra"
@@ -49,6 +52,25 @@ const (
 	OutputFormatJSON
 )
 
+// process exit status for each error type
+const (
+	ExitError       = 1
+	ExitNotFound    = 2
+	ExitExists      = 3
+	ExitEncrypted   = 4
+	ExitPowerState  = 5
+	ExitTimeout     = 6
+	ExitTransport   = 7
+	ExitInterrupted = 130
+)
+
+// ErrorResult is output for a failed command with --json
+type ErrorResult struct {
+	Error    string
+	Kind     string
+	ExitCode int
+}
+
 var vmx ws.Controller
 
 var rootCmd = &cobra.Command{
@@ -57,6 +79,20 @@ var rootCmd = &cobra.Command{
 	Short:   "control VMWare Workstation instances",
 	Long: `
 Control VMWare Workstation instances
+
+Exit status:
+  0    success
+  1    error
+  2    instance not found
+  3    instance exists
+  4    instance is encrypted
+  5    instance is in the wrong power state
+  6    timed out
+  7    transport error connecting to the VMware host
+  130  interrupted
+
+With --json, a failed command writes an object with Error, Kind and
+ExitCode to stdout.
 `,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		OutputJSON = true
@@ -74,18 +110,59 @@ Control VMWare Workstation instances
 	PersistentPostRun: func(cmd *cobra.Command, args []string) {
 		if vmx != nil {
 			err := vmx.Close()
-			cobra.CheckErr(err)
+			exitOnError(err)
 		}
 	},
 }
 
 func Execute() {
-	err := rootCmd.Execute()
+	// cancel controller operations in progress on SIGINT
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
 	if err != nil {
-		os.Exit(1)
+		status, _ := errorStatus(err)
+		os.Exit(status)
 	}
 }
 
+// return the exit status and kind name for err
+func errorStatus(err error) (int, string) {
+	var powerState *ws.ErrPowerState
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ExitInterrupted, "Interrupted"
+	case errors.Is(err, ws.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return ExitTimeout, "Timeout"
+	case errors.Is(err, ws.ErrTransport):
+		return ExitTransport, "Transport"
+	case errors.Is(err, ws.ErrNotFound):
+		return ExitNotFound, "NotFound"
+	case errors.Is(err, ws.ErrExists):
+		return ExitExists, "Exists"
+	case errors.Is(err, ws.ErrEncrypted):
+		return ExitEncrypted, "Encrypted"
+	case errors.As(err, &powerState):
+		return ExitPowerState, "PowerState"
+	}
+	return ExitError, "Error"
+}
+
+// output err and exit with its status; with --json the error is output
+// to stdout as an ErrorResult
+func exitOnError(err error) {
+	if err == nil {
+		return
+	}
+	status, kind := errorStatus(err)
+	if ViperGetBool("json") {
+		fmt.Println(FormatJSON(&ErrorResult{Error: err.Error(), Kind: kind, ExitCode: status}))
+	} else {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+	}
+	os.Exit(status)
+}
+
 func init() {
 	CobraInit(rootCmd)
 	OptionString(rootCmd, "timeout", "t", "60", "wait timeout in seconds")
@@ -93,7 +170,8 @@ func init() {
 	OptionSwitch(rootCmd, "json", "", "format output as JSON (default)")
 	OptionSwitch(rootCmd, "text", "", "format output as text")
 
-	OptionString(rootCmd, "shell", "", "ssh", "remote shell")
+	OptionString(rootCmd, "shell", "", "ssh", "remote shell: ssh, sshclient (built-in ssh client) or winexec")
+	OptionString(rootCmd, "backend", "", "cli", "controller backend: cli or vmrest")
 	OptionSwitch(rootCmd, "all", "a", "select all items")
 
 	OptionSwitch(rootCmd, "no-humanize", "n", "display sizes in bytes")
@@ -108,20 +186,23 @@ func init() {
 	OptionSwitch(rootCmd, "iso-attach", "", "set CD/DVD attached at boot")
 	OptionSwitch(rootCmd, "iso-detach", "", "set CD/DVD detached at boot")
 	OptionSwitch(rootCmd, "iso-disable", "", "remove the CD/DVD ISO device")
+
+	OptionString(rootCmd, "guest-user", "", "", "guest username for VMware Tools operations")
+	OptionString(rootCmd, "guest-password", "", "", "guest password for VMware Tools operations")
 }
 
-func InitController() {
-	c, err := ws.NewVMXController()
-	cobra.CheckErr(err)
+func InitController(ctx context.Context) {
+	c, err := ws.NewVMXController(ctx)
+	exitOnError(err)
 	if ViperGetBool("verbose") {
 		log.Printf("Controller: %s\n", FormatJSON(c))
 	}
 	vmx = c
 }
 
-func OutputInstanceState(vid, result string) {
-	state, err := vmx.GetState(vid)
-	cobra.CheckErr(err)
+func OutputInstanceState(ctx context.Context, vid, result string) {
+	state, err := vmx.GetState(ctx, vid)
+	exitOnError(err)
 	state.Result = result
 	fmt.Println(FormatJSON(&state))
 }
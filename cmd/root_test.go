@@ -1,6 +1,9 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"github.com/rstms/vmx/ws"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -15,3 +18,29 @@ func initTestConfig(t *testing.T) {
 func TestRoot(t *testing.T) {
 	initTestConfig(t)
 }
+
+func TestErrorStatus(t *testing.T) {
+	wrapped := func(err error) error {
+		return fmt.Errorf("[web1] %w", err)
+	}
+	cases := []struct {
+		err    error
+		status int
+		kind   string
+	}{
+		{fmt.Errorf("failed"), ExitError, "Error"},
+		{wrapped(ws.ErrNotFound), ExitNotFound, "NotFound"},
+		{wrapped(ws.ErrExists), ExitExists, "Exists"},
+		{wrapped(ws.ErrEncrypted), ExitEncrypted, "Encrypted"},
+		{wrapped(&ws.ErrPowerState{Want: "off", Have: "on"}), ExitPowerState, "PowerState"},
+		{wrapped(ws.ErrTimeout), ExitTimeout, "Timeout"},
+		{wrapped(context.DeadlineExceeded), ExitTimeout, "Timeout"},
+		{wrapped(ws.ErrTransport), ExitTransport, "Transport"},
+		{wrapped(context.Canceled), ExitInterrupted, "Interrupted"},
+	}
+	for _, c := range cases {
+		status, kind := errorStatus(c.err)
+		require.Equal(t, c.status, status, c.err.Error())
+		require.Equal(t, c.kind, kind, c.err.Error())
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright © 2025 Matt Krueger <mkrueger@rstms.net>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+ 1. Redistributions of source code must retain the above copyright notice,
+    this list of conditions and the following disclaimer.
+
+ 2. Redistributions in binary form must reproduce the above copyright notice,
+    this list of conditions and the following disclaimer in the documentation
+    and/or other materials provided with the distribution.
+
+ 3. Neither the name of the copyright holder nor the names of its contributors
+    may be used to endorse or promote products derived from this software
+    without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rstms/vmx/ws"
+	"github.com/spf13/cobra"
+)
+
+var seedCmd = &cobra.Command{
+	Use:   "seed VID",
+	Short: "attach cloud-init seed media",
+	Long: `
+Generate a cloud-init NoCloud 'cidata' ISO from the user-data, meta-data
+and optional network-config files, upload it into the instance directory
+and attach it as a second CD-ROM.  If --meta-data is not specified, the
+instance-id and local-hostname are set to the instance name.  The instance
+must be powered off.  Use --remove to detach and delete the seed ISO.
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		InitController(ctx)
+		vm, err := vmx.Get(ctx, args[0])
+		exitOnError(err)
+		options := ws.SeedOptions{Remove: ViperGetBool("seed.remove")}
+		if !options.Remove {
+			if ViperGetString("seed.user_data") == "" {
+				exitOnError(Fatalf("missing --user-data"))
+			}
+			options.UserData, err = readSeedFile("seed.user_data")
+			exitOnError(err)
+			options.MetaData, err = readSeedFile("seed.meta_data")
+			exitOnError(err)
+			options.NetworkConfig, err = readSeedFile("seed.network_config")
+			exitOnError(err)
+		}
+		result, err := vmx.Seed(ctx, vm.Name, options)
+		exitOnError(err)
+		if ViperGetBool("verbose") {
+			fmt.Printf("[%s] %s\n", vm.Name, result)
+		}
+	},
+}
+
+func readSeedFile(key string) ([]byte, error) {
+	filename := ViperGetString(key)
+	if filename == "" {
+		return []byte{}, nil
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, Fatal(err)
+	}
+	return data, nil
+}
+
+func init() {
+	CobraAddCommand(rootCmd, rootCmd, seedCmd)
+	OptionString(seedCmd, "user-data", "", "", "cloud-init user-data file")
+	OptionString(seedCmd, "meta-data", "", "", "cloud-init meta-data file")
+	OptionString(seedCmd, "network-config", "", "", "cloud-init network-config file")
+	OptionSwitch(seedCmd, "remove", "", "detach and delete the seed ISO")
+}
@@ -55,9 +55,10 @@ vmx sendkeys testvm 'echo $PATH\n'
 	Run: func(cmd *cobra.Command, args []string) {
 		vid := args[0]
 		keys := args[1]
-		InitController()
-		err := vmx.SendKeys(vid, keys)
-		cobra.CheckErr(err)
+		ctx := cmd.Context()
+		InitController(ctx)
+		err := vmx.SendKeys(ctx, vid, keys)
+		exitOnError(err)
 	},
 }
 
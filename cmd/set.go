@@ -49,14 +49,15 @@ Properties such as "power" can modify the running state of the VM
 `,
 	Args: cobra.ExactArgs(3),
 	Run: func(cmd *cobra.Command, args []string) {
-		InitController()
+		ctx := cmd.Context()
+		InitController(ctx)
 		vid := args[0]
 		name := args[1]
 		value := args[2]
-		vm, err := vmx.Get(vid)
-		cobra.CheckErr(err)
-		err = vmx.SetProperty(vm.Id, name, value)
-		cobra.CheckErr(err)
+		vm, err := vmx.Get(ctx, vid)
+		exitOnError(err)
+		err = vmx.SetProperty(ctx, vm.Id, name, value)
+		exitOnError(err)
 	},
 }
 
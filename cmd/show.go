@@ -46,7 +46,8 @@ Display VM instance data
 	Aliases: []string{"ps"},
 	Args:    cobra.RangeArgs(0, 1),
 	Run: func(cmd *cobra.Command, args []string) {
-		InitController()
+		ctx := cmd.Context()
+		InitController(ctx)
 		vid := ""
 		if len(args) > 0 {
 			vid = args[0]
@@ -55,8 +56,8 @@ Display VM instance data
 			Detail:  ViperGetBool("detail"),
 			Running: !ViperGetBool("all"),
 		}
-		vms, err := vmx.Show(vid, options)
-		cobra.CheckErr(err)
+		vms, err := vmx.Show(ctx, vid, options)
+		exitOnError(err)
 		result := make(map[string]any)
 		running := "all_"
 		if options.Running {
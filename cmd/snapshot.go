@@ -0,0 +1,165 @@
+/*
+Copyright © 2025 Matt Krueger <mkrueger@rstms.net>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+ 1. Redistributions of source code must retain the above copyright notice,
+    this list of conditions and the following disclaimer.
+
+ 2. Redistributions in binary form must reproduce the above copyright notice,
+    this list of conditions and the following disclaimer in the documentation
+    and/or other materials provided with the distribution.
+
+ 3. Neither the name of the copyright holder nor the names of its contributors
+    may be used to endorse or promote products derived from this software
+    without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rstms/vmx/ws"
+	"github.com/spf13/cobra"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "manage instance snapshots",
+	Long: `
+Create, list, revert, and delete VM instance snapshots using the vmrun 
+utility on the host.
+`,
+}
+
+var snapshotCreateCmd = &cobra.Command{
+	Use:   "create VID NAME",
+	Short: "create a snapshot",
+	Long: `
+Create a snapshot of the selected instance with the given NAME.
+`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		InitController(ctx)
+		vid := args[0]
+		name := args[1]
+		result, err := vmx.CreateSnapshot(ctx, vid, name)
+		exitOnError(err)
+		if OutputJSON && ViperGetBool("status") {
+			OutputInstanceState(ctx, vid, result)
+		}
+	},
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:     "list VID",
+	Aliases: []string{"ls"},
+	Short:   "list snapshots",
+	Long: `
+Output the snapshot tree of the selected instance.  Each snapshot includes 
+the name of its parent and its child snapshots.
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		InitController(ctx)
+		vm, err := vmx.Get(ctx, args[0])
+		exitOnError(err)
+		snapshots, err := vmx.ListSnapshots(ctx, vm.Name)
+		exitOnError(err)
+		if OutputJSON {
+			output := make(map[string]any)
+			output[vm.Name] = snapshots
+			fmt.Println(FormatJSON(output))
+		} else {
+			printSnapshotTree(snapshots, 0)
+		}
+	},
+}
+
+func printSnapshotTree(snapshots []*ws.Snapshot, depth int) {
+	for _, snapshot := range snapshots {
+		fmt.Printf("%s%s\n", strings.Repeat("  ", depth), snapshot.Name)
+		printSnapshotTree(snapshot.Children, depth+1)
+	}
+}
+
+var snapshotRevertCmd = &cobra.Command{
+	Use:   "revert VID NAME",
+	Short: "revert to a snapshot",
+	Long: `
+Revert the selected instance to the named snapshot.  The instance takes the
+power state saved in the snapshot.  Use --start to power on the instance 
+after the revert.  With --wait, the command returns when the instance
+reaches the saved power state: on for a snapshot taken while running,
+otherwise off.
+`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		InitController(ctx)
+		vid := args[0]
+		name := args[1]
+		options := ws.SnapshotOptions{
+			Wait:       ViperGetBool("wait"),
+			PowerOn:    ViperGetBool("revert.start"),
+			Background: ViperGetBool("revert.background"),
+		}
+		result, err := vmx.RevertSnapshot(ctx, vid, name, options)
+		exitOnError(err)
+		if OutputJSON && ViperGetBool("status") {
+			OutputInstanceState(ctx, vid, result)
+		}
+	},
+}
+
+var snapshotDeleteCmd = &cobra.Command{
+	Use:   "delete VID NAME",
+	Short: "delete a snapshot",
+	Long: `
+Delete the named snapshot of the selected instance.  Use --children to also
+delete all snapshots descended from it.
+`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		InitController(ctx)
+		vid := args[0]
+		name := args[1]
+		options := ws.SnapshotOptions{
+			DeleteChildren: ViperGetBool("delete.children"),
+		}
+		result, err := vmx.DeleteSnapshot(ctx, vid, name, options)
+		exitOnError(err)
+		if OutputJSON && ViperGetBool("status") {
+			OutputInstanceState(ctx, vid, result)
+		}
+	},
+}
+
+func init() {
+	CobraAddCommand(rootCmd, rootCmd, snapshotCmd)
+	CobraAddCommand(rootCmd, snapshotCmd, snapshotCreateCmd)
+	CobraAddCommand(rootCmd, snapshotCmd, snapshotListCmd)
+	CobraAddCommand(rootCmd, snapshotCmd, snapshotRevertCmd)
+	OptionSwitch(snapshotRevertCmd, "start", "", "power on the instance after revert")
+	OptionSwitch(snapshotRevertCmd, "background", "", "start in background mode")
+	CobraAddCommand(rootCmd, snapshotCmd, snapshotDeleteCmd)
+	OptionSwitch(snapshotDeleteCmd, "children", "", "also delete child snapshots")
+}
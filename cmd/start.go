@@ -47,15 +47,16 @@ This application is a tool to generate the needed files
 to quickly create a Cobra application.`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		InitController()
+		ctx := cmd.Context()
+		InitController(ctx)
 		vid := args[0]
 
 		if cmd.CalledAs() == "restart" {
 			options := ws.StopOptions{
 				Wait: true,
 			}
-			_, err := vmx.Stop(vid, options)
-			cobra.CheckErr(err)
+			_, err := vmx.Stop(ctx, vid, options)
+			exitOnError(err)
 		}
 
 		options := ws.StartOptions{
@@ -75,12 +76,12 @@ to quickly create a Cobra application.`,
 		}
 
 		isoOptions, err := InitIsoOptions()
-		cobra.CheckErr(err)
+		exitOnError(err)
 
-		result, err := vmx.Start(vid, options, *isoOptions)
-		cobra.CheckErr(err)
+		result, err := vmx.Start(ctx, vid, options, *isoOptions)
+		exitOnError(err)
 		if OutputJSON && ViperGetBool("status") {
-			OutputInstanceState(vid, result)
+			OutputInstanceState(ctx, vid, result)
 		}
 	},
 }
@@ -42,9 +42,10 @@ Show the status of the selected instance.
 `,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		InitController()
+		ctx := cmd.Context()
+		InitController(ctx)
 		vid := args[0]
-		OutputInstanceState(vid, "status")
+		OutputInstanceState(ctx, vid, "status")
 	},
 }
 
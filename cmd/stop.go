@@ -46,16 +46,19 @@ This application is a tool to generate the needed files
 to quickly create a Cobra application.`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		InitController()
+		ctx := cmd.Context()
+		InitController(ctx)
 		vid := args[0]
 		options := ws.StopOptions{
-			Wait:     ViperGetBool("wait"),
-			PowerOff: ViperGetBool("poweroff"),
+			Wait:         ViperGetBool("wait"),
+			PowerOff:     ViperGetBool("stop.poweroff"),
+			Escalate:     ViperGetBool("stop.escalate"),
+			GraceSeconds: ViperGetInt64("stop.grace"),
 		}
-		result, err := vmx.Stop(vid, options)
-		cobra.CheckErr(err)
+		result, err := vmx.Stop(ctx, vid, options)
+		exitOnError(err)
 		if OutputJSON && ViperGetBool("status") {
-			OutputInstanceState(vid, result)
+			OutputInstanceState(ctx, vid, result)
 		}
 	},
 }
@@ -63,4 +66,6 @@ to quickly create a Cobra application.`,
 func init() {
 	CobraAddCommand(rootCmd, rootCmd, stopCmd)
 	OptionSwitch(stopCmd, "poweroff", "", "BRS operation (forced power down)")
+	OptionSwitch(stopCmd, "escalate", "", "force power down if shutdown does not complete")
+	OptionInt(stopCmd, "grace", "", 0, "seconds to await shutdown before escalating [default: grace_seconds]")
 }
@@ -31,8 +31,12 @@ POSSIBILITY OF SUCH DAMAGE.
 package cmd
 
 import (
+	"fmt"
+	"github.com/rstms/vmx/ws"
 	"github.com/spf13/cobra"
+	"os"
 	"path/filepath"
+	"strings"
 )
 
 var uploadCmd = &cobra.Command{
@@ -43,10 +47,14 @@ Copy a LOCAL_FILE to the host VMWare directory of the selected instance.
 
 LOCAL_FILE may be a pathname or a filename.  If HOST_FILENAME is not provided
 the filename of LOCAL_FILE will used as HOST_FILENAME.
+
+When HOST_FILENAME is the instance VMX file, LOCAL_FILE is checked as in
+'vmx edit' and is not uploaded if it has lint errors unless --force is set.
 `,
 	Args: cobra.RangeArgs(2, 3),
 	Run: func(cmd *cobra.Command, args []string) {
-		InitController()
+		ctx := cmd.Context()
+		InitController(ctx)
 
 		localPathname := args[0]
 		vid := args[1]
@@ -55,11 +63,26 @@ the filename of LOCAL_FILE will used as HOST_FILENAME.
 		if len(args) > 2 {
 			hostFilename = args[2]
 		}
-		err := vmx.Upload(vid, localPathname, hostFilename)
-		cobra.CheckErr(err)
+		vm, err := vmx.Get(ctx, vid)
+		exitOnError(err)
+		if strings.EqualFold(hostFilename, vm.Name+".vmx") {
+			data, err := os.ReadFile(localPathname)
+			exitOnError(err)
+			issues, err := vmx.Lint(ctx, vm.Id, data)
+			exitOnError(err)
+			for _, issue := range issues {
+				fmt.Fprintf(os.Stderr, "[%s] %s\n", vm.Name, issue)
+			}
+			if ws.LintHasErrors(issues) && !ViperGetBool("upload.force") {
+				exitOnError(Fatalf("lint errors; not uploading %s (use --force to override)", localPathname))
+			}
+		}
+		err = vmx.Upload(ctx, vid, localPathname, hostFilename)
+		exitOnError(err)
 	},
 }
 
 func init() {
 	CobraAddCommand(rootCmd, rootCmd, uploadCmd)
+	OptionSwitch(uploadCmd, "force", "", "upload a VMX file despite lint errors")
 }
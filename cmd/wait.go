@@ -47,9 +47,10 @@ The default timeout (60 seconds) can be adjusted with the --timeout option
 	Run: func(cmd *cobra.Command, args []string) {
 		vid := args[0]
 		state := args[1]
-		InitController()
-		err := vmx.Wait(vid, state)
-		cobra.CheckErr(err)
+		ctx := cmd.Context()
+		InitController(ctx)
+		err := vmx.Wait(ctx, vid, state)
+		exitOnError(err)
 	},
 }
 
@@ -0,0 +1,189 @@
+package ws
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// archiveEntry is a file written to an archive from Path, or from Data if Path is empty
+type archiveEntry struct {
+	Name string
+	Path string
+	Data []byte
+}
+
+func (e *archiveEntry) open() (io.ReadCloser, int64, error) {
+	if e.Path == "" {
+		return io.NopCloser(bytes.NewReader(e.Data)), int64(len(e.Data)), nil
+	}
+	file, err := os.Open(e.Path)
+	if err != nil {
+		return nil, 0, wrap(err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, wrap(err)
+	}
+	return file, info.Size(), nil
+}
+
+func (e *archiveEntry) copyTo(filename string) error {
+	r, _, err := e.open()
+	if err != nil {
+		return wrap(err)
+	}
+	defer r.Close()
+	file, err := os.Create(filename)
+	if err != nil {
+		return wrap(err)
+	}
+	_, err = io.Copy(file, r)
+	if err != nil {
+		file.Close()
+		return wrap(err)
+	}
+	return file.Close()
+}
+
+// return the hex encoded SHA256 digest and size of the entry contents
+func (e *archiveEntry) SHA256() (string, int64, error) {
+	r, _, err := e.open()
+	if err != nil {
+		return "", 0, wrap(err)
+	}
+	defer r.Close()
+	digest := sha256.New()
+	size, err := io.Copy(digest, r)
+	if err != nil {
+		return "", 0, wrap(err)
+	}
+	return hex.EncodeToString(digest.Sum(nil)), size, nil
+}
+
+func writeTarEntry(archive *tar.Writer, entry archiveEntry) error {
+	r, size, err := entry.open()
+	if err != nil {
+		return wrap(err)
+	}
+	defer r.Close()
+	err = archive.WriteHeader(&tar.Header{
+		Name:   entry.Name,
+		Mode:   0644,
+		Size:   size,
+		Format: tar.FormatUSTAR,
+	})
+	if err != nil {
+		return wrap(err)
+	}
+	_, err = io.Copy(archive, r)
+	if err != nil {
+		return wrap(err)
+	}
+	return nil
+}
+
+// write entries to a tar archive in order
+func WriteTar(w io.Writer, entries []archiveEntry) error {
+	archive := tar.NewWriter(w)
+	for _, entry := range entries {
+		err := writeTarEntry(archive, entry)
+		if err != nil {
+			return wrap(err)
+		}
+	}
+	return archive.Close()
+}
+
+// return a writer compressing to w selected by the filename suffix: .zst, .gz or none for .tar
+func compressWriter(w io.Writer, filename string) (io.WriteCloser, error) {
+	lower := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(lower, ".zst") || strings.HasSuffix(lower, ".tzst"):
+		return zstd.NewWriter(w)
+	case strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz"):
+		return gzip.NewWriter(w), nil
+	case strings.HasSuffix(lower, ".tar"):
+		return nopWriteCloser{w}, nil
+	}
+	return nil, Fatalf("unsupported archive type: %s", filename)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// return a reader decompressing r if it has a zstd or gzip header
+func decompressReader(r io.Reader) (io.ReadCloser, error) {
+	reader := bufio.NewReader(r)
+	magic, err := reader.Peek(4)
+	if err != nil && err != io.EOF {
+		return nil, wrap(err)
+	}
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		decoder, err := zstd.NewReader(reader)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		return decoder.IOReadCloser(), nil
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		return gz, nil
+	}
+	return io.NopCloser(reader), nil
+}
+
+// extract the regular files of a flat tar archive to dir, returning the filenames in archive order
+func ExtractTar(r io.Reader, dir string) ([]string, error) {
+	archive := tar.NewReader(r)
+	names := []string{}
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, wrap(err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := strings.TrimPrefix(path.Clean(header.Name), "./")
+		if strings.ContainsAny(name, "/\\") || name == "." || name == ".." {
+			return nil, Fatalf("invalid archive entry: '%s'", header.Name)
+		}
+		file, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return nil, wrap(err)
+		}
+		_, err = io.Copy(file, archive)
+		if err != nil {
+			file.Close()
+			return nil, wrap(err)
+		}
+		err = file.Close()
+		if err != nil {
+			return nil, wrap(err)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
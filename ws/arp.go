@@ -1,6 +1,7 @@
 package ws
 
 import (
+	"context"
 	"log"
 	"regexp"
 	"strings"
@@ -15,7 +16,7 @@ func ArpScan(mac string, lines []string) (string, error) {
 		m := ARP_ADDR.FindStringSubmatch(line)
 		if len(m) == 3 {
 			//log.Printf("ip=%s mac=%s\n", m[1], m[2])
-			if m[2] == colonsMac || m[2] == dashesMac {
+			if strings.EqualFold(m[2], colonsMac) || strings.EqualFold(m[2], dashesMac) {
 				//log.Printf("MATCHED: %s\n", line)
 				return m[1], nil
 			}
@@ -24,8 +25,8 @@ func ArpScan(mac string, lines []string) (string, error) {
 	return "", nil
 }
 
-func (v *vmctl) ArpQuery(vm *VM) (string, error) {
-	//log.Printf("trying arp query for %s\n", vm.MacAddress)
+// set the IpAddress of each instance NIC found in the host ARP table
+func (v *vmctl) ArpQuery(ctx context.Context, vm *VM) error {
 	var command string
 	switch v.Remote {
 	case "windows":
@@ -36,15 +37,21 @@ func (v *vmctl) ArpQuery(vm *VM) (string, error) {
 		command = "arp -n"
 	default:
 		log.Printf("WARNING: arp query not implemented for remote os: '%s'", v.Remote)
-		return "", nil
+		return nil
 	}
-	lines, err := v.RemoteExec(command, nil)
+	lines, err := v.RemoteExec(ctx, command, nil)
 	if err != nil {
-		return "", Fatal(err)
+		return wrap(err)
 	}
-	addr, err := ArpScan(vm.MacAddress, lines)
-	if err != nil {
-		return "", Fatal(err)
+	for i, nic := range vm.Nics {
+		if nic.MacAddress == "" || nic.IpAddress != "" {
+			continue
+		}
+		addr, err := ArpScan(nic.MacAddress, lines)
+		if err != nil {
+			return wrap(err)
+		}
+		vm.Nics[i].IpAddress = addr
 	}
-	return addr, nil
+	return nil
 }
@@ -0,0 +1,399 @@
+package ws
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const BACKUP_MANIFEST_FILE = "vmx-backup.json"
+const BACKUP_VERSION = 1
+
+var WINDOWS_DIR_FILE_LINE = regexp.MustCompile(`^\d{1,4}[/.-]\d{1,2}[/.-]\d{1,4}\s+\d{1,2}:\d{2}(?:\s*[AaPp][Mm])?\s+(\d+)\s+(.+)$`)
+
+type BackupFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// BackupManifest is the last entry of a backup archive
+type BackupManifest struct {
+	Version    int          `json:"version"`
+	Name       string       `json:"name"`
+	Created    time.Time    `json:"created"`
+	PowerState string       `json:"power_state"`
+	VMX        string       `json:"vmx"`
+	Files      []BackupFile `json:"files"`
+}
+
+type RestoreOptions struct {
+	Root     string // vmware_roots directory; the first root is used if empty
+	Snapshot string // backup repository snapshot ID; the latest is used if empty
+}
+
+// return the regular file names and sizes from 'ls -al' or 'dir /-C' output
+func parseDirListing(hostOS string, lines []string) []BackupFile {
+	files := []BackupFile{}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if hostOS == "windows" {
+			m := WINDOWS_DIR_FILE_LINE.FindStringSubmatch(line)
+			if m != nil {
+				size, err := strconv.ParseInt(m[1], 10, 64)
+				if err == nil {
+					files = append(files, BackupFile{Name: m[2], Size: size})
+				}
+			}
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) >= 9 && strings.HasPrefix(fields[0], "-") {
+			size, err := strconv.ParseInt(fields[4], 10, 64)
+			if err == nil {
+				files = append(files, BackupFile{Name: strings.Join(fields[8:], " "), Size: size})
+			}
+		}
+	}
+	return files
+}
+
+// return the regular files in the instance directory, excluding lock files
+func (v *vmctl) instanceFiles(ctx context.Context, vm *VM) ([]BackupFile, error) {
+	dir, _ := path.Split(vm.Path)
+	lines, err := v.listFiles(ctx, dir, true, ALL_PATTERN)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	files := []BackupFile{}
+	for _, file := range parseDirListing(v.Remote, lines) {
+		if !strings.HasSuffix(strings.ToLower(file.Name), ".lck") {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// return the archive filename for a backup of name to dest, which may be a directory
+func backupFilename(name, dest string) string {
+	if IsDir(dest) {
+		return filepath.Join(dest, fmt.Sprintf("%s-%s.tar.zst", name, time.Now().UTC().Format("20060102T150405Z")))
+	}
+	return dest
+}
+
+// write a backup archive of the instance directory; the instance must be off or suspended
+func (v *vmctl) Backup(ctx context.Context, vid, dest string) (string, error) {
+	if v.debug {
+		log.Printf("Backup(%s, %s)\n", vid, dest)
+	}
+	vm, err := v.cli.GetVM(ctx, vid)
+	if err != nil {
+		return "", wrap(err)
+	}
+	err = v.cli.QueryPowerState(ctx, &vm)
+	if err != nil {
+		return "", wrap(err)
+	}
+	if vm.PowerState != "off" && vm.PowerState != "suspended" {
+		return "", wrapf("[%s] cannot backup instance: %w", vm.Name, &ErrPowerState{Want: "off", Have: vm.PowerState})
+	}
+	files, err := v.instanceFiles(ctx, &vm)
+	if err != nil {
+		return "", wrap(err)
+	}
+	manifest := BackupManifest{
+		Version:    BACKUP_VERSION,
+		Name:       vm.Name,
+		Created:    time.Now().UTC(),
+		PowerState: vm.PowerState,
+		Files:      []BackupFile{},
+	}
+	filename := backupFilename(vm.Name, dest)
+
+	// write to a temporary name so an incomplete archive is never mistaken for a backup
+	partial := filename + ".partial"
+	file, err := os.Create(partial)
+	if err != nil {
+		return "", wrap(err)
+	}
+	defer os.Remove(partial)
+	defer file.Close()
+	compressor, err := compressWriter(file, filename)
+	if err != nil {
+		return "", wrap(err)
+	}
+	archive := tar.NewWriter(compressor)
+
+	tempDir, err := os.MkdirTemp("", "vmx_backup.*")
+	if err != nil {
+		return "", wrap(err)
+	}
+	defer os.RemoveAll(tempDir)
+	for _, file := range files {
+		name := file.Name
+		if v.verbose {
+			fmt.Printf("[%s] Downloading %s\n", vm.Name, name)
+		}
+		localPath := filepath.Join(tempDir, name)
+		err := v.Download(ctx, vm.Name, localPath, name)
+		if err != nil {
+			return "", wrap(err)
+		}
+		entry := archiveEntry{Name: name, Path: localPath}
+		digest, size, err := entry.SHA256()
+		if err != nil {
+			return "", wrap(err)
+		}
+		if strings.EqualFold(name, vm.Name+".vmx") {
+			data, err := os.ReadFile(localPath)
+			if err != nil {
+				return "", wrap(err)
+			}
+			manifest.VMX = string(data)
+		}
+		err = writeTarEntry(archive, entry)
+		if err != nil {
+			return "", wrap(err)
+		}
+		os.Remove(localPath)
+		manifest.Files = append(manifest.Files, BackupFile{Name: name, Size: size, SHA256: digest})
+	}
+	if manifest.VMX == "" {
+		return "", Fatalf("[%s] VMX file not found in instance directory", vm.Name)
+	}
+
+	// fail if the instance was started during the backup
+	err = v.cli.QueryPowerState(ctx, &vm)
+	if err != nil {
+		return "", wrap(err)
+	}
+	if vm.PowerState != manifest.PowerState {
+		return "", wrapf("[%s] power state changed during backup: %w", vm.Name, &ErrPowerState{Want: manifest.PowerState, Have: vm.PowerState})
+	}
+
+	data, err := json.MarshalIndent(&manifest, "", "  ")
+	if err != nil {
+		return "", wrap(err)
+	}
+	err = writeTarEntry(archive, archiveEntry{Name: BACKUP_MANIFEST_FILE, Data: append(data, '\n')})
+	if err != nil {
+		return "", wrap(err)
+	}
+	err = archive.Close()
+	if err != nil {
+		return "", wrap(err)
+	}
+	err = compressor.Close()
+	if err != nil {
+		return "", wrap(err)
+	}
+	err = file.Close()
+	if err != nil {
+		return "", wrap(err)
+	}
+	err = os.Rename(partial, filename)
+	if err != nil {
+		return "", wrap(err)
+	}
+	return fmt.Sprintf("Backed up %d files to %s", len(manifest.Files), filename), nil
+}
+
+// read the backup manifest from an extracted archive and verify the file sizes and checksums
+func VerifyBackup(dir string) (*BackupManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, BACKUP_MANIFEST_FILE))
+	if err != nil {
+		return nil, Fatalf("backup manifest not found; the archive may be incomplete")
+	}
+	var manifest BackupManifest
+	err = json.Unmarshal(data, &manifest)
+	if err != nil {
+		return nil, Fatalf("invalid backup manifest: %v", err)
+	}
+	if manifest.Version != BACKUP_VERSION {
+		return nil, Fatalf("unsupported backup version: %d", manifest.Version)
+	}
+	for _, file := range manifest.Files {
+		if strings.ContainsAny(file.Name, "/\\") {
+			return nil, Fatalf("invalid backup filename: '%s'", file.Name)
+		}
+		entry := archiveEntry{Name: file.Name, Path: filepath.Join(dir, file.Name)}
+		digest, size, err := entry.SHA256()
+		if err != nil {
+			return nil, wrap(err)
+		}
+		if size != file.Size || digest != file.SHA256 {
+			return nil, Fatalf("backup checksum mismatch: %s", file.Name)
+		}
+	}
+	return &manifest, nil
+}
+
+// return the configured vmware_roots directory matching root
+func (v *vmctl) selectRoot(root string) (string, error) {
+	if root == "" {
+		return v.Roots[0], nil
+	}
+	normalized, err := PathNormalize(root)
+	if err != nil {
+		return "", wrap(err)
+	}
+	for _, vmRoot := range v.Roots {
+		if strings.EqualFold(strings.TrimRight(vmRoot, "/"), strings.TrimRight(normalized, "/")) {
+			return vmRoot, nil
+		}
+	}
+	return "", Fatalf("not a vmware_roots directory: %s", root)
+}
+
+// return the instance filename for a file restored from instance oldName to name
+func restoreFilename(oldName, name, file string) string {
+	ext := filepath.Ext(file)
+	switch strings.ToLower(ext) {
+	case ".vmx", ".vmsd", ".vmxf", ".nvram":
+		if strings.EqualFold(strings.TrimSuffix(file, ext), oldName) {
+			return name + ext
+		}
+	}
+	return file
+}
+
+// create an instance from a backup archive after verifying its checksums; an empty name uses the original name
+func (v *vmctl) Restore(ctx context.Context, filename, name string, options RestoreOptions) (string, error) {
+	if v.debug {
+		log.Printf("Restore(%s, %s, %+v)\n", filename, name, options)
+	}
+	root, err := v.selectRoot(options.Root)
+	if err != nil {
+		return "", wrap(err)
+	}
+	tempDir, err := os.MkdirTemp("", "vmx_restore.*")
+	if err != nil {
+		return "", wrap(err)
+	}
+	defer os.RemoveAll(tempDir)
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", wrap(err)
+	}
+	defer file.Close()
+	reader, err := decompressReader(file)
+	if err != nil {
+		return "", wrap(err)
+	}
+	defer reader.Close()
+	if v.verbose {
+		fmt.Printf("Extracting %s\n", filename)
+	}
+	_, err = ExtractTar(reader, tempDir)
+	if err != nil {
+		return "", wrap(err)
+	}
+	manifest, err := VerifyBackup(tempDir)
+	if err != nil {
+		return "", wrap(err)
+	}
+	return v.restoreInstance(ctx, manifest, tempDir, filename, name, root)
+}
+
+// create instance name in root from backup files extracted to dir
+func (v *vmctl) restoreInstance(ctx context.Context, manifest *BackupManifest, dir, source, name, root string) (string, error) {
+	if name == "" {
+		name = manifest.Name
+	}
+	_, err := v.cli.GetVM(ctx, name)
+	if err == nil {
+		return "", wrapf("restore failed, instance '%s' %w", name, ErrExists)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return "", wrap(err)
+	}
+
+	vmx, err := InitVMX(v.Remote, name, []byte(manifest.VMX))
+	if err != nil {
+		return "", wrap(err)
+	}
+	actions := []string{}
+	if name != manifest.Name {
+		// a copy under a new name gets a new identity
+		for _, edit := range []func() (string, error){
+			func() (string, error) { return vmx.SetName(name) },
+			vmx.ResetMacAddresses,
+			vmx.SetUUID,
+		} {
+			action, err := edit()
+			if err != nil {
+				return "", wrap(err)
+			}
+			actions = append(actions, action)
+		}
+		for _, key := range []string{"nvram", "extendedConfigFile"} {
+			value, ok := vmx.doc.Get(key)
+			if ok {
+				vmx.doc.Set(key, restoreFilename(manifest.Name, name, value))
+			}
+		}
+	}
+	vmxData, err := vmx.Read()
+	if err != nil {
+		return "", wrap(err)
+	}
+
+	vm, err := v.createInstanceDir(ctx, root, name)
+	if err != nil {
+		return "", wrap(err)
+	}
+	err = v.uploadRestoreFiles(ctx, manifest, dir, name, vm, vmxData)
+	if err != nil {
+		// don't leave a partial instance directory behind, even if ctx was canceled
+		rmErr := v.removeInstanceDir(context.WithoutCancel(ctx), vm)
+		if rmErr != nil {
+			log.Printf("WARNING: [%s] failed removing instance directory: %v\n", name, rmErr)
+		}
+		return "", wrap(err)
+	}
+	if v.verbose {
+		for _, action := range actions {
+			fmt.Printf("[%s] %s\n", vm.Name, action)
+		}
+	}
+	return fmt.Sprintf("Restored %s from %s", vm.Name, source), nil
+}
+
+// upload the backup files to the new instance directory, writing the VMX last
+func (v *vmctl) uploadRestoreFiles(ctx context.Context, manifest *BackupManifest, dir, name string, vm *VM, vmxData []byte) error {
+	instanceDir, _ := path.Split(vm.Path)
+	for _, file := range manifest.Files {
+		instanceFile := restoreFilename(manifest.Name, name, file.Name)
+		if strings.EqualFold(instanceFile, name+".vmx") {
+			continue
+		}
+		if v.verbose {
+			fmt.Printf("[%s] Uploading %s\n", vm.Name, instanceFile)
+		}
+		err := v.UploadFile(ctx, vm, filepath.Join(dir, file.Name), path.Join(instanceDir, instanceFile))
+		if err != nil {
+			return wrap(err)
+		}
+	}
+	localVMX := filepath.Join(dir, BACKUP_MANIFEST_FILE+".vmx")
+	err := os.WriteFile(localVMX, vmxData, 0600)
+	if err != nil {
+		return wrap(err)
+	}
+	err = v.UploadFile(ctx, vm, localVMX, vm.Path)
+	if err != nil {
+		return wrap(err)
+	}
+	return nil
+}
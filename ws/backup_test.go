@@ -0,0 +1,140 @@
+package ws
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseDirListing(t *testing.T) {
+	linux := []string{
+		"total 2097172",
+		"drwxr-xr-x 2 vmware vmware       4096 Oct 16 09:12 .",
+		"drwxr-xr-x 9 vmware vmware       4096 Oct 10 14:01 ..",
+		"-rw------- 1 vmware vmware 2147483648 Oct 16 09:12 web1.vmdk",
+		"-rw-r--r-- 1 vmware vmware       2912 Oct 16 09:12 web1.vmx",
+		"-rw------- 1 vmware vmware       8684 Oct 16 09:12 my notes.txt",
+		"drwxr-xr-x 2 vmware vmware       4096 Oct 16 09:12 web1.vmx.lck",
+	}
+	require.Equal(t, []BackupFile{
+		{Name: "web1.vmdk", Size: 2147483648},
+		{Name: "web1.vmx", Size: 2912},
+		{Name: "my notes.txt", Size: 8684},
+	}, parseDirListing("linux", linux))
+
+	windows := []string{
+		" Volume in drive C has no label.",
+		" Directory of C:\\Users\\vmware\\vms\\web1",
+		"",
+		"10/16/2026  09:12 AM    <DIR>          .",
+		"10/16/2026  09:12 AM    <DIR>          ..",
+		"10/16/2026  09:12 AM        2147483648 web1.vmdk",
+		"10/16/2026  09:12 AM              2912 web1.vmx",
+		"16.10.2026  21:12                 8684 my notes.txt",
+		"               3 File(s)     2147495244 bytes",
+	}
+	require.Equal(t, []BackupFile{
+		{Name: "web1.vmdk", Size: 2147483648},
+		{Name: "web1.vmx", Size: 2912},
+		{Name: "my notes.txt", Size: 8684},
+	}, parseDirListing("windows", windows))
+}
+
+func TestRestoreFilename(t *testing.T) {
+	require.Equal(t, "web2.vmx", restoreFilename("web1", "web2", "web1.vmx"))
+	require.Equal(t, "web2.nvram", restoreFilename("web1", "web2", "web1.nvram"))
+	require.Equal(t, "web2.vmsd", restoreFilename("web1", "web2", "WEB1.vmsd"))
+	require.Equal(t, "web1.vmdk", restoreFilename("web1", "web2", "web1.vmdk"))
+	require.Equal(t, "other.vmx", restoreFilename("web1", "web2", "other.vmx"))
+}
+
+func testBackupArchive(t *testing.T, filename string) string {
+	dir := t.TempDir()
+	diskFile := filepath.Join(dir, "web1.vmdk")
+	require.Nil(t, os.WriteFile(diskFile, []byte("disk contents"), 0600))
+	entries := []archiveEntry{
+		{Name: "web1.vmx", Data: []byte(`displayName = "web1"`)},
+		{Name: "web1.vmdk", Path: diskFile},
+	}
+	manifest := BackupManifest{Version: BACKUP_VERSION, Name: "web1", PowerState: "off", VMX: `displayName = "web1"`}
+	for _, entry := range entries {
+		digest, size, err := entry.SHA256()
+		require.Nil(t, err)
+		manifest.Files = append(manifest.Files, BackupFile{Name: entry.Name, Size: size, SHA256: digest})
+	}
+	data, err := json.Marshal(&manifest)
+	require.Nil(t, err)
+	entries = append(entries, archiveEntry{Name: BACKUP_MANIFEST_FILE, Data: data})
+
+	archiveFile := filepath.Join(dir, filename)
+	file, err := os.Create(archiveFile)
+	require.Nil(t, err)
+	compressor, err := compressWriter(file, filename)
+	require.Nil(t, err)
+	require.Nil(t, WriteTar(compressor, entries))
+	require.Nil(t, compressor.Close())
+	require.Nil(t, file.Close())
+	return archiveFile
+}
+
+func TestBackupArchive(t *testing.T) {
+	for _, filename := range []string{"web1.tar.zst", "web1.tar.gz", "web1.tar"} {
+		archiveFile := testBackupArchive(t, filename)
+		file, err := os.Open(archiveFile)
+		require.Nil(t, err)
+		reader, err := decompressReader(file)
+		require.Nil(t, err)
+		dir := t.TempDir()
+		names, err := ExtractTar(reader, dir)
+		require.Nil(t, err)
+		require.Nil(t, reader.Close())
+		require.Nil(t, file.Close())
+		require.Equal(t, []string{"web1.vmx", "web1.vmdk", BACKUP_MANIFEST_FILE}, names)
+
+		manifest, err := VerifyBackup(dir)
+		require.Nil(t, err)
+		require.Equal(t, "web1", manifest.Name)
+		require.Len(t, manifest.Files, 2)
+
+		require.Nil(t, os.WriteFile(filepath.Join(dir, "web1.vmdk"), []byte("corrupt"), 0600))
+		_, err = VerifyBackup(dir)
+		require.NotNil(t, err)
+	}
+
+	_, err := compressWriter(&bytes.Buffer{}, "web1.zip")
+	require.NotNil(t, err)
+}
+
+func TestVerifyBackupIncomplete(t *testing.T) {
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	archive := tar.NewWriter(&buf)
+	require.Nil(t, writeTarEntry(archive, archiveEntry{Name: "web1.vmx", Data: []byte("x")}))
+	require.Nil(t, archive.Close())
+	_, err := ExtractTar(&buf, dir)
+	require.Nil(t, err)
+	_, err = VerifyBackup(dir)
+	require.NotNil(t, err)
+}
+
+func TestRestoreFailureRemovesDir(t *testing.T) {
+	ctx := t.Context()
+	v, host := newFakeController(t)
+	manifest := BackupManifest{
+		Version: 1,
+		Name:    "web1",
+		VMX:     ".encoding = \"UTF-8\"\ndisplayName = \"web1\"\n",
+		Files:   []BackupFile{{Name: "web1.vmdk"}, {Name: "web1.vmx"}},
+	}
+
+	// the backup directory is missing web1.vmdk, so its upload fails
+	_, err := v.restoreInstance(ctx, &manifest, t.TempDir(), "backup", "web1", FAKE_VMWARE_ROOT)
+	require.NotNil(t, err)
+	require.Contains(t, host.Commands, "mkdir /vmware/web1")
+	require.Contains(t, host.Commands, "rm -rf /vmware/web1")
+	require.False(t, IsDir(host.local("/vmware/web1")))
+}
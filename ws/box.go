@@ -0,0 +1,378 @@
+package ws
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Vagrant box support for the vmware_desktop provider
+
+const BOX_PROVIDER = "vmware_desktop"
+const BOX_METADATA_FILE = "metadata.json"
+
+var BOX_PROVIDERS = map[string]bool{
+	"vmware_desktop":     true,
+	"vmware_workstation": true,
+	"vmware_fusion":      true,
+}
+
+type BoxMetadata struct {
+	Provider string `json:"provider"`
+}
+
+// extract a tar or gzip compressed tar Vagrant box to dir, returning the VMX filename
+func ExtractBox(r io.Reader, dir string) (string, error) {
+	archive, err := decompressReader(r)
+	if err != nil {
+		return "", wrap(err)
+	}
+	defer archive.Close()
+	names, err := ExtractTar(archive, dir)
+	if err != nil {
+		return "", wrap(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, BOX_METADATA_FILE))
+	if err != nil {
+		return "", Fatalf("box has no %s", BOX_METADATA_FILE)
+	}
+	var metadata BoxMetadata
+	err = json.Unmarshal(data, &metadata)
+	if err != nil {
+		return "", Fatalf("invalid box %s: %v", BOX_METADATA_FILE, err)
+	}
+	if !BOX_PROVIDERS[metadata.Provider] {
+		return "", Fatalf("unsupported box provider: '%s'", metadata.Provider)
+	}
+	vmxFiles := []string{}
+	for _, name := range names {
+		if strings.HasSuffix(strings.ToLower(name), ".vmx") {
+			vmxFiles = append(vmxFiles, name)
+		}
+	}
+	if len(vmxFiles) != 1 {
+		return "", Fatalf("expected one VMX file in box, found %d", len(vmxFiles))
+	}
+	return vmxFiles[0], nil
+}
+
+// prepare a box VMX for instance name, returning a map of instance filenames to box filenames
+func (v *VMX) RewriteBoxVMX(name string) (map[string]string, []string, error) {
+	if v.debug {
+		log.Printf("RewriteBoxVMX(%s)\n", name)
+	}
+	files := make(map[string]string)
+	actions := []string{}
+	action, err := v.SetName(name)
+	if err != nil {
+		return nil, nil, wrap(err)
+	}
+	actions = append(actions, action)
+	action, err = v.ResetMacAddresses()
+	if err != nil {
+		return nil, nil, wrap(err)
+	}
+	actions = append(actions, action)
+	action, err = v.SetUUID()
+	if err != nil {
+		return nil, nil, wrap(err)
+	}
+	actions = append(actions, action)
+
+	disks := v.Disks()
+	for _, slot := range SortedKeys(disks) {
+		filename := path.Base(strings.ReplaceAll(disks[slot], "\\", "/"))
+		if filename != disks[slot] {
+			v.doc.Set(slot+".fileName", filename)
+			actions = append(actions, fmt.Sprintf("Set %s disk %s", slot, filename))
+		}
+		files[filename] = filename
+	}
+	nvram, ok := v.doc.Get("nvram")
+	if ok {
+		files[name+".nvram"] = path.Base(strings.ReplaceAll(nvram, "\\", "/"))
+		v.doc.Set("nvram", name+".nvram")
+	}
+	// host-specific files are recreated by VMware
+	for _, key := range []string{"extendedConfigFile", "vmxstats.filename", "checkpoint.vmState", "sched.swap.derivedName"} {
+		v.doc.Delete(key)
+	}
+	return files, actions, nil
+}
+
+// return the VMDK descriptor file and extent files for a local VMDK
+func localVMDKFiles(dir, filename string) ([]string, error) {
+	file, err := os.Open(filepath.Join(dir, filename))
+	if err != nil {
+		return nil, wrap(err)
+	}
+	defer file.Close()
+	magic := make([]byte, 4)
+	_, err = io.ReadFull(file, magic)
+	if err == nil && binary.LittleEndian.Uint32(magic) == VMDK_SPARSE_MAGIC {
+		// monolithic sparse disks embed their descriptor
+		return []string{filename}, nil
+	}
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	data, err := io.ReadAll(io.LimitReader(file, 64*KB))
+	if err != nil {
+		return nil, wrap(err)
+	}
+	files := []string{}
+	for _, extent := range VMDKExtentFiles(data) {
+		if strings.ContainsAny(extent, "/\\") {
+			return nil, Fatalf("unsupported extent path in %s: %s", filename, extent)
+		}
+		if extent != filename {
+			files = append(files, extent)
+		}
+	}
+	return append(files, filename), nil
+}
+
+// create a directory for a new instance in root
+func (v *vmctl) createInstanceDir(ctx context.Context, root, name string) (*VM, error) {
+	// make a VID, which will fail if the instance exists
+	vid, err := v.cli.newVID(path.Join(root, name, name+".vmx"))
+	if err != nil {
+		return nil, wrap(err)
+	}
+	dir, _ := path.Split(vid.Path)
+	hostDir, err := PathFormat(v.Remote, dir)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	_, err = v.RemoteExec(ctx, "mkdir "+hostDir, nil)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	return &VM{Name: vid.Name, Id: vid.Id, Path: vid.Path}, nil
+}
+
+// create instance name from a local Vagrant vmware_desktop box
+func (v *vmctl) ImportBox(ctx context.Context, filename, name string) (string, error) {
+	if v.debug {
+		log.Printf("ImportBox(%s, %s)\n", filename, name)
+	}
+	_, err := v.cli.GetVM(ctx, name)
+	if err == nil {
+		return "", wrapf("import failed, instance '%s' %w", name, ErrExists)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return "", wrap(err)
+	}
+	tempDir, err := os.MkdirTemp("", "vmx_box.*")
+	if err != nil {
+		return "", wrap(err)
+	}
+	defer os.RemoveAll(tempDir)
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", wrap(err)
+	}
+	if v.verbose {
+		fmt.Printf("[%s] Extracting %s\n", name, filename)
+	}
+	vmxFile, err := ExtractBox(file, tempDir)
+	file.Close()
+	if err != nil {
+		return "", wrap(err)
+	}
+	data, err := os.ReadFile(filepath.Join(tempDir, vmxFile))
+	if err != nil {
+		return "", wrap(err)
+	}
+	vmx, err := InitVMX(v.Remote, name, data)
+	if err != nil {
+		return "", wrap(err)
+	}
+	files, actions, err := vmx.RewriteBoxVMX(name)
+	if err != nil {
+		return "", wrap(err)
+	}
+	uploads := make(map[string]string)
+	for instanceFile, boxFile := range files {
+		if !strings.HasSuffix(strings.ToLower(boxFile), ".vmdk") {
+			if IsFile(filepath.Join(tempDir, boxFile)) {
+				uploads[instanceFile] = boxFile
+			}
+			continue
+		}
+		vmdkFiles, err := localVMDKFiles(tempDir, boxFile)
+		if err != nil {
+			return "", wrap(err)
+		}
+		for _, vmdkFile := range vmdkFiles {
+			uploads[vmdkFile] = vmdkFile
+		}
+	}
+	vmxData, err := vmx.Read()
+	if err != nil {
+		return "", wrap(err)
+	}
+
+	vm, err := v.createInstanceDir(ctx, v.Roots[0], name)
+	if err != nil {
+		return "", wrap(err)
+	}
+	err = v.uploadBoxFiles(ctx, vm, tempDir, uploads, vmxData)
+	if err != nil {
+		// don't leave a partial instance directory behind, even if ctx was canceled
+		rmErr := v.removeInstanceDir(context.WithoutCancel(ctx), vm)
+		if rmErr != nil {
+			log.Printf("WARNING: [%s] failed removing instance directory: %v\n", name, rmErr)
+		}
+		return "", wrap(err)
+	}
+	if v.verbose {
+		for _, action := range actions {
+			fmt.Printf("[%s] %s\n", vm.Name, action)
+		}
+	}
+	return fmt.Sprintf("Imported %s from %s", vm.Name, filename), nil
+}
+
+// upload the extracted box files to the new instance directory, writing the VMX last
+func (v *vmctl) uploadBoxFiles(ctx context.Context, vm *VM, tempDir string, uploads map[string]string, vmxData []byte) error {
+	dir, _ := path.Split(vm.Path)
+	for _, instanceFile := range SortedKeys(uploads) {
+		if v.verbose {
+			fmt.Printf("[%s] Uploading %s\n", vm.Name, instanceFile)
+		}
+		err := v.UploadFile(ctx, vm, filepath.Join(tempDir, uploads[instanceFile]), path.Join(dir, instanceFile))
+		if err != nil {
+			return wrap(err)
+		}
+	}
+	localVMX := filepath.Join(tempDir, vm.Name+".vmx.new")
+	err := os.WriteFile(localVMX, vmxData, 0600)
+	if err != nil {
+		return wrap(err)
+	}
+	err = v.UploadFile(ctx, vm, localVMX, vm.Path)
+	if err != nil {
+		return wrap(err)
+	}
+	return nil
+}
+
+// write the instance to a gzip compressed Vagrant vmware_desktop box
+func (v *vmctl) PackageBox(ctx context.Context, vid, filename string) (string, error) {
+	if v.debug {
+		log.Printf("PackageBox(%s, %s)\n", vid, filename)
+	}
+	vm, err := v.cli.GetVM(ctx, vid)
+	if err != nil {
+		return "", wrap(err)
+	}
+	err = v.requirePowerState(ctx, &vm, "off", "package the instance")
+	if err != nil {
+		return "", wrap(err)
+	}
+	vmx, err := v.readVMX(ctx, &vm)
+	if err != nil {
+		return "", wrap(err)
+	}
+	tempDir, err := os.MkdirTemp("", "vmx_box.*")
+	if err != nil {
+		return "", wrap(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	download := func(name string) error {
+		if v.verbose {
+			fmt.Printf("[%s] Downloading %s\n", vm.Name, name)
+		}
+		return v.Download(ctx, vm.Name, filepath.Join(tempDir, name), name)
+	}
+	files := []string{vm.Name + ".vmx"}
+	disks := vmx.Disks()
+	for _, slot := range SortedKeys(disks) {
+		filename := disks[slot]
+		if strings.ContainsAny(filename, "/\\") {
+			return "", Fatalf("[%s] cannot package disk outside the instance directory: %s", vm.Name, filename)
+		}
+		err := download(filename)
+		if err != nil {
+			return "", wrap(err)
+		}
+		vmdkFiles, err := localVMDKFiles(tempDir, filename)
+		if err != nil {
+			return "", wrap(err)
+		}
+		for _, vmdkFile := range vmdkFiles {
+			if vmdkFile != filename {
+				err := download(vmdkFile)
+				if err != nil {
+					return "", wrap(err)
+				}
+			}
+		}
+		files = append(files, vmdkFiles...)
+	}
+	err = download(files[0])
+	if err != nil {
+		return "", wrap(err)
+	}
+	nvram, ok := vmx.doc.Get("nvram")
+	if ok && !strings.ContainsAny(nvram, "/\\") {
+		exists, err := v.instanceFileExists(ctx, &vm, nvram)
+		if err != nil {
+			return "", wrap(err)
+		}
+		if exists {
+			err := download(nvram)
+			if err != nil {
+				return "", wrap(err)
+			}
+			files = append(files, nvram)
+		}
+	}
+
+	metadata, err := json.Marshal(BoxMetadata{Provider: BOX_PROVIDER})
+	if err != nil {
+		return "", wrap(err)
+	}
+	entries := []archiveEntry{{Name: BOX_METADATA_FILE, Data: append(metadata, '\n')}}
+	for _, file := range files {
+		entries = append(entries, archiveEntry{Name: file, Path: filepath.Join(tempDir, file)})
+	}
+	if v.verbose {
+		fmt.Printf("[%s] Writing %s\n", vm.Name, filename)
+	}
+	err = WriteBox(filename, entries)
+	if err != nil {
+		return "", wrap(err)
+	}
+	return fmt.Sprintf("Packaged %s to %s", vm.Name, filename), nil
+}
+
+// write entries to a gzip compressed box file
+func WriteBox(filename string, entries []archiveEntry) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return wrap(err)
+	}
+	gz := gzip.NewWriter(file)
+	err = WriteTar(gz, entries)
+	if err == nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		file.Close()
+		return wrap(err)
+	}
+	return file.Close()
+}
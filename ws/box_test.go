@@ -0,0 +1,114 @@
+package ws
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testBoxVMX = `.encoding = "UTF-8"
+displayName = "debian-12"
+guestOS = "debian12-64"
+nvram = "debian-12.nvram"
+extendedConfigFile = "debian-12.vmxf"
+uuid.bios = "56 4d 00 00 00 00 00 00-00 00 00 00 00 00 00 01"
+uuid.location = "56 4d 00 00 00 00 00 00-00 00 00 00 00 00 00 01"
+scsi0.present = "TRUE"
+scsi0.virtualDev = "lsilogic"
+scsi0:0.present = "TRUE"
+scsi0:0.fileName = "C:\Users\build\boxes\disk-cl1.vmdk"
+ethernet0.present = "TRUE"
+ethernet0.connectionType = "nat"
+ethernet0.addressType = "static"
+ethernet0.address = "00:50:56:00:00:01"
+`
+
+const testSplitVMDK = `# Disk DescriptorFile
+version=1
+CID=fffffffe
+parentCID=ffffffff
+createType="twoGbMaxExtentSparse"
+
+# Extent description
+RW 4192256 SPARSE "disk-cl1-s001.vmdk"
+RW 4192256 SPARSE "disk-cl1-s002.vmdk"
+`
+
+func testBox(t *testing.T, provider string) []byte {
+	dir := t.TempDir()
+	entries := []archiveEntry{
+		{Name: "./metadata.json", Data: []byte(`{"provider": "` + provider + `"}`)},
+		{Name: "./debian-12.vmx", Data: []byte(testBoxVMX)},
+		{Name: "./disk-cl1.vmdk", Data: []byte(testSplitVMDK)},
+		{Name: "./disk-cl1-s001.vmdk", Data: []byte("extent 1")},
+		{Name: "./disk-cl1-s002.vmdk", Data: []byte("extent 2")},
+		{Name: "./debian-12.nvram", Data: []byte("nvram")},
+	}
+	filename := filepath.Join(dir, "test.box")
+	require.Nil(t, WriteBox(filename, entries))
+	data, err := os.ReadFile(filename)
+	require.Nil(t, err)
+	return data
+}
+
+func TestExtractBox(t *testing.T) {
+	initTestConfig(t)
+	dir := t.TempDir()
+	vmxFile, err := ExtractBox(bytes.NewReader(testBox(t, BOX_PROVIDER)), dir)
+	require.Nil(t, err)
+	require.Equal(t, "debian-12.vmx", vmxFile)
+	files, err := localVMDKFiles(dir, "disk-cl1.vmdk")
+	require.Nil(t, err)
+	require.Equal(t, []string{"disk-cl1-s001.vmdk", "disk-cl1-s002.vmdk", "disk-cl1.vmdk"}, files)
+
+	_, err = ExtractBox(bytes.NewReader(testBox(t, "virtualbox")), t.TempDir())
+	require.NotNil(t, err)
+
+	// uncompressed boxes are also accepted
+	gz, err := gzip.NewReader(bytes.NewReader(testBox(t, "vmware_workstation")))
+	require.Nil(t, err)
+	_, err = ExtractBox(gz, t.TempDir())
+	require.Nil(t, err)
+}
+
+func TestRewriteBoxVMX(t *testing.T) {
+	initTestConfig(t)
+	vmx, err := InitVMX("linux", "debian-12", []byte(testBoxVMX))
+	require.Nil(t, err)
+	files, _, err := vmx.RewriteBoxVMX("web1")
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{
+		"disk-cl1.vmdk": "disk-cl1.vmdk",
+		"web1.nvram":    "debian-12.nvram",
+	}, files)
+	require.Equal(t, map[string]string{"scsi0:0": "disk-cl1.vmdk"}, vmx.Disks())
+
+	name, _ := vmx.doc.Get("displayName")
+	require.Equal(t, "web1", name)
+	nvram, _ := vmx.doc.Get("nvram")
+	require.Equal(t, "web1.nvram", nvram)
+	uuid, _ := vmx.doc.Get("uuid.bios")
+	require.NotEqual(t, "56 4d 00 00 00 00 00 00-00 00 00 00 00 00 00 01", uuid)
+	require.False(t, vmx.doc.Has("uuid.location"))
+	require.False(t, vmx.doc.Has("extendedConfigFile"))
+	nics := vmx.Nics()
+	require.Len(t, nics, 1)
+	require.False(t, nics[0].StaticMac)
+}
+
+func TestImportBoxFailureRemovesDir(t *testing.T) {
+	ctx := t.Context()
+	v, host := newFakeController(t)
+	filename := filepath.Join(t.TempDir(), "test.box")
+	require.Nil(t, os.WriteFile(filename, testBox(t, BOX_PROVIDER), 0600))
+
+	host.FailMatch = "disk-cl1-s002.vmdk"
+	_, err := v.ImportBox(ctx, filename, "web1")
+	require.NotNil(t, err)
+	require.Contains(t, host.Commands, "mkdir /vmware/web1")
+	require.Contains(t, host.Commands, "rm -rf /vmware/web1")
+	require.False(t, IsDir(host.local("/vmware/web1")))
+}
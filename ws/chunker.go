@@ -0,0 +1,117 @@
+package ws
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"io"
+)
+
+// content-defined chunking with a gear rolling hash; changing these values
+// or the gear table changes chunk boundaries, which defeats deduplication
+// against existing repository data but does not affect correctness
+
+const CHUNK_MIN_SIZE = 256 * KB
+const CHUNK_MAX_SIZE = 4 * MB
+const CHUNK_MASK = uint64(1<<20-1) << 44 // 1 MiB average chunk size
+
+// fixed block size used to detect changed regions of host files
+const BLOCK_SIZE = 4 * MB
+
+var gearTable [256]uint64
+
+func init() {
+	for i := range gearTable {
+		sum := sha256.Sum256([]byte{byte(i)})
+		gearTable[i] = binary.LittleEndian.Uint64(sum[:8])
+	}
+}
+
+type chunker struct {
+	reader *bufio.Reader
+	buf    []byte
+}
+
+func newChunker(r io.Reader) *chunker {
+	return &chunker{
+		reader: bufio.NewReaderSize(r, int(CHUNK_MAX_SIZE)),
+		buf:    make([]byte, 0, CHUNK_MAX_SIZE),
+	}
+}
+
+// return the next chunk or io.EOF; the returned slice is reused by the next call
+func (c *chunker) Next() ([]byte, error) {
+	// the gear hash depends only on the last 64 bytes, so the minimum chunk is read without hashing
+	c.buf = c.buf[:CHUNK_MIN_SIZE-64]
+	n, err := io.ReadFull(c.reader, c.buf)
+	c.buf = c.buf[:n]
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		if n == 0 {
+			return nil, io.EOF
+		}
+		return c.buf, nil
+	}
+	if err != nil {
+		return nil, wrap(err)
+	}
+	var hash uint64
+	for int64(len(c.buf)) < CHUNK_MAX_SIZE {
+		b, err := c.reader.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, wrap(err)
+		}
+		c.buf = append(c.buf, b)
+		hash = (hash << 1) + gearTable[b]
+		if int64(len(c.buf)) >= CHUNK_MIN_SIZE && hash&CHUNK_MASK == 0 {
+			break
+		}
+	}
+	return c.buf, nil
+}
+
+// blockHasher is a writer returning the sha256 of each BLOCK_SIZE block written
+type blockHasher struct {
+	hash   hash.Hash
+	count  int64
+	blocks []string
+}
+
+func newBlockHasher() *blockHasher {
+	return &blockHasher{hash: sha256.New(), blocks: []string{}}
+}
+
+func (h *blockHasher) Write(data []byte) (int, error) {
+	written := len(data)
+	for len(data) > 0 {
+		n := BLOCK_SIZE - h.count
+		if int64(len(data)) < n {
+			n = int64(len(data))
+		}
+		h.hash.Write(data[:n])
+		h.count += n
+		data = data[n:]
+		if h.count == BLOCK_SIZE {
+			h.flush()
+		}
+	}
+	return written, nil
+}
+
+func (h *blockHasher) flush() {
+	h.blocks = append(h.blocks, hex.EncodeToString(h.hash.Sum(nil)))
+	h.hash.Reset()
+	h.count = 0
+}
+
+// return the block hashes, including a final partial block
+func (h *blockHasher) Sum() []string {
+	if h.count > 0 {
+		h.flush()
+	}
+	return h.blocks
+}
@@ -0,0 +1,115 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+type CloneOptions struct {
+	Linked   bool
+	Snapshot string
+}
+
+func (v *vmctl) Clone(ctx context.Context, src, dst string, options CloneOptions) (string, error) {
+	if v.debug {
+		log.Printf("Clone(%s, %s, %+v)\n", src, dst, options)
+	}
+	vm, err := v.cli.GetVM(ctx, src)
+	if err != nil {
+		return "", wrap(err)
+	}
+
+	// check for existing instance
+	_, err = v.cli.GetVM(ctx, dst)
+	if err == nil {
+		return "", wrapf("clone failed, instance '%s' %w", dst, ErrExists)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return "", wrap(err)
+	}
+
+	cloneType := "full"
+	if options.Linked {
+		if options.Snapshot == "" {
+			return "", Fatalf("linked clone requires a snapshot")
+		}
+		cloneType = "linked"
+	}
+
+	// create a directory for the new instance
+	vid, err := v.createInstanceDir(ctx, v.Roots[0], dst)
+	if err != nil {
+		return "", wrap(err)
+	}
+
+	hostPath, err := PathnameFormat(v.Remote, vid.Path)
+	if err != nil {
+		return "", wrap(err)
+	}
+	args := []string{hostPath, cloneType}
+	if options.Snapshot != "" {
+		args = append(args, "-snapshot="+options.Snapshot)
+	}
+	args = append(args, "-cloneName="+dst)
+
+	if v.verbose {
+		fmt.Printf("[%s] Requesting %s clone to %s\n", vm.Name, cloneType, dst)
+	}
+	_, err = v.vmrun(ctx, &vm, "clone", args...)
+	if err != nil {
+		// don't leave an empty or partial instance directory behind, even if ctx was canceled
+		rmErr := v.removeInstanceDir(context.WithoutCancel(ctx), vid)
+		if rmErr != nil {
+			log.Printf("WARNING: [%s] failed removing instance directory: %v\n", dst, rmErr)
+		}
+		return "", wrap(err)
+	}
+
+	clone, err := v.cli.GetVM(ctx, dst)
+	if err != nil {
+		return "", wrap(err)
+	}
+
+	// give the clone a new identity
+	vmxFilename := clone.Name + ".vmx"
+	data, err := v.ReadHostFile(ctx, &clone, vmxFilename)
+	if err != nil {
+		return "", wrap(err)
+	}
+	vmx, err := InitVMX(v.Remote, clone.Name, data)
+	if err != nil {
+		return "", wrap(err)
+	}
+	actions := []string{}
+	action, err := vmx.SetName(clone.Name)
+	if err != nil {
+		return "", wrap(err)
+	}
+	actions = append(actions, action)
+	action, err = vmx.ResetMacAddresses()
+	if err != nil {
+		return "", wrap(err)
+	}
+	actions = append(actions, action)
+	action, err = vmx.SetUUID()
+	if err != nil {
+		return "", wrap(err)
+	}
+	actions = append(actions, action)
+	data, err = vmx.Read()
+	if err != nil {
+		return "", wrap(err)
+	}
+	err = v.WriteHostFile(ctx, &clone, vmxFilename, data)
+	if err != nil {
+		return "", wrap(err)
+	}
+	if v.verbose {
+		for _, action := range actions {
+			fmt.Printf("[%s] %s\n", clone.Name, action)
+		}
+	}
+	return "cloned", nil
+}
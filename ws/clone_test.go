@@ -0,0 +1,21 @@
+package ws
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCloneFailureRemovesDir(t *testing.T) {
+	ctx := t.Context()
+	v, host := newFakeController(t)
+	_, err := v.Create(ctx, "web1", *NewCreateOptions(), IsoOptions{})
+	require.Nil(t, err)
+
+	// the fake host does not implement vmrun clone
+	_, err = v.Clone(ctx, "web1", "web2", CloneOptions{})
+	require.NotNil(t, err)
+	require.Contains(t, host.Commands, "mkdir /vmware/web2")
+	require.Contains(t, host.Commands, "rm -rf /vmware/web2")
+	require.False(t, IsDir(host.local("/vmware/web2")))
+	require.True(t, IsDir(host.local("/vmware/web1")))
+}
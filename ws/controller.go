@@ -1,7 +1,9 @@
 package ws
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/rstms/winexec/client"
 	"log"
@@ -9,6 +11,7 @@ import (
 	"os/user"
 	"regexp"
 	"runtime"
+	"sort"
 	"strings"
 	"time"
 )
@@ -21,6 +24,7 @@ var NONEXISTENT_VM_ERROR = regexp.MustCompile(`VMX : '[^']*' does not exist!`)
 
 const DEFAULT_INTERVAL_SECONDS = 3
 const DEFAULT_TIMEOUT_SECONDS = 300
+const DEFAULT_GRACE_SECONDS = 60
 
 type VID struct {
 	Id   string
@@ -32,7 +36,7 @@ type VMState struct {
 	Name       string
 	Path       string
 	Id         string
-	MacAddress string
+	Nics       []VMNic
 	IpAddress  string
 	PowerState string
 	Result     string
@@ -52,8 +56,8 @@ type VM struct {
 	RamSize  string
 	DiskSize string
 
-	MacAddress string
-	IpAddress  string
+	Nics      []VMNic
+	IpAddress string
 
 	IsoAttached      bool
 	IsoAttachOnStart bool
@@ -89,22 +93,62 @@ const (
 )
 
 type Controller interface {
-	Create(string, CreateOptions, IsoOptions) (string, error)
-	Get(string) (VM, error)
-	Modify(string, CreateOptions, IsoOptions) (*[]string, error)
-	Start(string, StartOptions, IsoOptions) (string, error)
-	Stop(string, StopOptions) (string, error)
-	Destroy(string, DestroyOptions) error
-	Show(string, ShowOptions) (*[]VMState, error)
-	GetProperty(string, string) (string, error)
-	SetProperty(string, string, string) error
-	Upload(string, string, string) error
-	Download(string, string, string) error
-	Files(string, FilesOptions) ([]string, error)
-	Wait(string, string) error
-	SendKeys(string, string) error
+	Create(context.Context, string, CreateOptions, IsoOptions) (string, error)
+	Get(context.Context, string) (VM, error)
+	Modify(context.Context, string, CreateOptions, IsoOptions) (*[]string, error)
+	Start(context.Context, string, StartOptions, IsoOptions) (string, error)
+	Stop(context.Context, string, StopOptions) (string, error)
+	Destroy(context.Context, string, DestroyOptions) error
+	Show(context.Context, string, ShowOptions) (*[]VMState, error)
+	GetProperty(context.Context, string, string) (string, error)
+	SetProperty(context.Context, string, string, string) error
+	Upload(context.Context, string, string, string) error
+	Download(context.Context, string, string, string) error
+	Files(context.Context, string, FilesOptions) ([]string, error)
+	Wait(context.Context, string, string) error
+	SendKeys(context.Context, string, string) error
 	Close() error
-	GetState(string) (*VMState, error)
+	GetState(context.Context, string) (*VMState, error)
+	CreateSnapshot(context.Context, string, string) (string, error)
+	ListSnapshots(context.Context, string) ([]*Snapshot, error)
+	RevertSnapshot(context.Context, string, string, SnapshotOptions) (string, error)
+	DeleteSnapshot(context.Context, string, string, SnapshotOptions) (string, error)
+	Clone(context.Context, string, string, CloneOptions) (string, error)
+	Suspend(context.Context, string, StopOptions) (string, error)
+	Pause(context.Context, string, StopOptions) (string, error)
+	Unpause(context.Context, string, StopOptions) (string, error)
+	Reboot(context.Context, string, StopOptions) (string, error)
+	GuestExec(context.Context, string, []string, GuestExecOptions) (*GuestExecResult, error)
+	GuestUpload(context.Context, string, string, string, GuestOptions) error
+	GuestDownload(context.Context, string, string, string, GuestOptions) error
+	GuestListDirectory(context.Context, string, string, GuestOptions) ([]string, error)
+	GuestMkdir(context.Context, string, string, GuestOptions) error
+	GuestRemove(context.Context, string, string, GuestOptions) error
+	GuestListProcesses(context.Context, string, GuestOptions) ([]GuestProcess, error)
+	GuestKillProcess(context.Context, string, int, GuestOptions) error
+	ListGuestInfo(context.Context, string) (map[string]string, error)
+	GetGuestInfo(context.Context, string, string, GuestInfoOptions) (string, error)
+	SetGuestInfo(context.Context, string, string, string, GuestInfoOptions) (string, error)
+	Seed(context.Context, string, SeedOptions) (string, error)
+	PlanSpec(context.Context, *Spec) (*SpecPlan, error)
+	ApplySpec(context.Context, *Spec, ApplyOptions) (*SpecPlan, error)
+	ExportSpec(context.Context, string) (*Spec, error)
+	Lint(context.Context, string, []byte) ([]LintIssue, error)
+	ListDisks(context.Context, string) ([]VMDisk, error)
+	AddDisk(context.Context, string, DiskOptions) (string, error)
+	RemoveDisk(context.Context, string, string, DiskOptions) (string, error)
+	ExpandDisk(context.Context, string, string, string) (*VMDisk, error)
+	ShrinkDisk(context.Context, string, string) (*VMDisk, error)
+	DefragDisk(context.Context, string, string) (*VMDisk, error)
+	ConvertDisk(context.Context, string, string, VDiskType) (*VMDisk, error)
+	ExportOVF(context.Context, string, string) (string, error)
+	ImportOVF(context.Context, string, string, OVFOptions) (string, error)
+	ImportBox(context.Context, string, string) (string, error)
+	PackageBox(context.Context, string, string) (string, error)
+	Backup(context.Context, string, string) (string, error)
+	Restore(context.Context, string, string, RestoreOptions) (string, error)
+	BackupRepo(context.Context, string, string) (string, error)
+	RestoreRepo(context.Context, string, string, string, RestoreOptions) (string, error)
 }
 
 type vmctl struct {
@@ -114,6 +158,7 @@ type vmctl struct {
 	Roots           []string
 	IsoPath         string
 	winexec         *client.WinexecClient
+	executor        Executor
 	cli             *vmcli
 	Shell           string
 	Local           string
@@ -124,6 +169,7 @@ type vmctl struct {
 	vmkey           map[string]string
 	IntervalSeconds int64
 	TimeoutSeconds  int64
+	GraceSeconds    int64
 }
 
 // return true if VMWare Workstation Host is localhost
@@ -133,7 +179,7 @@ func (v *vmctl) isLocal() (bool, error) {
 	}
 	fqdn, err := os.Hostname()
 	if err != nil {
-		return false, Fatal(err)
+		return false, wrap(err)
 	}
 	if v.Hostname == fqdn {
 		return true, nil
@@ -147,22 +193,22 @@ func (v *vmctl) isLocal() (bool, error) {
 	return false, nil
 }
 
-func (v *vmctl) detectRemoteOS() (string, error) {
+func (v *vmctl) detectRemoteOS(ctx context.Context) (string, error) {
 	if v.debug {
 		log.Println("detectRemoteOS")
 	}
-	olines, err := v.exec("ssh", append(v.sshArgs(), "env"), "", nil)
+	olines, err := v.probeRemote(ctx, "env")
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 	for _, line := range olines {
 		if WINDOWS_ENV_PATTERN.MatchString(strings.ToUpper(line)) {
 			return "windows", nil
 		}
 	}
-	olines, err = v.exec("ssh", append(v.sshArgs(), "uname"), "", nil)
+	olines, err = v.probeRemote(ctx, "uname")
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 	if len(olines) != 1 {
 		return "", Fatalf("unexpected uname response: %v", olines)
@@ -170,7 +216,16 @@ func (v *vmctl) detectRemoteOS() (string, error) {
 	return strings.ToLower(olines[0]), nil
 }
 
-func NewVMXController() (Controller, error) {
+// run a command on a host whose OS is not yet known; the ssh program's command is
+// passed as an argument since a windows host does not read it from stdin
+func (v *vmctl) probeRemote(ctx context.Context, command string) ([]string, error) {
+	if v.Shell == "ssh" {
+		return v.exec(ctx, "ssh", append(v.sshArgs(), command), "", nil)
+	}
+	return v.executor.Exec(ctx, command, nil)
+}
+
+func NewVMXController(ctx context.Context) (Controller, error) {
 
 	var prefix string
 	if ProgramName() != "vmx" {
@@ -179,7 +234,7 @@ func NewVMXController() (Controller, error) {
 
 	user, err := user.Current()
 	if err != nil {
-		return nil, Fatal(err)
+		return nil, wrap(err)
 	}
 
 	ViperSetDefault(prefix+"host", "localhost")
@@ -188,7 +243,9 @@ func NewVMXController() (Controller, error) {
 	ViperSetDefault(prefix+"disable_keepalives", true)
 	ViperSetDefault(prefix+"interval_seconds", DEFAULT_INTERVAL_SECONDS)
 	ViperSetDefault(prefix+"timeout_seconds", DEFAULT_TIMEOUT_SECONDS)
+	ViperSetDefault(prefix+"grace_seconds", DEFAULT_GRACE_SECONDS)
 	ViperSetDefault(prefix+"user", user.Username)
+	ViperSetDefault(prefix+"backend", "cli")
 
 	v := vmctl{
 		Hostname:        ViperGetString(prefix + "host"),
@@ -199,6 +256,7 @@ func NewVMXController() (Controller, error) {
 		Version:         Version,
 		IntervalSeconds: ViperGetInt64(prefix + "interval_seconds"),
 		TimeoutSeconds:  ViperGetInt64(prefix + "timeout_seconds"),
+		GraceSeconds:    ViperGetInt64(prefix + "grace_seconds"),
 	}
 
 	roots := ViperGetStringSlice(prefix + "vmware_roots")
@@ -206,13 +264,13 @@ func NewVMXController() (Controller, error) {
 	for i, root := range roots {
 		normalized, err := PathNormalize(root)
 		if err != nil {
-			return nil, Fatal(err)
+			return nil, wrap(err)
 		}
 		v.Roots[i] = normalized
 	}
 	path, err := PathNormalize(ViperGetString(prefix + "iso_path"))
 	if err != nil {
-		return nil, Fatal(err)
+		return nil, wrap(err)
 	}
 	v.IsoPath = path
 
@@ -221,7 +279,7 @@ func NewVMXController() (Controller, error) {
 	v.Local = runtime.GOOS
 	local, err := v.isLocal()
 	if err != nil {
-		return nil, Fatal(err)
+		return nil, wrap(err)
 	}
 	if local {
 		v.Remote = v.Local
@@ -237,62 +295,80 @@ func NewVMXController() (Controller, error) {
 		case "winexec":
 			w, err := client.NewWinexecClient()
 			if err != nil {
-				return nil, Fatal(err)
+				return nil, wrap(err)
 			}
 			v.winexec = w
 			v.Remote = "windows"
-		case "ssh":
-			v.Shell = "ssh"
-			remote, err := v.detectRemoteOS()
-			if err != nil {
-				return nil, Fatal(err)
-			}
-			if v.debug {
-				log.Printf("detected remote os: %s\n", remote)
-			}
-			v.Remote = remote
 		}
 	}
+	v.executor, err = newExecutor(ctx, &v, v.Shell, v.winexec)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if v.Remote == "" {
+		remote, err := v.detectRemoteOS(ctx)
+		if err != nil {
+			v.executor.Close()
+			return nil, wrap(err)
+		}
+		if v.debug {
+			log.Printf("detected remote os: %s\n", remote)
+		}
+		v.Remote = remote
+	}
 	v.mapVMKeys()
 	if v.debug {
 		local, err := v.isLocal()
 		if err != nil {
-			return nil, Fatal(err)
+			return nil, wrap(err)
 		}
 		log.Printf("isLocal=%v shell=%s local=%s remote=%s\n", local, v.Shell, v.Local, v.Remote)
 	}
-	return &v, nil
+	backend := ViperGetString(prefix + "backend")
+	switch backend {
+	case "cli":
+		return &v, nil
+	case "vmrest":
+		r, err := newVMRestController(&v, prefix)
+		if err != nil {
+			v.Close()
+			return nil, wrap(err)
+		}
+		return r, nil
+	}
+	v.Close()
+	return nil, Fatalf("unknown backend: %s", backend)
 }
 
 func (v *vmctl) Close() error {
 	if v.debug {
 		log.Println("Close")
 	}
-	return nil
+	return v.executor.Close()
 }
 
-func (v *vmctl) requirePowerState(vm *VM, state, action string) error {
-	err := v.cli.QueryPowerState(vm)
+func (v *vmctl) requirePowerState(ctx context.Context, vm *VM, state, action string) error {
+	err := v.cli.QueryPowerState(ctx, vm)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	if vm.PowerState != state {
-		return Fatalf("Power state '%s' is required to %s", state, action)
+		return wrapf("[%s] cannot %s: %w", vm.Name, action, &ErrPowerState{Want: state, Have: vm.PowerState})
 	}
 	return nil
 }
 
-func (v *vmctl) checkPowerState(vm *VM, command, state string) (bool, error) {
+func (v *vmctl) checkPowerState(ctx context.Context, vm *VM, command, state string) (bool, error) {
 	if v.debug {
 		log.Printf("checkPowerState(%s, %s, %s)\n", vm.Name, command, state)
 	}
 	err := v.validatePowerState(state)
 	if err != nil {
-		return false, Fatal(err)
+		return false, wrap(err)
 	}
-	err = v.cli.QueryPowerState(vm)
+	err = v.cli.QueryPowerState(ctx, vm)
 	if err != nil {
-		return false, Fatal(err)
+		return false, wrap(err)
 	}
 	if vm.PowerState == state {
 		log.Printf("[%s] ignoring %s in power state %s", vm.Name, command, vm.PowerState)
@@ -304,7 +380,7 @@ func (v *vmctl) checkPowerState(vm *VM, command, state string) (bool, error) {
 	return false, nil
 }
 
-func (v *vmctl) setStretch(vm *VM, enabled bool) error {
+func (v *vmctl) setStretch(ctx context.Context, vm *VM, enabled bool) error {
 	stretch := "FALSE"
 	action := "no_stretch"
 	if enabled {
@@ -315,9 +391,9 @@ func (v *vmctl) setStretch(vm *VM, enabled bool) error {
 		log.Printf("setStretch(%s) %s\n", vm.Name, action)
 	}
 	if stretch != "" {
-		err := v.cli.SetParam(vm, "gui.EnableStretchGuest", stretch)
+		err := v.cli.SetParam(ctx, vm, "gui.EnableStretchGuest", stretch)
 		if err != nil {
-			return Fatal(err)
+			return wrap(err)
 		}
 		if v.verbose {
 			fmt.Printf("[%s] display stretch %s\n", vm.Name, action)
@@ -338,34 +414,66 @@ func (v *vmctl) validatePowerState(state string) error {
 	}
 }
 
-func (v *vmctl) queryPowerState(vid string) (string, error) {
+func (v *vmctl) queryPowerState(ctx context.Context, vid string) (string, error) {
 	v.cli.Reset()
-	vm, err := v.cli.GetVM(vid)
+	vm, err := v.cli.GetVM(ctx, vid)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
-	err = v.cli.QueryPowerState(&vm)
+	err = v.cli.QueryPowerState(ctx, &vm)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 	return vm.PowerState, nil
 }
 
-func (v *vmctl) Wait(vid, state string) error {
+func (v *vmctl) Wait(ctx context.Context, vid, state string) error {
 	if v.debug {
 		log.Printf("Wait(%s, %s)\n", vid, state)
 	}
+	state = waitStateName(state)
+	ok, err := v.waitPowerState(ctx, vid, state, v.TimeoutSeconds)
+	if err != nil {
+		return wrap(err)
+	}
+	if !ok {
+		return wrapf("[%s] %w awaiting power state %s", vid, ErrTimeout, state)
+	}
+	return nil
+}
+
+// return the power state for a Wait state name or alias
+func waitStateName(state string) string {
 	switch strings.ToLower(state) {
 	case "up", "on", "running":
-		state = "on"
+		return "on"
 	case "down", "off", "stopped":
-		state = "off"
-	case "suspended":
-		state = "suspended"
+		return "off"
+	case "suspend", "suspended":
+		return "suspended"
+	case "pause", "paused":
+		return "paused"
 	}
+	return state
+}
+
+// sleep for interval, returning the context error early if ctx is done
+func sleepContext(ctx context.Context, interval time.Duration) error {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// poll until the power state matches; return false if timeoutSeconds elapses first
+func (v *vmctl) waitPowerState(ctx context.Context, vid, state string, timeoutSeconds int64) (bool, error) {
 	err := v.validatePowerState(state)
 	if err != nil {
-		return Fatal(err)
+		return false, wrap(err)
 	}
 
 	if v.verbose {
@@ -373,16 +481,16 @@ func (v *vmctl) Wait(vid, state string) error {
 	}
 	start := time.Now()
 	interval := time.Duration(v.IntervalSeconds) * time.Second
-	timeout := time.Duration(v.TimeoutSeconds) * time.Second
+	timeout := time.Duration(timeoutSeconds) * time.Second
 	checkPower := true
 	running := false
 	for {
 		if (state == "on") && !running {
 			// if waiting for poweredOn, ensure vmrun shows the instance before querying with vmrest API
 			checkPower = false
-			vms, err := v.Show("", ShowOptions{Running: true})
+			vms, err := v.Show(ctx, "", ShowOptions{Running: true})
 			if err != nil {
-				return Fatal(err)
+				return false, wrap(err)
 			}
 			for _, vm := range *vms {
 				if vm.Name == vid {
@@ -393,98 +501,101 @@ func (v *vmctl) Wait(vid, state string) error {
 		}
 
 		if checkPower {
-			newState, err := v.queryPowerState(vid)
+			newState, err := v.queryPowerState(ctx, vid)
 			if err != nil {
-				return Fatal(err)
+				return false, wrap(err)
 			}
 
 			if newState == state {
 				if v.verbose {
 					fmt.Printf("[%s] Detected power %s\n", vid, state)
 				}
-				return nil
+				return true, nil
 			}
 		}
-		if v.TimeoutSeconds != 0 {
+		if timeoutSeconds != 0 {
 			if time.Since(start) > timeout {
-				return Fatalf("[%s] Timed out awaiting power state %s", vid, state)
+				return false, nil
 			}
 		}
-		time.Sleep(interval)
+		err = sleepContext(ctx, interval)
+		if err != nil {
+			return false, wrap(err)
+		}
 		if running {
 			checkPower = true
 		}
 	}
 }
 
-func (v *vmctl) Get(vid string) (VM, error) {
+func (v *vmctl) Get(ctx context.Context, vid string) (VM, error) {
 	if v.debug {
 		log.Printf("Get(%s)\n", vid)
 	}
-	vm, err := v.cli.GetVM(vid)
+	vm, err := v.cli.GetVM(ctx, vid)
 	if err != nil {
-		return VM{}, Fatal(err)
+		return VM{}, wrap(err)
 	}
 	return vm, nil
 }
 
-func (v *vmctl) GetState(vid string) (*VMState, error) {
+func (v *vmctl) GetState(ctx context.Context, vid string) (*VMState, error) {
 
-	vm, err := v.Get(vid)
+	vm, err := v.Get(ctx, vid)
 	if err != nil {
-		return nil, Fatal(err)
+		return nil, wrap(err)
 	}
 
-	err = v.queryVM(&vm, QueryTypeState)
+	err = v.queryVM(ctx, &vm, QueryTypeState)
 	if err != nil {
-		return nil, Fatal(err)
+		return nil, wrap(err)
 	}
 	state := VMState{
 		Name:       vm.Name,
 		Path:       vm.Path,
 		Id:         vm.Id,
-		MacAddress: vm.MacAddress,
+		Nics:       vm.Nics,
 		IpAddress:  vm.IpAddress,
 		PowerState: vm.PowerState,
 	}
 	return &state, nil
 }
 
-func (v *vmctl) GetProperty(vid, property string) (string, error) {
+func (v *vmctl) GetProperty(ctx context.Context, vid, property string) (string, error) {
 	if v.verbose {
 		log.Printf("GetProperty(%s, %s)\n", vid, property)
 	}
-	vm, err := v.Get(vid)
+	vm, err := v.Get(ctx, vid)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 
 	switch strings.ToLower(property) {
 	case "vmx":
-		data, err := v.ReadHostFile(&vm, vm.Name+".vmx")
+		data, err := v.ReadHostFile(ctx, &vm, vm.Name+".vmx")
 		if err != nil {
-			return "", Fatal(err)
+			return "", wrap(err)
 		}
 		return string(data), nil
 
 	case "power", "powerstate":
-		err := v.cli.QueryPowerState(&vm)
+		err := v.cli.QueryPowerState(ctx, &vm)
 		if err != nil {
-			return "", Fatal(err)
+			return "", wrap(err)
 		}
 		return vm.PowerState, nil
 
 	case "ip", "ipaddress", "ipaddr":
-		err = v.getIpAddress(&vm)
+		err = v.getIpAddress(ctx, &vm)
 		if err != nil {
-			return "", Fatal(err)
+			return "", wrap(err)
 		}
 		return vm.IpAddress, nil
 
 	case "disk", "disks", "diskinfo", "disksize", "disksizemb", "diskcapacity":
-		disks, ok, err := v.getDisks(&vm)
+		disks, ok, err := v.getDisks(ctx, &vm)
 		if err != nil {
-			return "", Fatal(err)
+			return "", wrap(err)
 		}
 		if !ok {
 			return "", Fatalf("[%s] no disks found", vm.Name)
@@ -503,82 +614,96 @@ func (v *vmctl) GetProperty(vid, property string) (string, error) {
 		}
 
 	case "mac", "macaddr", "macaddress":
-		err := v.cli.GetMacAddress(&vm, nil)
+		err := v.cli.GetNics(ctx, &vm, nil)
+		if err != nil {
+			return "", wrap(err)
+		}
+		if len(vm.Nics) == 0 {
+			return "", nil
+		}
+		return vm.Nics[0].MacAddress, nil
+
+	case "nic", "nics":
+		err := v.cli.GetNics(ctx, &vm, nil)
+		if err != nil {
+			return "", wrap(err)
+		}
+		err = v.getIpAddress(ctx, &vm)
 		if err != nil {
-			return "", Fatal(err)
+			return "", wrap(err)
 		}
-		return vm.MacAddress, nil
+		return FormatJSON(vm.Nics), nil
 
 	case "state":
-		state, err := v.GetState(vid)
+		state, err := v.GetState(ctx, vid)
 		if err != nil {
-			return "", Fatal(err)
+			return "", wrap(err)
 		}
 		return FormatJSON(state), nil
 	}
 
 	switch strings.ToLower(property) {
 	case "config":
-		err = v.queryVM(&vm, QueryTypeConfig)
+		err = v.queryVM(ctx, &vm, QueryTypeConfig)
 		if err != nil {
-			return "", Fatal(err)
+			return "", wrap(err)
 		}
 		return FormatJSON(&vm), nil
 	case "all", "detail", "":
-		err := v.queryVM(&vm, QueryTypeAll)
+		err := v.queryVM(ctx, &vm, QueryTypeAll)
 		if err != nil {
-			return "", Fatal(err)
+			return "", wrap(err)
 		}
 		return FormatJSON(&vm), nil
 	}
 
 	// try property as a VM key
-	value, ok, err := v.queryVMProperty(&vm, property)
+	value, ok, err := v.queryVMProperty(ctx, &vm, property)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 	if ok {
 		return value, nil
 	}
 
 	// try property as a VMX key
-	value, err = v.cli.GetParam(&vm, property)
+	value, err = v.cli.GetParam(ctx, &vm, property)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 	return value, nil
 }
 
-func (v *vmctl) queryVM(vm *VM, queryType QueryType) error {
+func (v *vmctl) queryVM(ctx context.Context, vm *VM, queryType QueryType) error {
 	if v.debug {
 		log.Printf("queryVM(%s, %d)\n", vm.Name, queryType)
 	}
 	if queryType == QueryTypeConfig || queryType == QueryTypeAll {
-		err := v.cli.GetConfig(vm)
+		err := v.cli.GetConfig(ctx, vm)
 		if err != nil {
-			return Fatal(err)
+			return wrap(err)
 		}
-		_, _, err = v.getDisks(vm)
+		_, _, err = v.getDisks(ctx, vm)
 		if err != nil {
-			return Fatal(err)
+			return wrap(err)
 		}
 	}
 	if queryType == QueryTypeState || queryType == QueryTypeAll {
-		err := v.cli.QueryPowerState(vm)
+		err := v.cli.QueryPowerState(ctx, vm)
 		if err != nil {
 			if checkEncryptedError(vm, err) {
 				vm.Encrypted = true
 				return nil
 			}
-			return Fatal(err)
+			return wrap(err)
 		}
-		err = v.cli.GetMacAddress(vm, nil)
+		err = v.cli.GetNics(ctx, vm, nil)
 		if err != nil {
-			return Fatal(err)
+			return wrap(err)
 		}
-		err = v.getIpAddress(vm)
+		err = v.getIpAddress(ctx, vm)
 		if err != nil {
-			return Fatal(err)
+			return wrap(err)
 		}
 	}
 	return nil
@@ -590,7 +715,7 @@ func (v *vmctl) mapVMKeys() error {
 	}
 	vmap, err := v.toMap(&VM{})
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	v.vmkey = make(map[string]string)
 	for k, _ := range vmap {
@@ -607,16 +732,16 @@ func (v *vmctl) toMap(vm *VM) (map[string]any, error) {
 	}
 	data, err := json.Marshal(vm)
 	if err != nil {
-		return vmap, Fatal(err)
+		return vmap, wrap(err)
 	}
 	err = json.Unmarshal([]byte(data), &vmap)
 	if err != nil {
-		return vmap, Fatal(err)
+		return vmap, wrap(err)
 	}
 	return vmap, nil
 }
 
-func (v *vmctl) queryVMProperty(vm *VM, property string) (string, bool, error) {
+func (v *vmctl) queryVMProperty(ctx context.Context, vm *VM, property string) (string, bool, error) {
 	if v.debug {
 		log.Printf("queryVMProperty(%s, %s)\n", vm.Name, property)
 	}
@@ -626,21 +751,21 @@ func (v *vmctl) queryVMProperty(vm *VM, property string) (string, bool, error) {
 		return "", false, nil
 	}
 
-	err := v.queryVM(vm, QueryTypeAll)
+	err := v.queryVM(ctx, vm, QueryTypeAll)
 	if err != nil {
-		return "", false, Fatal(err)
+		return "", false, wrap(err)
 	}
 
 	vmap, err := v.toMap(vm)
 	if err != nil {
-		return "", false, Fatal(err)
+		return "", false, wrap(err)
 	}
 
 	value, ok := vmap[key]
 	if ok {
 		data, err := json.Marshal(value)
 		if err != nil {
-			return "", false, Fatal(err)
+			return "", false, wrap(err)
 		}
 		return string(data), true, nil
 		//return fmt.Sprintf("%v", value), true, nil
@@ -658,20 +783,20 @@ func FormatVMXBool(value string) (string, error) {
 	return "", Fatalf("cannot format '%s' as boolean", value)
 }
 
-func (v *vmctl) SetProperty(vid, property, value string) error {
+func (v *vmctl) SetProperty(ctx context.Context, vid, property, value string) error {
 	if v.debug {
 		log.Printf("SetProperty(%s, %s, %s)\n", vid, property, value)
 	}
-	vm, err := v.cli.GetVM(vid)
+	vm, err := v.cli.GetVM(ctx, vid)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	if v.verbose {
 		fmt.Printf("[%s] setting %s=%s\n", vm.Name, property, value)
 	}
 
 	if property == "vmx" {
-		return v.WriteHostFile(&vm, vm.Name+".vmx", []byte(value))
+		return v.WriteHostFile(ctx, &vm, vm.Name+".vmx", []byte(value))
 	} else {
 		key, ok := v.vmkey[strings.ToLower(property)]
 		if ok {
@@ -681,12 +806,16 @@ func (v *vmctl) SetProperty(vid, property, value string) error {
 				return Fatalf("Property '%s' is read-only", key)
 
 			case "DiskSize":
-				return Fatalf("Use host utility vmware-vdiskmanager to modify %s", key)
+				_, err := v.ExpandDisk(ctx, vm.Name, "", value)
+				if err != nil {
+					return wrap(err)
+				}
+				return nil
 
 			case "Running", "PowerState":
 				return Fatalf("Use 'start', 'stop', or 'kill' to modify %s", key)
 
-			case "MacAddress", "IsoFile", "IsoAttached", "IsoBootConnected", "SerialAttched", "SerialPipe", "VncEnabled", "VncPort", "FileShareEnabled", "ClipboardEnabled":
+			case "Nics", "IsoFile", "IsoAttached", "IsoBootConnected", "SerialAttched", "SerialPipe", "VncEnabled", "VncPort", "FileShareEnabled", "ClipboardEnabled":
 				return Fatalf("Use modify command to change %s", key)
 
 			case "CpuCount":
@@ -696,7 +825,7 @@ func (v *vmctl) SetProperty(vid, property, value string) error {
 				property = "memsize"
 				size, err := SizeParse(value)
 				if err != nil {
-					return Fatal(err)
+					return wrap(err)
 				}
 				value = fmt.Sprintf("%d", size/MB)
 
@@ -707,32 +836,32 @@ func (v *vmctl) SetProperty(vid, property, value string) error {
 				property = "guestOS"
 
 			}
-			err := v.requirePowerState(&vm, "off", fmt.Sprintf("modify '%s'", key))
+			err := v.requirePowerState(ctx, &vm, "off", fmt.Sprintf("modify '%s'", key))
 
 			if err != nil {
-				return Fatal(err)
+				return wrap(err)
 			}
 		}
-		err = v.cli.SetParam(&vm, property, value)
+		err = v.cli.SetParam(ctx, &vm, property, value)
 		if err != nil {
-			return Fatal(err)
+			return wrap(err)
 		}
 	}
 	return nil
 }
 
-func (v *vmctl) getIpAddress(vm *VM) error {
+func (v *vmctl) getIpAddress(ctx context.Context, vm *VM) error {
 	if v.debug {
 		log.Printf("getIpAddress(%s)\n", vm.Name)
 	}
 	path, err := PathnameFormat(v.Remote, vm.Path)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	var exitCode int
-	olines, err := v.RemoteExec("vmrun getGuestIpAddress "+path, &exitCode)
+	olines, err := v.RemoteExec(ctx, "vmrun getGuestIpAddress "+path, &exitCode)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	if v.debug {
 		log.Printf("getIpAddress: exitCode: %d\n", exitCode)
@@ -744,39 +873,54 @@ func (v *vmctl) getIpAddress(vm *VM) error {
 		}
 		vm.IpAddress = addr
 	}
-	if vm.IpAddress == "" && vm.MacAddress != "" {
-		addr, err := v.ArpQuery(vm)
+	if len(vm.Nics) == 1 && vm.IpAddress != "" {
+		vm.Nics[0].IpAddress = vm.IpAddress
+		return nil
+	}
+	// resolve each adapter's address from the host ARP table
+	if len(vm.Nics) > 1 || vm.IpAddress == "" {
+		err := v.ArpQuery(ctx, vm)
 		if err != nil {
-			return Fatal(err)
+			return wrap(err)
+		}
+	}
+	for _, nic := range vm.Nics {
+		if vm.IpAddress == "" && nic.IpAddress != "" {
+			vm.IpAddress = nic.IpAddress
 		}
-		vm.IpAddress = addr
 	}
 	return nil
 }
 
-func (v *vmctl) getDisks(vm *VM) ([]VMDisk, bool, error) {
+func (v *vmctl) getDisks(ctx context.Context, vm *VM) ([]VMDisk, bool, error) {
 	if v.debug {
 		log.Printf("getDisk(%s)\n", vm.Name)
 	}
 	disks := []VMDisk{}
-	vmxData, err := v.ReadHostFile(vm, fmt.Sprintf("%s.vmx", vm.Name))
+	vmxData, err := v.ReadHostFile(ctx, vm, fmt.Sprintf("%s.vmx", vm.Name))
 	if err != nil {
-		return disks, false, Fatal(err)
+		return disks, false, wrap(err)
 	}
 
 	var found bool
 	vmdks, err := ScanVMX(vmxData)
 	if err != nil {
-		return disks, false, Fatal(err)
+		return disks, false, wrap(err)
+	}
+	devices := []string{}
+	for device := range vmdks {
+		devices = append(devices, device)
 	}
-	for device, filename := range vmdks {
-		vmdkData, err := v.ReadHostFile(vm, filename)
+	sort.Strings(devices)
+	for _, device := range devices {
+		filename := vmdks[device]
+		vmdkData, err := v.ReadHostFile(ctx, vm, filename)
 		if err != nil {
-			return disks, false, Fatal(err)
+			return disks, false, wrap(err)
 		}
 		disk, err := NewVMDisk(device, filename, vmdkData)
 		if err != nil {
-			return disks, false, Fatal(err)
+			return disks, false, wrap(err)
 		}
 		if !found {
 			vm.DiskSize = disk.Size
@@ -791,7 +935,7 @@ func (v *vmctl) getDisks(vm *VM) ([]VMDisk, bool, error) {
 }
 
 func checkEncryptedError(vm *VM, err error) bool {
-	if ENCRYPTED_VM_ERROR.MatchString(fmt.Sprintf("%v", err)) {
+	if errors.Is(err, ErrEncrypted) {
 		log.Printf("WARNING: %s is encrypted\n", vm.Name)
 		vm.Encrypted = true
 		return true
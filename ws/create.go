@@ -1,6 +1,8 @@
 package ws
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"path"
@@ -40,8 +42,8 @@ type CreateOptions struct {
 	SharedHostPath   string
 	SharedGuestPath  string
 
-	ModifyNIC  bool
-	MacAddress string
+	ModifyNIC bool
+	NIC       NICOptions
 
 	ModifyTTY    bool
 	SerialPipe   string
@@ -63,6 +65,12 @@ type CreateOptions struct {
 	Device0   string
 	Device1   string
 
+	ModifyGuestInfo bool
+	GuestInfo       map[string]string
+
+	ModifySeedISO bool
+	SeedISOFile   string
+
 	Wait bool
 }
 
@@ -87,45 +95,48 @@ func NewCreateOptions() *CreateOptions {
 		ModifyGuestOS:   true,
 		GuestOS:         "other",
 		ModifyNIC:       true,
-		MacAddress:      "auto",
+		NIC:             NICOptions{MacAddress: "auto"},
 		ModifyClipboard: true,
 		ModifyFloppy:    true,
 		VNCPort:         5900,
 	}
 }
 
-func (v *vmctl) Create(name string, options CreateOptions, isoOptions IsoOptions) (string, error) {
+func (v *vmctl) Create(ctx context.Context, name string, options CreateOptions, isoOptions IsoOptions) (string, error) {
 
 	if v.debug {
 		log.Printf("Create(name='%s', options='%+v' isoOptions='%+v'\n", name, options, isoOptions)
 	}
 
 	// check for existing instance
-	_, err := v.cli.GetVM(name)
+	_, err := v.cli.GetVM(ctx, name)
 	if err == nil {
-		return "", Fatalf("create failed, instance '%s' exists", name)
+		return "", wrapf("create failed, instance '%s' %w", name, ErrExists)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return "", wrap(err)
 	}
 
 	// log create options
 	ostr := FormatJSON(&options)
 	log.Printf("create: %s\n%s\n", name, ostr)
 
-	vm, err := v.cli.Create(name, options.GuestOS)
+	vm, err := v.cli.Create(ctx, name, options.GuestOS)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 
 	options.Name = vm.Name
 	options.DiskName = vm.Name + ".vmdk"
 
-	err = v.cli.CreateDisk(vm, options.DiskName, options.DiskSize, options.DiskSingleFile, options.DiskPreallocated)
+	err = v.cli.CreateDisk(ctx, vm, options.DiskName, options.DiskSize, DiskAdapterType(DEFAULT_DISK_CONTROLLER), options.DiskSingleFile, options.DiskPreallocated)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 
-	actions, err := v.Modify(vm.Name, options, isoOptions)
+	actions, err := v.Modify(ctx, vm.Name, options, isoOptions)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 
 	if v.verbose {
@@ -135,17 +146,17 @@ func (v *vmctl) Create(name string, options CreateOptions, isoOptions IsoOptions
 	}
 
 	if options.Wait {
-		err := v.Wait(name, "off")
+		err := v.Wait(ctx, name, "off")
 		if err != nil {
-			return "", Fatal(err)
+			return "", wrap(err)
 		}
-		_, err = v.Start(name, StartOptions{Background: true, Wait: true}, IsoOptions{})
+		_, err = v.Start(ctx, name, StartOptions{Background: true, Wait: true}, IsoOptions{})
 		if err != nil {
-			return "", Fatal(err)
+			return "", wrap(err)
 		}
-		_, err = v.Stop(name, StopOptions{Wait: true})
+		_, err = v.Stop(ctx, name, StopOptions{Wait: true})
 		if err != nil {
-			return "", Fatal(err)
+			return "", wrap(err)
 		}
 		return "created", nil
 	}
@@ -153,33 +164,42 @@ func (v *vmctl) Create(name string, options CreateOptions, isoOptions IsoOptions
 	return "create pending", nil
 }
 
-func (v *vmctl) Destroy(vid string, options DestroyOptions) error {
+func (v *vmctl) Destroy(ctx context.Context, vid string, options DestroyOptions) error {
 	if v.debug {
 		log.Printf("Destroy: %s %+v\n", vid, options)
 	}
-	vm, err := v.Get(vid)
+	vm, err := v.Get(ctx, vid)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
-	err = v.cli.QueryPowerState(&vm)
+	err = v.cli.QueryPowerState(ctx, &vm)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	if vm.PowerState != "off" {
 		if options.Force {
-			_, err := v.Stop(vid, StopOptions{PowerOff: true, Wait: true})
+			_, err := v.Stop(ctx, vid, StopOptions{PowerOff: true, Wait: true})
 			if err != nil {
-				return Fatal(err)
+				return wrap(err)
 			}
 		} else {
-			return Fatalf("[%s] --kill required; power state is '%s'", vm.Name, vm.PowerState)
+			return wrapf("[%s] --kill required: %w", vm.Name, &ErrPowerState{Want: "off", Have: vm.PowerState})
 		}
 
 	}
+	err = v.removeInstanceDir(ctx, &vm)
+	if err != nil {
+		return wrap(err)
+	}
+	return nil
+}
+
+// delete the directory containing the instance files
+func (v *vmctl) removeInstanceDir(ctx context.Context, vm *VM) error {
 	dir, _ := path.Split(vm.Path)
 	hostPath, err := PathnameFormat(v.Remote, dir)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	hostPath = strings.TrimRight(hostPath, "/\\")
 	var command string
@@ -189,9 +209,9 @@ func (v *vmctl) Destroy(vid string, options DestroyOptions) error {
 	default:
 		command = "rm -rf " + hostPath
 	}
-	_, err = v.RemoteExec(command, nil)
+	_, err = v.RemoteExec(ctx, command, nil)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	return nil
 }
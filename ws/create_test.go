@@ -0,0 +1,65 @@
+package ws
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateModifyDestroy(t *testing.T) {
+	ctx := t.Context()
+	v, host := newFakeController(t)
+	options := NewCreateOptions()
+	options.CpuCount = 2
+	options.MemorySize = "2G"
+	options.GuestOS = "ubuntu-64"
+	result, err := v.Create(ctx, "web1", *options, IsoOptions{})
+	require.Nil(t, err)
+	require.Equal(t, "create pending", result)
+	require.True(t, IsFile(host.local("/vmware/web1/web1.vmx")))
+	require.True(t, IsFile(host.local("/vmware/web1/web1.vmdk")))
+
+	_, err = v.Create(ctx, "web1", *options, IsoOptions{})
+	require.NotNil(t, err)
+
+	vm, err := v.Get(ctx, "web1")
+	require.Nil(t, err)
+	require.Equal(t, "/vmware/web1/web1.vmx", vm.Path)
+	err = v.cli.GetConfig(ctx, &vm)
+	require.Nil(t, err)
+	require.Equal(t, 2, vm.CpuCount)
+	require.Equal(t, "2G", vm.RamSize)
+
+	_, err = v.Modify(ctx, "web1", CreateOptions{ModifyCpu: true, CpuCount: 4}, IsoOptions{})
+	require.Nil(t, err)
+	value, err := v.GetProperty(ctx, "web1", "CpuCount")
+	require.Nil(t, err)
+	require.Equal(t, "4", value)
+
+	require.Nil(t, v.Destroy(ctx, "web1", DestroyOptions{}))
+	require.False(t, IsDir(host.local("/vmware/web1")))
+	v.cli.Reset()
+	_, err = v.Get(ctx, "web1")
+	require.NotNil(t, err)
+}
+
+func TestFakeHostTransfer(t *testing.T) {
+	ctx := t.Context()
+	v, host := newFakeController(t)
+	_, err := v.Create(ctx, "web1", *NewCreateOptions(), IsoOptions{})
+	require.Nil(t, err)
+
+	localFile := filepath.Join(t.TempDir(), "notes.txt")
+	require.Nil(t, os.WriteFile(localFile, []byte("notes"), 0600))
+	require.Nil(t, v.Upload(ctx, "web1", localFile, "notes.txt"))
+	data, err := os.ReadFile(host.local("/vmware/web1/notes.txt"))
+	require.Nil(t, err)
+	require.Equal(t, "notes", string(data))
+
+	downloaded := filepath.Join(t.TempDir(), "web1.vmx")
+	require.Nil(t, v.Download(ctx, "web1", downloaded, "web1.vmx"))
+	data, err = os.ReadFile(downloaded)
+	require.Nil(t, err)
+	require.Contains(t, string(data), `displayName = "web1"`)
+}
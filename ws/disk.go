@@ -3,10 +3,17 @@ package ws
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"encoding/binary"
 	"encoding/json"
+	"fmt"
 	"github.com/vmware/govmomi/vmdk"
 	"log"
+	"os"
+	"path"
 	"regexp"
+	"strconv"
+	"strings"
 )
 
 var VMDK_VMX_LINE = regexp.MustCompile(`^([^:]+:\d+).fileName = "([^.]+.[vV][mM][dD][kK])"\s*$`)
@@ -26,7 +33,7 @@ const (
 var diskTypeName = map[VDiskType]string{
 	DiskTypeSingleFileGrowable:     "single_file_growable",
 	DiskTypeMultiFileGrowable:      "multiple_file_growable",
-	DiskTypeSingleFilePreallocated: "sigle_file_preallocated",
+	DiskTypeSingleFilePreallocated: "single_file_preallocated",
 	DiskTypeMultiFilePreallocated:  "multiple_file_preallocated",
 	DiskTypeESXPreallocated:        "preallocated_ESX",
 	DiskTypeStreaming:              "compressed_streaming_optimized",
@@ -42,9 +49,20 @@ type VMDisk struct {
 	File       string
 	Capacity   int64
 	Size       string
+	Delta      bool
 	Descriptor map[string]any
 }
 
+// return the disk type for a type name or number
+func ParseVDiskType(name string) (VDiskType, error) {
+	for diskType, typeName := range diskTypeName {
+		if strings.EqualFold(name, typeName) || name == strconv.Itoa(int(diskType)) {
+			return diskType, nil
+		}
+	}
+	return 0, Fatalf("invalid disk type: '%s'", name)
+}
+
 func ParseDiskType(singleFile, preallocated bool) VDiskType {
 	switch {
 	case singleFile && preallocated:
@@ -72,7 +90,7 @@ func ScanVMX(data []byte) (map[string]string, error) {
 	}
 	err := scanner.Err()
 	if err != nil {
-		return disks, Fatal(err)
+		return disks, wrap(err)
 	}
 	return disks, nil
 }
@@ -85,31 +103,633 @@ func NewVMDisk(device, filename string, data []byte) (*VMDisk, error) {
 	}
 	err := disk.parseVMDK(data)
 	if err != nil {
-		return nil, Fatal(err)
+		return nil, wrap(err)
 	}
 	return &disk, nil
 }
 
 func (d *VMDisk) parseVMDK(data []byte) error {
 
+	// single-file sparse disks embed the descriptor after the binary header
+	if len(data) >= VMDK_SECTOR_SIZE && binary.LittleEndian.Uint32(data) == VMDK_SPARSE_MAGIC {
+		reader, err := OpenVMDKSparse(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return wrap(err)
+		}
+		data, err = reader.Descriptor()
+		if err != nil {
+			return wrap(err)
+		}
+	}
+
 	buf := bytes.NewBuffer(data)
 
 	descriptor, err := vmdk.ParseDescriptor(buf)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	descriptorData, err := json.MarshalIndent(descriptor, "", "  ")
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	err = json.Unmarshal(descriptorData, &d.Descriptor)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	log.Printf("descriptorData: %s\n", string(descriptorData))
 
 	d.Capacity = descriptor.Capacity()
 	d.Size = FormatSize(d.Capacity)
+	// a snapshot delta disk has a parent content ID
+	d.Delta = descriptor.ParentCID != 0 && descriptor.ParentCID != 0xffffffff
 
 	return nil
 }
+
+var DISK_SLOT_PATTERN = regexp.MustCompile(`^(nvme|scsi|sata|ide)(\d+):(\d+)$`)
+var VMDK_EXTENT_LINE = regexp.MustCompile(`^(?:RW|RDONLY|NOACCESS) \d+ \w+ "([^"]+)"`)
+
+const DEFAULT_DISK_CONTROLLER = "nvme"
+const DEFAULT_SCSI_ADAPTER = "lsilogic"
+
+type diskController struct {
+	buses    int
+	units    int
+	reserved int
+}
+
+// bus and unit limits per controller type; scsi unit 7 is the controller itself
+var diskControllers = map[string]diskController{
+	"nvme": {buses: 4, units: 15, reserved: -1},
+	"scsi": {buses: 4, units: 16, reserved: 7},
+	"sata": {buses: 4, units: 30, reserved: -1},
+	"ide":  {buses: 2, units: 2, reserved: -1},
+}
+
+var SCSI_ADAPTERS = map[string]bool{
+	"lsilogic": true,
+	"pvscsi":   true,
+}
+
+type DiskOptions struct {
+	Controller   string // nvme, scsi, sata or ide
+	Slot         string // device such as 'sata0:1'; the first free slot is used if empty
+	Adapter      string // scsi controller type: lsilogic or pvscsi
+	File         string // VMDK filename in the instance directory
+	Size         string
+	SingleFile   bool
+	Preallocated bool
+	Attach       bool   // attach an existing VMDK instead of creating one
+	Image        string // local raw, qcow2 or VMDK image converted to create the disk
+	Stream       bool   // upload the image as a streamOptimized VMDK
+	Delete       bool   // delete the VMDK files when the disk is removed
+}
+
+// return controller, bus and unit for a disk slot such as 'scsi0:1'
+func ParseDiskSlot(slot string) (string, int, int, error) {
+	m := DISK_SLOT_PATTERN.FindStringSubmatch(strings.ToLower(slot))
+	if m == nil {
+		return "", 0, 0, Fatalf("invalid disk slot: '%s'", slot)
+	}
+	controller := diskControllers[m[1]]
+	bus, _ := strconv.Atoi(m[2])
+	unit, _ := strconv.Atoi(m[3])
+	if bus >= controller.buses || unit >= controller.units || unit == controller.reserved {
+		return "", 0, 0, Fatalf("invalid disk slot: '%s'", slot)
+	}
+	return m[1], bus, unit, nil
+}
+
+// return the vmcli disk adapter type for a controller
+func DiskAdapterType(controller string) string {
+	if controller == "ide" {
+		return "ide"
+	}
+	return "lsilogic"
+}
+
+// return the files referenced by the extent lines of a VMDK descriptor
+func VMDKExtentFiles(data []byte) []string {
+	files := []string{}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		m := VMDK_EXTENT_LINE.FindSubmatch(bytes.TrimSpace(line))
+		if m != nil {
+			files = append(files, string(m[1]))
+		}
+	}
+	return files
+}
+
+// return the present disk devices and their VMDK filenames
+func (v *VMX) Disks() map[string]string {
+	disks := make(map[string]string)
+	for _, key := range v.doc.Keys() {
+		slot, ok := strings.CutSuffix(key, ".fileName")
+		if !ok || !DISK_SLOT_PATTERN.MatchString(strings.ToLower(slot)) || !v.doc.GetBool(slot+".present", false) {
+			continue
+		}
+		filename, _ := v.doc.Get(key)
+		if strings.HasSuffix(strings.ToLower(filename), ".vmdk") {
+			disks[slot] = filename
+		}
+	}
+	return disks
+}
+
+// return the first unused slot on a controller
+func (v *VMX) FreeDiskSlot(controller string) (string, error) {
+	limits, ok := diskControllers[controller]
+	if !ok {
+		return "", Fatalf("invalid disk controller: '%s'", controller)
+	}
+	for bus := 0; bus < limits.buses; bus++ {
+		for unit := 0; unit < limits.units; unit++ {
+			slot := fmt.Sprintf("%s%d:%d", controller, bus, unit)
+			if unit != limits.reserved && !v.doc.GetBool(slot+".present", false) {
+				return slot, nil
+			}
+		}
+	}
+	return "", Fatalf("no free %s disk slot", controller)
+}
+
+// attach a VMDK at slot, enabling the controller; adapter selects the scsi controller type
+func (v *VMX) AddDisk(slot, filename, adapter string) (string, error) {
+	if v.debug {
+		log.Printf("AddDisk(%s, %s, %s)\n", slot, filename, adapter)
+	}
+	controller, bus, _, err := ParseDiskSlot(slot)
+	if err != nil {
+		return "", wrap(err)
+	}
+	slot = strings.ToLower(slot)
+	if v.doc.GetBool(slot+".present", false) {
+		return "", Fatalf("disk slot %s is in use", slot)
+	}
+	busKey := fmt.Sprintf("%s%d", controller, bus)
+	if controller != "ide" {
+		v.doc.SetBool(busKey+".present", true)
+	}
+	if controller == "scsi" {
+		if adapter == "" && !v.doc.Has(busKey+".virtualDev") {
+			adapter = DEFAULT_SCSI_ADAPTER
+		}
+		if adapter != "" {
+			if !SCSI_ADAPTERS[adapter] {
+				return "", Fatalf("invalid scsi adapter: '%s'", adapter)
+			}
+			v.doc.Set(busKey+".virtualDev", adapter)
+		}
+	} else if adapter != "" {
+		return "", Fatalf("adapter type is only valid for scsi disks")
+	}
+	v.doc.DeleteTree(slot)
+	v.doc.SetBool(slot+".present", true)
+	v.doc.Set(slot+".fileName", filename)
+	return fmt.Sprintf("Added disk %s %s", slot, filename), nil
+}
+
+// detach the disk at slot, returning its filename
+func (v *VMX) RemoveDisk(slot string) (string, error) {
+	if v.debug {
+		log.Printf("RemoveDisk(%s)\n", slot)
+	}
+	_, _, _, err := ParseDiskSlot(slot)
+	if err != nil {
+		return "", wrap(err)
+	}
+	var filename string
+	for key, value := range v.Disks() {
+		if strings.EqualFold(key, slot) {
+			filename = value
+		}
+	}
+	if filename == "" {
+		return "", Fatalf("no disk at slot %s", slot)
+	}
+	v.doc.DeleteTree(slot)
+	return filename, nil
+}
+
+func (v *vmctl) ListDisks(ctx context.Context, vid string) ([]VMDisk, error) {
+	if v.debug {
+		log.Printf("ListDisks(%s)\n", vid)
+	}
+	vm, err := v.cli.GetVM(ctx, vid)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	disks, _, err := v.getDisks(ctx, &vm)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	return disks, nil
+}
+
+func (v *vmctl) readVMX(ctx context.Context, vm *VM) (*VMX, error) {
+	data, err := v.ReadHostFile(ctx, vm, vm.Name+".vmx")
+	if err != nil {
+		return nil, wrap(err)
+	}
+	vmx, err := InitVMX(v.Remote, vm.Name, data)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	return vmx, nil
+}
+
+func (v *vmctl) writeVMX(ctx context.Context, vm *VM, vmx *VMX) error {
+	data, err := vmx.Read()
+	if err != nil {
+		return wrap(err)
+	}
+	return v.WriteHostFile(ctx, vm, vm.Name+".vmx", data)
+}
+
+// return true if filename exists in the instance directory
+func (v *vmctl) instanceFileExists(ctx context.Context, vm *VM, filename string) (bool, error) {
+	files, err := v.Files(ctx, vm.Name, FilesOptions{All: true})
+	if err != nil {
+		return false, wrap(err)
+	}
+	for _, file := range files {
+		_, name := path.Split(strings.ReplaceAll(strings.TrimSpace(file), "\\", "/"))
+		if strings.EqualFold(name, filename) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// create or attach a VMDK disk
+func (v *vmctl) AddDisk(ctx context.Context, vid string, options DiskOptions) (string, error) {
+	if v.debug {
+		log.Printf("AddDisk(%s, %+v)\n", vid, options)
+	}
+	vm, err := v.cli.GetVM(ctx, vid)
+	if err != nil {
+		return "", wrap(err)
+	}
+	err = v.requirePowerState(ctx, &vm, "off", "add a disk")
+	if err != nil {
+		return "", wrap(err)
+	}
+	vmx, err := v.readVMX(ctx, &vm)
+	if err != nil {
+		return "", wrap(err)
+	}
+	slot := strings.ToLower(options.Slot)
+	if slot == "" {
+		controller := strings.ToLower(options.Controller)
+		if controller == "" {
+			controller = DEFAULT_DISK_CONTROLLER
+		}
+		slot, err = vmx.FreeDiskSlot(controller)
+		if err != nil {
+			return "", wrap(err)
+		}
+	}
+	controller, _, _, err := ParseDiskSlot(slot)
+	if err != nil {
+		return "", wrap(err)
+	}
+	if options.Controller != "" && !strings.EqualFold(options.Controller, controller) {
+		return "", Fatalf("conflict: controller %s, slot %s", options.Controller, slot)
+	}
+	filename := options.File
+	if filename == "" {
+		filename = fmt.Sprintf("%s-%s.vmdk", vm.Name, strings.ReplaceAll(slot, ":", "-"))
+	}
+	if strings.ContainsAny(filename, "/\\") || !strings.HasSuffix(strings.ToLower(filename), ".vmdk") {
+		return "", Fatalf("invalid disk filename: '%s'", filename)
+	}
+	exists, err := v.instanceFileExists(ctx, &vm, filename)
+	if err != nil {
+		return "", wrap(err)
+	}
+	switch {
+	case options.Attach && !exists:
+		return "", Fatalf("[%s] disk file not found: %s", vm.Name, filename)
+	case !options.Attach && exists:
+		return "", Fatalf("[%s] disk file exists: %s", vm.Name, filename)
+	}
+	action, err := vmx.AddDisk(slot, filename, strings.ToLower(options.Adapter))
+	if err != nil {
+		return "", wrap(err)
+	}
+	switch {
+	case options.Attach:
+	case options.Image != "":
+		err = v.importDisk(ctx, &vm, filename, DiskAdapterType(controller), options)
+		if err != nil {
+			return "", wrap(err)
+		}
+	default:
+		size := options.Size
+		if size == "" {
+			size = "16G"
+		}
+		if v.verbose {
+			fmt.Printf("[%s] Creating %s disk %s\n", vm.Name, size, filename)
+		}
+		err = v.cli.CreateDisk(ctx, &vm, filename, size, DiskAdapterType(controller), options.SingleFile, options.Preallocated)
+		if err != nil {
+			return "", wrap(err)
+		}
+	}
+	err = v.writeVMX(ctx, &vm, vmx)
+	if err != nil {
+		return "", wrap(err)
+	}
+	return action, nil
+}
+
+// detach a disk, optionally deleting its VMDK files
+func (v *vmctl) RemoveDisk(ctx context.Context, vid, slot string, options DiskOptions) (string, error) {
+	if v.debug {
+		log.Printf("RemoveDisk(%s, %s, %+v)\n", vid, slot, options)
+	}
+	vm, err := v.cli.GetVM(ctx, vid)
+	if err != nil {
+		return "", wrap(err)
+	}
+	err = v.requirePowerState(ctx, &vm, "off", "remove a disk")
+	if err != nil {
+		return "", wrap(err)
+	}
+	vmx, err := v.readVMX(ctx, &vm)
+	if err != nil {
+		return "", wrap(err)
+	}
+	filename, err := vmx.RemoveDisk(slot)
+	if err != nil {
+		return "", wrap(err)
+	}
+	files := []string{}
+	if options.Delete {
+		files, err = v.vmdkFiles(ctx, &vm, filename)
+		if err != nil {
+			return "", wrap(err)
+		}
+	}
+	err = v.writeVMX(ctx, &vm, vmx)
+	if err != nil {
+		return "", wrap(err)
+	}
+	for _, file := range files {
+		err := v.cli.DeleteDisk(ctx, &vm, file)
+		if err != nil {
+			return "", wrap(err)
+		}
+	}
+	if options.Delete {
+		return fmt.Sprintf("Removed disk %s; deleted %s", slot, strings.Join(files, ", ")), nil
+	}
+	return fmt.Sprintf("Removed disk %s %s", slot, filename), nil
+}
+
+// return the extent files and descriptor of a VMDK in the instance directory
+func (v *vmctl) vmdkFiles(ctx context.Context, vm *VM, filename string) ([]string, error) {
+	data, err := v.ReadHostFile(ctx, vm, filename)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	files := []string{}
+	for _, extent := range VMDKExtentFiles(data) {
+		if !strings.EqualFold(extent, filename) && !strings.ContainsAny(extent, "/\\") {
+			files = append(files, extent)
+		}
+	}
+	return append(files, filename), nil
+}
+
+// return the disk selected by slot or filename; an empty name selects the first disk
+func (v *vmctl) findDisk(ctx context.Context, vm *VM, name string) (*VMDisk, error) {
+	disks, found, err := v.getDisks(ctx, vm)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if !found {
+		return nil, Fatalf("[%s] no disks found", vm.Name)
+	}
+	if name == "" {
+		return &disks[0], nil
+	}
+	for _, disk := range disks {
+		if strings.EqualFold(disk.Device, name) || strings.EqualFold(disk.File, name) {
+			return &disk, nil
+		}
+	}
+	return nil, Fatalf("[%s] disk not found: %s", vm.Name, name)
+}
+
+// check the instance is powered off and has no snapshots, returning the selected disk
+func (v *vmctl) prepareDiskChange(ctx context.Context, vid, name, action string) (*VM, *VMDisk, error) {
+	vm, err := v.cli.GetVM(ctx, vid)
+	if err != nil {
+		return nil, nil, wrap(err)
+	}
+	err = v.requirePowerState(ctx, &vm, "off", action)
+	if err != nil {
+		return nil, nil, wrap(err)
+	}
+	snapshots, err := v.ListSnapshots(ctx, vm.Name)
+	if err != nil {
+		return nil, nil, wrap(err)
+	}
+	if len(snapshots) > 0 {
+		return nil, nil, Fatalf("[%s] cannot %s: instance has %d snapshots", vm.Name, action, len(snapshots))
+	}
+	disk, err := v.findDisk(ctx, &vm, name)
+	if err != nil {
+		return nil, nil, wrap(err)
+	}
+	if disk.Delta {
+		return nil, nil, Fatalf("[%s] cannot %s: %s is a snapshot delta disk", vm.Name, action, disk.File)
+	}
+	return &vm, disk, nil
+}
+
+// run vmware-vdiskmanager on a disk and return the disk with refreshed capacity
+func (v *vmctl) diskManager(ctx context.Context, vm *VM, disk *VMDisk, flag string, args ...string) (*VMDisk, error) {
+	_, hostPathname, err := v.cli.diskPathnames(vm, disk.File)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	args = append(append([]string{flag}, args...), hostPathname)
+	err = v.cli.DiskManager(ctx, vm, args...)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	return v.findDisk(ctx, vm, disk.Device)
+}
+
+// grow a disk to size; the guest partitions and filesystems are not changed
+func (v *vmctl) ExpandDisk(ctx context.Context, vid, name, size string) (*VMDisk, error) {
+	if v.debug {
+		log.Printf("ExpandDisk(%s, %s, %s)\n", vid, name, size)
+	}
+	vm, disk, err := v.prepareDiskChange(ctx, vid, name, "expand a disk")
+	if err != nil {
+		return nil, wrap(err)
+	}
+	newSize, err := SizeParse(size)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if newSize <= disk.Capacity {
+		return nil, Fatalf("[%s] new size %s must be larger than current size %s", vm.Name, FormatSize(newSize), disk.Size)
+	}
+	return v.diskManager(ctx, vm, disk, "-x", fmt.Sprintf("%dMB", newSize/MB))
+}
+
+// reclaim unused space in a growable disk
+func (v *vmctl) ShrinkDisk(ctx context.Context, vid, name string) (*VMDisk, error) {
+	if v.debug {
+		log.Printf("ShrinkDisk(%s, %s)\n", vid, name)
+	}
+	vm, disk, err := v.prepareDiskChange(ctx, vid, name, "shrink a disk")
+	if err != nil {
+		return nil, wrap(err)
+	}
+	return v.diskManager(ctx, vm, disk, "-k")
+}
+
+func (v *vmctl) DefragDisk(ctx context.Context, vid, name string) (*VMDisk, error) {
+	if v.debug {
+		log.Printf("DefragDisk(%s, %s)\n", vid, name)
+	}
+	vm, disk, err := v.prepareDiskChange(ctx, vid, name, "defragment a disk")
+	if err != nil {
+		return nil, wrap(err)
+	}
+	return v.diskManager(ctx, vm, disk, "-d")
+}
+
+// convert a disk to diskType; the converted copy replaces the original files
+func (v *vmctl) ConvertDisk(ctx context.Context, vid, name string, diskType VDiskType) (*VMDisk, error) {
+	if v.debug {
+		log.Printf("ConvertDisk(%s, %s, %s)\n", vid, name, diskType)
+	}
+	vm, disk, err := v.prepareDiskChange(ctx, vid, name, "convert a disk")
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if _, ok := diskTypeName[diskType]; !ok {
+		return nil, Fatalf("invalid disk type: %d", diskType)
+	}
+	_, hostPathname, err := v.cli.diskPathnames(vm, disk.File)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	baseFile := strings.TrimSuffix(disk.File, ".vmdk")
+	tempFile := baseFile + "-convert.vmdk"
+	_, tempPathname, err := v.cli.diskPathnames(vm, tempFile)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	origFile := baseFile + "-original.vmdk"
+	_, origPathname, err := v.cli.diskPathnames(vm, origFile)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if v.verbose {
+		fmt.Printf("[%s] Converting %s to %s\n", vm.Name, disk.File, diskType)
+	}
+	err = v.cli.DiskManager(ctx, vm, "-r", hostPathname, "-t", strconv.Itoa(int(diskType)), tempPathname)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	// keep the original files until the converted copy is in place
+	err = v.cli.DiskManager(ctx, vm, "-n", hostPathname, origPathname)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	err = v.cli.DiskManager(ctx, vm, "-n", tempPathname, hostPathname)
+	if err != nil {
+		rbErr := v.cli.DiskManager(context.WithoutCancel(ctx), vm, "-n", origPathname, hostPathname)
+		if rbErr != nil {
+			log.Printf("WARNING: [%s] failed restoring %s from %s: %v\n", vm.Name, disk.File, origFile, rbErr)
+		}
+		return nil, wrap(err)
+	}
+	files, err := v.vmdkFiles(ctx, vm, origFile)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	for _, file := range files {
+		err := v.cli.DeleteDisk(ctx, vm, file)
+		if err != nil {
+			return nil, wrap(err)
+		}
+	}
+	return v.findDisk(ctx, vm, disk.Device)
+}
+
+// convert the local image in options.Image to a sparse VMDK and upload it to the instance directory as filename
+func (v *vmctl) importDisk(ctx context.Context, vm *VM, filename, adapter string, options DiskOptions) error {
+	image, err := OpenDiskImage(options.Image)
+	if err != nil {
+		return wrap(err)
+	}
+	defer image.Close()
+	var capacity int64
+	if options.Size != "" {
+		capacity, err = SizeParse(options.Size)
+		if err != nil {
+			return wrap(err)
+		}
+	}
+	format := VMDKMonolithicSparse
+	uploadName := filename
+	if options.Stream {
+		format = VMDKStreamOptimized
+		uploadName = strings.TrimSuffix(filename, ".vmdk") + "-stream.vmdk"
+	}
+	tempFile, err := os.CreateTemp("", "vmx_import.*.vmdk")
+	if err != nil {
+		return wrap(err)
+	}
+	defer os.Remove(tempFile.Name())
+	if v.verbose {
+		fmt.Printf("[%s] Converting %s image %s to %s\n", vm.Name, image.Format, options.Image, format)
+	}
+	_, err = WriteVMDK(tempFile, image, image.Size(), VMDKOptions{
+		Format:     format,
+		ExtentName: uploadName,
+		Adapter:    adapter,
+		Capacity:   capacity,
+	})
+	if err != nil {
+		tempFile.Close()
+		return wrap(err)
+	}
+	err = tempFile.Close()
+	if err != nil {
+		return wrap(err)
+	}
+	if v.verbose {
+		fmt.Printf("[%s] Uploading %s\n", vm.Name, uploadName)
+	}
+	err = v.Upload(ctx, vm.Name, tempFile.Name(), uploadName)
+	if err != nil {
+		return wrap(err)
+	}
+	if !options.Stream {
+		return nil
+	}
+	// streamOptimized disks are read-only; convert to a growable disk on the host
+	_, streamPathname, err := v.cli.diskPathnames(vm, uploadName)
+	if err != nil {
+		return wrap(err)
+	}
+	_, hostPathname, err := v.cli.diskPathnames(vm, filename)
+	if err != nil {
+		return wrap(err)
+	}
+	err = v.cli.DiskManager(ctx, vm, "-r", streamPathname, "-t", strconv.Itoa(DiskTypeSingleFileGrowable), hostPathname)
+	if err != nil {
+		return wrap(err)
+	}
+	return v.cli.DeleteDisk(ctx, vm, uploadName)
+}
@@ -0,0 +1,158 @@
+package ws
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func TestParseDiskSlot(t *testing.T) {
+	controller, bus, unit, err := ParseDiskSlot("SCSI1:15")
+	require.Nil(t, err)
+	require.Equal(t, "scsi", controller)
+	require.Equal(t, 1, bus)
+	require.Equal(t, 15, unit)
+	for _, slot := range []string{"scsi0:7", "ide2:0", "ide0:2", "nvme0:15", "sata4:0", "usb0:0", "nvme0"} {
+		_, _, _, err := ParseDiskSlot(slot)
+		require.NotNil(t, err, slot)
+	}
+}
+
+func TestVMXDisks(t *testing.T) {
+	initTestConfig(t)
+	vmx, err := InitVMX("linux", "db", []byte(`displayName = "db"
+nvme0.present = "TRUE"
+nvme0:0.present = "TRUE"
+nvme0:0.fileName = "db.vmdk"
+scsi0:0.present = "FALSE"
+scsi0:0.fileName = "old.vmdk"
+`))
+	require.Nil(t, err)
+
+	slot, err := vmx.FreeDiskSlot("nvme")
+	require.Nil(t, err)
+	require.Equal(t, "nvme0:1", slot)
+	slot, err = vmx.FreeDiskSlot("scsi")
+	require.Nil(t, err)
+	require.Equal(t, "scsi0:0", slot)
+
+	_, err = vmx.AddDisk("scsi0:0", "db-data.vmdk", "pvscsi")
+	require.Nil(t, err)
+	_, err = vmx.AddDisk("sata0:1", "db-log.vmdk", "")
+	require.Nil(t, err)
+	_, err = vmx.AddDisk("nvme0:0", "other.vmdk", "")
+	require.NotNil(t, err)
+	_, err = vmx.AddDisk("sata0:2", "other.vmdk", "pvscsi")
+	require.NotNil(t, err)
+	_, err = vmx.AddDisk("scsi0:1", "other.vmdk", "buslogic")
+	require.NotNil(t, err)
+
+	require.Equal(t, map[string]string{
+		"nvme0:0": "db.vmdk",
+		"scsi0:0": "db-data.vmdk",
+		"sata0:1": "db-log.vmdk",
+	}, vmx.Disks())
+	adapter, _ := vmx.doc.Get("scsi0.virtualDev")
+	require.Equal(t, "pvscsi", adapter)
+	require.True(t, vmx.doc.GetBool("sata0.present", false))
+
+	filename, err := vmx.RemoveDisk("SATA0:1")
+	require.Nil(t, err)
+	require.Equal(t, "db-log.vmdk", filename)
+	_, err = vmx.RemoveDisk("sata0:1")
+	require.NotNil(t, err)
+	require.Len(t, vmx.Disks(), 2)
+}
+
+func TestVMDKExtentFiles(t *testing.T) {
+	data := []byte(`# Disk DescriptorFile
+version=1
+createType="twoGbMaxExtentSparse"
+
+# Extent description
+RW 8323072 SPARSE "db-data-s001.vmdk"
+RW 8323072 SPARSE "db-data-s002.vmdk"
+
+ddb.adapterType = "lsilogic"
+`)
+	require.Equal(t, []string{"db-data-s001.vmdk", "db-data-s002.vmdk"}, VMDKExtentFiles(data))
+}
+
+func TestParseVDiskType(t *testing.T) {
+	diskType, err := ParseVDiskType("thin_provisioned")
+	require.Nil(t, err)
+	require.Equal(t, VDiskType(DiskTypeThin), diskType)
+	diskType, err = ParseVDiskType("2")
+	require.Nil(t, err)
+	require.Equal(t, VDiskType(DiskTypeSingleFilePreallocated), diskType)
+	_, err = ParseVDiskType("7")
+	require.NotNil(t, err)
+	_, err = ParseVDiskType("")
+	require.NotNil(t, err)
+}
+
+func TestVMDiskDelta(t *testing.T) {
+	initTestConfig(t)
+	descriptor := `# Disk DescriptorFile
+version=1
+CID=1a2b3c4d
+parentCID=%s
+createType="monolithicSparse"
+
+# Extent description
+RW 33554432 SPARSE "db-000001.vmdk"
+`
+	disk, err := NewVMDisk("nvme0:0", "db-000001.vmdk", []byte(fmt.Sprintf(descriptor, "5e6f7a8b")))
+	require.Nil(t, err)
+	require.True(t, disk.Delta)
+	require.Equal(t, int64(16*GB), disk.Capacity)
+	disk, err = NewVMDisk("nvme0:0", "db.vmdk", []byte(fmt.Sprintf(descriptor, "ffffffff")))
+	require.Nil(t, err)
+	require.False(t, disk.Delta)
+}
+
+func TestConvertDiskReplacesOriginal(t *testing.T) {
+	ctx := t.Context()
+	v, host := newFakeController(t)
+	_, err := v.Create(ctx, "web1", *NewCreateOptions(), IsoOptions{})
+	require.Nil(t, err)
+
+	_, err = v.ConvertDisk(ctx, "web1", "", DiskTypeMultiFileGrowable)
+	require.Nil(t, err)
+	require.True(t, IsFile(host.local("/vmware/web1/web1.vmdk")))
+	require.False(t, IsFile(host.local("/vmware/web1/web1-convert.vmdk")))
+	require.False(t, IsFile(host.local("/vmware/web1/web1-original.vmdk")))
+}
+
+func TestConvertDiskFailureKeepsOriginal(t *testing.T) {
+	ctx := t.Context()
+	v, host := newFakeController(t)
+	_, err := v.Create(ctx, "web1", *NewCreateOptions(), IsoOptions{})
+	require.Nil(t, err)
+	original := []byte("# Disk DescriptorFile\n# original\n")
+	require.Nil(t, os.WriteFile(host.local("/vmware/web1/web1.vmdk"), original, 0600))
+
+	// renaming the converted copy into place fails
+	host.FailMatch = "-n /vmware/web1/web1-convert.vmdk"
+	_, err = v.ConvertDisk(ctx, "web1", "", DiskTypeMultiFileGrowable)
+	require.NotNil(t, err)
+	data, err := os.ReadFile(host.local("/vmware/web1/web1.vmdk"))
+	require.Nil(t, err)
+	require.Equal(t, original, data)
+	require.False(t, IsFile(host.local("/vmware/web1/web1-original.vmdk")))
+}
+
+func TestDiskManagerQuotesArgs(t *testing.T) {
+	ctx := t.Context()
+	v, host := newFakeController(t)
+	_, err := v.Create(ctx, "web1", *NewCreateOptions(), IsoOptions{})
+	require.Nil(t, err)
+	vm, err := v.cli.GetVM(ctx, "web1")
+	require.Nil(t, err)
+
+	// the fake host splits commands on whitespace, so the command fails
+	err = v.cli.DiskManager(ctx, &vm, "-x", "20G", "/vmware/web 1/web1.vmdk")
+	require.NotNil(t, err)
+	require.Contains(t, host.Commands, "vmware-vdiskmanager -x 20G '/vmware/web 1/web1.vmdk'")
+}
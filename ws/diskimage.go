@@ -0,0 +1,96 @@
+package ws
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+)
+
+const (
+	DiskImageRaw   = "raw"
+	DiskImageQCOW2 = "qcow2"
+	DiskImageVMDK  = "vmdk"
+)
+
+type diskImageReader interface {
+	io.ReaderAt
+	Size() int64
+}
+
+// DiskImage reads the virtual disk contents of a raw, qcow2 or sparse VMDK image
+type DiskImage struct {
+	Format string
+	reader diskImageReader
+	file   *os.File
+}
+
+type rawImage struct {
+	io.ReaderAt
+	size int64
+}
+
+func (r *rawImage) Size() int64 {
+	return r.size
+}
+
+// detect the image format of r
+func NewDiskImage(r io.ReaderAt, size int64) (*DiskImage, error) {
+	magic := make([]byte, 4)
+	if size >= 4 {
+		_, err := r.ReadAt(magic, 0)
+		if err != nil {
+			return nil, wrap(err)
+		}
+	}
+	var image DiskImage
+	var err error
+	switch {
+	case binary.BigEndian.Uint32(magic) == QCOW2_MAGIC:
+		image.Format = DiskImageQCOW2
+		image.reader, err = OpenQCOW2(r, size)
+	case binary.LittleEndian.Uint32(magic) == VMDK_SPARSE_MAGIC:
+		image.Format = DiskImageVMDK
+		image.reader, err = OpenVMDKSparse(r, size)
+	default:
+		image.Format = DiskImageRaw
+		image.reader = &rawImage{ReaderAt: r, size: size}
+	}
+	if err != nil {
+		return nil, wrap(err)
+	}
+	return &image, nil
+}
+
+func OpenDiskImage(filename string) (*DiskImage, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, wrap(err)
+	}
+	image, err := NewDiskImage(file, info.Size())
+	if err != nil {
+		file.Close()
+		return nil, wrap(err)
+	}
+	image.file = file
+	return image, nil
+}
+
+func (d *DiskImage) Size() int64 {
+	return d.reader.Size()
+}
+
+func (d *DiskImage) ReadAt(p []byte, off int64) (int, error) {
+	return d.reader.ReadAt(p, off)
+}
+
+func (d *DiskImage) Close() error {
+	if d.file == nil {
+		return nil
+	}
+	return d.file.Close()
+}
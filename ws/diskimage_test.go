@@ -0,0 +1,175 @@
+package ws
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// return test disk contents with random, zero and partially filled regions
+func testDiskData(size int) []byte {
+	rng := rand.New(rand.NewSource(1))
+	data := make([]byte, size)
+	for offset := 0; offset < size; offset += 48 * 1024 {
+		if rng.Intn(3) == 0 {
+			continue
+		}
+		end := min(offset+rng.Intn(48*1024)+1, size)
+		rng.Read(data[offset:end])
+	}
+	return data
+}
+
+// return a qcow2 v3 image of data; odd clusters are compressed, and zero clusters use the zero flag
+func testQCOW2(t *testing.T, data []byte, clusterBits uint32) []byte {
+	clusterSize := 1 << clusterBits
+	l2Entries := clusterSize / 8
+	numClusters := (len(data) + clusterSize - 1) / clusterSize
+	numL2 := (numClusters + l2Entries - 1) / l2Entries
+
+	l1Offset := clusterSize
+	l2Offset := 2 * clusterSize
+	image := make([]byte, l2Offset+numL2*clusterSize)
+	header := image[:104]
+	binary.BigEndian.PutUint32(header[0:], QCOW2_MAGIC)
+	binary.BigEndian.PutUint32(header[4:], 3)
+	binary.BigEndian.PutUint32(header[20:], clusterBits)
+	binary.BigEndian.PutUint64(header[24:], uint64(len(data)))
+	binary.BigEndian.PutUint32(header[36:], uint32(numL2))
+	binary.BigEndian.PutUint64(header[40:], uint64(l1Offset))
+	binary.BigEndian.PutUint32(header[96:], 4)
+	binary.BigEndian.PutUint32(header[100:], 104)
+	for i := 0; i < numL2; i++ {
+		binary.BigEndian.PutUint64(image[l1Offset+i*8:], uint64(l2Offset+i*clusterSize)|1<<63)
+	}
+
+	offsetBits := 62 - (clusterBits - 8)
+	for i := 0; i < numClusters; i++ {
+		cluster := make([]byte, clusterSize)
+		copy(cluster, data[i*clusterSize:])
+		var entry uint64
+		switch {
+		case isZero(cluster):
+			entry = qcow2ZeroFlag
+		case i%2 == 1:
+			var compressed bytes.Buffer
+			fw, err := flate.NewWriter(&compressed, flate.BestSpeed)
+			require.Nil(t, err)
+			_, err = fw.Write(cluster)
+			require.Nil(t, err)
+			require.Nil(t, fw.Close())
+			// compressed clusters are packed at unaligned offsets
+			offset := len(image) + 100
+			image = append(image, make([]byte, 100)...)
+			image = append(image, compressed.Bytes()...)
+			sectors := (offset%512 + compressed.Len() + 511) / 512
+			entry = qcow2CompressedFlag | uint64(sectors-1)<<offsetBits | uint64(offset)
+		default:
+			offset := (len(image) + clusterSize - 1) / clusterSize * clusterSize
+			image = append(image, make([]byte, offset-len(image))...)
+			image = append(image, cluster...)
+			entry = uint64(offset) | 1<<63
+		}
+		binary.BigEndian.PutUint64(image[l2Offset+i*8:], entry)
+	}
+	return image
+}
+
+func readImage(t *testing.T, data []byte) (*DiskImage, []byte) {
+	image, err := NewDiskImage(bytes.NewReader(data), int64(len(data)))
+	require.Nil(t, err)
+	contents := make([]byte, image.Size())
+	_, err = image.ReadAt(contents, 0)
+	require.Nil(t, err)
+	return image, contents
+}
+
+func writeTestVMDK(t *testing.T, image *DiskImage, options VMDKOptions) []byte {
+	filename := filepath.Join(t.TempDir(), options.ExtentName)
+	file, err := os.Create(filename)
+	require.Nil(t, err)
+	length, err := WriteVMDK(file, image, image.Size(), options)
+	require.Nil(t, err)
+	require.Nil(t, file.Close())
+	data, err := os.ReadFile(filename)
+	require.Nil(t, err)
+	require.LessOrEqual(t, int64(len(data)), length)
+	return data
+}
+
+func TestQCOW2Reader(t *testing.T) {
+	data := testDiskData(1024*1024 + 4096)
+	image, contents := readImage(t, testQCOW2(t, data, 16))
+	require.Equal(t, DiskImageQCOW2, image.Format)
+	require.Equal(t, data, contents)
+
+	// reads crossing cluster boundaries
+	chunk := make([]byte, 100000)
+	_, err := image.ReadAt(chunk, 65000)
+	require.Nil(t, err)
+	require.Equal(t, data[65000:165000], chunk)
+}
+
+func TestVMDKRoundTrip(t *testing.T) {
+	initTestConfig(t)
+	data := testDiskData(3*1024*1024 + 1536)
+	sources := map[string][]byte{
+		DiskImageRaw:   data,
+		DiskImageQCOW2: testQCOW2(t, data, 12),
+	}
+	for sourceFormat, source := range sources {
+		for _, format := range []string{VMDKMonolithicSparse, VMDKStreamOptimized} {
+			name := sourceFormat + "-" + format
+			image, _ := readImage(t, source)
+			require.Equal(t, sourceFormat, image.Format, name)
+			vmdkData := writeTestVMDK(t, image, VMDKOptions{
+				Format:     format,
+				ExtentName: "test.vmdk",
+				Capacity:   4 * MB,
+			})
+
+			converted, contents := readImage(t, vmdkData)
+			require.Equal(t, DiskImageVMDK, converted.Format, name)
+			require.Equal(t, 4*MB, converted.Size(), name)
+			require.Equal(t, data, contents[:len(data)], name)
+			require.True(t, isZero(contents[len(data):]), name)
+
+			disk, err := NewVMDisk("nvme0:1", "test.vmdk", vmdkData)
+			require.Nil(t, err, name)
+			require.Equal(t, 4*MB, disk.Capacity, name)
+			require.Contains(t, string(vmdkData[:4096]), `createType="`+format+`"`, name)
+			require.False(t, disk.Delta, name)
+			require.Equal(t, []string{"test.vmdk"}, VMDKExtentFiles(vmdkData), name)
+		}
+	}
+}
+
+func TestVMDKSparseSkipsZeroGrains(t *testing.T) {
+	data := make([]byte, 16*MB)
+	copy(data[8*MB:], "not empty")
+	image, err := NewDiskImage(bytes.NewReader(data), int64(len(data)))
+	require.Nil(t, err)
+	vmdkData := writeTestVMDK(t, image, VMDKOptions{ExtentName: "sparse.vmdk"})
+	require.Less(t, len(vmdkData), 256*1024)
+	_, contents := readImage(t, vmdkData)
+	require.Equal(t, data, contents)
+}
+
+func TestOpenQCOW2Errors(t *testing.T) {
+	data := testQCOW2(t, make([]byte, 65536), 16)
+	backing := bytes.Clone(data)
+	binary.BigEndian.PutUint64(backing[8:], 512)
+	_, err := NewDiskImage(bytes.NewReader(backing), int64(len(backing)))
+	require.NotNil(t, err)
+	zstd := bytes.Clone(data)
+	binary.BigEndian.PutUint64(zstd[72:], qcow2CompressionFeature)
+	_, err = NewDiskImage(bytes.NewReader(zstd), int64(len(zstd)))
+	require.NotNil(t, err)
+	_, err = WriteVMDK(nil, bytes.NewReader(data), int64(len(data)), VMDKOptions{Format: "vmfs", ExtentName: "x.vmdk"})
+	require.NotNil(t, err)
+}
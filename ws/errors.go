@@ -0,0 +1,59 @@
+package ws
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"runtime"
+	"strings"
+)
+
+// errors returned by Controller methods; test with errors.Is
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrExists    = errors.New("exists")
+	ErrEncrypted = errors.New("instance is encrypted")
+	ErrTimeout   = errors.New("timed out")
+	ErrTransport = errors.New("transport error")
+)
+
+// ErrPowerState is returned when an operation requires a different power
+// state; test with errors.As
+type ErrPowerState struct {
+	Want string
+	Have string
+}
+
+func (e *ErrPowerState) Error() string {
+	return fmt.Sprintf("power state is '%s', '%s' is required", e.Have, e.Want)
+}
+
+// wrap prefixes err with the caller location like Fatal; Fatal formats err
+// with %v, hiding it from errors.Is and errors.As, so wrap uses %w
+func wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+	return located(err)
+}
+
+// wrapf is Fatalf with support for %w
+func wrapf(format string, args ...any) error {
+	return located(fmt.Errorf(format, args...))
+}
+
+// prefix err with the location of the caller of wrap or wrapf
+func located(err error) error {
+	pc := make([]uintptr, 1)
+	if runtime.Callers(3, pc) == 0 {
+		return err
+	}
+	frame, _ := runtime.CallersFrames(pc).Next()
+	if frame.Function == "" {
+		return err
+	}
+	_, function := path.Split(frame.Function)
+	parts := strings.Split(function, ".")
+	_, file := path.Split(frame.File)
+	return fmt.Errorf("%s:%d %s: %w", file, frame.Line, parts[len(parts)-1], err)
+}
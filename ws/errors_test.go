@@ -0,0 +1,40 @@
+package ws
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	ctx := t.Context()
+	v, _ := newFakeController(t)
+
+	_, err := v.Get(ctx, "web1")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	_, err = v.Create(ctx, "web1", *NewCreateOptions(), IsoOptions{})
+	require.Nil(t, err)
+	_, err = v.Create(ctx, "web1", *NewCreateOptions(), IsoOptions{})
+	require.ErrorIs(t, err, ErrExists)
+	require.NotErrorIs(t, err, ErrNotFound)
+
+	_, err = v.Pause(ctx, "web1", StopOptions{})
+	var powerState *ErrPowerState
+	require.ErrorAs(t, err, &powerState)
+	require.Equal(t, "on", powerState.Want)
+	require.Equal(t, "off", powerState.Have)
+
+	v.TimeoutSeconds = 1
+	err = v.Wait(ctx, "web1", "running")
+	require.ErrorIs(t, err, ErrTimeout)
+}
+
+func TestErrorWrap(t *testing.T) {
+	err := wrap(wrapf("[%s] %w", "web1", ErrTransport))
+	require.ErrorIs(t, err, ErrTransport)
+	require.Contains(t, err.Error(), "TestErrorWrap: ")
+	require.Contains(t, err.Error(), "[web1] transport error")
+	require.Nil(t, wrap(nil))
+	require.False(t, errors.Is(Fatal(ErrTransport), ErrTransport))
+}
@@ -2,14 +2,16 @@ package ws
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
+	"regexp"
 	"strings"
 )
 
-func (v *vmctl) LocalExec(command string, exitCode *int) ([]string, error) {
+func (v *vmctl) LocalExec(ctx context.Context, command string, exitCode *int) ([]string, error) {
 	if v.debug {
 		log.Printf("LocalExec('%s', %v)\n", command, exitCode)
 	}
@@ -25,65 +27,113 @@ func (v *vmctl) LocalExec(command string, exitCode *int) ([]string, error) {
 		}
 		args = []string{"-c", command}
 	}
-	return v.exec(shell, args, "", exitCode)
+	return v.exec(ctx, shell, args, "", exitCode)
+}
+
+var POSIX_SAFE_ARG = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+var WINDOWS_SAFE_ARG = regexp.MustCompile(`^[A-Za-z0-9_@+=:,./\\-]+$`)
+var CMD_METACHARACTERS = regexp.MustCompile(`[()%!^"<>&|]`)
+
+// quote arg as a single word for the host shell: sh on POSIX hosts, cmd on windows
+func hostQuote(remote, arg string) string {
+	if arg == "" {
+		return `""`
+	}
+	if remote == "windows" {
+		if WINDOWS_SAFE_ARG.MatchString(arg) {
+			return arg
+		}
+		// quote for the program's argument parser, then escape every cmd
+		// metacharacter, quotes included, so cmd passes the word unchanged
+		return CMD_METACHARACTERS.ReplaceAllString(windowsQuote(arg), "^$0")
+	}
+	if POSIX_SAFE_ARG.MatchString(arg) {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// double-quote arg for the windows C runtime argument parser
+func windowsQuote(arg string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	slashes := 0
+	for _, c := range arg {
+		switch c {
+		case '\\':
+			slashes++
+			continue
+		case '"':
+			b.WriteString(strings.Repeat(`\`, slashes*2+1))
+		default:
+			b.WriteString(strings.Repeat(`\`, slashes))
+		}
+		slashes = 0
+		b.WriteRune(c)
+	}
+	b.WriteString(strings.Repeat(`\`, slashes*2))
+	b.WriteByte('"')
+	return b.String()
+}
+
+type secretsKey struct{}
+
+// return ctx with secret added to the strings redacted from logs and errors
+func withSecret(ctx context.Context, secrets ...string) context.Context {
+	values, _ := ctx.Value(secretsKey{}).([]string)
+	for _, secret := range secrets {
+		if secret != "" && secret != `""` {
+			values = append(values, secret)
+		}
+	}
+	return context.WithValue(ctx, secretsKey{}, values)
+}
+
+// return s with the secrets in ctx replaced
+func redact(ctx context.Context, s string) string {
+	values, _ := ctx.Value(secretsKey{}).([]string)
+	for _, secret := range values {
+		s = strings.ReplaceAll(s, secret, "********")
+	}
+	return s
 }
 
 func (v *vmctl) sshArgs() []string {
 	return []string{"-q", "-i", v.KeyFile, v.Username + "@" + v.Hostname}
 }
 
-func (v *vmctl) RemoteExec(command string, exitCode *int) ([]string, error) {
+func (v *vmctl) RemoteExec(ctx context.Context, command string, exitCode *int) ([]string, error) {
 	if v.debug {
-		log.Printf("RemoteExec('%s', %v)\n", command, exitCode)
-	}
-	switch v.Shell {
-	case "winexec":
-		stdout, _, err := v.winexec.Exec("cmd", []string{"/c", command}, exitCode)
-		if err != nil {
-			return []string{}, Fatal(err)
-		}
-		return strings.Split(strings.TrimSpace(stdout), "\n"), nil
-	case "ssh":
-		args := v.sshArgs()
-		if v.Remote == "windows" {
-			args = append(args, command)
-			command = ""
-		}
-		return v.exec(v.Shell, args, command, exitCode)
-	case "sh":
-		return v.exec(v.Shell, []string{}, command, exitCode)
-	case "cmd":
-		return v.exec(v.Shell, []string{"/c", command}, "", exitCode)
+		log.Printf("RemoteExec('%s', %v)\n", redact(ctx, command), exitCode)
 	}
-	return []string{}, Fatalf("unexpected shell: %s", v.Shell)
+	return v.executor.Exec(ctx, command, exitCode)
 }
 
-func (v *vmctl) RemoteSpawn(command string, exitCode *int) error {
+func (v *vmctl) RemoteSpawn(ctx context.Context, command string, exitCode *int) error {
 	if v.debug {
-		log.Printf("RemoteSpawn('%s', %v)\n", command, exitCode)
-	}
-	switch v.Shell {
-	case "winexec":
-		return v.winexec.Spawn(command, exitCode)
-	case "ssh":
-		args := v.sshArgs()
-		if v.Remote == "windows" {
-			args = append(args, command)
-			command = ""
-		}
-		_, err := v.exec(v.Shell, args, command, exitCode)
-		return Fatal(err)
-	case "sh":
-		return v.spawn("/bin/sh", command, exitCode)
-	case "cmd":
-		return v.spawn("cmd", command, exitCode)
-	}
-	return Fatalf("unexpected shell: %s", v.Shell)
+		log.Printf("RemoteSpawn('%s', %v)\n", redact(ctx, command), exitCode)
+	}
+	return v.executor.Spawn(ctx, command, exitCode)
 }
 
-func (v *vmctl) spawn(shell, command string, exitCode *int) error {
+// run f in a goroutine for client libraries without context support; return
+// the context error without waiting for f to finish if ctx is done first
+func callContext(ctx context.Context, f func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- f()
+	}()
+	select {
+	case <-ctx.Done():
+		return wrap(ctx.Err())
+	case err := <-done:
+		return err
+	}
+}
+
+func (v *vmctl) spawn(ctx context.Context, shell, command string, exitCode *int) error {
 	if v.debug {
-		log.Printf("spawn('%s', %v)\n", command, exitCode)
+		log.Printf("spawn('%s', %v)\n", redact(ctx, command), exitCode)
 	}
 	stdin := ""
 	args := []string{}
@@ -92,7 +142,7 @@ func (v *vmctl) spawn(shell, command string, exitCode *int) error {
 	} else {
 		stdin = command + "&"
 	}
-	cmd := exec.Command(shell, args...)
+	cmd := exec.CommandContext(ctx, shell, args...)
 	if len(stdin) > 0 {
 		cmd.Stdin = bytes.NewBuffer([]byte(stdin + "\n"))
 	} else {
@@ -101,6 +151,9 @@ func (v *vmctl) spawn(shell, command string, exitCode *int) error {
 	cmd.Stdout = nil
 	cmd.Stderr = nil
 	err := cmd.Run()
+	if ctx.Err() != nil {
+		return wrap(ctx.Err())
+	}
 	switch e := err.(type) {
 	case nil:
 		if exitCode != nil {
@@ -108,10 +161,10 @@ func (v *vmctl) spawn(shell, command string, exitCode *int) error {
 		}
 	case *exec.ExitError:
 		if exitCode == nil {
-			err = Fatalf("Process '%s' exited %d", cmd, e.ProcessState.ExitCode())
+			err = Fatalf("Process '%s' exited %d", redact(ctx, cmd.String()), e.ProcessState.ExitCode())
 		} else {
 			*exitCode = e.ProcessState.ExitCode()
-			log.Printf("WARNING: process '%s' exited %d\n", cmd, *exitCode)
+			log.Printf("WARNING: process '%s' exited %d\n", redact(ctx, cmd.String()), *exitCode)
 			err = nil
 		}
 	}
@@ -119,13 +172,17 @@ func (v *vmctl) spawn(shell, command string, exitCode *int) error {
 }
 
 // note: if exitCode is nil, exit != 0 is an error, otherwise the exit code will be set
-func (v *vmctl) exec(command string, args []string, stdin string, exitCode *int) ([]string, error) {
+func (v *vmctl) exec(ctx context.Context, command string, args []string, stdin string, exitCode *int) ([]string, error) {
+	olines, _, err := v.execOutput(ctx, command, args, stdin, exitCode)
+	return olines, err
+}
+
+// exec returning the stderr output with the stdout lines
+func (v *vmctl) execOutput(ctx context.Context, command string, args []string, stdin string, exitCode *int) ([]string, string, error) {
 	if v.debug {
-		log.Printf("exec('%s', %v, '%s', %v)\n", command, args, stdin, exitCode)
+		log.Printf("exec('%s', %s, '%s', %v)\n", command, redact(ctx, fmt.Sprintf("%v", args)), redact(ctx, stdin), exitCode)
 	}
-	olines := []string{}
-	elines := []string{}
-	cmd := exec.Command(command, args...)
+	cmd := exec.CommandContext(ctx, command, args...)
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
 	if len(stdin) > 0 {
@@ -134,24 +191,12 @@ func (v *vmctl) exec(command string, args []string, stdin string, exitCode *int)
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 	err := cmd.Run()
-
-	estr := strings.TrimSpace(stderr.String())
-	if estr != "" {
-		elines = strings.Split(estr, "\n")
-		for i, line := range elines {
-			log.Printf("stderr[%d] %s\n", i, line)
-		}
-	}
-	ostr := strings.TrimSpace(stdout.String())
-	if ostr != "" {
-		olines = strings.Split(ostr, "\n")
-		if v.debug {
-			for i, line := range olines {
-				log.Printf("stdout[%d] %s\n", i, line)
-			}
-		}
+	if ctx.Err() != nil {
+		return []string{}, "", wrap(ctx.Err())
 	}
 
+	olines := v.outputLines(stdout.String(), stderr.String())
+
 	switch e := err.(type) {
 	case nil:
 		if exitCode != nil {
@@ -159,31 +204,45 @@ func (v *vmctl) exec(command string, args []string, stdin string, exitCode *int)
 		}
 	case *exec.ExitError:
 		if exitCode == nil {
-			err = Fatalf("Process '%s' exited %d\n%s", cmd, e.ProcessState.ExitCode(), stderr.String())
+			err = Fatalf("Process '%s' exited %d\n%s", redact(ctx, cmd.String()), e.ProcessState.ExitCode(), stderr.String())
 		} else {
 			*exitCode = e.ProcessState.ExitCode()
-			log.Printf("WARNING: process '%s' exited %d\n%s", cmd, *exitCode, stderr.String())
+			log.Printf("WARNING: process '%s' exited %d\n%s", redact(ctx, cmd.String()), *exitCode, stderr.String())
 			err = nil
 		}
 	}
 
-	return olines, err
+	return olines, stderr.String(), err
 }
 
-func (v *vmctl) scpUpload(remoteDest, localSource string) error {
-	args := []string{"-i", v.KeyFile, localSource, v.Username + "@" + v.Hostname + ":" + remoteDest}
-	_, err := v.exec("scp", args, "", nil)
-	if err != nil {
-		return Fatal(err)
-	}
-	return nil
+// return the output of a failed command for an error message; vmrun writes
+// its errors to stdout
+func commandOutput(olines []string, stderr string) string {
+	output := strings.Join(olines, "\n")
+	stderr = strings.TrimSpace(stderr)
+	if output != "" && stderr != "" {
+		output += "\n"
+	}
+	return output + stderr
 }
 
-func (v *vmctl) scpDownload(localDest, remoteSource string) error {
-	args := []string{"-i", v.KeyFile, v.Username + "@" + v.Hostname + ":" + remoteSource, localDest}
-	_, err := v.exec("scp", args, "", nil)
-	if err != nil {
-		return Fatal(err)
+// log stderr lines and return stdout lines
+func (v *vmctl) outputLines(stdout, stderr string) []string {
+	olines := []string{}
+	estr := strings.TrimSpace(stderr)
+	if estr != "" {
+		for i, line := range strings.Split(estr, "\n") {
+			log.Printf("stderr[%d] %s\n", i, line)
+		}
 	}
-	return nil
+	ostr := strings.TrimSpace(stdout)
+	if ostr != "" {
+		olines = strings.Split(ostr, "\n")
+		if v.debug {
+			for i, line := range olines {
+				log.Printf("stdout[%d] %s\n", i, line)
+			}
+		}
+	}
+	return olines
 }
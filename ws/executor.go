@@ -0,0 +1,239 @@
+package ws
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/rstms/winexec/client"
+	"io"
+	"log"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// Executor runs commands and transfers files on the VMware host
+type Executor interface {
+	// run a command, returning stdout lines; if exitCode is nil, exit != 0 is an error, otherwise the exit code is set
+	Exec(ctx context.Context, command string, exitCode *int) ([]string, error)
+	// start a command without waiting for it to exit
+	Spawn(ctx context.Context, command string, exitCode *int) error
+	// copy a local file to a host pathname
+	Upload(ctx context.Context, hostDest, localSource string) error
+	// copy a host file to a local pathname
+	Download(ctx context.Context, localDest, hostSource string) error
+	Close() error
+}
+
+// streamExecutor is implemented by executors that can write the standard
+// output of a POSIX host command to a writer without buffering it
+type streamExecutor interface {
+	Stream(ctx context.Context, w io.Writer, command string) error
+}
+
+// return the Executor for shell; the winexec client is used only by the winexec shell;
+// ssh runs the ssh program so ~/.ssh/config applies, sshclient opts in to the built-in client
+func newExecutor(ctx context.Context, v *vmctl, shell string, winexec *client.WinexecClient) (Executor, error) {
+	switch shell {
+	case "ssh":
+		return &sshExecutor{v: v}, nil
+	case "sshclient":
+		return newNativeSSHExecutor(ctx, v)
+	case "winexec":
+		return &winexecExecutor{client: winexec}, nil
+	case "sh":
+		return &localExecutor{v: v, shell: "sh"}, nil
+	case "cmd":
+		return &localExecutor{v: v, shell: "cmd"}, nil
+	}
+	return nil, Fatalf("unexpected shell: %s", shell)
+}
+
+// messages written by the ssh program when it cannot connect or authenticate
+var SSH_CONNECTION_ERROR = regexp.MustCompile(`(?m)^(ssh: |kex_exchange_identification: |Connection (closed|reset|timed out) by |Host key verification failed|Permission denied \()`)
+
+// sshExecutor runs commands with the ssh client program and transfers files with scp
+type sshExecutor struct {
+	v *vmctl
+}
+
+func (e *sshExecutor) Exec(ctx context.Context, command string, exitCode *int) ([]string, error) {
+	args := e.v.sshArgs()
+	if e.v.Remote == "windows" {
+		args = append(args, command)
+		command = ""
+	}
+	var status int
+	olines, stderr, err := e.v.execOutput(ctx, "ssh", args, command, &status)
+	if err != nil {
+		return olines, wrap(err)
+	}
+	// ssh exits 255 when the connection fails, but so does vmrun on ordinary
+	// failures, so the connection failure is detected from the ssh message
+	if status == 255 && SSH_CONNECTION_ERROR.MatchString(stderr) {
+		return olines, wrapf("%w: ssh connection to %s failed\n%s", ErrTransport, e.v.Hostname, strings.TrimSpace(stderr))
+	}
+	if exitCode != nil {
+		*exitCode = status
+	} else if status != 0 {
+		return olines, wrapf("ssh command '%s' exited %d\n%s", redact(ctx, command), status, commandOutput(olines, stderr))
+	}
+	return olines, nil
+}
+
+func (e *sshExecutor) Spawn(ctx context.Context, command string, exitCode *int) error {
+	_, err := e.Exec(ctx, command, exitCode)
+	if err != nil {
+		return wrap(err)
+	}
+	return nil
+}
+
+func (e *sshExecutor) Upload(ctx context.Context, hostDest, localSource string) error {
+	args := []string{"-i", e.v.KeyFile, localSource, e.v.Username + "@" + e.v.Hostname + ":" + hostDest}
+	_, err := e.v.exec(ctx, "scp", args, "", nil)
+	if err != nil {
+		return wrap(err)
+	}
+	return nil
+}
+
+func (e *sshExecutor) Download(ctx context.Context, localDest, hostSource string) error {
+	args := []string{"-i", e.v.KeyFile, e.v.Username + "@" + e.v.Hostname + ":" + hostSource, localDest}
+	_, err := e.v.exec(ctx, "scp", args, "", nil)
+	if err != nil {
+		return wrap(err)
+	}
+	return nil
+}
+
+func (e *sshExecutor) Stream(ctx context.Context, w io.Writer, command string) error {
+	if e.v.debug {
+		log.Printf("Stream('%s')\n", redact(ctx, command))
+	}
+	cmd := exec.CommandContext(ctx, "ssh", append(e.v.sshArgs(), command)...)
+	var stderr bytes.Buffer
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return wrap(ctx.Err())
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 255 && SSH_CONNECTION_ERROR.MatchString(stderr.String()) {
+		return wrapf("%w: ssh connection to %s failed\n%s", ErrTransport, e.v.Hostname, stderr.String())
+	}
+	if err != nil {
+		return wrapf("Process '%s' failed: %w\n%s", redact(ctx, cmd.String()), err, stderr.String())
+	}
+	return nil
+}
+
+func (e *sshExecutor) Close() error {
+	return nil
+}
+
+// winexecExecutor runs commands and transfers files with a winexec server
+type winexecExecutor struct {
+	client *client.WinexecClient
+}
+
+func (e *winexecExecutor) Exec(ctx context.Context, command string, exitCode *int) ([]string, error) {
+	var stdout, stderr string
+	var status int
+	err := callContext(ctx, func() error {
+		var err error
+		stdout, stderr, err = e.client.Exec("cmd", []string{"/c", command}, &status)
+		return err
+	})
+	if err != nil {
+		return []string{}, e.transportError(err)
+	}
+	if exitCode != nil {
+		*exitCode = status
+	} else if status != 0 {
+		return []string{}, wrapf("winexec command '%s' exited %d\n%s", redact(ctx, command), status, commandOutput([]string{strings.TrimSpace(stdout)}, stderr))
+	}
+	return strings.Split(strings.TrimSpace(stdout), "\n"), nil
+}
+
+func (e *winexecExecutor) Spawn(ctx context.Context, command string, exitCode *int) error {
+	var status int
+	err := callContext(ctx, func() error {
+		return e.client.Spawn(command, &status)
+	})
+	if err != nil {
+		return e.transportError(err)
+	}
+	if exitCode != nil {
+		*exitCode = status
+	} else if status != 0 {
+		return wrapf("winexec spawned command '%s' exited %d", redact(ctx, command), status)
+	}
+	return nil
+}
+
+func (e *winexecExecutor) Upload(ctx context.Context, hostDest, localSource string) error {
+	err := callContext(ctx, func() error {
+		return e.client.Upload(hostDest, localSource, true)
+	})
+	if err != nil {
+		return wrap(err)
+	}
+	return nil
+}
+
+func (e *winexecExecutor) Download(ctx context.Context, localDest, hostSource string) error {
+	err := callContext(ctx, func() error {
+		return e.client.Download(localDest, hostSource)
+	})
+	if err != nil {
+		return wrap(err)
+	}
+	return nil
+}
+
+// given an exit code pointer, winexec client Exec and Spawn fail only when
+// the server request fails; context errors are not transport errors
+func (e *winexecExecutor) transportError(err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return wrap(err)
+	}
+	return wrapf("%w: winexec: %v", ErrTransport, err)
+}
+
+func (e *winexecExecutor) Close() error {
+	return nil
+}
+
+// localExecutor runs commands with sh or cmd when VMware is on the local host
+type localExecutor struct {
+	v     *vmctl
+	shell string
+}
+
+func (e *localExecutor) Exec(ctx context.Context, command string, exitCode *int) ([]string, error) {
+	if e.shell == "cmd" {
+		return e.v.exec(ctx, "cmd", []string{"/c", command}, "", exitCode)
+	}
+	return e.v.exec(ctx, "sh", []string{}, command, exitCode)
+}
+
+func (e *localExecutor) Spawn(ctx context.Context, command string, exitCode *int) error {
+	if e.shell == "cmd" {
+		return e.v.spawn(ctx, "cmd", command, exitCode)
+	}
+	return e.v.spawn(ctx, "/bin/sh", command, exitCode)
+}
+
+func (e *localExecutor) Upload(ctx context.Context, hostDest, localSource string) error {
+	return e.v.copyFile(ctx, hostDest, localSource)
+}
+
+func (e *localExecutor) Download(ctx context.Context, localDest, hostSource string) error {
+	return e.v.copyFile(ctx, localDest, hostSource)
+}
+
+func (e *localExecutor) Close() error {
+	return nil
+}
@@ -0,0 +1,45 @@
+package ws
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// return a controller using the ssh shell with a fake ssh program running script
+func newFakeSSHController(t *testing.T, script string) *vmctl {
+	initTestConfig(t)
+	dir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "ssh"), []byte("#!/bin/sh\ncat >/dev/null\n"+script), 0700))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	v := &vmctl{Hostname: "fakehost", Username: "vmware", KeyFile: "id_fake", Shell: "ssh", Local: "linux", Remote: "linux"}
+	v.executor = &sshExecutor{v: v}
+	v.cli = NewCliClient(v)
+	return v
+}
+
+func TestSSHExecutorExitStatus(t *testing.T) {
+	ctx := t.Context()
+	// vmrun exits 255 on ordinary failures, writing the error to stdout
+	v := newFakeSSHController(t, "echo \"Error: VMX : '/vmware/web1/web1.vmx' does not exist!\"\nexit 255\n")
+	var exitCode int
+	olines, err := v.RemoteExec(ctx, "vmrun -T ws getGuestIPAddress /vmware/web1/web1.vmx", &exitCode)
+	require.Nil(t, err)
+	require.Equal(t, 255, exitCode)
+	require.Len(t, olines, 1)
+
+	_, err = v.RemoteExec(ctx, "vmrun -T ws start /vmware/web1/web1.vmx", nil)
+	require.NotNil(t, err)
+	require.False(t, errors.Is(err, ErrTransport))
+	require.Contains(t, err.Error(), "does not exist!")
+
+	err = v.cli.exec(ctx, &VM{Name: "web1", Path: "/vmware/web1/web1.vmx"}, "power query -f json", nil)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// the ssh program reports connection failures on stderr
+	v = newFakeSSHController(t, "echo 'ssh: connect to host fakehost port 22: Connection refused' >&2\nexit 255\n")
+	_, err = v.RemoteExec(ctx, "vmrun -T ws list", &exitCode)
+	require.ErrorIs(t, err, ErrTransport)
+}
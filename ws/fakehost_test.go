@@ -0,0 +1,379 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const FAKE_VMWARE_ROOT = "/vmware"
+
+// fakeHost is an in-process Executor emulating a linux VMware Workstation
+// host: host pathnames are mapped into a temp directory, and the vmrun and
+// vmcli commands used by the controller edit VMX files and track power state
+type fakeHost struct {
+	t     *testing.T
+	dir   string
+	power map[string]string
+	// number of checkToolsState queries reporting tools stopped after a reset
+	rebooting map[string]int
+	// if set, checkToolsState reports tools as installed but not running
+	NoTools  bool
+	Commands []string
+	// if set, Exec fails with Fail, emulating a broken host connection
+	Fail error
+	// if set, commands containing FailMatch exit 1 and uploads to
+	// host pathnames containing FailMatch fail
+	FailMatch string
+}
+
+// return a controller using a fake host with one vmware_roots directory
+func newFakeController(t *testing.T) (*vmctl, *fakeHost) {
+	initTestConfig(t)
+	host := &fakeHost{t: t, dir: t.TempDir(), power: make(map[string]string), rebooting: make(map[string]int)}
+	require.Nil(t, os.MkdirAll(host.local(FAKE_VMWARE_ROOT), 0700))
+	v := &vmctl{
+		Hostname:        "fakehost",
+		Roots:           []string{FAKE_VMWARE_ROOT},
+		IsoPath:         FAKE_VMWARE_ROOT + "/iso",
+		executor:        host,
+		Shell:           "fake",
+		Local:           "linux",
+		Remote:          "linux",
+		Version:         Version,
+		IntervalSeconds: 0,
+		TimeoutSeconds:  5,
+		GraceSeconds:    1,
+	}
+	v.cli = NewCliClient(v)
+	require.Nil(t, v.mapVMKeys())
+	return v, host
+}
+
+// return the local pathname of a host pathname
+func (h *fakeHost) local(hostPath string) string {
+	return filepath.Join(h.dir, filepath.FromSlash(hostPath))
+}
+
+func (h *fakeHost) PowerState(hostPath string) string {
+	state, ok := h.power[hostPath]
+	if !ok {
+		return "off"
+	}
+	return state
+}
+
+func (h *fakeHost) Exec(ctx context.Context, command string, exitCode *int) ([]string, error) {
+	h.Commands = append(h.Commands, command)
+	if h.Fail != nil {
+		return []string{}, h.Fail
+	}
+	args := strings.Fields(command)
+	var lines []string
+	var err error
+	code := 0
+	switch {
+	case h.FailMatch != "" && strings.Contains(command, h.FailMatch):
+		err = fmt.Errorf("command failed")
+	case args[0] == "find":
+		lines, err = h.find(args)
+	case args[0] == "ls":
+		lines, err = h.ls(args)
+	case args[0] == "mkdir":
+		err = os.Mkdir(h.local(args[1]), 0700)
+	case args[0] == "rm":
+		if args[1] == "-rf" {
+			err = os.RemoveAll(h.local(args[2]))
+		} else {
+			err = os.Remove(h.local(args[1]))
+		}
+	case args[0] == "vmrun":
+		lines, code, err = h.vmrun(args[1:])
+	case args[0] == "vmcli":
+		lines, err = h.vmcli(args[1:])
+	case args[0] == "vmware-vdiskmanager":
+		err = h.vdiskmanager(args[1:])
+	default:
+		err = fmt.Errorf("unsupported command")
+	}
+	if err != nil {
+		lines = []string{err.Error()}
+		code = 1
+	}
+	if exitCode != nil {
+		*exitCode = code
+		return lines, nil
+	}
+	if code != 0 {
+		return lines, Fatalf("fake host: '%s' exited %d: %s", command, code, strings.Join(lines, "; "))
+	}
+	return lines, nil
+}
+
+func (h *fakeHost) Spawn(ctx context.Context, command string, exitCode *int) error {
+	_, err := h.Exec(ctx, command, exitCode)
+	return err
+}
+
+func (h *fakeHost) Upload(ctx context.Context, hostDest, localSource string) error {
+	if h.FailMatch != "" && strings.Contains(hostDest, h.FailMatch) {
+		return Fatalf("fake host: upload to '%s' failed", hostDest)
+	}
+	data, err := os.ReadFile(localSource)
+	if err != nil {
+		return Fatal(err)
+	}
+	return os.WriteFile(h.local(hostDest), data, 0600)
+}
+
+func (h *fakeHost) Download(ctx context.Context, localDest, hostSource string) error {
+	data, err := os.ReadFile(h.local(hostSource))
+	if err != nil {
+		return Fatal(err)
+	}
+	return os.WriteFile(localDest, data, 0600)
+}
+
+func (h *fakeHost) Close() error {
+	return nil
+}
+
+// find ROOT -maxdepth 2 -type f -name '*.vmx'
+func (h *fakeHost) find(args []string) ([]string, error) {
+	root := args[1]
+	matches, err := filepath.Glob(filepath.Join(h.local(root), "*", "*.vmx"))
+	if err != nil {
+		return nil, err
+	}
+	lines := []string{}
+	for _, match := range matches {
+		rel, err := filepath.Rel(h.local(root), match)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, path.Join(root, filepath.ToSlash(rel)))
+	}
+	return lines, nil
+}
+
+// ls [-al] DIR
+func (h *fakeHost) ls(args []string) ([]string, error) {
+	dir := args[len(args)-1]
+	entries, err := os.ReadDir(h.local(dir))
+	if err != nil {
+		return nil, err
+	}
+	lines := []string{}
+	for _, entry := range entries {
+		if len(args) == 2 {
+			lines = append(lines, entry.Name())
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		mode := "-rw-------"
+		if entry.IsDir() {
+			mode = "drwx------"
+		}
+		lines = append(lines, fmt.Sprintf("%s 1 vmware vmware %d Oct 16 09:12 %s", mode, info.Size(), entry.Name()))
+	}
+	return lines, nil
+}
+
+func (h *fakeHost) readVMX(hostPath string) (*VMXDocument, error) {
+	data, err := os.ReadFile(h.local(hostPath))
+	if err != nil {
+		return nil, fmt.Errorf("VMX : '%s' does not exist!", hostPath)
+	}
+	return ParseVMXDocument(data), nil
+}
+
+func (h *fakeHost) writeVMX(hostPath string, doc *VMXDocument) error {
+	return os.WriteFile(h.local(hostPath), doc.Bytes(), 0600)
+}
+
+// vmrun [-T ws] COMMAND [VMX_PATH] [ARGS...]
+func (h *fakeHost) vmrun(args []string) ([]string, int, error) {
+	if len(args) > 1 && args[0] == "-T" {
+		args = args[2:]
+	}
+	if args[0] == "list" {
+		running := []string{}
+		for hostPath, state := range h.power {
+			if state == "on" || state == "paused" {
+				running = append(running, hostPath)
+			}
+		}
+		sort.Strings(running)
+		return append([]string{fmt.Sprintf("Total running VMs: %d", len(running))}, running...), 0, nil
+	}
+	if len(args) < 2 {
+		return nil, 0, fmt.Errorf("missing vmx path")
+	}
+	hostPath := args[1]
+	_, err := h.readVMX(hostPath)
+	if err != nil {
+		return []string{"Error: " + err.Error()}, 255, nil
+	}
+	state := h.PowerState(hostPath)
+	switch args[0] {
+	case "start":
+		if state == "on" {
+			return []string{"Error: The virtual machine is already running"}, 255, nil
+		}
+		h.power[hostPath] = "on"
+	case "stop":
+		if state != "on" {
+			return []string{"Error: The virtual machine is not powered on"}, 255, nil
+		}
+		h.power[hostPath] = "off"
+	case "suspend":
+		if state != "on" {
+			return []string{"Error: The virtual machine is not powered on"}, 255, nil
+		}
+		h.power[hostPath] = "suspended"
+	case "reset":
+		if state != "on" {
+			return []string{"Error: The virtual machine is not powered on"}, 255, nil
+		}
+		h.rebooting[hostPath] = 2
+	case "snapshot":
+		vmsd := strings.TrimSuffix(hostPath, ".vmx") + ".vmsd"
+		data, _ := os.ReadFile(h.local(vmsd))
+		doc := ParseVMXDocument(data)
+		count, _, _ := doc.GetInt("snapshot.numSnapshots")
+		key := fmt.Sprintf("snapshot%d", count)
+		doc.Set(key+".displayName", args[2])
+		if state == "on" {
+			doc.Set(key+".type", "1")
+		}
+		doc.SetInt("snapshot.numSnapshots", count+1)
+		return []string{}, 0, os.WriteFile(h.local(vmsd), doc.Bytes(), 0600)
+	case "listSnapshots":
+		data, _ := os.ReadFile(h.local(strings.TrimSuffix(hostPath, ".vmx") + ".vmsd"))
+		doc := ParseVMXDocument(data)
+		count, _, _ := doc.GetInt("snapshot.numSnapshots")
+		lines := []string{fmt.Sprintf("Total snapshots: %d", count)}
+		for i := 0; i < count; i++ {
+			name, _ := doc.Get(fmt.Sprintf("snapshot%d.displayName", i))
+			lines = append(lines, name)
+		}
+		return lines, 0, nil
+	case "revertToSnapshot":
+		data, _ := os.ReadFile(h.local(strings.TrimSuffix(hostPath, ".vmx") + ".vmsd"))
+		doc := ParseVMXDocument(data)
+		count, _, _ := doc.GetInt("snapshot.numSnapshots")
+		for i := 0; i < count; i++ {
+			key := fmt.Sprintf("snapshot%d", i)
+			if name, _ := doc.Get(key + ".displayName"); name == args[2] {
+				h.power[hostPath] = "off"
+				if doc.Has(key + ".type") {
+					h.power[hostPath] = "on"
+				}
+				return []string{}, 0, nil
+			}
+		}
+		return []string{"Error: A snapshot with the name does not exist"}, 255, nil
+	case "checkToolsState":
+		if h.rebooting[hostPath] > 0 {
+			h.rebooting[hostPath]--
+			return []string{"installed"}, 0, nil
+		}
+		if state != "on" || h.NoTools {
+			return []string{"installed"}, 0, nil
+		}
+		return []string{"running"}, 0, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported vmrun command: %s", args[0])
+	}
+	return []string{}, 0, nil
+}
+
+func (h *fakeHost) vmcli(args []string) ([]string, error) {
+	switch {
+	case len(args) >= 2 && args[0] == "VM" && args[1] == "Create":
+		return nil, h.createVM(args[2:])
+	case len(args) >= 2 && args[0] == "Disk" && args[1] == "Create":
+		flags := parseFakeFlags(args[2:])
+		return nil, os.WriteFile(h.local(flags["-f"]), []byte("# Disk DescriptorFile\n"), 0600)
+	}
+	hostPath := args[len(args)-1]
+	args = args[:len(args)-1]
+	doc, err := h.readVMX(hostPath)
+	if err != nil {
+		return nil, err
+	}
+	command := strings.Join(args, " ")
+	switch {
+	case command == "power query -f json":
+		data, err := json.Marshal(map[string]string{"PowerState": h.PowerState(hostPath)})
+		return []string{string(data)}, err
+	case command == "configParams query -f json":
+		params := make(map[string]string)
+		for _, key := range doc.Keys() {
+			params[key], _ = doc.Get(key)
+		}
+		data, err := json.Marshal(params)
+		return []string{string(data)}, err
+	case len(args) == 4 && args[0] == "configParams" && args[1] == "SetEntry":
+		doc.Set(args[2], args[3])
+		return nil, h.writeVMX(hostPath, doc)
+	}
+	return nil, fmt.Errorf("unsupported vmcli command: %s", command)
+}
+
+// -r SOURCE -t TYPE DEST copies a disk, -n SOURCE DEST renames it
+func (h *fakeHost) vdiskmanager(args []string) error {
+	switch {
+	case len(args) == 5 && args[0] == "-r" && args[2] == "-t":
+		data, err := os.ReadFile(h.local(args[1]))
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(h.local(args[4]), data, 0600)
+	case len(args) == 3 && args[0] == "-n":
+		return os.Rename(h.local(args[1]), h.local(args[2]))
+	}
+	return fmt.Errorf("unsupported vmware-vdiskmanager command: %s", strings.Join(args, " "))
+}
+
+// VM Create -n NAME -d DIR -g|-c GUEST_OS
+func (h *fakeHost) createVM(args []string) error {
+	flags := parseFakeFlags(args)
+	name := flags["-n"]
+	guestOS, ok := flags["-g"]
+	if !ok {
+		guestOS = flags["-c"]
+	}
+	dir := strings.TrimRight(flags["-d"], "/")
+	doc := ParseVMXDocument([]byte(".encoding = \"UTF-8\"\n"))
+	doc.Set("config.version", "8")
+	doc.Set("virtualHW.version", "21")
+	doc.Set("displayName", name)
+	doc.Set("guestOS", guestOS)
+	doc.Set("nvme0.present", "TRUE")
+	doc.Set("nvme0:0.present", "TRUE")
+	doc.Set("nvme0:0.fileName", name+".vmdk")
+	err := os.WriteFile(h.local(path.Join(dir, name+".vmdk")), []byte("# Disk DescriptorFile\n"), 0600)
+	if err != nil {
+		return err
+	}
+	return h.writeVMX(path.Join(dir, name+".vmx"), doc)
+}
+
+func parseFakeFlags(args []string) map[string]string {
+	flags := make(map[string]string)
+	for i := 0; i+1 < len(args); i += 2 {
+		flags[args[i]] = args[i+1]
+	}
+	return flags
+}
@@ -1,192 +1,165 @@
 package ws
 
 import (
+	"context"
 	"io"
 	"log"
 	"os"
 	"path"
 )
 
-func (v *vmctl) ReadHostFile(vm *VM, filename string) ([]byte, error) {
+func (v *vmctl) ReadHostFile(ctx context.Context, vm *VM, filename string) ([]byte, error) {
 	if v.debug {
 		log.Printf("ReadHostFile(%s, %s)\n", vm.Name, filename)
 	}
 	tempFile, err := os.CreateTemp("", "vmx_read.*")
 	if err != nil {
-		return []byte{}, Fatal(err)
+		return []byte{}, wrap(err)
 	}
 	localPath := tempFile.Name()
 	err = tempFile.Close()
 	if err != nil {
-		return []byte{}, Fatal(err)
+		return []byte{}, wrap(err)
 	}
 	defer os.Remove(localPath)
 
-	err = v.Download(vm.Name, localPath, filename)
+	err = v.Download(ctx, vm.Name, localPath, filename)
 	if err != nil {
-		return []byte{}, Fatal(err)
+		return []byte{}, wrap(err)
 	}
 	return os.ReadFile(localPath)
 }
 
-func (v *vmctl) WriteHostFile(vm *VM, filename string, data []byte) error {
+func (v *vmctl) WriteHostFile(ctx context.Context, vm *VM, filename string, data []byte) error {
 	if v.debug {
 		log.Printf("WriteHostFile(%s, %s, (%d bytes))\n", vm.Name, filename, len(data))
 	}
 	tempFile, err := os.CreateTemp("", "vmx_write.*")
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	localPath := tempFile.Name()
 	err = tempFile.Close()
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	defer os.Remove(localPath)
 	err = os.WriteFile(localPath, data, 0600)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
-	return v.Upload(vm.Name, localPath, filename)
+	return v.Upload(ctx, vm.Name, localPath, filename)
 }
 
-func (v *vmctl) copyFile(dstPath, srcPath string) error {
+func (v *vmctl) copyFile(ctx context.Context, dstPath, srcPath string) error {
 	if v.debug {
 		log.Printf("copyFile(%s, %s)\n", dstPath, srcPath)
 	}
 	dst, err := os.Create(dstPath)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	defer dst.Close()
 	src, err := os.Open(srcPath)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	defer src.Close()
+	stop := context.AfterFunc(ctx, func() { src.Close() })
 	_, err = io.Copy(dst, src)
+	if !stop() {
+		return wrap(ctx.Err())
+	}
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	return nil
 }
 
-func (v *vmctl) Download(vid string, localDestPathname, vmDirFilename string) error {
+func (v *vmctl) Download(ctx context.Context, vid string, localDestPathname, vmDirFilename string) error {
 	if v.debug {
 		log.Printf("Download(%s, %s, %s)\n", vid, localDestPathname, vmDirFilename)
 	}
-	vm, err := v.cli.GetVM(vid)
+	vm, err := v.cli.GetVM(ctx, vid)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	dir, _ := path.Split(vm.Path)
 	remoteSourcePathname := path.Join(dir, vmDirFilename)
-	return v.DownloadFile(&vm, localDestPathname, remoteSourcePathname)
+	return v.DownloadFile(ctx, &vm, localDestPathname, remoteSourcePathname)
 }
 
-func (v *vmctl) DownloadFile(vm *VM, localDestPathname, remoteSourcePathname string) error {
+func (v *vmctl) DownloadFile(ctx context.Context, vm *VM, localDestPathname, remoteSourcePathname string) error {
 	if v.debug {
 		log.Printf("DownloadFile(%s, %s, %s)\n", vm.Name, localDestPathname, remoteSourcePathname)
 	}
 
 	localDest, err := PathnameFormat(v.Local, localDestPathname)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
-
-	local, err := v.isLocal()
+	remoteSource, err := PathnameFormat(v.Remote, remoteSourcePathname)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
-	if local {
-		localSource, err := PathnameFormat(v.Local, remoteSourcePathname)
-		if err != nil {
-			return Fatal(err)
-		}
-		err = v.copyFile(localDest, localSource)
-		if err != nil {
-			return Fatal(err)
-		}
-		return nil
-	}
-
-	remoteSource, err := PathnameFormat(v.Remote, remoteSourcePathname)
+	err = v.executor.Download(ctx, localDest, remoteSource)
 	if err != nil {
-		return Fatal(err)
-	}
-	switch v.Shell {
-	case "winexec":
-		err = v.winexec.Download(localDest, remoteSource)
-		if err != nil {
-			return Fatal(err)
-		}
-	case "ssh":
-		err = v.scpDownload(localDest, remoteSource)
-		if err != nil {
-			return Fatal(err)
-		}
-	default:
-		return Fatalf("unexpected shell: %s", v.Shell)
+		return wrap(err)
 	}
 	return nil
 }
 
-func (v *vmctl) Upload(vid, localSourcePathname, vmDirFilename string) error {
+func (v *vmctl) Upload(ctx context.Context, vid, localSourcePathname, vmDirFilename string) error {
 	if v.debug {
 		log.Printf("Upload(%s, %s, %s)\n", vid, localSourcePathname, vmDirFilename)
 	}
-	vm, err := v.cli.GetVM(vid)
+	vm, err := v.cli.GetVM(ctx, vid)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	dir, _ := path.Split(vm.Path)
 	remoteDestPathname := path.Join(dir, vmDirFilename)
-	return v.UploadFile(&vm, localSourcePathname, remoteDestPathname)
+	return v.UploadFile(ctx, &vm, localSourcePathname, remoteDestPathname)
 }
 
-func (v *vmctl) UploadFile(vm *VM, localSourcePathname, remoteDestPathname string) error {
+func (v *vmctl) UploadFile(ctx context.Context, vm *VM, localSourcePathname, remoteDestPathname string) error {
 	if v.debug {
 		log.Printf("UploadFile(%s, %s, %s)\n", vm.Name, localSourcePathname, remoteDestPathname)
 	}
 
 	localSource, err := PathnameFormat(v.Local, localSourcePathname)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
-	local, err := v.isLocal()
+	remoteDest, err := PathnameFormat(v.Remote, remoteDestPathname)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
-	if local {
-		localDest, err := PathnameFormat(v.Local, remoteDestPathname)
-		if err != nil {
-			return Fatal(err)
-		}
-		err = v.copyFile(localDest, localSource)
-		if err != nil {
-			return Fatal(err)
-		}
-		return nil
+	err = v.executor.Upload(ctx, remoteDest, localSource)
+	if err != nil {
+		return wrap(err)
 	}
+	return nil
+}
 
-	remoteDest, err := PathnameFormat(v.Remote, remoteDestPathname)
+func (v *vmctl) removeHostFile(ctx context.Context, remotePathname string) error {
+	if v.debug {
+		log.Printf("removeHostFile(%s)\n", remotePathname)
+	}
+	hostPathname, err := PathnameFormat(v.Remote, remotePathname)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
-
-	switch v.Shell {
-	case "winexec":
-		err = v.winexec.Upload(remoteDest, localSource, true)
-		if err != nil {
-			return Fatal(err)
-		}
-	case "ssh":
-		err = v.scpUpload(remoteDest, localSource)
-		if err != nil {
-			return Fatal(err)
-		}
+	var command string
+	switch v.Remote {
+	case "windows":
+		command = "del " + hostPathname
 	default:
-		return Fatalf("unexpected shell: %s", v.Shell)
+		command = "rm " + hostPathname
+	}
+	_, err = v.RemoteExec(ctx, command, nil)
+	if err != nil {
+		return wrap(err)
 	}
 	return nil
 }
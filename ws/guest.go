@@ -0,0 +1,375 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var GUEST_EXIT_CODE = regexp.MustCompile(`Guest program exited with non-zero exit code:\s*(-?\d+)`)
+var GUEST_PROCESS = regexp.MustCompile(`^pid=(\d+), owner=(.*?), cmd=(.*)$`)
+
+type GuestAuth struct {
+	Username string
+	Password string
+}
+
+type GuestOptions struct {
+	Username string
+	Password string
+}
+
+type GuestExecOptions struct {
+	Username     string
+	Password     string
+	Wait         bool
+	Interactive  bool
+	ActiveWindow bool
+	Interpreter  string
+}
+
+type GuestProcess struct {
+	Pid     int    `json:"pid"`
+	Owner   string `json:"owner"`
+	Command string `json:"cmd"`
+}
+
+type GuestExecResult struct {
+	Name     string
+	Command  string
+	ExitCode int
+	Result   string
+}
+
+// return guest credentials from the arguments, the config file, or the environment
+func (v *vmctl) guestAuth(username, password string) (*GuestAuth, error) {
+	var prefix string
+	if ProgramName() != "vmx" {
+		prefix = "vmx."
+	}
+	if username == "" {
+		username = ViperGetString(prefix + "guest_user")
+	}
+	if username == "" {
+		username = os.Getenv("VMX_GUEST_USER")
+	}
+	if password == "" {
+		password = ViperGetString(prefix + "guest_password")
+	}
+	if password == "" {
+		password = os.Getenv("VMX_GUEST_PASSWORD")
+	}
+	if username == "" {
+		return nil, Fatalf("missing guest credentials: set guest_user and guest_password")
+	}
+	return &GuestAuth{Username: username, Password: password}, nil
+}
+
+// parse the guest exit code from the output of a failed vmrun guest command
+func ParseGuestExitCode(lines []string) (int, bool) {
+	for _, line := range lines {
+		m := GUEST_EXIT_CODE.FindStringSubmatch(line)
+		if len(m) == 2 {
+			code, err := strconv.Atoi(m[1])
+			if err == nil {
+				return code, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// run a program in the guest using VMware Tools; if options.Interpreter is set, command[0] is a script
+func (v *vmctl) GuestExec(ctx context.Context, vid string, command []string, options GuestExecOptions) (*GuestExecResult, error) {
+	if v.debug {
+		log.Printf("GuestExec(%s, %v, %+v)\n", vid, command, options)
+	}
+	if len(command) == 0 {
+		return nil, Fatalf("missing guest command")
+	}
+	vm, auth, err := v.guestVM(ctx, vid, "run a guest program", GuestOptions{Username: options.Username, Password: options.Password})
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	args := []string{}
+	if !options.Wait {
+		args = append(args, "-noWait")
+	}
+	if options.ActiveWindow {
+		args = append(args, "-activeWindow")
+	}
+	if options.Interactive {
+		args = append(args, "-interactive")
+	}
+
+	var vmrunCommand string
+	if options.Interpreter != "" {
+		vmrunCommand = "runScriptInGuest"
+		args = append(args, options.Interpreter, strings.Join(command, " "))
+	} else {
+		vmrunCommand = "runProgramInGuest"
+		for _, arg := range command {
+			args = append(args, arg)
+		}
+	}
+
+	result := GuestExecResult{Name: vm.Name, Command: strings.Join(command, " ")}
+	if v.verbose {
+		fmt.Printf("[%s] Running guest command: %s\n", vm.Name, result.Command)
+	}
+	var exitCode int
+	olines, err := v.vmrunExec(ctx, vm, auth, vmrunCommand, &exitCode, args...)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if exitCode != 0 {
+		code, ok := ParseGuestExitCode(olines)
+		if !ok {
+			return nil, Fatalf("[%s] vmrun %s failed: %s", vm.Name, vmrunCommand, strings.Join(olines, "; "))
+		}
+		result.ExitCode = code
+	}
+	switch {
+	case !options.Wait:
+		result.Result = "guest command started"
+	case result.ExitCode == 0:
+		result.Result = "guest command succeeded"
+	default:
+		result.Result = "guest command failed"
+	}
+	return &result, nil
+}
+
+// prepare a running instance for a guest operation
+func (v *vmctl) guestVM(ctx context.Context, vid, action string, options GuestOptions) (*VM, *GuestAuth, error) {
+	vm, err := v.cli.GetVM(ctx, vid)
+	if err != nil {
+		return nil, nil, wrap(err)
+	}
+	err = v.requirePowerState(ctx, &vm, "on", action)
+	if err != nil {
+		return nil, nil, wrap(err)
+	}
+	auth, err := v.guestAuth(options.Username, options.Password)
+	if err != nil {
+		return nil, nil, wrap(err)
+	}
+	return &vm, auth, nil
+}
+
+// return a unique pathname in the instance directory for staging a guest file transfer
+func guestStagingPathname(vm *VM, filename string) string {
+	dir, _ := path.Split(vm.Path)
+	_, base := path.Split(strings.ReplaceAll(filename, "\\", "/"))
+	return path.Join(dir, fmt.Sprintf("vmx_guest_cp.%d.%s", time.Now().UnixNano(), base))
+}
+
+// copy a local file into the guest, staging it in the instance directory if the host is remote
+func (v *vmctl) GuestUpload(ctx context.Context, vid, localSourcePathname, guestDestPathname string, options GuestOptions) error {
+	if v.debug {
+		log.Printf("GuestUpload(%s, %s, %s)\n", vid, localSourcePathname, guestDestPathname)
+	}
+	vm, auth, err := v.guestVM(ctx, vid, "copy files to the guest", options)
+	if err != nil {
+		return wrap(err)
+	}
+	local, err := v.isLocal()
+	if err != nil {
+		return wrap(err)
+	}
+	var hostPathname string
+	if local {
+		abs, err := filepath.Abs(localSourcePathname)
+		if err != nil {
+			return wrap(err)
+		}
+		hostPathname = abs
+	} else {
+		hostPathname = guestStagingPathname(vm, localSourcePathname)
+		err = v.UploadFile(ctx, vm, localSourcePathname, hostPathname)
+		if err != nil {
+			return wrap(err)
+		}
+		defer v.removeHostFile(ctx, hostPathname)
+	}
+	hostPath, err := PathnameFormat(v.Remote, hostPathname)
+	if err != nil {
+		return wrap(err)
+	}
+	if v.verbose {
+		fmt.Printf("[%s] Copying %s to guest %s\n", vm.Name, localSourcePathname, guestDestPathname)
+	}
+	_, err = v.vmrunExec(ctx, vm, auth, "copyFileFromHostToGuest", nil, hostPath, guestDestPathname)
+	if err != nil {
+		return wrap(err)
+	}
+	return nil
+}
+
+// copy a guest file to a local file, staging it in the instance directory if the host is remote
+func (v *vmctl) GuestDownload(ctx context.Context, vid, guestSourcePathname, localDestPathname string, options GuestOptions) error {
+	if v.debug {
+		log.Printf("GuestDownload(%s, %s, %s)\n", vid, guestSourcePathname, localDestPathname)
+	}
+	vm, auth, err := v.guestVM(ctx, vid, "copy files from the guest", options)
+	if err != nil {
+		return wrap(err)
+	}
+	local, err := v.isLocal()
+	if err != nil {
+		return wrap(err)
+	}
+	var hostPathname string
+	if local {
+		abs, err := filepath.Abs(localDestPathname)
+		if err != nil {
+			return wrap(err)
+		}
+		hostPathname = abs
+	} else {
+		hostPathname = guestStagingPathname(vm, guestSourcePathname)
+	}
+	hostPath, err := PathnameFormat(v.Remote, hostPathname)
+	if err != nil {
+		return wrap(err)
+	}
+	if v.verbose {
+		fmt.Printf("[%s] Copying guest %s to %s\n", vm.Name, guestSourcePathname, localDestPathname)
+	}
+	_, err = v.vmrunExec(ctx, vm, auth, "copyFileFromGuestToHost", nil, guestSourcePathname, hostPath)
+	if err != nil {
+		return wrap(err)
+	}
+	if !local {
+		defer v.removeHostFile(ctx, hostPathname)
+		err = v.DownloadFile(ctx, vm, localDestPathname, hostPathname)
+		if err != nil {
+			return wrap(err)
+		}
+	}
+	return nil
+}
+
+// parse the output of 'vmrun listDirectoryInGuest'
+func ParseGuestDirectoryList(lines []string) []string {
+	files := []string{}
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if line == "" || strings.HasPrefix(line, "Directory list:") {
+			continue
+		}
+		files = append(files, line)
+	}
+	return files
+}
+
+func (v *vmctl) GuestListDirectory(ctx context.Context, vid, guestDir string, options GuestOptions) ([]string, error) {
+	if v.debug {
+		log.Printf("GuestListDirectory(%s, %s)\n", vid, guestDir)
+	}
+	vm, auth, err := v.guestVM(ctx, vid, "list guest files", options)
+	if err != nil {
+		return []string{}, wrap(err)
+	}
+	olines, err := v.vmrunExec(ctx, vm, auth, "listDirectoryInGuest", nil, guestDir)
+	if err != nil {
+		return []string{}, wrap(err)
+	}
+	return ParseGuestDirectoryList(olines), nil
+}
+
+func (v *vmctl) GuestMkdir(ctx context.Context, vid, guestDir string, options GuestOptions) error {
+	if v.debug {
+		log.Printf("GuestMkdir(%s, %s)\n", vid, guestDir)
+	}
+	vm, auth, err := v.guestVM(ctx, vid, "create guest directories", options)
+	if err != nil {
+		return wrap(err)
+	}
+	_, err = v.vmrunExec(ctx, vm, auth, "createDirectoryInGuest", nil, guestDir)
+	if err != nil {
+		return wrap(err)
+	}
+	return nil
+}
+
+func (v *vmctl) GuestRemove(ctx context.Context, vid, guestPathname string, options GuestOptions) error {
+	if v.debug {
+		log.Printf("GuestRemove(%s, %s)\n", vid, guestPathname)
+	}
+	vm, auth, err := v.guestVM(ctx, vid, "delete guest files", options)
+	if err != nil {
+		return wrap(err)
+	}
+	_, err = v.vmrunExec(ctx, vm, auth, "deleteFileInGuest", nil, guestPathname)
+	if err != nil {
+		return wrap(err)
+	}
+	return nil
+}
+
+// parse the output of 'vmrun listProcessesInGuest'
+func ParseGuestProcessList(lines []string) ([]GuestProcess, error) {
+	processes := []GuestProcess{}
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if line == "" || strings.HasPrefix(line, "Process list:") {
+			continue
+		}
+		m := GUEST_PROCESS.FindStringSubmatch(line)
+		if len(m) != 4 {
+			return processes, Fatalf("failed parsing guest process: '%s'", line)
+		}
+		pid, err := strconv.Atoi(m[1])
+		if err != nil {
+			return processes, wrap(err)
+		}
+		processes = append(processes, GuestProcess{Pid: pid, Owner: m[2], Command: m[3]})
+	}
+	return processes, nil
+}
+
+func (v *vmctl) GuestListProcesses(ctx context.Context, vid string, options GuestOptions) ([]GuestProcess, error) {
+	if v.debug {
+		log.Printf("GuestListProcesses(%s)\n", vid)
+	}
+	vm, auth, err := v.guestVM(ctx, vid, "list guest processes", options)
+	if err != nil {
+		return []GuestProcess{}, wrap(err)
+	}
+	olines, err := v.vmrunExec(ctx, vm, auth, "listProcessesInGuest", nil)
+	if err != nil {
+		return []GuestProcess{}, wrap(err)
+	}
+	processes, err := ParseGuestProcessList(olines)
+	if err != nil {
+		return []GuestProcess{}, wrap(err)
+	}
+	return processes, nil
+}
+
+func (v *vmctl) GuestKillProcess(ctx context.Context, vid string, pid int, options GuestOptions) error {
+	if v.debug {
+		log.Printf("GuestKillProcess(%s, %d)\n", vid, pid)
+	}
+	vm, auth, err := v.guestVM(ctx, vid, "kill guest processes", options)
+	if err != nil {
+		return wrap(err)
+	}
+	if v.verbose {
+		fmt.Printf("[%s] Killing guest process %d\n", vm.Name, pid)
+	}
+	_, err = v.vmrunExec(ctx, vm, auth, "killProcessInGuest", nil, strconv.Itoa(pid))
+	if err != nil {
+		return wrap(err)
+	}
+	return nil
+}
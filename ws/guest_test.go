@@ -0,0 +1,78 @@
+package ws
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"log"
+	"os"
+	"testing"
+)
+
+func TestParseGuestExitCode(t *testing.T) {
+	lines := []string{"Guest program exited with non-zero exit code: 3"}
+	code, ok := ParseGuestExitCode(lines)
+	require.True(t, ok)
+	require.Equal(t, 3, code)
+
+	_, ok = ParseGuestExitCode([]string{"Error: Invalid user name or password for the guest OS"})
+	require.False(t, ok)
+}
+
+func TestParseGuestDirectoryList(t *testing.T) {
+	lines := []string{"Directory list: 3\r", "bin\r", "etc\r", "tmp\r"}
+	files := ParseGuestDirectoryList(lines)
+	require.Equal(t, []string{"bin", "etc", "tmp"}, files)
+}
+
+func TestParseGuestProcessList(t *testing.T) {
+	lines := []string{
+		"Process list: 3",
+		"pid=1, owner=root, cmd=/sbin/init splash",
+		"pid=412, owner=NT AUTHORITY\\SYSTEM, cmd=C:\\Windows\\System32\\svchost.exe -k netsvcs, -p",
+		"pid=2231, owner=tester, cmd=sleep 1000\r",
+	}
+	processes, err := ParseGuestProcessList(lines)
+	require.Nil(t, err)
+	require.Len(t, processes, 3)
+	require.Equal(t, GuestProcess{Pid: 1, Owner: "root", Command: "/sbin/init splash"}, processes[0])
+	require.Equal(t, "NT AUTHORITY\\SYSTEM", processes[1].Owner)
+	require.Equal(t, "C:\\Windows\\System32\\svchost.exe -k netsvcs, -p", processes[1].Command)
+	require.Equal(t, 2231, processes[2].Pid)
+	require.Equal(t, "sleep 1000", processes[2].Command)
+
+	_, err = ParseGuestProcessList([]string{"garbage"})
+	require.NotNil(t, err)
+}
+
+func TestHostQuote(t *testing.T) {
+	require.Equal(t, "/vmware/web1/web1.vmx", hostQuote("linux", "/vmware/web1/web1.vmx"))
+	require.Equal(t, `""`, hostQuote("linux", ""))
+	require.Equal(t, `'Ubuntu 64-bit'`, hostQuote("linux", "Ubuntu 64-bit"))
+	require.Equal(t, `'a"b$c;d`+"`"+`e'\''f'`, hostQuote("linux", `a"b$c;d`+"`"+`e'f`))
+	require.Equal(t, `C:\vmware\web1\web1.vmx`, hostQuote("windows", `C:\vmware\web1\web1.vmx`))
+	require.Equal(t, `""`, hostQuote("windows", ""))
+	require.Equal(t, `^"C:\Virtual Machines\web1.vmx^"`, hostQuote("windows", `C:\Virtual Machines\web1.vmx`))
+	require.Equal(t, `^"a\^"b ^&^| ^%PATH^%\\^"`, hostQuote("windows", `a"b &| %PATH%\`))
+}
+
+func TestVMRunGuestAuth(t *testing.T) {
+	ctx := t.Context()
+	v, host := newFakeController(t)
+	vm := VM{Name: "web1", Path: "/vmware/web1/web1.vmx"}
+	var exitCode int
+
+	_, err := v.vmrunExec(ctx, &vm, &GuestAuth{Username: "admin"}, "listProcessesInGuest", &exitCode)
+	require.Nil(t, err)
+	require.Equal(t, `vmrun -T ws -gu admin -gp "" listProcessesInGuest /vmware/web1/web1.vmx`, host.Commands[len(host.Commands)-1])
+
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+	v.debug = true
+	password := "pa$$ word;`id`"
+	_, err = v.vmrunExec(ctx, &vm, &GuestAuth{Username: "admin", Password: password}, "runProgramInGuest", &exitCode, "/bin/echo", "a b")
+	require.Nil(t, err)
+	require.Equal(t, `vmrun -T ws -gu admin -gp 'pa$$ word;`+"`id`"+`' runProgramInGuest /vmware/web1/web1.vmx /bin/echo 'a b'`, host.Commands[len(host.Commands)-1])
+	require.Contains(t, logged.String(), "RemoteExec(")
+	require.NotContains(t, logged.String(), password)
+}
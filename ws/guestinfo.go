@@ -0,0 +1,198 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+const GUESTINFO_PREFIX = "guestinfo."
+
+type GuestInfoOptions struct {
+	RuntimeConfig bool
+}
+
+// return key with the 'guestinfo.' prefix
+func GuestInfoKey(key string) string {
+	if strings.HasPrefix(key, GUESTINFO_PREFIX) {
+		return key
+	}
+	return GUESTINFO_PREFIX + key
+}
+
+func checkGuestInfoKey(key string) error {
+	if key == GUESTINFO_PREFIX || strings.ContainsAny(key, " \t=\"#") {
+		return Fatalf("invalid guestinfo key: '%s'", key)
+	}
+	return nil
+}
+
+// vmrun variable values are passed on the command line
+func checkGuestInfo(key, value string) error {
+	err := checkGuestInfoKey(key)
+	if err != nil {
+		return wrap(err)
+	}
+	if strings.ContainsAny(value, "\"\r\n") {
+		return Fatalf("invalid guestinfo value for '%s': quotes and newlines are not allowed", key)
+	}
+	return nil
+}
+
+// return the guestinfo variables in the VMX configuration
+func (v *VMX) GuestInfo() map[string]string {
+	values := make(map[string]string)
+	for _, key := range v.doc.Keys() {
+		if strings.HasPrefix(key, GUESTINFO_PREFIX) {
+			values[key], _ = v.doc.Get(key)
+		}
+	}
+	return values
+}
+
+// set guestinfo variables in the VMX configuration; an empty value removes the key
+func (v *VMX) SetGuestInfo(values map[string]string) (string, error) {
+	if v.debug {
+		log.Printf("SetGuestInfo(%v)\n", values)
+	}
+	keys := []string{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	set := []string{}
+	removed := []string{}
+	for _, key := range keys {
+		value := values[key]
+		key = GuestInfoKey(key)
+		err := checkGuestInfoKey(key)
+		if err != nil {
+			return "", wrap(err)
+		}
+		if value == "" {
+			v.doc.Delete(key)
+			removed = append(removed, key)
+			continue
+		}
+		v.doc.Set(key, value)
+		set = append(set, key)
+	}
+	actions := []string{}
+	if len(set) > 0 {
+		actions = append(actions, "Set "+strings.Join(set, ", "))
+	}
+	if len(removed) > 0 {
+		actions = append(actions, "Removed "+strings.Join(removed, ", "))
+	}
+	return strings.Join(actions, "; "), nil
+}
+
+// return the vmrun variable type and name for a guestinfo key
+func guestInfoVariable(key string, options GuestInfoOptions) (string, string) {
+	key = GuestInfoKey(key)
+	if options.RuntimeConfig {
+		return "runtimeConfig", key
+	}
+	return "guestVar", strings.TrimPrefix(key, GUESTINFO_PREFIX)
+}
+
+func (v *vmctl) readGuestInfoVMX(ctx context.Context, vm *VM) (map[string]string, error) {
+	data, err := v.ReadHostFile(ctx, vm, vm.Name+".vmx")
+	if err != nil {
+		return nil, wrap(err)
+	}
+	vmx, err := InitVMX(v.Remote, vm.Name, data)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	return vmx.GuestInfo(), nil
+}
+
+// return the guestinfo variables persisted in the instance VMX file
+func (v *vmctl) ListGuestInfo(ctx context.Context, vid string) (map[string]string, error) {
+	if v.debug {
+		log.Printf("ListGuestInfo(%s)\n", vid)
+	}
+	vm, err := v.cli.GetVM(ctx, vid)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	values, err := v.readGuestInfoVMX(ctx, &vm)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	return values, nil
+}
+
+// read a guestinfo variable from a running instance, or from the VMX file if the instance is off
+func (v *vmctl) GetGuestInfo(ctx context.Context, vid, key string, options GuestInfoOptions) (string, error) {
+	if v.debug {
+		log.Printf("GetGuestInfo(%s, %s, %+v)\n", vid, key, options)
+	}
+	vm, err := v.cli.GetVM(ctx, vid)
+	if err != nil {
+		return "", wrap(err)
+	}
+	err = v.cli.QueryPowerState(ctx, &vm)
+	if err != nil {
+		return "", wrap(err)
+	}
+	if vm.PowerState == "off" {
+		values, err := v.readGuestInfoVMX(ctx, &vm)
+		if err != nil {
+			return "", wrap(err)
+		}
+		return values[GuestInfoKey(key)], nil
+	}
+	varType, name := guestInfoVariable(key, options)
+	olines, err := v.vmrun(ctx, &vm, "readVariable", varType, name)
+	if err != nil {
+		return "", wrap(err)
+	}
+	return strings.TrimRight(strings.Join(olines, "\n"), "\r\n"), nil
+}
+
+// write a guestinfo variable to a running instance, or into the VMX file if the instance is off
+func (v *vmctl) SetGuestInfo(ctx context.Context, vid, key, value string, options GuestInfoOptions) (string, error) {
+	if v.debug {
+		log.Printf("SetGuestInfo(%s, %s, %s, %+v)\n", vid, key, value, options)
+	}
+	err := checkGuestInfoKey(GuestInfoKey(key))
+	if err != nil {
+		return "", wrap(err)
+	}
+	vm, err := v.cli.GetVM(ctx, vid)
+	if err != nil {
+		return "", wrap(err)
+	}
+	err = v.cli.QueryPowerState(ctx, &vm)
+	if err != nil {
+		return "", wrap(err)
+	}
+	if vm.PowerState == "off" {
+		options := CreateOptions{
+			ModifyGuestInfo: true,
+			GuestInfo:       map[string]string{key: value},
+		}
+		actions, err := v.Modify(ctx, vm.Name, options, IsoOptions{})
+		if err != nil {
+			return "", wrap(err)
+		}
+		return strings.Join(*actions, "; "), nil
+	}
+	err = checkGuestInfo(GuestInfoKey(key), value)
+	if err != nil {
+		return "", wrap(err)
+	}
+	varType, name := guestInfoVariable(key, options)
+	if v.verbose {
+		fmt.Printf("[%s] Writing %s variable %s\n", vm.Name, varType, name)
+	}
+	_, err = v.vmrun(ctx, &vm, "writeVariable", varType, name, value)
+	if err != nil {
+		return "", wrap(err)
+	}
+	return fmt.Sprintf("Wrote %s %s", varType, name), nil
+}
@@ -0,0 +1,48 @@
+package ws
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestVMXGuestInfo(t *testing.T) {
+	initTestConfig(t)
+	data := []byte(`displayName = "test"
+guestinfo.metadata = "old"
+guestinfo.metadata.encoding = "base64"
+guestinfo.hostname = "test.example.org"
+`)
+	vmx, err := InitVMX("linux", "test", data)
+	require.Nil(t, err)
+	_, err = vmx.SetGuestInfo(map[string]string{
+		"metadata":           "bmV3",
+		"guestinfo.hostname": "",
+		"userdata":           "dXNlcg==",
+	})
+	require.Nil(t, err)
+	values := vmx.GuestInfo()
+	require.Equal(t, map[string]string{
+		"guestinfo.metadata":          "bmV3",
+		"guestinfo.metadata.encoding": "base64",
+		"guestinfo.userdata":          "dXNlcg==",
+	}, values)
+
+	_, err = vmx.SetGuestInfo(map[string]string{"quoted": `has "quotes"`})
+	require.Nil(t, err)
+	data, err = vmx.Read()
+	require.Nil(t, err)
+	require.Contains(t, string(data), `guestinfo.quoted = "has |22quotes|22"`)
+	require.Equal(t, `has "quotes"`, vmx.GuestInfo()["guestinfo.quoted"])
+
+	_, err = vmx.SetGuestInfo(map[string]string{"bad key": "value"})
+	require.NotNil(t, err)
+}
+
+func TestGuestInfoVariable(t *testing.T) {
+	varType, name := guestInfoVariable("guestinfo.metadata", GuestInfoOptions{})
+	require.Equal(t, "guestVar", varType)
+	require.Equal(t, "metadata", name)
+	varType, name = guestInfoVariable("metadata", GuestInfoOptions{RuntimeConfig: true})
+	require.Equal(t, "runtimeConfig", varType)
+	require.Equal(t, "guestinfo.metadata", name)
+}
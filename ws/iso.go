@@ -28,11 +28,11 @@ func (v *vmctl) CheckISODownload(vm *VM, options *IsoOptions) error {
 		// generate a normalized full pathname for the ISO file
 		vmxFilename, err := FormatIsoPathname(v.IsoPath, filename)
 		if err != nil {
-			return Fatal(err)
+			return wrap(err)
 		}
 		err = v.winexec.GetISO(vmxFilename, url, options.IsoCA, options.IsoClientCert, options.IsoClientKey, nil)
 		if err != nil {
-			return Fatal(err)
+			return wrap(err)
 		}
 		options.IsoFile = vmxFilename
 	}
@@ -0,0 +1,264 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// minimal ISO9660 image writer with Joliet names; all files are written in the root directory
+
+const ISO_SECTOR_SIZE = 2048
+
+// sector layout: system area, PVD, Joliet SVD, terminator, 4 path tables, 2 root directories
+const (
+	isoPrimaryDescriptorSector = 16
+	isoJolietDescriptorSector  = 17
+	isoTerminatorSector        = 18
+	isoPrimaryLPathSector      = 19
+	isoPrimaryMPathSector      = 20
+	isoJolietLPathSector       = 21
+	isoJolietMPathSector       = 22
+	isoPrimaryRootSector       = 23
+	isoJolietRootSector        = 24
+	isoFirstFileSector         = 25
+)
+
+type ISOFile struct {
+	Name string
+	Data []byte
+}
+
+type isoEntry struct {
+	name   []byte
+	sector uint32
+	size   uint32
+}
+
+func isoBoth16(b []byte, value uint16) {
+	binary.LittleEndian.PutUint16(b[0:2], value)
+	binary.BigEndian.PutUint16(b[2:4], value)
+}
+
+func isoBoth32(b []byte, value uint32) {
+	binary.LittleEndian.PutUint32(b[0:4], value)
+	binary.BigEndian.PutUint32(b[4:8], value)
+}
+
+func isoSectors(size int) uint32 {
+	return uint32((size + ISO_SECTOR_SIZE - 1) / ISO_SECTOR_SIZE)
+}
+
+// return the 8.3 d-character name for a primary volume descriptor directory record
+func isoPrimaryName(name string) string {
+	mapChars := func(s string, max int) string {
+		s = strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+				return r
+			case r >= 'a' && r <= 'z':
+				return r - 'a' + 'A'
+			}
+			return '_'
+		}, s)
+		if len(s) > max {
+			s = s[:max]
+		}
+		return s
+	}
+	base, ext, _ := strings.Cut(name, ".")
+	return mapChars(base, 8) + "." + mapChars(ext, 3) + ";1"
+}
+
+func isoJolietName(name string) []byte {
+	var buf bytes.Buffer
+	for _, c := range utf16.Encode([]rune(name)) {
+		binary.Write(&buf, binary.BigEndian, c)
+	}
+	return buf.Bytes()
+}
+
+// ISO9660 7-byte directory record date
+func isoRecordTime(t time.Time) []byte {
+	t = t.UTC()
+	return []byte{byte(t.Year() - 1900), byte(t.Month()), byte(t.Day()), byte(t.Hour()), byte(t.Minute()), byte(t.Second()), 0}
+}
+
+// ISO9660 17-byte volume descriptor date
+func isoVolumeTime(t time.Time) []byte {
+	if t.IsZero() {
+		b := []byte(strings.Repeat("0", 16) + "\x00")
+		return b
+	}
+	t = t.UTC()
+	return append([]byte(fmt.Sprintf("%04d%02d%02d%02d%02d%02d00", t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second())), 0)
+}
+
+func isoDirectoryRecord(name []byte, sector, size uint32, dir bool, now time.Time) []byte {
+	length := 33 + len(name)
+	if length%2 != 0 {
+		length++
+	}
+	record := make([]byte, length)
+	record[0] = byte(length)
+	isoBoth32(record[2:10], sector)
+	isoBoth32(record[10:18], size)
+	copy(record[18:25], isoRecordTime(now))
+	if dir {
+		record[25] = 2
+	}
+	isoBoth16(record[28:32], 1)
+	record[32] = byte(len(name))
+	copy(record[33:], name)
+	return record
+}
+
+func isoRootDirectory(sector uint32, entries []isoEntry, now time.Time) ([]byte, error) {
+	dir := isoDirectoryRecord([]byte{0}, sector, ISO_SECTOR_SIZE, true, now)
+	dir = append(dir, isoDirectoryRecord([]byte{1}, sector, ISO_SECTOR_SIZE, true, now)...)
+	for _, entry := range entries {
+		dir = append(dir, isoDirectoryRecord(entry.name, entry.sector, entry.size, false, now)...)
+	}
+	if len(dir) > ISO_SECTOR_SIZE {
+		return nil, Fatalf("ISO root directory overflow")
+	}
+	return dir, nil
+}
+
+func isoPathTable(rootSector uint32, bigEndian bool) []byte {
+	table := make([]byte, 10)
+	table[0] = 1
+	if bigEndian {
+		binary.BigEndian.PutUint32(table[2:6], rootSector)
+		binary.BigEndian.PutUint16(table[6:8], 1)
+	} else {
+		binary.LittleEndian.PutUint32(table[2:6], rootSector)
+		binary.LittleEndian.PutUint16(table[6:8], 1)
+	}
+	return table
+}
+
+func isoVolumeDescriptor(joliet bool, volumeID string, totalSectors uint32, now time.Time) []byte {
+	d := make([]byte, ISO_SECTOR_SIZE)
+	d[0] = 1
+	if joliet {
+		d[0] = 2
+	}
+	copy(d[1:6], "CD001")
+	d[6] = 1
+
+	// text fields are space padded; Joliet text fields are UCS-2 space padded
+	text := func(b []byte, value string) {
+		if joliet {
+			for i := 0; i+1 < len(b); i += 2 {
+				b[i], b[i+1] = 0, ' '
+			}
+			copy(b, isoJolietName(value))
+		} else {
+			copy(b, bytes.Repeat([]byte{' '}, len(b)))
+			copy(b, value)
+		}
+	}
+	text(d[8:40], "")
+	text(d[40:72], volumeID)
+	isoBoth32(d[80:88], totalSectors)
+	if joliet {
+		copy(d[88:91], "%/E")
+	}
+	isoBoth16(d[120:124], 1)
+	isoBoth16(d[124:128], 1)
+	isoBoth16(d[128:132], ISO_SECTOR_SIZE)
+	isoBoth32(d[132:140], 10)
+	rootSector := uint32(isoPrimaryRootSector)
+	lPath, mPath := uint32(isoPrimaryLPathSector), uint32(isoPrimaryMPathSector)
+	if joliet {
+		rootSector = isoJolietRootSector
+		lPath, mPath = isoJolietLPathSector, isoJolietMPathSector
+	}
+	binary.LittleEndian.PutUint32(d[140:144], lPath)
+	binary.BigEndian.PutUint32(d[148:152], mPath)
+	copy(d[156:190], isoDirectoryRecord([]byte{0}, rootSector, ISO_SECTOR_SIZE, true, now))
+	text(d[190:318], "")
+	text(d[318:446], "")
+	text(d[446:574], "")
+	text(d[574:702], "VMX")
+	text(d[702:739], "")
+	text(d[739:776], "")
+	text(d[776:813], "")
+	copy(d[813:830], isoVolumeTime(now))
+	copy(d[830:847], isoVolumeTime(now))
+	copy(d[847:864], isoVolumeTime(time.Time{}))
+	copy(d[864:881], isoVolumeTime(time.Time{}))
+	d[881] = 1
+	return d
+}
+
+// return an ISO9660 image containing files in the root directory
+func NewISOImage(volumeID string, files []ISOFile) ([]byte, error) {
+	if len(volumeID) == 0 || len(volumeID) > 16 {
+		return nil, Fatalf("invalid ISO volume id: '%s'", volumeID)
+	}
+	now := time.Now()
+	sorted := make([]ISOFile, len(files))
+	copy(sorted, files)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	primary := []isoEntry{}
+	joliet := []isoEntry{}
+	names := make(map[string]bool)
+	sector := uint32(isoFirstFileSector)
+	for _, file := range sorted {
+		if file.Name == "" || len(file.Name) > 64 || strings.ContainsAny(file.Name, "/\\;") {
+			return nil, Fatalf("invalid ISO file name: '%s'", file.Name)
+		}
+		primaryName := isoPrimaryName(file.Name)
+		if names[primaryName] {
+			return nil, Fatalf("duplicate ISO file name: '%s'", primaryName)
+		}
+		names[primaryName] = true
+		size := uint32(len(file.Data))
+		primary = append(primary, isoEntry{name: []byte(primaryName), sector: sector, size: size})
+		joliet = append(joliet, isoEntry{name: isoJolietName(file.Name), sector: sector, size: size})
+		sector += isoSectors(len(file.Data))
+	}
+	totalSectors := sector
+	sort.Slice(primary, func(i, j int) bool { return bytes.Compare(primary[i].name, primary[j].name) < 0 })
+
+	image := make([]byte, int(totalSectors)*ISO_SECTOR_SIZE)
+	at := func(sector int) []byte {
+		return image[sector*ISO_SECTOR_SIZE : (sector+1)*ISO_SECTOR_SIZE]
+	}
+
+	copy(at(isoPrimaryDescriptorSector), isoVolumeDescriptor(false, volumeID, totalSectors, now))
+	copy(at(isoJolietDescriptorSector), isoVolumeDescriptor(true, volumeID, totalSectors, now))
+	terminator := at(isoTerminatorSector)
+	terminator[0] = 255
+	copy(terminator[1:6], "CD001")
+	terminator[6] = 1
+
+	copy(at(isoPrimaryLPathSector), isoPathTable(isoPrimaryRootSector, false))
+	copy(at(isoPrimaryMPathSector), isoPathTable(isoPrimaryRootSector, true))
+	copy(at(isoJolietLPathSector), isoPathTable(isoJolietRootSector, false))
+	copy(at(isoJolietMPathSector), isoPathTable(isoJolietRootSector, true))
+
+	dir, err := isoRootDirectory(isoPrimaryRootSector, primary, now)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	copy(at(isoPrimaryRootSector), dir)
+	dir, err = isoRootDirectory(isoJolietRootSector, joliet, now)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	copy(at(isoJolietRootSector), dir)
+
+	for i, file := range sorted {
+		offset := int(joliet[i].sector) * ISO_SECTOR_SIZE
+		copy(image[offset:], file.Data)
+	}
+	return image, nil
+}
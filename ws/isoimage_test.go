@@ -0,0 +1,79 @@
+package ws
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"unicode/utf16"
+)
+
+// read the root directory files of an ISO image using the primary or Joliet volume descriptor
+func readTestISO(t *testing.T, image []byte, joliet bool) (string, map[string][]byte) {
+	require.Zero(t, len(image)%ISO_SECTOR_SIZE)
+	sector := isoPrimaryDescriptorSector
+	if joliet {
+		sector = isoJolietDescriptorSector
+	}
+	d := image[sector*ISO_SECTOR_SIZE : (sector+1)*ISO_SECTOR_SIZE]
+	require.Equal(t, "CD001", string(d[1:6]))
+	require.Equal(t, uint32(len(image)/ISO_SECTOR_SIZE), binary.LittleEndian.Uint32(d[80:84]))
+	require.Equal(t, uint32(len(image)/ISO_SECTOR_SIZE), binary.BigEndian.Uint32(d[84:88]))
+	decode := func(b []byte) string {
+		if !joliet {
+			return string(b)
+		}
+		u := make([]uint16, len(b)/2)
+		for i := range u {
+			u[i] = binary.BigEndian.Uint16(b[i*2:])
+		}
+		return string(utf16.Decode(u))
+	}
+	volumeID := decode(d[40:72])
+	root := d[156:190]
+	offset := int(binary.LittleEndian.Uint32(root[2:6])) * ISO_SECTOR_SIZE
+	size := int(binary.LittleEndian.Uint32(root[10:14]))
+	dir := image[offset : offset+size]
+	files := make(map[string][]byte)
+	for i := 0; i < len(dir) && dir[i] != 0; i += int(dir[i]) {
+		record := dir[i : i+int(dir[i])]
+		name := record[33 : 33+int(record[32])]
+		if record[25]&2 != 0 {
+			continue
+		}
+		extent := int(binary.LittleEndian.Uint32(record[2:6])) * ISO_SECTOR_SIZE
+		length := int(binary.BigEndian.Uint32(record[14:18]))
+		files[decode(name)] = image[extent : extent+length]
+	}
+	return volumeID, files
+}
+
+func TestNewSeedISO(t *testing.T) {
+	userData := []byte("#cloud-config\nhostname: test\n")
+	networkConfig := make([]byte, ISO_SECTOR_SIZE+17)
+	for i := range networkConfig {
+		networkConfig[i] = byte(i)
+	}
+	image, err := NewSeedISO("test", SeedOptions{UserData: userData, NetworkConfig: networkConfig})
+	require.Nil(t, err)
+
+	volumeID, files := readTestISO(t, image, true)
+	require.Equal(t, "cidata", volumeID[:6])
+	require.Len(t, files, 3)
+	require.Equal(t, userData, files["user-data"])
+	require.Equal(t, "instance-id: test\nlocal-hostname: test\n", string(files["meta-data"]))
+	require.Equal(t, networkConfig, files["network-config"])
+
+	volumeID, files = readTestISO(t, image, false)
+	require.Equal(t, "cidata", volumeID[:6])
+	require.Equal(t, userData, files["USER_DAT.;1"])
+	require.Equal(t, networkConfig, files["NETWORK_.;1"])
+}
+
+func TestNewISOImageErrors(t *testing.T) {
+	_, err := NewISOImage("", []ISOFile{})
+	require.NotNil(t, err)
+	_, err = NewISOImage("cidata", []ISOFile{{Name: "a/b"}})
+	require.NotNil(t, err)
+	_, err = NewISOImage("cidata", []ISOFile{{Name: "user-data1"}, {Name: "user-data2"}})
+	require.NotNil(t, err)
+}
@@ -1,17 +1,18 @@
 package ws
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strconv"
 	"unicode"
 )
 
-func (v *vmctl) SendKeys(vid, keys string) error {
+func (v *vmctl) SendKeys(ctx context.Context, vid, keys string) error {
 	var buf string
-	vm, err := v.Get(vid)
+	vm, err := v.Get(ctx, vid)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	if v.debug {
 		log.Printf("keys:\n%s\n\n", HexDump([]byte(keys)))
@@ -20,7 +21,7 @@ func (v *vmctl) SendKeys(vid, keys string) error {
 	for len(keys) > 0 {
 		char, multi, tail, err := strconv.UnquoteChar(keys, byte('"'))
 		if err != nil {
-			return Fatal(err)
+			return wrap(err)
 		}
 		if multi {
 			return Fatalf("multibyte encoding not supported: '%s'", keys)
@@ -33,7 +34,7 @@ func (v *vmctl) SendKeys(vid, keys string) error {
 	}
 
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	for _, key := range unquoted {
 
@@ -43,9 +44,9 @@ func (v *vmctl) SendKeys(vid, keys string) error {
 			buf += string(key)
 		} else {
 			if len(buf) > 0 {
-				err := v.sendBuf(&vm, buf)
+				err := v.sendBuf(ctx, &vm, buf)
 				if err != nil {
-					return Fatal(err)
+					return wrap(err)
 				}
 				buf = ""
 			}
@@ -53,49 +54,49 @@ func (v *vmctl) SendKeys(vid, keys string) error {
 			if !ok {
 				return Fatalf("cannot encode: %02x %s\n", key, strconv.Quote(string(key)))
 			}
-			err = v.sendCode(&vm, hid.Code, hid.Modifier)
+			err = v.sendCode(ctx, &vm, hid.Code, hid.Modifier)
 			if err != nil {
-				return Fatal(err)
+				return wrap(err)
 			}
 		}
 	}
 	if len(buf) > 0 {
-		err := v.sendBuf(&vm, buf)
+		err := v.sendBuf(ctx, &vm, buf)
 		if err != nil {
-			return Fatal(err)
+			return wrap(err)
 		}
 	}
 	return nil
 }
 
-func (v *vmctl) sendBuf(vm *VM, buf string) error {
+func (v *vmctl) sendBuf(ctx context.Context, vm *VM, buf string) error {
 	if v.debug {
 		fmt.Printf("sendBuf(%s, '%s')\n", vm.Name, buf)
 	}
 	path, err := PathnameFormat(v.Remote, vm.Path)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
-	_, err = v.RemoteExec(fmt.Sprintf("vmcli %s mks sendKeySequence %s", path, buf), nil)
+	_, err = v.RemoteExec(ctx, fmt.Sprintf("vmcli %s mks sendKeySequence %s", path, buf), nil)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	return nil
 }
 
-func (v *vmctl) sendCode(vm *VM, code, mod uint32) error {
+func (v *vmctl) sendCode(ctx context.Context, vm *VM, code, mod uint32) error {
 	if v.debug {
 		fmt.Printf("sendCode(%s, %04x, %04x)\n", vm.Name, code, mod)
 	}
 	path, err := PathnameFormat(v.Remote, vm.Path)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 
 	code = code<<16 | 0x0007
-	_, err = v.RemoteExec(fmt.Sprintf("vmcli %s mks sendKeyEvent %d %d", path, code, mod), nil)
+	_, err = v.RemoteExec(ctx, fmt.Sprintf("vmcli %s mks sendKeyEvent %d %d", path, code, mod), nil)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	return nil
 }
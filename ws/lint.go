@@ -0,0 +1,167 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var LINT_DISK_FILENAME = regexp.MustCompile(`^((ide|sata|scsi|nvme)\d+:\d+)\.fileName$`)
+var LINT_MAC_KEY = regexp.MustCompile(`^ethernet\d+\.(address|generatedAddress)$`)
+var LINT_USB_QUIRKS = regexp.MustCompile(`^0x([[:xdigit:]]{4}):0x([[:xdigit:]]{4})(\s.*)?$`)
+var LINT_USB_AUTOCONNECT = regexp.MustCompile(`^vid:([[:xdigit:]]{4}) pid:([[:xdigit:]]{4})(\s.*)?$`)
+
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+)
+
+type LintIssue struct {
+	Severity string `json:"severity"`
+	Line     int    `json:"line"`
+	Key      string `json:"key"`
+	Message  string `json:"message"`
+}
+
+func (i LintIssue) String() string {
+	if i.Key == "" {
+		return fmt.Sprintf("%s: line %d: %s", i.Severity, i.Line, i.Message)
+	}
+	return fmt.Sprintf("%s: line %d: %s: %s", i.Severity, i.Line, i.Key, i.Message)
+}
+
+func LintHasErrors(issues []LintIssue) bool {
+	for _, issue := range issues {
+		if issue.Severity == LintError {
+			return true
+		}
+	}
+	return false
+}
+
+// check VMX data for problems; fileExists is called for disk files referenced by the
+// configuration and may be nil to skip the check
+func LintVMX(data []byte, fileExists func(string) bool) []LintIssue {
+	doc := ParseVMXDocument(data)
+	issues := []LintIssue{}
+	add := func(severity string, line int, key, format string, args ...any) {
+		issues = append(issues, LintIssue{Severity: severity, Line: line, Key: key, Message: fmt.Sprintf(format, args...)})
+	}
+
+	lines := make(map[string]int)
+	for i, entry := range doc.entries {
+		lineNumber := i + 1
+		if entry.key == "" {
+			trimmed := strings.TrimSpace(entry.raw)
+			if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+				add(LintError, lineNumber, "", "unparsed line: '%s'", trimmed)
+			}
+			continue
+		}
+		lkey := strings.ToLower(entry.key)
+		first, ok := lines[lkey]
+		if ok {
+			add(LintError, lineNumber, entry.key, "duplicate key; first defined on line %d", first)
+			continue
+		}
+		lines[lkey] = lineNumber
+	}
+	line := func(key string) int {
+		return lines[strings.ToLower(key)]
+	}
+
+	// devices not present with leftover settings
+	for _, key := range doc.Keys() {
+		device, ok := strings.CutSuffix(key, ".present")
+		if !ok || doc.GetBool(key, true) {
+			continue
+		}
+		leftover := []string{}
+		for _, k := range SortedKeys(doc.Tree(device)) {
+			if !strings.EqualFold(k, key) {
+				leftover = append(leftover, k)
+			}
+		}
+		if len(leftover) > 0 {
+			add(LintWarning, line(key), key, "device is not present but has settings: %s", strings.Join(leftover, ", "))
+		}
+	}
+
+	for _, key := range doc.Keys() {
+		value, _ := doc.Get(key)
+		switch {
+		case strings.EqualFold(key, "guestOS"):
+			if !VMGuestOSValues[value] {
+				add(LintWarning, line(key), key, "unknown guestOS '%s'", value)
+			}
+		case LINT_DISK_FILENAME.MatchString(key):
+			device := LINT_DISK_FILENAME.FindStringSubmatch(key)[1]
+			if !doc.GetBool(device+".present", false) {
+				continue
+			}
+			deviceType, _ := doc.Get(device + ".deviceType")
+			if strings.HasPrefix(deviceType, "cdrom") {
+				continue
+			}
+			if value == "" {
+				add(LintError, line(key), key, "missing disk filename")
+			} else if strings.HasSuffix(strings.ToLower(value), ".vmdk") && fileExists != nil && !fileExists(value) {
+				add(LintError, line(key), key, "disk file '%s' not found", value)
+			}
+		case LINT_MAC_KEY.MatchString(key):
+			if !MAC_PATTERN.MatchString(value) {
+				add(LintError, line(key), key, "malformed MAC address '%s'", value)
+			}
+		case strings.HasPrefix(strings.ToLower(key), "usb.quirks."):
+			if !LINT_USB_QUIRKS.MatchString(value) {
+				add(LintError, line(key), key, "invalid USB device ID '%s'", value)
+			}
+		case strings.HasPrefix(strings.ToLower(key), "usb.autoconnect."):
+			if !LINT_USB_AUTOCONNECT.MatchString(value) {
+				add(LintError, line(key), key, "invalid USB device ID '%s'", value)
+			}
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Line < issues[j].Line })
+	return issues
+}
+
+// lint the instance VMX file, or data if it is not nil
+func (v *vmctl) Lint(ctx context.Context, vid string, data []byte) ([]LintIssue, error) {
+	if v.debug {
+		log.Printf("Lint(%s)\n", vid)
+	}
+	vm, err := v.cli.GetVM(ctx, vid)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if data == nil {
+		data, err = v.ReadHostFile(ctx, &vm, vm.Name+".vmx")
+		if err != nil {
+			return nil, wrap(err)
+		}
+	}
+	files, err := v.Files(ctx, vm.Name, FilesOptions{All: true})
+	if err != nil {
+		return nil, wrap(err)
+	}
+	names := make(map[string]bool)
+	for _, file := range files {
+		_, name := path.Split(strings.ReplaceAll(strings.TrimSpace(file), "\\", "/"))
+		names[strings.ToLower(name)] = true
+	}
+	fileExists := func(filename string) bool {
+		filename = strings.ReplaceAll(filename, "\\", "/")
+		if strings.Contains(filename, "/") {
+			// only files in the instance directory are checked
+			return true
+		}
+		return names[strings.ToLower(filename)]
+	}
+	return LintVMX(data, fileExists), nil
+}
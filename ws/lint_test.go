@@ -0,0 +1,43 @@
+package ws
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestLintVMX(t *testing.T) {
+	fileExists := func(filename string) bool {
+		return filename == "test.vmdk"
+	}
+	tests := []struct {
+		name     string
+		vmx      string
+		severity string
+		key      string
+		line     int
+	}{
+		{"clean", "displayName = \"test\"\nguestOS = \"ubuntu-64\"\nnvme0:0.present = \"TRUE\"\nnvme0:0.fileName = \"test.vmdk\"\n", "", "", 0},
+		{"unparsed", "displayName = \"test\"\nnot a setting\n", LintError, "", 2},
+		{"duplicate", "displayName = \"test\"\nmemsize = \"1024\"\nMemSize = \"2048\"\n", LintError, "MemSize", 3},
+		{"leftover", "sound.present = \"FALSE\"\nsound.virtualDev = \"hdaudio\"\n", LintWarning, "sound.present", 1},
+		{"guestos", "guestOS = \"bogus-64\"\n", LintWarning, "guestOS", 1},
+		{"missing disk", "sata0:1.present = \"TRUE\"\nsata0:1.fileName = \"missing.vmdk\"\n", LintError, "sata0:1.fileName", 2},
+		{"cdrom", "sata0:1.present = \"TRUE\"\nsata0:1.deviceType = \"cdrom-image\"\nsata0:1.fileName = \"missing.iso\"\n", "", "", 0},
+		{"absent disk", "sata0:1.present = \"FALSE\"\n", "", "", 0},
+		{"mac", "ethernet0.address = \"00:50:56:zz:00:01\"\n", LintError, "ethernet0.address", 1},
+		{"usb quirks", "usb.quirks.device0 = \"0x1234:0x56 skip-reset\"\n", LintError, "usb.quirks.device0", 1},
+		{"usb autoconnect", "usb.autoConnect.device0 = \"vid:0bda pid:8153\"\n", "", "", 0},
+	}
+	for _, test := range tests {
+		issues := LintVMX([]byte(test.vmx), fileExists)
+		if test.severity == "" {
+			require.Empty(t, issues, test.name)
+			continue
+		}
+		require.Len(t, issues, 1, test.name)
+		require.Equal(t, test.severity, issues[0].Severity, test.name)
+		require.Equal(t, test.key, issues[0].Key, test.name)
+		require.Equal(t, test.line, issues[0].Line, test.name)
+		require.Equal(t, test.severity == LintError, LintHasErrors(issues), test.name)
+	}
+}
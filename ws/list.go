@@ -1,6 +1,7 @@
 package ws
 
 import (
+	"context"
 	"log"
 	"path"
 	"regexp"
@@ -18,7 +19,7 @@ type FilesOptions struct {
 	Iso    bool
 }
 
-func (v *vmctl) Files(vid string, options FilesOptions) ([]string, error) {
+func (v *vmctl) Files(ctx context.Context, vid string, options FilesOptions) ([]string, error) {
 	if v.debug {
 		log.Printf("Files(%s, %+v)\n", vid, options)
 	}
@@ -30,14 +31,14 @@ func (v *vmctl) Files(vid string, options FilesOptions) ([]string, error) {
 	if options.Iso {
 		p, err := FormatIsoPath(v.IsoPath, vid)
 		if err != nil {
-			return lines, Fatal(err)
+			return lines, wrap(err)
 		}
 		paths = []string{p}
 		pattern = ISO_PATTERN
 	} else if vid == "" {
-		vmids, err := v.cli.GetVIDs()
+		vmids, err := v.cli.GetVIDs(ctx)
 		if err != nil {
-			return lines, Fatal(err)
+			return lines, wrap(err)
 		}
 		for _, vmid := range vmids {
 			vmPath, _ := path.Split(vmid.Path)
@@ -45,9 +46,9 @@ func (v *vmctl) Files(vid string, options FilesOptions) ([]string, error) {
 			paths = append(paths, vmPath)
 		}
 	} else {
-		vm, err := v.cli.GetVM(vid)
+		vm, err := v.cli.GetVM(ctx, vid)
 		if err != nil {
-			return lines, Fatal(err)
+			return lines, wrap(err)
 		}
 		vmPath, _ := path.Split(vm.Path)
 		paths = []string{vmPath}
@@ -58,9 +59,9 @@ func (v *vmctl) Files(vid string, options FilesOptions) ([]string, error) {
 	}
 
 	for _, listPath := range paths {
-		plines, err := v.listFiles(listPath, options.Detail, pattern)
+		plines, err := v.listFiles(ctx, listPath, options.Detail, pattern)
 		if err != nil {
-			return lines, Fatal(err)
+			return lines, wrap(err)
 		}
 		lines = append(lines, plines...)
 	}
@@ -72,7 +73,7 @@ func (v *vmctl) Files(vid string, options FilesOptions) ([]string, error) {
 	return lines, nil
 }
 
-func (v *vmctl) listFiles(listPath string, detail bool, pattern *regexp.Regexp) ([]string, error) {
+func (v *vmctl) listFiles(ctx context.Context, listPath string, detail bool, pattern *regexp.Regexp) ([]string, error) {
 
 	if v.debug {
 		log.Printf("listFiles(%s, %v, %+v)\n", listPath, detail, *pattern)
@@ -82,7 +83,7 @@ func (v *vmctl) listFiles(listPath string, detail bool, pattern *regexp.Regexp)
 
 	n, err := PathNormalize(listPath)
 	if err != nil {
-		return lines, Fatal(err)
+		return lines, wrap(err)
 	}
 	if n != listPath {
 		log.Printf("WARNING: listFiles received non-normalized path: '%s'\n", listPath)
@@ -91,7 +92,7 @@ func (v *vmctl) listFiles(listPath string, detail bool, pattern *regexp.Regexp)
 
 	hostPath, err := PathnameFormat(v.Remote, listPath)
 	if err != nil {
-		return lines, Fatal(err)
+		return lines, wrap(err)
 	}
 
 	var command string
@@ -110,9 +111,9 @@ func (v *vmctl) listFiles(listPath string, detail bool, pattern *regexp.Regexp)
 		}
 	}
 
-	olines, err := v.RemoteExec(command, nil)
+	olines, err := v.RemoteExec(ctx, command, nil)
 	if err != nil {
-		return lines, Fatal(err)
+		return lines, wrap(err)
 	}
 
 	log.Printf("listFiles pattern: %+v\n", pattern)
@@ -126,7 +127,7 @@ func (v *vmctl) listFiles(listPath string, detail bool, pattern *regexp.Regexp)
 		} else if pattern.MatchString(line) {
 			nline, err := PathNormalize(path.Join(listPath, line))
 			if err != nil {
-				return lines, Fatal(err)
+				return lines, wrap(err)
 			}
 			lines = append(lines, nline)
 		}
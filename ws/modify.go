@@ -1,10 +1,11 @@
 package ws
 
 import (
+	"context"
 	"log"
 )
 
-func (v *vmctl) Modify(vid string, options CreateOptions, isoOptions IsoOptions) (*[]string, error) {
+func (v *vmctl) Modify(ctx context.Context, vid string, options CreateOptions, isoOptions IsoOptions) (*[]string, error) {
 	if v.debug {
 		log.Printf("Modify(%s, options, isoOptions)\n", vid)
 		copts := FormatJSON(&options)
@@ -12,50 +13,53 @@ func (v *vmctl) Modify(vid string, options CreateOptions, isoOptions IsoOptions)
 		iopts := FormatJSON(&isoOptions)
 		log.Printf("IsoOptions: %s\n", iopts)
 	}
-	vm, err := v.cli.GetVM(vid)
+	vm, err := v.cli.GetVM(ctx, vid)
 	if err != nil {
-		return nil, Fatal(err)
+		return nil, wrap(err)
 	}
 
-	err = v.requirePowerState(&vm, "off", "modify the instance")
+	err = v.requirePowerState(ctx, &vm, "off", "modify the instance")
 
 	if err != nil {
-		return nil, Fatal(err)
+		return nil, wrap(err)
 	}
 
 	vmxFilename := vm.Name + ".vmx"
-	hostData, err := v.ReadHostFile(&vm, vmxFilename)
+	hostData, err := v.ReadHostFile(ctx, &vm, vmxFilename)
 	if err != nil {
-		return nil, Fatal(err)
+		return nil, wrap(err)
 	}
 	vmx, err := InitVMX(v.Remote, vm.Name, hostData)
 	if err != nil {
-		return nil, Fatal(err)
+		return nil, wrap(err)
 	}
 
 	if isoOptions.ModifyISO {
 		if isoOptions.IsoFile != "" {
 			err := v.CheckISODownload(&vm, &isoOptions)
 			if err != nil {
-				return nil, Fatal(err)
+				return nil, wrap(err)
 			}
 			formatted, err := FormatIsoPathname(v.IsoPath, isoOptions.IsoFile)
 			if err != nil {
-				return nil, Fatal(err)
+				return nil, wrap(err)
 			}
 			isoOptions.IsoFile = formatted
 		}
 	}
 
 	actions, err := vmx.Configure(&options, &isoOptions)
+	if err != nil {
+		return nil, wrap(err)
+	}
 
 	editedData, err := vmx.Read()
 	if err != nil {
-		return nil, Fatal(err)
+		return nil, wrap(err)
 	}
-	err = v.WriteHostFile(&vm, vmxFilename, editedData)
+	err = v.WriteHostFile(ctx, &vm, vmxFilename, editedData)
 	if err != nil {
-		return nil, Fatal(err)
+		return nil, wrap(err)
 	}
 
 	return &actions, nil
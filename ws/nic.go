@@ -0,0 +1,242 @@
+package ws
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const MAX_NICS = 10
+const DEFAULT_NIC_DEVICE = "e1000"
+
+var NIC_DEVICES = map[string]bool{
+	"e1000":   true,
+	"e1000e":  true,
+	"vmxnet3": true,
+}
+
+var NIC_VNET_PATTERN = regexp.MustCompile(`(?i)^(?:/dev/)?vmnet(\d+)$`)
+var NIC_PVN_ID_PATTERN = regexp.MustCompile(`^([[:xdigit:]]{2} ){7}[[:xdigit:]]{2}-([[:xdigit:]]{2} ){7}[[:xdigit:]]{2}$`)
+
+type VMNic struct {
+	Index          int
+	Connection     string
+	Network        string
+	Device         string
+	MacAddress     string
+	StaticMac      bool
+	StartConnected bool
+	IpAddress      string
+}
+
+// NICOptions selects the changes made to adapter ethernet<Index>; empty fields are left unchanged
+type NICOptions struct {
+	Index                int
+	Remove               bool
+	Connection           string // nat, bridged, hostonly, vmnetN or segment:NAME
+	Device               string // e1000, e1000e or vmxnet3
+	MacAddress           string // auto or a static MAC
+	ModifyStartConnected bool
+	StartConnected       bool
+}
+
+func NICKey(index int) string {
+	return fmt.Sprintf("ethernet%d", index)
+}
+
+// return a LAN segment ID derived from the segment name, so all instances using the name share the segment
+func LANSegmentID(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	hex := []string{}
+	for _, b := range sum[:16] {
+		hex = append(hex, fmt.Sprintf("%02x", b))
+	}
+	return strings.Join(hex[:8], " ") + "-" + strings.Join(hex[8:], " ")
+}
+
+// return the VMX connectionType and vnet or pvnID values for a connection
+func parseNICConnection(hostOS, connection string) (string, string, string, error) {
+	lower := strings.ToLower(connection)
+	switch lower {
+	case "nat", "bridged", "hostonly":
+		return lower, "", "", nil
+	}
+	m := NIC_VNET_PATTERN.FindStringSubmatch(connection)
+	if m != nil {
+		if hostOS == "windows" {
+			return "custom", "VMnet" + m[1], "", nil
+		}
+		return "custom", "/dev/vmnet" + m[1], "", nil
+	}
+	prefix, segment, ok := strings.Cut(connection, ":")
+	if ok && segment != "" && (strings.EqualFold(prefix, "segment") || strings.EqualFold(prefix, "lan")) {
+		if NIC_PVN_ID_PATTERN.MatchString(segment) {
+			return "pvn", "", segment, nil
+		}
+		return "pvn", "", LANSegmentID(segment), nil
+	}
+	return "", "", "", Fatalf("invalid NIC connection: '%s'", connection)
+}
+
+// return the adapter settings using get to read config values; ok is false if the adapter is not present
+func parseNIC(index int, get func(string) string) (VMNic, bool) {
+	key := NICKey(index)
+	if strings.ToUpper(get(key+".present")) != "TRUE" {
+		return VMNic{}, false
+	}
+	nic := VMNic{
+		Index:          index,
+		Connection:     strings.ToLower(get(key + ".connectionType")),
+		Device:         get(key + ".virtualDev"),
+		StartConnected: strings.ToUpper(get(key+".startConnected")) != "FALSE",
+	}
+	switch nic.Connection {
+	case "":
+		// VMware default
+		nic.Connection = "bridged"
+	case "custom":
+		m := NIC_VNET_PATTERN.FindStringSubmatch(get(key + ".vnet"))
+		if m != nil {
+			nic.Network = "vmnet" + m[1]
+		}
+	case "pvn":
+		nic.Connection = "segment"
+		nic.Network = get(key + ".pvnID")
+	}
+	if nic.Device == "" {
+		nic.Device = DEFAULT_NIC_DEVICE
+	}
+	nic.StaticMac = strings.ToLower(get(key+".addressType")) == "static"
+	if nic.StaticMac {
+		nic.MacAddress = get(key + ".address")
+	} else {
+		nic.MacAddress = get(key + ".generatedAddress")
+	}
+	return nic, true
+}
+
+// return the present network adapters in index order
+func (v *VMX) Nics() []VMNic {
+	nics := []VMNic{}
+	for i := 0; i < MAX_NICS; i++ {
+		nic, ok := parseNIC(i, func(key string) string {
+			value, _ := v.doc.Get(key)
+			return value
+		})
+		if ok {
+			nics = append(nics, nic)
+		}
+	}
+	return nics
+}
+
+func (v *VMX) SetEthernet(options NICOptions) (string, error) {
+	if v.debug {
+		log.Printf("SetEthernet(%+v)\n", options)
+	}
+	if options.Index < 0 || options.Index >= MAX_NICS {
+		return "", Fatalf("invalid NIC index: %d", options.Index)
+	}
+	nic := NICKey(options.Index)
+	if options.Remove {
+		v.doc.DeleteTree(nic)
+		v.doc.SetBool(nic+".present", false)
+		return fmt.Sprintf("Removed %s", nic), nil
+	}
+
+	actions := []string{}
+	if !v.doc.GetBool(nic+".present", false) {
+		v.doc.SetBool(nic+".present", true)
+		actions = append(actions, fmt.Sprintf("Added %s", nic))
+	}
+
+	device := options.Device
+	if device == "" && !v.doc.Has(nic+".virtualDev") {
+		device = DEFAULT_NIC_DEVICE
+	}
+	if device != "" {
+		device = strings.ToLower(device)
+		if !NIC_DEVICES[device] {
+			return "", Fatalf("invalid NIC device: '%s'", options.Device)
+		}
+		v.doc.Set(nic+".virtualDev", device)
+		actions = append(actions, fmt.Sprintf("Set %s device: %s", nic, device))
+	}
+
+	if options.Connection != "" {
+		connectionType, vnet, pvnID, err := parseNICConnection(v.hostOS, options.Connection)
+		if err != nil {
+			return "", wrap(err)
+		}
+		v.doc.Set(nic+".connectionType", connectionType)
+		v.doc.Delete(nic + ".vnet")
+		v.doc.Delete(nic + ".pvnID")
+		if vnet != "" {
+			v.doc.Set(nic+".vnet", vnet)
+		}
+		if pvnID != "" {
+			v.doc.Set(nic+".pvnID", pvnID)
+		}
+		actions = append(actions, fmt.Sprintf("Set %s connection: %s", nic, options.Connection))
+	}
+
+	mac := options.MacAddress
+	if mac == "" && !v.doc.Has(nic+".addressType") {
+		mac = "auto"
+	}
+	switch {
+	case mac == "":
+	case mac == "auto":
+		v.doc.Set(nic+".addressType", "generated")
+		v.doc.Delete(nic + ".address")
+		// VMware generates a new address when these are missing
+		v.doc.Delete(nic + ".generatedAddress")
+		v.doc.Delete(nic + ".generatedAddressOffset")
+		actions = append(actions, fmt.Sprintf("Set %s auto-generated MAC address", nic))
+	case MAC_PATTERN.MatchString(mac):
+		v.doc.Set(nic+".addressType", "static")
+		v.doc.Set(nic+".address", mac)
+		v.doc.Delete(nic + ".generatedAddress")
+		v.doc.Delete(nic + ".generatedAddressOffset")
+		actions = append(actions, fmt.Sprintf("Set %s MAC address: %s", nic, mac))
+	default:
+		return "", Fatalf("invalid MAC address: '%s'", mac)
+	}
+
+	if options.ModifyStartConnected {
+		v.doc.SetBool(nic+".startConnected", options.StartConnected)
+		actions = append(actions, fmt.Sprintf("Set %s connect at power on: %v", nic, options.StartConnected))
+	}
+	return strings.Join(actions, "; "), nil
+}
+
+// switch all adapters to generated MAC addresses; VMware assigns new addresses at power on
+func (v *VMX) ResetMacAddresses() (string, error) {
+	if v.debug {
+		log.Println("ResetMacAddresses()")
+	}
+	reset := []string{}
+	for _, nic := range v.Nics() {
+		_, err := v.SetEthernet(NICOptions{Index: nic.Index, MacAddress: "auto"})
+		if err != nil {
+			return "", wrap(err)
+		}
+		reset = append(reset, NICKey(nic.Index))
+	}
+	if len(reset) == 0 {
+		return "No network adapters", nil
+	}
+	return "Set auto-generated MAC address: " + strings.Join(reset, ", "), nil
+}
+
+// return the NIC index from a name of the form 'N' or 'ethernetN'
+func ParseNICIndex(name string) (int, error) {
+	index, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(name), "ethernet"))
+	if err != nil || index < 0 || index >= MAX_NICS {
+		return 0, Fatalf("invalid NIC: '%s'", name)
+	}
+	return index, nil
+}
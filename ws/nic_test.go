@@ -0,0 +1,79 @@
+package ws
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestVMXSetEthernet(t *testing.T) {
+	initTestConfig(t)
+	vmx, err := InitVMX("linux", "router", []byte(`displayName = "router"
+ethernet0.present = "TRUE"
+ethernet0.connectionType = "nat"
+ethernet0.virtualDev = "vmxnet3"
+ethernet0.addressType = "generated"
+ethernet0.generatedAddress = "00:0c:29:aa:bb:cc"
+`))
+	require.Nil(t, err)
+
+	_, err = vmx.SetEthernet(NICOptions{Index: 1, Connection: "vmnet2", Device: "e1000e", MacAddress: "00:50:56:00:00:01"})
+	require.Nil(t, err)
+	_, err = vmx.SetEthernet(NICOptions{Index: 2, Connection: "segment:lab", ModifyStartConnected: true})
+	require.Nil(t, err)
+	_, err = vmx.SetEthernet(NICOptions{Index: 0, Connection: "bridged"})
+	require.Nil(t, err)
+
+	nics := vmx.Nics()
+	require.Len(t, nics, 3)
+	require.Equal(t, VMNic{Index: 0, Connection: "bridged", Device: "vmxnet3", MacAddress: "00:0c:29:aa:bb:cc", StartConnected: true}, nics[0])
+	require.Equal(t, VMNic{Index: 1, Connection: "custom", Network: "vmnet2", Device: "e1000e", MacAddress: "00:50:56:00:00:01", StaticMac: true, StartConnected: true}, nics[1])
+	require.Equal(t, "segment", nics[2].Connection)
+	require.Equal(t, LANSegmentID("lab"), nics[2].Network)
+	require.Equal(t, DEFAULT_NIC_DEVICE, nics[2].Device)
+	require.False(t, nics[2].StartConnected)
+	require.Regexp(t, NIC_PVN_ID_PATTERN, nics[2].Network)
+
+	vnet, ok := vmx.doc.Get("ethernet1.vnet")
+	require.True(t, ok)
+	require.Equal(t, "/dev/vmnet2", vnet)
+
+	_, err = vmx.ResetMacAddresses()
+	require.Nil(t, err)
+	for _, nic := range vmx.Nics() {
+		require.False(t, nic.StaticMac)
+		require.Empty(t, nic.MacAddress)
+	}
+
+	_, err = vmx.SetEthernet(NICOptions{Index: 1, Remove: true})
+	require.Nil(t, err)
+	require.Len(t, vmx.Nics(), 2)
+	require.Equal(t, map[string]string{"ethernet1.present": "FALSE"}, vmx.doc.Tree("ethernet1"))
+
+	_, err = vmx.SetEthernet(NICOptions{Index: 10})
+	require.NotNil(t, err)
+	_, err = vmx.SetEthernet(NICOptions{Index: 3, Device: "pcnet32"})
+	require.NotNil(t, err)
+	_, err = vmx.SetEthernet(NICOptions{Index: 3, Connection: "vmnet"})
+	require.NotNil(t, err)
+	_, err = vmx.SetEthernet(NICOptions{Index: 3, MacAddress: "00:50:56"})
+	require.NotNil(t, err)
+}
+
+func TestParseNICConnection(t *testing.T) {
+	connectionType, vnet, _, err := parseNICConnection("windows", "VMnet3")
+	require.Nil(t, err)
+	require.Equal(t, "custom", connectionType)
+	require.Equal(t, "VMnet3", vnet)
+	pvnID := "52 d6 0d 5a 0f 9b 39 a0-bd 0a 03 cc 5c 5c 1d 9f"
+	connectionType, _, id, err := parseNICConnection("linux", "lan:"+pvnID)
+	require.Nil(t, err)
+	require.Equal(t, "pvn", connectionType)
+	require.Equal(t, pvnID, id)
+	require.Equal(t, LANSegmentID("lab"), LANSegmentID("lab"))
+	require.NotEqual(t, LANSegmentID("lab"), LANSegmentID("dmz"))
+	index, err := ParseNICIndex("ethernet3")
+	require.Nil(t, err)
+	require.Equal(t, 3, index)
+	_, err = ParseNICIndex("10")
+	require.NotNil(t, err)
+}
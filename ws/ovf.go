@@ -0,0 +1,946 @@
+package ws
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"github.com/vmware/govmomi/ovf"
+	"hash"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const OVF_DISK_FORMAT = "http://www.vmware.com/interfaces/specifications/vmdk.html#streamOptimized"
+const DEFAULT_OVF_NETWORK = "nat"
+
+var OVF_BYTE_UNITS_PATTERN = regexp.MustCompile(`^byte\s*\*\s*2\^(\d+)$`)
+var OVF_MANIFEST_LINE = regexp.MustCompile(`^(SHA1|SHA256)\(([^)]+)\)\s*=\s*([[:xdigit:]]+)$`)
+
+// OVFDisk is a virtual disk in an OVF package
+type OVFDisk struct {
+	Slot     string // VMX device such as 'nvme0:0'
+	Adapter  string // scsi controller type
+	File     string // VMDK filename in the package
+	Size     int64  // VMDK file size
+	Capacity int64
+}
+
+// OVFSystem is the virtual machine configuration carried in an OVF descriptor
+type OVFSystem struct {
+	Name     string
+	GuestOS  string
+	CpuCount int
+	MemoryMB int64
+	EFIBoot  bool
+	Version  string
+	Disks    []OVFDisk
+	Nics     []VMNic // Connection is the OVF network name
+}
+
+type OVFOptions struct {
+	Network string // connection used for OVF networks with no local equivalent
+}
+
+// return the vSphere osType for a VMX guestOS value
+func OVFGuestOSType(guestOS string) string {
+	base, is64 := strings.CutSuffix(guestOS, "-64")
+	switch {
+	case guestOS == "":
+		return "otherGuest"
+	case base == "other" && is64:
+		return "otherGuest64"
+	case !is64:
+		return guestOS + "Guest"
+	case base[len(base)-1] >= '0' && base[len(base)-1] <= '9':
+		return base + "_64Guest"
+	}
+	return base + "64Guest"
+}
+
+// return the VMX guestOS value for a vSphere osType
+func VMXGuestOS(osType string) string {
+	if osType == "otherGuest64" {
+		return "other-64"
+	}
+	guestOS := strings.TrimSuffix(osType, "Guest")
+	if base, ok := strings.CutSuffix(guestOS, "_64"); ok {
+		return base + "-64"
+	}
+	if base, ok := strings.CutSuffix(guestOS, "64"); ok && base != "" {
+		return base + "-64"
+	}
+	return guestOS
+}
+
+// return the OVF network name for a NIC; the name is a valid NIC connection
+func ovfNetworkName(nic VMNic) string {
+	switch nic.Connection {
+	case "custom":
+		if nic.Network != "" {
+			return nic.Network
+		}
+	case "segment":
+		return "segment:" + nic.Network
+	}
+	return nic.Connection
+}
+
+var ovfNicTypes = map[string]string{
+	"e1000":   "E1000",
+	"e1000e":  "E1000e",
+	"vmxnet3": "VmxNet3",
+}
+
+type ovfController struct {
+	InstanceID   int
+	Name         string
+	Bus          int
+	ResourceType ovf.CIMResourceType
+	SubType      string
+}
+
+type ovfDiskItem struct {
+	InstanceID int
+	Index      int
+	Parent     int
+	Unit       int
+	OVFDisk
+}
+
+type ovfNicItem struct {
+	InstanceID  int
+	Unit        int
+	SubType     string
+	NetworkName string
+	VMNic
+}
+
+func ovfControllerType(controller, adapter string) (ovf.CIMResourceType, string) {
+	switch controller {
+	case "scsi":
+		if adapter == "pvscsi" {
+			return ovf.ParallelScsiHba, "VirtualSCSI"
+		}
+		return ovf.ParallelScsiHba, DEFAULT_SCSI_ADAPTER
+	case "ide":
+		return ovf.IdeController, ""
+	case "sata":
+		return ovf.OtherStorage, "vmware.sata.ahci"
+	}
+	return ovf.OtherStorage, "vmware.nvme.controller"
+}
+
+var ovfTemplate = template.Must(template.New("ovf").Funcs(template.FuncMap{
+	"xml": func(value any) (string, error) {
+		var buf bytes.Buffer
+		err := xml.EscapeText(&buf, []byte(fmt.Sprintf("%v", value)))
+		return buf.String(), err
+	},
+	"add": func(a, b int) int { return a + b },
+}).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<Envelope xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:cim="http://schemas.dmtf.org/wbem/wscim/1/common" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData" xmlns:vmw="http://www.vmware.com/schema/ovf" xmlns:vssd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_VirtualSystemSettingData" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+  <References>
+{{- range .DiskItems}}
+    <File ovf:href="{{xml .File}}" ovf:id="file{{add .Index 1}}" ovf:size="{{.Size}}"/>
+{{- end}}
+  </References>
+  <DiskSection>
+    <Info>Virtual disk information</Info>
+{{- range .DiskItems}}
+    <Disk ovf:capacity="{{.Capacity}}" ovf:capacityAllocationUnits="byte" ovf:diskId="vmdisk{{add .Index 1}}" ovf:fileRef="file{{add .Index 1}}" ovf:format="{{$.DiskFormat}}"/>
+{{- end}}
+  </DiskSection>
+  <NetworkSection>
+    <Info>The list of logical networks</Info>
+{{- range .Networks}}
+    <Network ovf:name="{{xml .}}">
+      <Description>The {{xml .}} network</Description>
+    </Network>
+{{- end}}
+  </NetworkSection>
+  <VirtualSystem ovf:id="{{xml .Name}}">
+    <Info>A virtual machine</Info>
+    <Name>{{xml .Name}}</Name>
+    <OperatingSystemSection ovf:id="1" vmw:osType="{{xml .OSType}}">
+      <Info>The kind of installed guest operating system</Info>
+    </OperatingSystemSection>
+    <VirtualHardwareSection>
+      <Info>Virtual hardware requirements</Info>
+      <System>
+        <vssd:ElementName>Virtual Hardware Family</vssd:ElementName>
+        <vssd:InstanceID>0</vssd:InstanceID>
+        <vssd:VirtualSystemIdentifier>{{xml .Name}}</vssd:VirtualSystemIdentifier>
+        <vssd:VirtualSystemType>vmx-{{xml .Version}}</vssd:VirtualSystemType>
+      </System>
+      <Item>
+        <rasd:AllocationUnits>hertz * 10^6</rasd:AllocationUnits>
+        <rasd:Description>Number of Virtual CPUs</rasd:Description>
+        <rasd:ElementName>{{.CpuCount}} virtual CPU(s)</rasd:ElementName>
+        <rasd:InstanceID>1</rasd:InstanceID>
+        <rasd:ResourceType>3</rasd:ResourceType>
+        <rasd:VirtualQuantity>{{.CpuCount}}</rasd:VirtualQuantity>
+      </Item>
+      <Item>
+        <rasd:AllocationUnits>byte * 2^20</rasd:AllocationUnits>
+        <rasd:Description>Memory Size</rasd:Description>
+        <rasd:ElementName>{{.MemoryMB}}MB of memory</rasd:ElementName>
+        <rasd:InstanceID>2</rasd:InstanceID>
+        <rasd:ResourceType>4</rasd:ResourceType>
+        <rasd:VirtualQuantity>{{.MemoryMB}}</rasd:VirtualQuantity>
+      </Item>
+{{- range .Controllers}}
+      <Item>
+        <rasd:Address>{{.Bus}}</rasd:Address>
+        <rasd:Description>{{.Name}} controller</rasd:Description>
+        <rasd:ElementName>{{.Name}}{{.Bus}}</rasd:ElementName>
+        <rasd:InstanceID>{{.InstanceID}}</rasd:InstanceID>
+{{- if .SubType}}
+        <rasd:ResourceSubType>{{.SubType}}</rasd:ResourceSubType>
+{{- end}}
+        <rasd:ResourceType>{{printf "%d" .ResourceType}}</rasd:ResourceType>
+      </Item>
+{{- end}}
+{{- range .DiskItems}}
+      <Item>
+        <rasd:AddressOnParent>{{.Unit}}</rasd:AddressOnParent>
+        <rasd:ElementName>{{.Slot}}</rasd:ElementName>
+        <rasd:HostResource>ovf:/disk/vmdisk{{add .Index 1}}</rasd:HostResource>
+        <rasd:InstanceID>{{.InstanceID}}</rasd:InstanceID>
+        <rasd:Parent>{{.Parent}}</rasd:Parent>
+        <rasd:ResourceType>17</rasd:ResourceType>
+      </Item>
+{{- end}}
+{{- range .NicItems}}
+      <Item>
+        <rasd:AddressOnParent>{{.Unit}}</rasd:AddressOnParent>
+        <rasd:AutomaticAllocation>{{.StartConnected}}</rasd:AutomaticAllocation>
+        <rasd:Connection>{{xml .NetworkName}}</rasd:Connection>
+        <rasd:ElementName>ethernet{{.Index}}</rasd:ElementName>
+        <rasd:InstanceID>{{.InstanceID}}</rasd:InstanceID>
+        <rasd:ResourceSubType>{{.SubType}}</rasd:ResourceSubType>
+        <rasd:ResourceType>10</rasd:ResourceType>
+      </Item>
+{{- end}}
+{{- if .EFIBoot}}
+      <vmw:Config ovf:required="false" vmw:key="firmware" vmw:value="efi"/>
+{{- end}}
+    </VirtualHardwareSection>
+  </VirtualSystem>
+</Envelope>
+`))
+
+type ovfTemplateData struct {
+	*OVFSystem
+	OSType      string
+	DiskFormat  string
+	Networks    []string
+	Controllers []ovfController
+	DiskItems   []ovfDiskItem
+	NicItems    []ovfNicItem
+}
+
+// return the OVF descriptor for a system; disk files are streamOptimized VMDKs
+func OVFDescriptor(system *OVFSystem) ([]byte, error) {
+	data := ovfTemplateData{
+		OVFSystem:  system,
+		OSType:     OVFGuestOSType(system.GuestOS),
+		DiskFormat: OVF_DISK_FORMAT,
+	}
+	if data.Version == "" {
+		data.Version = "19"
+	}
+	instanceID := 3
+	controllers := make(map[string]int)
+	units := []int{}
+	for _, disk := range system.Disks {
+		controller, bus, unit, err := ParseDiskSlot(disk.Slot)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		units = append(units, unit)
+		name := fmt.Sprintf("%s%d", controller, bus)
+		if _, ok := controllers[name]; ok {
+			continue
+		}
+		resourceType, subType := ovfControllerType(controller, disk.Adapter)
+		controllers[name] = instanceID
+		data.Controllers = append(data.Controllers, ovfController{
+			InstanceID:   instanceID,
+			Name:         controller,
+			Bus:          bus,
+			ResourceType: resourceType,
+			SubType:      subType,
+		})
+		instanceID++
+	}
+	for i, disk := range system.Disks {
+		data.DiskItems = append(data.DiskItems, ovfDiskItem{
+			InstanceID: instanceID,
+			Index:      i,
+			Parent:     controllers[strings.Split(disk.Slot, ":")[0]],
+			Unit:       units[i],
+			OVFDisk:    disk,
+		})
+		instanceID++
+	}
+	networks := make(map[string]bool)
+	for _, nic := range system.Nics {
+		name := ovfNetworkName(nic)
+		if !networks[name] {
+			networks[name] = true
+			data.Networks = append(data.Networks, name)
+		}
+		subType, ok := ovfNicTypes[nic.Device]
+		if !ok {
+			subType = ovfNicTypes[DEFAULT_NIC_DEVICE]
+		}
+		data.NicItems = append(data.NicItems, ovfNicItem{
+			InstanceID:  instanceID,
+			Unit:        nic.Index,
+			SubType:     subType,
+			NetworkName: name,
+			VMNic:       nic,
+		})
+		instanceID++
+	}
+	var buf bytes.Buffer
+	err := ovfTemplate.Execute(&buf, &data)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	return buf.Bytes(), nil
+}
+
+// return the byte multiplier for OVF allocation units such as 'byte * 2^20' or 'MegaBytes'
+func ovfAllocationUnits(units string) (int64, error) {
+	units = strings.TrimSpace(units)
+	m := OVF_BYTE_UNITS_PATTERN.FindStringSubmatch(units)
+	if m != nil {
+		shift, err := strconv.Atoi(m[1])
+		if err != nil || shift > 40 {
+			return 0, Fatalf("invalid OVF allocation units: '%s'", units)
+		}
+		return int64(1) << shift, nil
+	}
+	switch strings.ToLower(units) {
+	case "", "byte", "bytes":
+		return 1, nil
+	case "kb", "kilobytes":
+		return KB, nil
+	case "mb", "megabytes":
+		return MB, nil
+	case "gb", "gigabytes":
+		return GB, nil
+	}
+	return 0, Fatalf("invalid OVF allocation units: '%s'", units)
+}
+
+// return the VMX controller name and scsi adapter for an OVF controller item
+func ovfItemController(item *ovf.ResourceAllocationSettingData) (string, string) {
+	subType := ""
+	if item.ResourceSubType != nil {
+		subType = strings.ToLower(*item.ResourceSubType)
+	}
+	switch *item.ResourceType {
+	case ovf.ParallelScsiHba:
+		if subType == "virtualscsi" {
+			return "scsi", "pvscsi"
+		}
+		return "scsi", DEFAULT_SCSI_ADAPTER
+	case ovf.IdeController:
+		return "ide", ""
+	case ovf.OtherStorage:
+		switch {
+		case strings.Contains(subType, "sata") || strings.Contains(subType, "ahci"):
+			return "sata", ""
+		case strings.Contains(subType, "nvme"):
+			return "nvme", ""
+		}
+	}
+	return "", ""
+}
+
+// parse an OVF descriptor; disk slots are empty when the OVF controller has no VMX equivalent
+func ParseOVF(r io.Reader) (*OVFSystem, error) {
+	envelope, err := ovf.Unmarshal(r)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	vs := envelope.VirtualSystem
+	if vs == nil {
+		return nil, Fatalf("OVF descriptor has no VirtualSystem")
+	}
+	system := OVFSystem{
+		Name:     vs.ID,
+		CpuCount: 1,
+		MemoryMB: 1024,
+	}
+	if vs.Name != nil && *vs.Name != "" {
+		system.Name = *vs.Name
+	}
+	if vs.OperatingSystem != nil && vs.OperatingSystem.OSType != nil {
+		system.GuestOS = VMXGuestOS(*vs.OperatingSystem.OSType)
+	}
+	if len(vs.VirtualHardware) == 0 {
+		return nil, Fatalf("OVF descriptor has no VirtualHardwareSection")
+	}
+	hardware := vs.VirtualHardware[0]
+	if hardware.System != nil && hardware.System.VirtualSystemType != nil {
+		for _, systemType := range strings.Split(*hardware.System.VirtualSystemType, " ") {
+			if version, ok := strings.CutPrefix(systemType, "vmx-"); ok {
+				system.Version = version
+			}
+		}
+	}
+	for _, config := range append(hardware.Config, hardware.ExtraConfig...) {
+		if config.Key == "firmware" && config.Value == "efi" {
+			system.EFIBoot = true
+		}
+	}
+
+	files := make(map[string]ovf.File)
+	for _, file := range envelope.References {
+		files[file.ID] = file
+	}
+	disks := make(map[string]ovf.VirtualDiskDesc)
+	if envelope.Disk != nil {
+		for _, disk := range envelope.Disk.Disks {
+			disks[disk.DiskID] = disk
+		}
+	}
+
+	items := make(map[string]*ovf.ResourceAllocationSettingData)
+	controllerCount := make(map[string]int)
+	controllerBus := make(map[string]int)
+	for i := range hardware.Item {
+		item := &hardware.Item[i]
+		items[item.InstanceID] = item
+		if item.ResourceType == nil {
+			continue
+		}
+		controller, _ := ovfItemController(item)
+		if controller == "" {
+			continue
+		}
+		bus := controllerCount[controller]
+		if item.Address != nil {
+			address, err := strconv.Atoi(*item.Address)
+			if err == nil {
+				bus = address
+			}
+		}
+		controllerBus[item.InstanceID] = bus
+		controllerCount[controller]++
+	}
+
+	for _, item := range hardware.Item {
+		if item.ResourceType == nil {
+			continue
+		}
+		switch *item.ResourceType {
+		case ovf.Processor:
+			if item.VirtualQuantity != nil {
+				system.CpuCount = int(*item.VirtualQuantity)
+			}
+		case ovf.Memory:
+			if item.VirtualQuantity != nil {
+				units := ""
+				if item.AllocationUnits != nil {
+					units = *item.AllocationUnits
+				}
+				multiplier, err := ovfAllocationUnits(units)
+				if err != nil {
+					return nil, wrap(err)
+				}
+				system.MemoryMB = int64(*item.VirtualQuantity) * multiplier / MB
+			}
+		case ovf.DiskDrive:
+			disk, err := parseOVFDisk(&item, items, controllerBus, disks, files)
+			if err != nil {
+				return nil, wrap(err)
+			}
+			system.Disks = append(system.Disks, *disk)
+		case ovf.EthernetAdapter:
+			nic := VMNic{
+				Index:          len(system.Nics),
+				Connection:     DEFAULT_OVF_NETWORK,
+				Device:         DEFAULT_NIC_DEVICE,
+				StartConnected: item.AutomaticAllocation == nil || *item.AutomaticAllocation,
+			}
+			if len(item.Connection) > 0 {
+				nic.Connection = item.Connection[0]
+			}
+			if item.ResourceSubType != nil {
+				for device, subType := range ovfNicTypes {
+					if strings.EqualFold(subType, *item.ResourceSubType) {
+						nic.Device = device
+					}
+				}
+			}
+			system.Nics = append(system.Nics, nic)
+		}
+	}
+	return &system, nil
+}
+
+// return the disk attached by an OVF disk drive item
+func parseOVFDisk(item *ovf.ResourceAllocationSettingData, items map[string]*ovf.ResourceAllocationSettingData, controllerBus map[string]int, disks map[string]ovf.VirtualDiskDesc, files map[string]ovf.File) (*OVFDisk, error) {
+	if len(item.HostResource) == 0 {
+		return nil, Fatalf("OVF disk item %s has no HostResource", item.InstanceID)
+	}
+	diskID := item.HostResource[0]
+	diskID = strings.TrimPrefix(diskID, "ovf:")
+	diskID = strings.TrimPrefix(diskID, "/disk/")
+	desc, ok := disks[diskID]
+	if !ok {
+		return nil, Fatalf("OVF disk not found: %s", item.HostResource[0])
+	}
+	if desc.FileRef == nil {
+		return nil, Fatalf("OVF disk %s has no file", desc.DiskID)
+	}
+	file, ok := files[*desc.FileRef]
+	if !ok {
+		return nil, Fatalf("OVF file not found: %s", *desc.FileRef)
+	}
+	if file.Compression != nil && *file.Compression != "" && *file.Compression != "identity" {
+		return nil, Fatalf("unsupported OVF file compression: %s", *file.Compression)
+	}
+	if file.ChunkSize != nil && *file.ChunkSize != 0 {
+		return nil, Fatalf("chunked OVF files are not supported: %s", file.Href)
+	}
+	disk := OVFDisk{
+		File: file.Href,
+		Size: int64(file.Size),
+	}
+	if desc.Capacity != "" {
+		capacity, err := strconv.ParseInt(desc.Capacity, 10, 64)
+		if err != nil {
+			return nil, Fatalf("invalid OVF disk capacity: '%s'", desc.Capacity)
+		}
+		units := ""
+		if desc.CapacityAllocationUnits != nil {
+			units = *desc.CapacityAllocationUnits
+		}
+		multiplier, err := ovfAllocationUnits(units)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		disk.Capacity = capacity * multiplier
+	}
+	if item.Parent == nil {
+		return &disk, nil
+	}
+	parent, ok := items[*item.Parent]
+	if !ok || parent.ResourceType == nil {
+		return &disk, nil
+	}
+	controller, adapter := ovfItemController(parent)
+	if controller == "" || item.AddressOnParent == nil {
+		return &disk, nil
+	}
+	slot := fmt.Sprintf("%s%d:%s", controller, controllerBus[parent.InstanceID], *item.AddressOnParent)
+	_, _, _, err := ParseDiskSlot(slot)
+	if err == nil {
+		disk.Slot = slot
+		disk.Adapter = adapter
+	}
+	return &disk, nil
+}
+
+// return the OVF system for the VMX configuration; disk files and sizes are not set
+func (v *VMX) OVFSystem() (*OVFSystem, error) {
+	system := OVFSystem{
+		Name:     v.name,
+		CpuCount: 1,
+		MemoryMB: 1024,
+		Nics:     v.Nics(),
+	}
+	system.GuestOS, _ = v.doc.Get("guestOS")
+	system.Version, _ = v.doc.Get("virtualHW.version")
+	firmware, _ := v.doc.Get("firmware")
+	system.EFIBoot = strings.EqualFold(firmware, "efi")
+	cpuCount, ok, err := v.doc.GetInt("numvcpus")
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if ok {
+		system.CpuCount = cpuCount
+	}
+	memory, ok, err := v.doc.GetInt("memsize")
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if ok {
+		system.MemoryMB = int64(memory)
+	}
+	disks := v.Disks()
+	for _, slot := range SortedKeys(disks) {
+		controller, bus, _, err := ParseDiskSlot(slot)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		disk := OVFDisk{Slot: slot}
+		if controller == "scsi" {
+			disk.Adapter, _ = v.doc.Get(fmt.Sprintf("scsi%d.virtualDev", bus))
+		}
+		system.Disks = append(system.Disks, disk)
+	}
+	return &system, nil
+}
+
+// return the OVF manifest listing the SHA256 digest of each entry
+func ovfManifest(entries []archiveEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		digest, _, err := entry.SHA256()
+		if err != nil {
+			return nil, wrap(err)
+		}
+		fmt.Fprintf(&buf, "SHA256(%s)= %s\n", entry.Name, digest)
+	}
+	return buf.Bytes(), nil
+}
+
+// write an OVA archive; the descriptor must be the first entry
+func WriteOVA(w io.Writer, entries []archiveEntry) error {
+	return WriteTar(w, entries)
+}
+
+// extract an OVA archive to dir, returning the descriptor filename
+func ExtractOVA(r io.Reader, dir string) (string, error) {
+	names, err := ExtractTar(r, dir)
+	if err != nil {
+		return "", wrap(err)
+	}
+	for _, name := range names {
+		if strings.HasSuffix(strings.ToLower(name), ".ovf") {
+			return name, nil
+		}
+	}
+	return "", Fatalf("OVA contains no OVF descriptor")
+}
+
+// check the digests in the manifest file for the descriptor in dir, if present
+func VerifyOVFManifest(dir, descriptor string) error {
+	filename := filepath.Join(dir, strings.TrimSuffix(descriptor, filepath.Ext(descriptor))+".mf")
+	manifest, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return wrap(err)
+	}
+	defer manifest.Close()
+	scanner := bufio.NewScanner(manifest)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		m := OVF_MANIFEST_LINE.FindStringSubmatch(line)
+		if m == nil {
+			return Fatalf("invalid OVF manifest line: '%s'", line)
+		}
+		var digest hash.Hash
+		if m[1] == "SHA1" {
+			digest = sha1.New()
+		} else {
+			digest = sha256.New()
+		}
+		name := filepath.Base(m[2])
+		file, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			return wrap(err)
+		}
+		_, err = io.Copy(digest, file)
+		file.Close()
+		if err != nil {
+			return wrap(err)
+		}
+		if !strings.EqualFold(hex.EncodeToString(digest.Sum(nil)), m[3]) {
+			return Fatalf("OVF manifest digest mismatch: %s", name)
+		}
+	}
+	return scanner.Err()
+}
+
+// export the instance to an OVA archive, or to an OVF descriptor with its files if filename ends with '.ovf'
+func (v *vmctl) ExportOVF(ctx context.Context, vid, filename string) (string, error) {
+	if v.debug {
+		log.Printf("ExportOVF(%s, %s)\n", vid, filename)
+	}
+	vm, err := v.cli.GetVM(ctx, vid)
+	if err != nil {
+		return "", wrap(err)
+	}
+	err = v.requirePowerState(ctx, &vm, "off", "export the instance")
+	if err != nil {
+		return "", wrap(err)
+	}
+	vmx, err := v.readVMX(ctx, &vm)
+	if err != nil {
+		return "", wrap(err)
+	}
+	system, err := vmx.OVFSystem()
+	if err != nil {
+		return "", wrap(err)
+	}
+	tempDir, err := os.MkdirTemp("", "vmx_export.*")
+	if err != nil {
+		return "", wrap(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	diskFiles := vmx.Disks()
+	entries := []archiveEntry{}
+	for i := range system.Disks {
+		disk := &system.Disks[i]
+		disk.File = fmt.Sprintf("%s-disk%d.vmdk", vm.Name, i+1)
+		localPath := filepath.Join(tempDir, disk.File)
+		err := v.exportDisk(ctx, &vm, diskFiles[disk.Slot], localPath)
+		if err != nil {
+			return "", wrap(err)
+		}
+		disk.Size, disk.Capacity, err = vmdkFileCapacity(localPath)
+		if err != nil {
+			return "", wrap(err)
+		}
+		entries = append(entries, archiveEntry{Name: disk.File, Path: localPath})
+	}
+	descriptor, err := OVFDescriptor(system)
+	if err != nil {
+		return "", wrap(err)
+	}
+	writeDir := strings.HasSuffix(strings.ToLower(filename), ".ovf")
+	descriptorName := vm.Name + ".ovf"
+	if writeDir {
+		descriptorName = filepath.Base(filename)
+	}
+	entries = append([]archiveEntry{{Name: descriptorName, Data: descriptor}}, entries...)
+	manifest, err := ovfManifest(entries)
+	if err != nil {
+		return "", wrap(err)
+	}
+	manifestEntry := archiveEntry{
+		Name: strings.TrimSuffix(descriptorName, filepath.Ext(descriptorName)) + ".mf",
+		Data: manifest,
+	}
+	entries = append(entries[:1], append([]archiveEntry{manifestEntry}, entries[1:]...)...)
+
+	if writeDir {
+		for _, entry := range entries {
+			err := entry.copyTo(filepath.Join(filepath.Dir(filename), entry.Name))
+			if err != nil {
+				return "", wrap(err)
+			}
+		}
+		return fmt.Sprintf("Exported %s to %s", vm.Name, filename), nil
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return "", wrap(err)
+	}
+	err = WriteOVA(file, entries)
+	if err != nil {
+		file.Close()
+		return "", wrap(err)
+	}
+	err = file.Close()
+	if err != nil {
+		return "", wrap(err)
+	}
+	return fmt.Sprintf("Exported %s to %s", vm.Name, filename), nil
+}
+
+// convert an instance disk to a streamOptimized VMDK on the host and download it to localPath
+func (v *vmctl) exportDisk(ctx context.Context, vm *VM, filename, localPath string) error {
+	_, hostPathname, err := v.cli.diskPathnames(vm, filename)
+	if err != nil {
+		return wrap(err)
+	}
+	streamFile := strings.TrimSuffix(filename, ".vmdk") + "-export.vmdk"
+	_, streamPathname, err := v.cli.diskPathnames(vm, streamFile)
+	if err != nil {
+		return wrap(err)
+	}
+	if v.verbose {
+		fmt.Printf("[%s] Converting %s to %s\n", vm.Name, filename, VMDKStreamOptimized)
+	}
+	err = v.cli.DiskManager(ctx, vm, "-r", hostPathname, "-t", strconv.Itoa(DiskTypeStreaming), streamPathname)
+	if err != nil {
+		return wrap(err)
+	}
+	if v.verbose {
+		fmt.Printf("[%s] Downloading %s\n", vm.Name, streamFile)
+	}
+	err = v.Download(ctx, vm.Name, localPath, streamFile)
+	if err != nil {
+		v.cli.DeleteDisk(ctx, vm, streamFile)
+		return wrap(err)
+	}
+	return v.cli.DeleteDisk(ctx, vm, streamFile)
+}
+
+// return the file size and capacity of a local sparse VMDK
+func vmdkFileCapacity(filename string) (int64, int64, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return 0, 0, wrap(err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return 0, 0, wrap(err)
+	}
+	reader, err := OpenVMDKSparse(file, info.Size())
+	if err != nil {
+		return 0, 0, wrap(err)
+	}
+	return info.Size(), reader.Size(), nil
+}
+
+// create instance name from an OVA archive or OVF descriptor; an empty name uses the OVF name
+func (v *vmctl) ImportOVF(ctx context.Context, filename, name string, options OVFOptions) (string, error) {
+	if v.debug {
+		log.Printf("ImportOVF(%s, %s, %+v)\n", filename, name, options)
+	}
+	dir := filepath.Dir(filename)
+	descriptor := filepath.Base(filename)
+	if !strings.HasSuffix(strings.ToLower(filename), ".ovf") {
+		tempDir, err := os.MkdirTemp("", "vmx_import.*")
+		if err != nil {
+			return "", wrap(err)
+		}
+		defer os.RemoveAll(tempDir)
+		file, err := os.Open(filename)
+		if err != nil {
+			return "", wrap(err)
+		}
+		descriptor, err = ExtractOVA(file, tempDir)
+		file.Close()
+		if err != nil {
+			return "", wrap(err)
+		}
+		dir = tempDir
+	}
+	err := VerifyOVFManifest(dir, descriptor)
+	if err != nil {
+		return "", wrap(err)
+	}
+	file, err := os.Open(filepath.Join(dir, descriptor))
+	if err != nil {
+		return "", wrap(err)
+	}
+	system, err := ParseOVF(file)
+	file.Close()
+	if err != nil {
+		return "", wrap(err)
+	}
+	if name == "" {
+		name = system.Name
+	}
+	network := options.Network
+	if network == "" {
+		network = DEFAULT_OVF_NETWORK
+	}
+	nics := []NICOptions{}
+	for i, nic := range system.Nics {
+		connection := nic.Connection
+		_, _, _, err := parseNICConnection(v.Remote, connection)
+		if err != nil {
+			connection = network
+		}
+		nics = append(nics, NICOptions{
+			Index:                i,
+			Connection:           connection,
+			Device:               nic.Device,
+			MacAddress:           "auto",
+			ModifyStartConnected: true,
+			StartConnected:       nic.StartConnected,
+		})
+	}
+
+	createOptions := NewCreateOptions()
+	createOptions.CpuCount = system.CpuCount
+	createOptions.MemorySize = fmt.Sprintf("%dM", system.MemoryMB)
+	createOptions.EFIBoot = system.EFIBoot
+	if system.GuestOS != "" {
+		createOptions.GuestOS = system.GuestOS
+	}
+	if len(nics) > 0 {
+		createOptions.NIC = nics[0]
+	} else {
+		createOptions.NIC = NICOptions{Remove: true}
+	}
+	// check the disk images before creating the instance
+	for _, disk := range system.Disks {
+		image, err := OpenDiskImage(filepath.Join(dir, filepath.Base(disk.File)))
+		if err != nil {
+			return "", wrap(err)
+		}
+		image.Close()
+	}
+	_, err = v.Create(ctx, name, *createOptions, IsoOptions{})
+	if err != nil {
+		return "", wrap(err)
+	}
+	err = v.importOVFDevices(ctx, name, dir, system, nics)
+	if err != nil {
+		// don't leave a partially configured instance behind, even if ctx was canceled
+		rmErr := v.Destroy(context.WithoutCancel(ctx), name, DestroyOptions{Force: true})
+		if rmErr != nil {
+			log.Printf("WARNING: [%s] failed destroying instance: %v\n", name, rmErr)
+		}
+		return "", wrap(err)
+	}
+	return fmt.Sprintf("Imported %s from %s", name, filename), nil
+}
+
+// add the OVF NICs after the first and replace the disk added by Create with the OVF disks
+func (v *vmctl) importOVFDevices(ctx context.Context, name, dir string, system *OVFSystem, nics []NICOptions) error {
+	for _, nic := range nics[min(1, len(nics)):] {
+		_, err := v.Modify(ctx, name, CreateOptions{ModifyNIC: true, NIC: nic}, IsoOptions{})
+		if err != nil {
+			return wrap(err)
+		}
+	}
+	_, err := v.RemoveDisk(ctx, name, "nvme0:0", DiskOptions{Delete: true})
+	if err != nil {
+		return wrap(err)
+	}
+	sort.SliceStable(system.Disks, func(i, j int) bool {
+		return system.Disks[i].Slot != "" && system.Disks[j].Slot == ""
+	})
+	for _, disk := range system.Disks {
+		image := filepath.Join(dir, filepath.Base(disk.File))
+		diskOptions := DiskOptions{
+			Slot:    disk.Slot,
+			Adapter: disk.Adapter,
+			Image:   image,
+		}
+		if disk.Capacity > 0 {
+			diskOptions.Size = fmt.Sprintf("%dK", (disk.Capacity+KB-1)/KB)
+		}
+		action, err := v.AddDisk(ctx, name, diskOptions)
+		if err != nil {
+			return wrap(err)
+		}
+		if v.verbose {
+			fmt.Printf("[%s] %s\n", name, action)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package ws
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testVSphereOVF = `<?xml version="1.0" encoding="UTF-8"?>
+<Envelope xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData" xmlns:vmw="http://www.vmware.com/schema/ovf" xmlns:vssd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_VirtualSystemSettingData">
+  <References>
+    <File ovf:href="appliance-disk1.vmdk" ovf:id="file1" ovf:size="1048576"/>
+  </References>
+  <DiskSection>
+    <Info>Virtual disk information</Info>
+    <Disk ovf:capacity="20" ovf:capacityAllocationUnits="byte * 2^30" ovf:diskId="vmdisk1" ovf:fileRef="file1" ovf:format="http://www.vmware.com/interfaces/specifications/vmdk.html#streamOptimized"/>
+  </DiskSection>
+  <NetworkSection>
+    <Info>The list of logical networks</Info>
+    <Network ovf:name="VM Network"/>
+  </NetworkSection>
+  <VirtualSystem ovf:id="appliance">
+    <Info>A virtual machine</Info>
+    <OperatingSystemSection ovf:id="96" vmw:osType="debian12_64Guest">
+      <Info>The kind of installed guest operating system</Info>
+    </OperatingSystemSection>
+    <VirtualHardwareSection>
+      <Info>Virtual hardware requirements</Info>
+      <System>
+        <vssd:ElementName>Virtual Hardware Family</vssd:ElementName>
+        <vssd:InstanceID>0</vssd:InstanceID>
+        <vssd:VirtualSystemType>vmx-13 vmx-14</vssd:VirtualSystemType>
+      </System>
+      <Item>
+        <rasd:InstanceID>1</rasd:InstanceID>
+        <rasd:ResourceType>3</rasd:ResourceType>
+        <rasd:VirtualQuantity>2</rasd:VirtualQuantity>
+      </Item>
+      <Item>
+        <rasd:AllocationUnits>byte * 2^30</rasd:AllocationUnits>
+        <rasd:InstanceID>2</rasd:InstanceID>
+        <rasd:ResourceType>4</rasd:ResourceType>
+        <rasd:VirtualQuantity>4</rasd:VirtualQuantity>
+      </Item>
+      <Item>
+        <rasd:Address>0</rasd:Address>
+        <rasd:InstanceID>3</rasd:InstanceID>
+        <rasd:ResourceSubType>lsilogic</rasd:ResourceSubType>
+        <rasd:ResourceType>6</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:AddressOnParent>0</rasd:AddressOnParent>
+        <rasd:HostResource>ovf:/disk/vmdisk1</rasd:HostResource>
+        <rasd:InstanceID>4</rasd:InstanceID>
+        <rasd:Parent>3</rasd:Parent>
+        <rasd:ResourceType>17</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:AddressOnParent>7</rasd:AddressOnParent>
+        <rasd:AutomaticAllocation>true</rasd:AutomaticAllocation>
+        <rasd:Connection>VM Network</rasd:Connection>
+        <rasd:InstanceID>5</rasd:InstanceID>
+        <rasd:ResourceSubType>VmxNet3</rasd:ResourceSubType>
+        <rasd:ResourceType>10</rasd:ResourceType>
+      </Item>
+      <vmw:Config ovf:required="false" vmw:key="firmware" vmw:value="efi"/>
+    </VirtualHardwareSection>
+  </VirtualSystem>
+</Envelope>
+`
+
+func TestOVFGuestOS(t *testing.T) {
+	for guestOS, osType := range map[string]string{
+		"ubuntu-64":    "ubuntu64Guest",
+		"debian12-64":  "debian12_64Guest",
+		"windows11-64": "windows11_64Guest",
+		"other-64":     "otherGuest64",
+		"other":        "otherGuest",
+	} {
+		require.Equal(t, osType, OVFGuestOSType(guestOS))
+		require.Equal(t, guestOS, VMXGuestOS(osType))
+	}
+}
+
+func TestOVFRoundTrip(t *testing.T) {
+	initTestConfig(t)
+	vmx, err := InitVMX("linux", "web<1>", []byte(`displayName = "web<1>"
+guestOS = "ubuntu-64"
+virtualHW.version = "21"
+numvcpus = "4"
+memsize = "8192"
+firmware = "efi"
+nvme0.present = "TRUE"
+nvme0:0.present = "TRUE"
+nvme0:0.fileName = "web.vmdk"
+scsi1.present = "TRUE"
+scsi1.virtualDev = "pvscsi"
+scsi1:2.present = "TRUE"
+scsi1:2.fileName = "data.vmdk"
+ethernet0.present = "TRUE"
+ethernet0.connectionType = "nat"
+ethernet0.virtualDev = "vmxnet3"
+ethernet1.present = "TRUE"
+ethernet1.connectionType = "custom"
+ethernet1.vnet = "/dev/vmnet3"
+ethernet1.startConnected = "FALSE"
+`))
+	require.Nil(t, err)
+	system, err := vmx.OVFSystem()
+	require.Nil(t, err)
+	require.Len(t, system.Disks, 2)
+	require.Equal(t, "pvscsi", system.Disks[1].Adapter)
+	for i := range system.Disks {
+		system.Disks[i].File = "disk" + system.Disks[i].Slot[:4] + ".vmdk"
+		system.Disks[i].Size = int64(1000 * (i + 1))
+		system.Disks[i].Capacity = int64(i+1) * GB
+	}
+
+	descriptor, err := OVFDescriptor(system)
+	require.Nil(t, err)
+	require.Contains(t, string(descriptor), `vmw:osType="ubuntu64Guest"`)
+	require.Contains(t, string(descriptor), `<Name>web&lt;1&gt;</Name>`)
+
+	parsed, err := ParseOVF(bytes.NewReader(descriptor))
+	require.Nil(t, err)
+	require.Equal(t, system.Disks, parsed.Disks)
+	require.Equal(t, "web<1>", parsed.Name)
+	require.Equal(t, "ubuntu-64", parsed.GuestOS)
+	require.Equal(t, 4, parsed.CpuCount)
+	require.Equal(t, int64(8192), parsed.MemoryMB)
+	require.True(t, parsed.EFIBoot)
+	require.Equal(t, "21", parsed.Version)
+	require.Len(t, parsed.Nics, 2)
+	require.Equal(t, VMNic{Index: 0, Connection: "nat", Device: "vmxnet3", StartConnected: true}, parsed.Nics[0])
+	require.Equal(t, VMNic{Index: 1, Connection: "vmnet3", Device: "e1000"}, parsed.Nics[1])
+}
+
+func TestParseVSphereOVF(t *testing.T) {
+	system, err := ParseOVF(strings.NewReader(testVSphereOVF))
+	require.Nil(t, err)
+	require.Equal(t, "appliance", system.Name)
+	require.Equal(t, "debian12-64", system.GuestOS)
+	require.Equal(t, 2, system.CpuCount)
+	require.Equal(t, int64(4096), system.MemoryMB)
+	require.True(t, system.EFIBoot)
+	require.Equal(t, "14", system.Version)
+	require.Equal(t, []OVFDisk{{
+		Slot:     "scsi0:0",
+		Adapter:  "lsilogic",
+		File:     "appliance-disk1.vmdk",
+		Size:     1048576,
+		Capacity: 20 * GB,
+	}}, system.Disks)
+	require.Equal(t, []VMNic{{Connection: "VM Network", Device: "vmxnet3", StartConnected: true}}, system.Nics)
+}
+
+func TestOVAArchive(t *testing.T) {
+	dir := t.TempDir()
+	diskFile := filepath.Join(dir, "disk.vmdk")
+	require.Nil(t, os.WriteFile(diskFile, []byte("disk contents"), 0600))
+	entries := []archiveEntry{
+		{Name: "test.ovf", Data: []byte(testVSphereOVF)},
+		{Name: "disk1.vmdk", Path: diskFile},
+	}
+	manifest, err := ovfManifest(entries)
+	require.Nil(t, err)
+	entries = append(entries, archiveEntry{Name: "test.mf", Data: manifest})
+	var ova bytes.Buffer
+	require.Nil(t, WriteOVA(&ova, entries))
+
+	extractDir := t.TempDir()
+	descriptor, err := ExtractOVA(bytes.NewReader(ova.Bytes()), extractDir)
+	require.Nil(t, err)
+	require.Equal(t, "test.ovf", descriptor)
+	require.Nil(t, VerifyOVFManifest(extractDir, descriptor))
+	data, err := os.ReadFile(filepath.Join(extractDir, "disk1.vmdk"))
+	require.Nil(t, err)
+	require.Equal(t, "disk contents", string(data))
+
+	require.Nil(t, os.WriteFile(filepath.Join(extractDir, "disk1.vmdk"), []byte("corrupt"), 0600))
+	require.NotNil(t, VerifyOVFManifest(extractDir, descriptor))
+}
+
+func TestImportOVFFailure(t *testing.T) {
+	ctx := t.Context()
+	v, host := newFakeController(t)
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "appliance.ovf")
+	require.Nil(t, os.WriteFile(filename, []byte(testVSphereOVF), 0600))
+
+	// a missing disk image fails before the instance is created
+	_, err := v.ImportOVF(ctx, filename, "web1", OVFOptions{})
+	require.NotNil(t, err)
+	require.NotContains(t, host.Commands, "mkdir /vmware/web1")
+
+	// a failure adding the disk destroys the instance
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "appliance-disk1.vmdk"), make([]byte, 1024*1024), 0600))
+	host.FailMatch = "ls /vmware/web1"
+	_, err = v.ImportOVF(ctx, filename, "web1", OVFOptions{})
+	require.NotNil(t, err)
+	require.Contains(t, host.Commands, "mkdir /vmware/web1")
+	require.Contains(t, host.Commands, "rm -rf /vmware/web1")
+	require.False(t, IsDir(host.local("/vmware/web1")))
+}
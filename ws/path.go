@@ -23,11 +23,11 @@ var UNIX_FILE_LIST = regexp.MustCompile(`^\S+\s+\S+\s+\S+\s+\S+\s+(\d+)\s+\S+\s+
 func PathCompare(first, second string) (bool, error) {
 	first, err := PathNormalize(first)
 	if err != nil {
-		return false, Fatal(err)
+		return false, wrap(err)
 	}
 	second, err = PathNormalize(second)
 	if err != nil {
-		return false, Fatal(err)
+		return false, wrap(err)
 	}
 	return first == second, nil
 }
@@ -64,7 +64,7 @@ func PathFormat(os, inPath string) (string, error) {
 	}
 	inPath, err := PathnameFormat(os, inPath)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 	inPath = strings.TrimRight(inPath, "/\\")
 	if debug {
@@ -79,7 +79,7 @@ func PathnameFormat(os, inPath string) (string, error) {
 	}
 	inPath, err := PathNormalize(inPath)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 	switch os {
 	case "windows", "scp":
@@ -108,7 +108,7 @@ func PathToName(inPath string) (string, error) {
 	}
 	inPath, err := PathNormalize(inPath)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 	_, filename := path.Split(inPath)
 	name, _, _ := strings.Cut(filename, ".")
@@ -137,7 +137,7 @@ func ParseFileList(os string, lines []string) ([]VMFile, error) {
 		if len(match) == 3 {
 			length, err := strconv.ParseUint(match[1], 10, 64)
 			if err != nil {
-				return []VMFile{}, Fatal(err)
+				return []VMFile{}, wrap(err)
 			}
 			files = append(files, VMFile{Name: match[2], Length: length})
 		}
@@ -178,7 +178,7 @@ func PathChdirCommand(os string, inPath string) (string, error) {
 func IsIsoPath(inPath string) (bool, error) {
 	nPath, err := PathNormalize(inPath)
 	if err != nil {
-		return false, Fatal(err)
+		return false, wrap(err)
 	}
 	ret := false
 	switch {
@@ -201,11 +201,11 @@ func FormatIsoPath(isoPath, subPath string) (string, error) {
 	}
 	nPath, err := PathNormalize(subPath)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 	nIsoPath, err := PathNormalize(isoPath)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 
 	// reject `^/.*`
@@ -244,12 +244,12 @@ func FormatIsoPathname(isoPath, subPath string) (string, error) {
 	}
 	n, err := PathNormalize(subPath)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 	subPath = n
 	n, err = PathNormalize(isoPath)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 	isoPath = n
 
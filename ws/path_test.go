@@ -115,16 +115,17 @@ func TestPathCompare(t *testing.T) {
 }
 
 func TestFileListUnix(t *testing.T) {
+	ctx := t.Context()
 	initTestConfig(t)
 	viper.Set("debug", true)
 	viper.Set("verbose", true)
 	viper.Set("relay", "")
 	viper.Set("hostname", "localhost")
-	v, err := NewVMXController()
+	v, err := NewVMXController(ctx)
 	require.Nil(t, err)
 	vmx := v.(*vmctl)
 	require.IsType(t, &vmctl{}, vmx)
-	lines, err := vmx.exec("sh", []string{"-c", "ls -l ."}, "", nil)
+	lines, err := vmx.exec(ctx, "sh", []string{"-c", "ls -l ."}, "", nil)
 	files, err := ParseFileList("unix", lines)
 	require.Nil(t, err)
 	require.NotEmpty(t, files)
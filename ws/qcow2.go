@@ -0,0 +1,199 @@
+package ws
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+)
+
+// qcow2 image reader supporting uncompressed and deflate-compressed clusters
+
+const QCOW2_MAGIC = 0x514649fb
+
+const (
+	qcow2OffsetMask     = 0x00fffffffffffe00
+	qcow2CompressedFlag = 1 << 62
+	qcow2ZeroFlag       = 1
+)
+
+// incompatible feature bits
+const (
+	qcow2DirtyFeature        = 1 << 0
+	qcow2CorruptFeature      = 1 << 1
+	qcow2ExternalDataFeature = 1 << 2
+	qcow2CompressionFeature  = 1 << 3
+	qcow2ExtendedL2Feature   = 1 << 4
+)
+
+type qcow2Header struct {
+	Magic                 uint32
+	Version               uint32
+	BackingFileOffset     uint64
+	BackingFileSize       uint32
+	ClusterBits           uint32
+	Size                  uint64
+	CryptMethod           uint32
+	L1Size                uint32
+	L1TableOffset         uint64
+	RefcountTableOffset   uint64
+	RefcountTableClusters uint32
+	NbSnapshots           uint32
+	SnapshotsOffset       uint64
+}
+
+type QCOW2Reader struct {
+	r            io.ReaderAt
+	fileSize     int64
+	header       qcow2Header
+	clusterSize  int64
+	l1           []uint64
+	l2           map[uint64][]uint64
+	clusterIndex int64
+	cluster      []byte
+}
+
+func OpenQCOW2(r io.ReaderAt, fileSize int64) (*QCOW2Reader, error) {
+	data := make([]byte, 104)
+	_, err := r.ReadAt(data, 0)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	var header qcow2Header
+	err = binary.Read(bytes.NewReader(data), binary.BigEndian, &header)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if header.Magic != QCOW2_MAGIC {
+		return nil, Fatalf("not a qcow2 image")
+	}
+	switch header.Version {
+	case 2:
+	case 3:
+		incompatible := binary.BigEndian.Uint64(data[72:80])
+		if incompatible&qcow2CorruptFeature != 0 {
+			return nil, Fatalf("qcow2 image is marked corrupt")
+		}
+		if incompatible&(qcow2ExternalDataFeature|qcow2CompressionFeature|qcow2ExtendedL2Feature) != 0 {
+			return nil, Fatalf("unsupported qcow2 features: 0x%x", incompatible)
+		}
+	default:
+		return nil, Fatalf("unsupported qcow2 version: %d", header.Version)
+	}
+	if header.BackingFileOffset != 0 {
+		return nil, Fatalf("qcow2 images with a backing file are not supported")
+	}
+	if header.CryptMethod != 0 {
+		return nil, Fatalf("encrypted qcow2 images are not supported")
+	}
+	if header.ClusterBits < 9 || header.ClusterBits > 21 {
+		return nil, Fatalf("invalid qcow2 cluster size")
+	}
+	reader := QCOW2Reader{
+		r:            r,
+		fileSize:     fileSize,
+		header:       header,
+		clusterSize:  int64(1) << header.ClusterBits,
+		l2:           make(map[uint64][]uint64),
+		clusterIndex: -1,
+	}
+	reader.l1, err = readUint64Table(r, int64(header.L1TableOffset), int64(header.L1Size))
+	if err != nil {
+		return nil, wrap(err)
+	}
+	return &reader, nil
+}
+
+func readUint64Table(r io.ReaderAt, offset, count int64) ([]uint64, error) {
+	data := make([]byte, count*8)
+	_, err := r.ReadAt(data, offset)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	table := make([]uint64, count)
+	for i := range table {
+		table[i] = binary.BigEndian.Uint64(data[i*8:])
+	}
+	return table, nil
+}
+
+func (q *QCOW2Reader) Size() int64 {
+	return int64(q.header.Size)
+}
+
+// read cluster index into the cluster buffer
+func (q *QCOW2Reader) readCluster(index int64) error {
+	if index == q.clusterIndex {
+		return nil
+	}
+	q.clusterIndex = -1
+	if q.cluster == nil {
+		q.cluster = make([]byte, q.clusterSize)
+	}
+	clear(q.cluster)
+	l2Entries := q.clusterSize / 8
+	l1Index := index / l2Entries
+	if l1Index >= int64(len(q.l1)) {
+		q.clusterIndex = index
+		return nil
+	}
+	l2Offset := q.l1[l1Index] & qcow2OffsetMask
+	if l2Offset == 0 {
+		q.clusterIndex = index
+		return nil
+	}
+	l2, ok := q.l2[l2Offset]
+	if !ok {
+		var err error
+		l2, err = readUint64Table(q.r, int64(l2Offset), l2Entries)
+		if err != nil {
+			return wrap(err)
+		}
+		q.l2[l2Offset] = l2
+	}
+	entry := l2[index%l2Entries]
+	switch {
+	case entry&qcow2CompressedFlag != 0:
+		// compressed cluster descriptor: host offset and additional 512-byte sector count
+		offsetBits := 62 - (q.header.ClusterBits - 8)
+		offset := int64(entry & (1<<offsetBits - 1))
+		sectors := int64((entry>>offsetBits)&(1<<(62-offsetBits)-1)) + 1
+		length := min(sectors*512-(offset&511), q.fileSize-offset)
+		compressed := make([]byte, length)
+		_, err := q.r.ReadAt(compressed, offset)
+		if err != nil && err != io.EOF {
+			return wrap(err)
+		}
+		_, err = io.ReadFull(flate.NewReader(bytes.NewReader(compressed)), q.cluster)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return wrap(err)
+		}
+	case q.header.Version >= 3 && entry&qcow2ZeroFlag != 0:
+	case entry&qcow2OffsetMask != 0:
+		_, err := q.r.ReadAt(q.cluster, int64(entry&qcow2OffsetMask))
+		if err != nil && err != io.EOF {
+			return wrap(err)
+		}
+	}
+	q.clusterIndex = index
+	return nil
+}
+
+func (q *QCOW2Reader) ReadAt(p []byte, off int64) (int, error) {
+	size := q.Size()
+	count := 0
+	for count < len(p) {
+		if off >= size {
+			return count, io.EOF
+		}
+		err := q.readCluster(off / q.clusterSize)
+		if err != nil {
+			return count, wrap(err)
+		}
+		clusterStart := off / q.clusterSize * q.clusterSize
+		n := copy(p[count:], q.cluster[off-clusterStart:min(q.clusterSize, size-clusterStart)])
+		count += n
+		off += int64(n)
+	}
+	return count, nil
+}
@@ -0,0 +1,789 @@
+package ws
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A backup repository is a local directory of zstd compressed content-defined
+// chunks named by their sha256, and per-instance snapshot manifests listing
+// the chunks of each instance file:
+//
+//	vmx-repo.json
+//	chunks/ab/abcdef...
+//	snapshots/NAME/20261016T021500Z.json
+
+const REPO_CONFIG_FILE = "vmx-repo.json"
+const REPO_VERSION = 1
+const SNAPSHOT_ID_FORMAT = "20060102T150405Z"
+
+type RepoConfig struct {
+	Version      int   `json:"version"`
+	ChunkMinSize int64 `json:"chunk_min_size"`
+	ChunkMaxSize int64 `json:"chunk_max_size"`
+	BlockSize    int64 `json:"block_size"`
+}
+
+type RepoChunk struct {
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+type RepoFile struct {
+	BackupFile
+	Chunks []RepoChunk `json:"chunks"`
+	Blocks []string    `json:"blocks"`
+}
+
+type RepoSnapshot struct {
+	Version    int        `json:"version"`
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Created    time.Time  `json:"created"`
+	PowerState string     `json:"power_state"`
+	VMX        string     `json:"vmx"`
+	Files      []RepoFile `json:"files"`
+}
+
+type RetentionPolicy struct {
+	KeepDaily  int
+	KeepWeekly int
+}
+
+type Repository struct {
+	Dir       string
+	NewChunks int
+	NewBytes  int64
+	encoder   *zstd.Encoder
+	decoder   *zstd.Decoder
+}
+
+// open the backup repository in dir, initializing it if create is set
+func OpenRepository(dir string, create bool) (*Repository, error) {
+	configFile := filepath.Join(dir, REPO_CONFIG_FILE)
+	expected := RepoConfig{
+		Version:      REPO_VERSION,
+		ChunkMinSize: CHUNK_MIN_SIZE,
+		ChunkMaxSize: CHUNK_MAX_SIZE,
+		BlockSize:    BLOCK_SIZE,
+	}
+	if IsFile(configFile) {
+		data, err := os.ReadFile(configFile)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		var config RepoConfig
+		err = json.Unmarshal(data, &config)
+		if err != nil {
+			return nil, Fatalf("invalid repository config: %v", err)
+		}
+		if config != expected {
+			return nil, Fatalf("unsupported repository config: %+v", config)
+		}
+	} else {
+		if !create {
+			return nil, Fatalf("not a backup repository: %s", dir)
+		}
+		for _, subdir := range []string{"chunks", "snapshots"} {
+			err := os.MkdirAll(filepath.Join(dir, subdir), 0700)
+			if err != nil {
+				return nil, wrap(err)
+			}
+		}
+		data, err := json.MarshalIndent(&expected, "", "  ")
+		if err != nil {
+			return nil, wrap(err)
+		}
+		err = writeFileAtomic(configFile, append(data, '\n'))
+		if err != nil {
+			return nil, wrap(err)
+		}
+	}
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		encoder.Close()
+		return nil, wrap(err)
+	}
+	return &Repository{Dir: dir, encoder: encoder, decoder: decoder}, nil
+}
+
+func (r *Repository) Close() error {
+	r.decoder.Close()
+	return r.encoder.Close()
+}
+
+// write data to a temporary file and rename it into place
+func writeFileAtomic(filename string, data []byte) error {
+	tempFile := filename + ".tmp"
+	err := os.WriteFile(tempFile, data, 0600)
+	if err != nil {
+		return wrap(err)
+	}
+	err = os.Rename(tempFile, filename)
+	if err != nil {
+		os.Remove(tempFile)
+		return wrap(err)
+	}
+	return nil
+}
+
+func (r *Repository) chunkPath(sum string) string {
+	return filepath.Join(r.Dir, "chunks", sum[:2], sum)
+}
+
+// store a chunk if the repository does not already contain it
+func (r *Repository) putChunk(data []byte) (RepoChunk, error) {
+	digest := sha256.Sum256(data)
+	chunk := RepoChunk{SHA256: hex.EncodeToString(digest[:]), Size: int64(len(data))}
+	filename := r.chunkPath(chunk.SHA256)
+	if IsFile(filename) {
+		return chunk, nil
+	}
+	err := os.MkdirAll(filepath.Dir(filename), 0700)
+	if err != nil {
+		return chunk, wrap(err)
+	}
+	err = writeFileAtomic(filename, r.encoder.EncodeAll(data, nil))
+	if err != nil {
+		return chunk, wrap(err)
+	}
+	r.NewChunks++
+	r.NewBytes += chunk.Size
+	return chunk, nil
+}
+
+// read and verify a chunk
+func (r *Repository) getChunk(chunk RepoChunk) ([]byte, error) {
+	compressed, err := os.ReadFile(r.chunkPath(chunk.SHA256))
+	if err != nil {
+		return nil, Fatalf("repository chunk missing: %s", chunk.SHA256)
+	}
+	data, err := r.decoder.DecodeAll(compressed, nil)
+	if err != nil {
+		return nil, Fatalf("repository chunk corrupt: %s: %v", chunk.SHA256, err)
+	}
+	digest := sha256.Sum256(data)
+	if int64(len(data)) != chunk.Size || hex.EncodeToString(digest[:]) != chunk.SHA256 {
+		return nil, Fatalf("repository chunk corrupt: %s", chunk.SHA256)
+	}
+	return data, nil
+}
+
+// store the chunks of a local file, returning its snapshot entry
+func (r *Repository) StoreFile(name, localPath string) (RepoFile, error) {
+	entry := RepoFile{BackupFile: BackupFile{Name: name}, Chunks: []RepoChunk{}}
+	file, err := os.Open(localPath)
+	if err != nil {
+		return entry, wrap(err)
+	}
+	defer file.Close()
+	hash := sha256.New()
+	blocks := newBlockHasher()
+	chunker := newChunker(io.TeeReader(file, io.MultiWriter(hash, blocks)))
+	for {
+		data, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return entry, wrap(err)
+		}
+		chunk, err := r.putChunk(data)
+		if err != nil {
+			return entry, wrap(err)
+		}
+		entry.Chunks = append(entry.Chunks, chunk)
+		entry.Size += chunk.Size
+	}
+	entry.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	entry.Blocks = blocks.Sum()
+	return entry, nil
+}
+
+// write the content of a snapshot file to w, verifying its checksum
+func (r *Repository) WriteFile(w io.Writer, file RepoFile) error {
+	hash := sha256.New()
+	var size int64
+	for _, chunk := range file.Chunks {
+		data, err := r.getChunk(chunk)
+		if err != nil {
+			return wrap(err)
+		}
+		hash.Write(data)
+		size += chunk.Size
+		_, err = w.Write(data)
+		if err != nil {
+			return wrap(err)
+		}
+	}
+	if size != file.Size || hex.EncodeToString(hash.Sum(nil)) != file.SHA256 {
+		return Fatalf("snapshot checksum mismatch: %s", file.Name)
+	}
+	return nil
+}
+
+// snapshotReader provides random access to the content of a snapshot file
+type snapshotReader struct {
+	repo    *Repository
+	file    *RepoFile
+	offsets []int64
+	index   int
+	data    []byte
+}
+
+func (r *Repository) openFile(file *RepoFile) *snapshotReader {
+	offsets := make([]int64, len(file.Chunks))
+	var offset int64
+	for i, chunk := range file.Chunks {
+		offsets[i] = offset
+		offset += chunk.Size
+	}
+	return &snapshotReader{repo: r, file: file, offsets: offsets, index: -1}
+}
+
+func (s *snapshotReader) ReadAt(p []byte, offset int64) (int, error) {
+	n := 0
+	for n < len(p) {
+		pos := offset + int64(n)
+		if pos >= s.file.Size {
+			return n, io.EOF
+		}
+		i := sort.Search(len(s.offsets), func(i int) bool { return s.offsets[i] > pos }) - 1
+		if i != s.index {
+			data, err := s.repo.getChunk(s.file.Chunks[i])
+			if err != nil {
+				return n, wrap(err)
+			}
+			s.index = i
+			s.data = data
+		}
+		n += copy(p[n:], s.data[pos-s.offsets[i]:])
+	}
+	return n, nil
+}
+
+func (r *Repository) snapshotDir(name string) string {
+	return filepath.Join(r.Dir, "snapshots", name)
+}
+
+// return the names of instances with snapshots
+func (r *Repository) Instances() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(r.Dir, "snapshots"))
+	if err != nil {
+		return nil, wrap(err)
+	}
+	names := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// return the snapshot IDs of instance name, oldest first
+func (r *Repository) SnapshotIDs(name string) ([]string, error) {
+	entries, err := os.ReadDir(r.snapshotDir(name))
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, wrap(err)
+	}
+	ids := []string{}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if ok && !entry.IsDir() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// read snapshot id of instance name; an empty id returns the latest snapshot
+func (r *Repository) LoadSnapshot(name, id string) (*RepoSnapshot, error) {
+	if id == "" {
+		ids, err := r.SnapshotIDs(name)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		if len(ids) == 0 {
+			return nil, Fatalf("no snapshots found for '%s'", name)
+		}
+		id = ids[len(ids)-1]
+	}
+	data, err := os.ReadFile(filepath.Join(r.snapshotDir(name), id+".json"))
+	if err != nil {
+		return nil, Fatalf("snapshot not found: %s %s", name, id)
+	}
+	var snapshot RepoSnapshot
+	err = json.Unmarshal(data, &snapshot)
+	if err != nil {
+		return nil, Fatalf("invalid snapshot %s %s: %v", name, id, err)
+	}
+	if snapshot.Version != REPO_VERSION {
+		return nil, Fatalf("unsupported snapshot version: %d", snapshot.Version)
+	}
+	return &snapshot, nil
+}
+
+func (r *Repository) SaveSnapshot(snapshot *RepoSnapshot) error {
+	dir := r.snapshotDir(snapshot.Name)
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return wrap(err)
+	}
+	filename := filepath.Join(dir, snapshot.ID+".json")
+	if IsFile(filename) {
+		return Fatalf("snapshot exists: %s %s", snapshot.Name, snapshot.ID)
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return wrap(err)
+	}
+	return writeFileAtomic(filename, append(data, '\n'))
+}
+
+// return the backup manifest equivalent of a snapshot
+func (s *RepoSnapshot) Manifest() *BackupManifest {
+	manifest := BackupManifest{
+		Version:    BACKUP_VERSION,
+		Name:       s.Name,
+		Created:    s.Created,
+		PowerState: s.PowerState,
+		VMX:        s.VMX,
+		Files:      []BackupFile{},
+	}
+	for _, file := range s.Files {
+		manifest.Files = append(manifest.Files, file.BackupFile)
+	}
+	return &manifest
+}
+
+// return the snapshot IDs kept by the retention policy; ids must be sorted oldest first
+func retainSnapshots(ids []string, policy RetentionPolicy) map[string]bool {
+	keep := make(map[string]bool)
+	var lastDay, lastWeek string
+	var daily, weekly int
+	for i := len(ids) - 1; i >= 0; i-- {
+		created, err := time.Parse(SNAPSHOT_ID_FORMAT, ids[i])
+		if err != nil {
+			// never remove snapshots this program did not name
+			keep[ids[i]] = true
+			continue
+		}
+		day := created.Format("2006-01-02")
+		if day != lastDay {
+			lastDay = day
+			if daily < policy.KeepDaily {
+				keep[ids[i]] = true
+				daily++
+			}
+		}
+		year, week := created.ISOWeek()
+		weekKey := fmt.Sprintf("%d-%02d", year, week)
+		if weekKey != lastWeek {
+			lastWeek = weekKey
+			if weekly < policy.KeepWeekly {
+				keep[ids[i]] = true
+				weekly++
+			}
+		}
+	}
+	return keep
+}
+
+// remove snapshots of instance name, or of all instances if name is empty,
+// not kept by the retention policy, then remove unreferenced chunks
+func (r *Repository) Prune(name string, policy RetentionPolicy) (string, error) {
+	if policy.KeepDaily <= 0 && policy.KeepWeekly <= 0 {
+		return "", Fatalf("retention policy would remove all snapshots")
+	}
+	names := []string{name}
+	if name == "" {
+		var err error
+		names, err = r.Instances()
+		if err != nil {
+			return "", wrap(err)
+		}
+	}
+	removed := 0
+	for _, name := range names {
+		ids, err := r.SnapshotIDs(name)
+		if err != nil {
+			return "", wrap(err)
+		}
+		keep := retainSnapshots(ids, policy)
+		for _, id := range ids {
+			if !keep[id] {
+				err := os.Remove(filepath.Join(r.snapshotDir(name), id+".json"))
+				if err != nil {
+					return "", wrap(err)
+				}
+				removed++
+			}
+		}
+	}
+	chunks, size, err := r.removeUnreferenced()
+	if err != nil {
+		return "", wrap(err)
+	}
+	return fmt.Sprintf("Removed %d snapshots and %d chunks (%s)", removed, chunks, FormatSize(size)), nil
+}
+
+// remove chunks not referenced by any snapshot, returning the count and compressed size removed
+func (r *Repository) removeUnreferenced() (int, int64, error) {
+	referenced := make(map[string]bool)
+	names, err := r.Instances()
+	if err != nil {
+		return 0, 0, wrap(err)
+	}
+	for _, name := range names {
+		ids, err := r.SnapshotIDs(name)
+		if err != nil {
+			return 0, 0, wrap(err)
+		}
+		for _, id := range ids {
+			snapshot, err := r.LoadSnapshot(name, id)
+			if err != nil {
+				return 0, 0, wrap(err)
+			}
+			for _, file := range snapshot.Files {
+				for _, chunk := range file.Chunks {
+					referenced[chunk.SHA256] = true
+				}
+			}
+		}
+	}
+	count := 0
+	var size int64
+	err = filepath.WalkDir(filepath.Join(r.Dir, "chunks"), func(pathname string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || referenced[entry.Name()] {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		err = os.Remove(pathname)
+		if err != nil {
+			return err
+		}
+		count++
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, 0, wrap(err)
+	}
+	return count, size, nil
+}
+
+// return the stream executor if changed blocks of host files can be detected and fetched
+func (v *vmctl) incrementalTransfer() (streamExecutor, error) {
+	local, err := v.isLocal()
+	if err != nil {
+		return nil, wrap(err)
+	}
+	stream, ok := v.executor.(streamExecutor)
+	if local || !ok || v.Remote == "windows" {
+		return nil, nil
+	}
+	return stream, nil
+}
+
+// return the host command writing the sha256 of each BLOCK_SIZE block of a
+// POSIX host file; GNU split is linux only, so other hosts use perl, which
+// macOS ships with Digest::SHA, to read the file in a single pass
+func blockHashCommand(remote, hostPath string) string {
+	if remote == "linux" {
+		return fmt.Sprintf("split -b %d --filter=sha256sum %s", BLOCK_SIZE, hostQuote(remote, hostPath))
+	}
+	script := fmt.Sprintf(`open(F, "<", $ARGV[0]) or die "$ARGV[0]: $!\n"; binmode F; while (read(F, $buf, %d)) { print Digest::SHA::sha256_hex($buf), "\n" }`, BLOCK_SIZE)
+	return fmt.Sprintf("perl -MDigest::SHA -e %s %s", hostQuote(remote, script), hostQuote(remote, hostPath))
+}
+
+// return the sha256 of each BLOCK_SIZE block of a POSIX host file
+func (v *vmctl) hostBlockHashes(ctx context.Context, hostPath string, size int64) ([]string, error) {
+	count := (size + BLOCK_SIZE - 1) / BLOCK_SIZE
+	lines, err := v.RemoteExec(ctx, blockHashCommand(v.Remote, hostPath), nil)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	return parseBlockHashes(lines, count)
+}
+
+// parse sha256sum or shasum output, expecting count hashes
+func parseBlockHashes(lines []string, count int64) ([]string, error) {
+	hashes := []string{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		_, err := hex.DecodeString(fields[0])
+		if err != nil || len(fields[0]) != sha256.Size*2 {
+			return nil, Fatalf("unexpected block hash output: %s", line)
+		}
+		hashes = append(hashes, fields[0])
+	}
+	if int64(len(hashes)) != count {
+		return nil, Fatalf("expected %d block hashes, got %d", count, len(hashes))
+	}
+	return hashes, nil
+}
+
+// write a host file to localPath, copying blocks unchanged since the previous
+// snapshot from the repository and fetching the rest; returns the host block
+// hashes and the number of bytes transferred
+func (v *vmctl) fetchChangedBlocks(ctx context.Context, stream streamExecutor, repo *Repository, hostPath, localPath string, size int64, previous *RepoFile) ([]string, int64, error) {
+	hashes, err := v.hostBlockHashes(ctx, hostPath, size)
+	if err != nil {
+		return nil, 0, wrap(err)
+	}
+	unchanged := func(i int) bool {
+		return i < len(previous.Blocks) && previous.Blocks[i] == hashes[i]
+	}
+	file, err := os.Create(localPath)
+	if err != nil {
+		return nil, 0, wrap(err)
+	}
+	defer file.Close()
+	reader := repo.openFile(previous)
+	buf := make([]byte, BLOCK_SIZE)
+	var transferred int64
+	for i := 0; i < len(hashes); {
+		if unchanged(i) {
+			length := min(BLOCK_SIZE, size-int64(i)*BLOCK_SIZE)
+			_, err := reader.ReadAt(buf[:length], int64(i)*BLOCK_SIZE)
+			if err != nil && err != io.EOF {
+				return nil, 0, wrap(err)
+			}
+			_, err = file.Write(buf[:length])
+			if err != nil {
+				return nil, 0, wrap(err)
+			}
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(hashes) && !unchanged(j) {
+			j++
+		}
+		start, err := file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, 0, wrap(err)
+		}
+		err = stream.Stream(ctx, file, fmt.Sprintf("dd if=%s bs=%d skip=%d count=%d 2>/dev/null", hostQuote(v.Remote, hostPath), BLOCK_SIZE, i, j-i))
+		if err != nil {
+			return nil, 0, wrap(err)
+		}
+		end, err := file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, 0, wrap(err)
+		}
+		transferred += end - start
+		i = j
+	}
+	err = file.Close()
+	if err != nil {
+		return nil, 0, wrap(err)
+	}
+	return hashes, transferred, nil
+}
+
+// store a snapshot of the instance in a backup repository; the instance must be off or suspended
+func (v *vmctl) BackupRepo(ctx context.Context, vid, dir string) (string, error) {
+	if v.debug {
+		log.Printf("BackupRepo(%s, %s)\n", vid, dir)
+	}
+	vm, err := v.cli.GetVM(ctx, vid)
+	if err != nil {
+		return "", wrap(err)
+	}
+	err = v.cli.QueryPowerState(ctx, &vm)
+	if err != nil {
+		return "", wrap(err)
+	}
+	if vm.PowerState != "off" && vm.PowerState != "suspended" {
+		return "", wrapf("[%s] cannot backup instance: %w", vm.Name, &ErrPowerState{Want: "off", Have: vm.PowerState})
+	}
+	files, err := v.instanceFiles(ctx, &vm)
+	if err != nil {
+		return "", wrap(err)
+	}
+	stream, err := v.incrementalTransfer()
+	if err != nil {
+		return "", wrap(err)
+	}
+	repo, err := OpenRepository(dir, true)
+	if err != nil {
+		return "", wrap(err)
+	}
+	defer repo.Close()
+	previousFiles := make(map[string]*RepoFile)
+	ids, err := repo.SnapshotIDs(vm.Name)
+	if err != nil {
+		return "", wrap(err)
+	}
+	if len(ids) > 0 {
+		previous, err := repo.LoadSnapshot(vm.Name, ids[len(ids)-1])
+		if err != nil {
+			return "", wrap(err)
+		}
+		for i := range previous.Files {
+			previousFiles[previous.Files[i].Name] = &previous.Files[i]
+		}
+	}
+
+	tempDir, err := os.MkdirTemp("", "vmx_backup.*")
+	if err != nil {
+		return "", wrap(err)
+	}
+	defer os.RemoveAll(tempDir)
+	created := time.Now().UTC()
+	snapshot := RepoSnapshot{
+		Version:    REPO_VERSION,
+		ID:         created.Format(SNAPSHOT_ID_FORMAT),
+		Name:       vm.Name,
+		Created:    created,
+		PowerState: vm.PowerState,
+		Files:      []RepoFile{},
+	}
+	instanceDir, _ := path.Split(vm.Path)
+	var transferred int64
+	for _, file := range files {
+		localPath := filepath.Join(tempDir, file.Name)
+		previous, ok := previousFiles[file.Name]
+		var hostBlocks []string
+		if stream != nil && ok && file.Size >= BLOCK_SIZE {
+			if v.verbose {
+				fmt.Printf("[%s] Comparing %s\n", vm.Name, file.Name)
+			}
+			hostPath, err := PathnameFormat(v.Remote, path.Join(instanceDir, file.Name))
+			if err != nil {
+				return "", wrap(err)
+			}
+			var count int64
+			hostBlocks, count, err = v.fetchChangedBlocks(ctx, stream, repo, hostPath, localPath, file.Size, previous)
+			if err != nil {
+				return "", wrap(err)
+			}
+			transferred += count
+		} else {
+			if v.verbose {
+				fmt.Printf("[%s] Downloading %s\n", vm.Name, file.Name)
+			}
+			err := v.Download(ctx, vm.Name, localPath, file.Name)
+			if err != nil {
+				return "", wrap(err)
+			}
+			transferred += file.Size
+		}
+		if strings.EqualFold(file.Name, vm.Name+".vmx") {
+			data, err := os.ReadFile(localPath)
+			if err != nil {
+				return "", wrap(err)
+			}
+			snapshot.VMX = string(data)
+		}
+		entry, err := repo.StoreFile(file.Name, localPath)
+		if err != nil {
+			return "", wrap(err)
+		}
+		os.Remove(localPath)
+		if hostBlocks != nil && !slices.Equal(hostBlocks, entry.Blocks) {
+			return "", Fatalf("[%s] %s changed during backup", vm.Name, file.Name)
+		}
+		snapshot.Files = append(snapshot.Files, entry)
+	}
+	if snapshot.VMX == "" {
+		return "", Fatalf("[%s] VMX file not found in instance directory", vm.Name)
+	}
+
+	// fail if the instance was started during the backup
+	err = v.cli.QueryPowerState(ctx, &vm)
+	if err != nil {
+		return "", wrap(err)
+	}
+	if vm.PowerState != snapshot.PowerState {
+		return "", wrapf("[%s] power state changed during backup: %w", vm.Name, &ErrPowerState{Want: snapshot.PowerState, Have: vm.PowerState})
+	}
+	err = repo.SaveSnapshot(&snapshot)
+	if err != nil {
+		return "", wrap(err)
+	}
+	return fmt.Sprintf("Backed up %d files to %s snapshot %s: %d new chunks (%s), %s transferred",
+		len(snapshot.Files), dir, snapshot.ID, repo.NewChunks, FormatSize(repo.NewBytes), FormatSize(transferred)), nil
+}
+
+// create an instance from a backup repository snapshot of instance vid; an
+// empty options.Snapshot selects the latest snapshot and an empty name uses the original name
+func (v *vmctl) RestoreRepo(ctx context.Context, dir, vid, name string, options RestoreOptions) (string, error) {
+	if v.debug {
+		log.Printf("RestoreRepo(%s, %s, %s, %+v)\n", dir, vid, name, options)
+	}
+	root, err := v.selectRoot(options.Root)
+	if err != nil {
+		return "", wrap(err)
+	}
+	repo, err := OpenRepository(dir, false)
+	if err != nil {
+		return "", wrap(err)
+	}
+	defer repo.Close()
+	snapshot, err := repo.LoadSnapshot(vid, options.Snapshot)
+	if err != nil {
+		return "", wrap(err)
+	}
+	tempDir, err := os.MkdirTemp("", "vmx_restore.*")
+	if err != nil {
+		return "", wrap(err)
+	}
+	defer os.RemoveAll(tempDir)
+	for _, file := range snapshot.Files {
+		if strings.ContainsAny(file.Name, "/\\") {
+			return "", Fatalf("invalid snapshot filename: '%s'", file.Name)
+		}
+		if v.verbose {
+			fmt.Printf("Extracting %s\n", file.Name)
+		}
+		localFile, err := os.Create(filepath.Join(tempDir, file.Name))
+		if err != nil {
+			return "", wrap(err)
+		}
+		err = repo.WriteFile(localFile, file)
+		if err != nil {
+			localFile.Close()
+			return "", wrap(err)
+		}
+		err = localFile.Close()
+		if err != nil {
+			return "", wrap(err)
+		}
+	}
+	source := fmt.Sprintf("%s snapshot %s", dir, snapshot.ID)
+	return v.restoreInstance(ctx, snapshot.Manifest(), tempDir, source, name, root)
+}
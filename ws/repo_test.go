@@ -0,0 +1,211 @@
+package ws
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/stretchr/testify/require"
+	"io"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testRandomData(seed int64, size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
+
+func testChunks(t *testing.T, data []byte) []string {
+	chunker := newChunker(bytes.NewReader(data))
+	sums := []string{}
+	total := 0
+	for {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		require.LessOrEqual(t, int64(len(chunk)), CHUNK_MAX_SIZE)
+		total += len(chunk)
+		sum := sha256.Sum256(chunk)
+		sums = append(sums, hex.EncodeToString(sum[:]))
+	}
+	require.Equal(t, len(data), total)
+	return sums
+}
+
+func TestChunker(t *testing.T) {
+	data := testRandomData(1, int(16*MB))
+	chunks := testChunks(t, data)
+	require.Greater(t, len(chunks), 4)
+	require.Equal(t, chunks, testChunks(t, data))
+
+	// an insertion changes only the chunks near it
+	edited := append(append(append([]byte{}, data[:8*MB]...), []byte("inserted")...), data[8*MB:]...)
+	shared := 0
+	original := make(map[string]bool)
+	for _, sum := range chunks {
+		original[sum] = true
+	}
+	editedChunks := testChunks(t, edited)
+	for _, sum := range editedChunks {
+		if original[sum] {
+			shared++
+		}
+	}
+	require.GreaterOrEqual(t, shared, len(editedChunks)-2)
+
+	require.Empty(t, testChunks(t, []byte{}))
+	require.Len(t, testChunks(t, []byte("small")), 1)
+}
+
+func TestBlockHasher(t *testing.T) {
+	data := testRandomData(2, int(BLOCK_SIZE+100))
+	hasher := newBlockHasher()
+	_, err := io.Copy(hasher, bytes.NewReader(data))
+	require.Nil(t, err)
+	first := sha256.Sum256(data[:BLOCK_SIZE])
+	last := sha256.Sum256(data[BLOCK_SIZE:])
+	require.Equal(t, []string{hex.EncodeToString(first[:]), hex.EncodeToString(last[:])}, hasher.Sum())
+}
+
+func TestParseBlockHashes(t *testing.T) {
+	sum := sha256.Sum256([]byte("block"))
+	line := hex.EncodeToString(sum[:]) + "  -"
+	hashes, err := parseBlockHashes([]string{line, line}, 2)
+	require.Nil(t, err)
+	require.Equal(t, []string{hex.EncodeToString(sum[:]), hex.EncodeToString(sum[:])}, hashes)
+	_, err = parseBlockHashes([]string{line}, 2)
+	require.NotNil(t, err)
+	_, err = parseBlockHashes([]string{"dd: error"}, 1)
+	require.NotNil(t, err)
+}
+
+func testStoreFile(t *testing.T, repo *Repository, name string, data []byte) RepoFile {
+	localPath := filepath.Join(t.TempDir(), name)
+	require.Nil(t, os.WriteFile(localPath, data, 0600))
+	file, err := repo.StoreFile(name, localPath)
+	require.Nil(t, err)
+	return file
+}
+
+func TestRepository(t *testing.T) {
+	initTestConfig(t)
+	dir := t.TempDir()
+	_, err := OpenRepository(dir, false)
+	require.NotNil(t, err)
+	repo, err := OpenRepository(dir, true)
+	require.Nil(t, err)
+	defer repo.Close()
+
+	data := testRandomData(3, int(10*MB))
+	file := testStoreFile(t, repo, "web1.vmdk", data)
+	require.Equal(t, int64(len(data)), file.Size)
+	require.Len(t, file.Blocks, 3)
+	stored := repo.NewChunks
+	require.Equal(t, len(file.Chunks), stored)
+
+	// unchanged data adds no chunks
+	testStoreFile(t, repo, "web1.vmdk", data)
+	require.Equal(t, stored, repo.NewChunks)
+
+	var buf bytes.Buffer
+	require.Nil(t, repo.WriteFile(&buf, file))
+	require.Equal(t, data, buf.Bytes())
+
+	reader := repo.openFile(&file)
+	block := make([]byte, 1000)
+	n, err := reader.ReadAt(block, 5*MB-500)
+	require.Nil(t, err)
+	require.Equal(t, data[5*MB-500:5*MB+500], block[:n])
+	n, err = reader.ReadAt(block, int64(len(data))-10)
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, data[len(data)-10:], block[:n])
+
+	snapshot := RepoSnapshot{Version: REPO_VERSION, ID: "20261016T021500Z", Name: "web1", Files: []RepoFile{file}}
+	require.Nil(t, repo.SaveSnapshot(&snapshot))
+	require.NotNil(t, repo.SaveSnapshot(&snapshot))
+	loaded, err := repo.LoadSnapshot("web1", "")
+	require.Nil(t, err)
+	require.Equal(t, snapshot.Files, loaded.Files)
+	require.Equal(t, []BackupFile{file.BackupFile}, loaded.Manifest().Files)
+
+	// a corrupt chunk fails the restore checksum
+	chunkFile := repo.chunkPath(file.Chunks[0].SHA256)
+	require.Nil(t, os.WriteFile(chunkFile, repo.encoder.EncodeAll([]byte("corrupt"), nil), 0600))
+	require.NotNil(t, repo.WriteFile(io.Discard, file))
+}
+
+func TestRetainSnapshots(t *testing.T) {
+	ids := []string{}
+	start := time.Date(2026, 9, 1, 2, 0, 0, 0, time.UTC)
+	for day := 0; day < 30; day++ {
+		for _, hour := range []int{0, 12} {
+			ids = append(ids, start.AddDate(0, 0, day).Add(time.Duration(hour)*time.Hour).Format(SNAPSHOT_ID_FORMAT))
+		}
+	}
+	keep := retainSnapshots(ids, RetentionPolicy{KeepDaily: 7, KeepWeekly: 4})
+	// the latest of the last 7 days, plus the latest of the 2 weeks before them
+	require.Len(t, keep, 9)
+	require.True(t, keep["20260913T140000Z"])
+	require.True(t, keep[ids[len(ids)-1]])
+	require.False(t, keep[ids[len(ids)-2]])
+	require.False(t, keep[ids[0]])
+
+	keep = retainSnapshots(append(ids, "manual"), RetentionPolicy{KeepDaily: 1})
+	require.Len(t, keep, 2)
+	require.True(t, keep["manual"])
+}
+
+func TestRepositoryPrune(t *testing.T) {
+	initTestConfig(t)
+	repo, err := OpenRepository(t.TempDir(), true)
+	require.Nil(t, err)
+	defer repo.Close()
+	old := testStoreFile(t, repo, "web1.vmdk", testRandomData(4, int(2*MB)))
+	current := testStoreFile(t, repo, "web1.vmdk", testRandomData(5, int(2*MB)))
+	for id, file := range map[string]RepoFile{"20261001T020000Z": old, "20261015T020000Z": current} {
+		require.Nil(t, repo.SaveSnapshot(&RepoSnapshot{Version: REPO_VERSION, ID: id, Name: "web1", Files: []RepoFile{file}}))
+	}
+
+	_, err = repo.Prune("", RetentionPolicy{})
+	require.NotNil(t, err)
+	_, err = repo.Prune("", RetentionPolicy{KeepDaily: 1})
+	require.Nil(t, err)
+	ids, err := repo.SnapshotIDs("web1")
+	require.Nil(t, err)
+	require.Equal(t, []string{"20261015T020000Z"}, ids)
+	require.False(t, IsFile(repo.chunkPath(old.Chunks[0].SHA256)))
+	require.Nil(t, repo.WriteFile(io.Discard, current))
+}
+
+func TestBlockHashCommand(t *testing.T) {
+	blockSize := int(BLOCK_SIZE)
+	data := testRandomData(7, blockSize*2+100)
+	hostPath := filepath.Join(t.TempDir(), "Ubuntu 64-bit.vmdk")
+	require.Nil(t, os.WriteFile(hostPath, data, 0600))
+	expected := []string{}
+	for i := 0; i < len(data); i += blockSize {
+		sum := sha256.Sum256(data[i:min(i+blockSize, len(data))])
+		expected = append(expected, hex.EncodeToString(sum[:]))
+	}
+	for _, remote := range []string{"linux", "darwin"} {
+		command := blockHashCommand(remote, hostPath)
+		name := strings.Fields(command)[0]
+		if _, err := exec.LookPath(name); err != nil {
+			t.Logf("skipping %s: %v", remote, err)
+			continue
+		}
+		output, err := exec.Command("sh", "-c", command).Output()
+		require.Nil(t, err, command)
+		hashes, err := parseBlockHashes(strings.Split(string(output), "\n"), int64(len(expected)))
+		require.Nil(t, err)
+		require.Equal(t, expected, hashes, remote)
+	}
+}
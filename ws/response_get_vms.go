@@ -3,5 +3,5 @@ package ws
 type VmRestGetVmsResponse []struct {
 	ID   string `json:"id,omitzero"`
 	Path string `json:"path,omitzero"`
-	Name string `json: "name,omitzero"`
+	Name string `json:"name,omitzero"`
 }
@@ -0,0 +1,110 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+)
+
+const SEED_VOLUME_ID = "cidata"
+
+type SeedOptions struct {
+	UserData      []byte
+	MetaData      []byte
+	NetworkConfig []byte
+	Remove        bool
+}
+
+// return the NoCloud seed ISO filename in the instance directory
+func SeedISOFilename(vm *VM) string {
+	return vm.Name + "-seed.iso"
+}
+
+// return a cloud-init NoCloud 'cidata' ISO image; meta-data defaults to the instance name
+func NewSeedISO(name string, options SeedOptions) ([]byte, error) {
+	metaData := options.MetaData
+	if len(metaData) == 0 {
+		metaData = []byte(fmt.Sprintf("instance-id: %s\nlocal-hostname: %s\n", name, name))
+	}
+	files := []ISOFile{
+		{Name: "user-data", Data: options.UserData},
+		{Name: "meta-data", Data: metaData},
+	}
+	if len(options.NetworkConfig) > 0 {
+		files = append(files, ISOFile{Name: "network-config", Data: options.NetworkConfig})
+	}
+	image, err := NewISOImage(SEED_VOLUME_ID, files)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	return image, nil
+}
+
+func (v *vmctl) Seed(ctx context.Context, vid string, options SeedOptions) (string, error) {
+	if v.debug {
+		log.Printf("Seed(%s, remove=%v)\n", vid, options.Remove)
+	}
+	vm, err := v.cli.GetVM(ctx, vid)
+	if err != nil {
+		return "", wrap(err)
+	}
+	err = v.requirePowerState(ctx, &vm, "off", "attach seed media")
+	if err != nil {
+		return "", wrap(err)
+	}
+	filename := SeedISOFilename(&vm)
+	if options.Remove {
+		_, err := v.Modify(ctx, vm.Name, CreateOptions{ModifySeedISO: true}, IsoOptions{})
+		if err != nil {
+			return "", wrap(err)
+		}
+		err = v.removeSeedISO(ctx, &vm, filename)
+		if err != nil {
+			return "", wrap(err)
+		}
+		return "seed removed", nil
+	}
+	if len(options.UserData) == 0 {
+		return "", Fatalf("missing user-data")
+	}
+	image, err := NewSeedISO(vm.Name, options)
+	if err != nil {
+		return "", wrap(err)
+	}
+	if v.verbose {
+		fmt.Printf("[%s] Uploading seed ISO %s (%d bytes)\n", vm.Name, filename, len(image))
+	}
+	err = v.WriteHostFile(ctx, &vm, filename, image)
+	if err != nil {
+		return "", wrap(err)
+	}
+	_, err = v.Modify(ctx, vm.Name, CreateOptions{ModifySeedISO: true, SeedISOFile: filename}, IsoOptions{})
+	if err != nil {
+		return "", wrap(err)
+	}
+	return "seeded", nil
+}
+
+// delete the seed ISO from the instance directory if it is present
+func (v *vmctl) removeSeedISO(ctx context.Context, vm *VM, filename string) error {
+	files, err := v.instanceFiles(ctx, vm)
+	if err != nil {
+		return wrap(err)
+	}
+	for _, file := range files {
+		if strings.EqualFold(file.Name, filename) {
+			if v.verbose {
+				fmt.Printf("[%s] Deleting seed ISO %s\n", vm.Name, filename)
+			}
+			dir, _ := path.Split(vm.Path)
+			err := v.removeHostFile(ctx, path.Join(dir, filename))
+			if err != nil {
+				return wrap(err)
+			}
+			return nil
+		}
+	}
+	return nil
+}
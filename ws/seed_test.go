@@ -0,0 +1,30 @@
+package ws
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSeedRemove(t *testing.T) {
+	ctx := t.Context()
+	v, host := newFakeController(t)
+	_, err := v.Create(ctx, "web1", *NewCreateOptions(), IsoOptions{})
+	require.Nil(t, err)
+
+	result, err := v.Seed(ctx, "web1", SeedOptions{UserData: []byte("#cloud-config\n")})
+	require.Nil(t, err)
+	require.Equal(t, "seeded", result)
+	require.True(t, IsFile(host.local("/vmware/web1/web1-seed.iso")))
+
+	result, err = v.Seed(ctx, "web1", SeedOptions{Remove: true})
+	require.Nil(t, err)
+	require.Equal(t, "seed removed", result)
+	require.False(t, IsFile(host.local("/vmware/web1/web1-seed.iso")))
+	doc, err := host.readVMX("/vmware/web1/web1.vmx")
+	require.Nil(t, err)
+	require.False(t, doc.GetBool("ide1:1.present", false))
+
+	// removing a seed that was never written is not an error
+	_, err = v.Seed(ctx, "web1", SeedOptions{Remove: true})
+	require.Nil(t, err)
+}
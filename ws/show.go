@@ -1,6 +1,7 @@
 package ws
 
 import (
+	"context"
 	"log"
 	"strings"
 )
@@ -10,7 +11,7 @@ type ShowOptions struct {
 	Detail  bool
 }
 
-func (v *vmctl) Show(name string, options ShowOptions) (*[]VMState, error) {
+func (v *vmctl) Show(ctx context.Context, name string, options ShowOptions) (*[]VMState, error) {
 	if v.debug {
 		log.Printf("Show(%s, %+v)\n", name, options)
 	}
@@ -19,31 +20,31 @@ func (v *vmctl) Show(name string, options ShowOptions) (*[]VMState, error) {
 
 	if name == "" && options.Running {
 		// we only need the running vms, so spoof vids with only the Name using vmrun output
-		olines, err := v.RemoteExec("vmrun list", nil)
+		olines, err := v.RemoteExec(ctx, "vmrun list", nil)
 		if err != nil {
-			return nil, Fatal(err)
+			return nil, wrap(err)
 		}
 		for _, line := range olines {
 			if !strings.HasPrefix(line, "Total running VMs:") {
 				runningName, err := PathToName(line)
 				if err != nil {
-					return nil, Fatal(err)
+					return nil, wrap(err)
 				}
 				vids = append(vids, &VID{Name: runningName})
 			}
 		}
 	} else {
 		// set vids from API
-		v, err := v.cli.GetVIDs()
+		v, err := v.cli.GetVIDs(ctx)
 		if err != nil {
-			return nil, Fatal(err)
+			return nil, wrap(err)
 		}
 		vids = v
 	}
 
 	selected := []*VID{}
 	for _, vid := range vids {
-		if name == "" || (strings.ToLower(name) == strings.ToLower(vid.Name)) {
+		if showNameMatch(name, vid.Name) {
 			selected = append(selected, vid)
 		}
 	}
@@ -51,9 +52,9 @@ func (v *vmctl) Show(name string, options ShowOptions) (*[]VMState, error) {
 	vms := make([]VMState, len(selected))
 	for i, vid := range selected {
 		if options.Detail {
-			state, err := v.GetState(vid.Name)
+			state, err := v.GetState(ctx, vid.Name)
 			if err != nil {
-				return nil, Fatal(err)
+				return nil, wrap(err)
 			}
 			vms[i] = *state
 		} else {
@@ -62,3 +63,8 @@ func (v *vmctl) Show(name string, options ShowOptions) (*[]VMState, error) {
 	}
 	return &vms, nil
 }
+
+// Show selects instances by name case-insensitively; an empty name selects all
+func showNameMatch(name, vmName string) bool {
+	return name == "" || strings.EqualFold(name, vmName)
+}
@@ -43,7 +43,7 @@ func SizeParse(param string) (int64, error) {
 	}
 	fsize, err := strconv.ParseFloat(number, 64)
 	if err != nil {
-		return 0, Fatal(err)
+		return 0, wrap(err)
 	}
 	size := int64(fsize * float64(multiplier))
 	//fmt.Printf("%s == %d\n", param, size)
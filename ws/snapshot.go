@@ -0,0 +1,183 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+var SNAPSHOT_TOTAL = regexp.MustCompile(`^Total snapshots:\s*(\d+)`)
+var SNAPSHOT_NAME_KEY = regexp.MustCompile(`(?i)^(snapshot\d+)\.displayName$`)
+
+type SnapshotOptions struct {
+	Wait           bool
+	PowerOn        bool
+	Background     bool
+	DeleteChildren bool
+}
+
+type Snapshot struct {
+	Name     string
+	Parent   string
+	Children []*Snapshot
+}
+
+// parse the output of 'vmrun listSnapshots VMX showTree' into a snapshot tree
+func ParseSnapshotTree(lines []string) ([]*Snapshot, error) {
+	roots := []*Snapshot{}
+	stack := []*Snapshot{}
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || SNAPSHOT_TOTAL.MatchString(line) {
+			continue
+		}
+		name := strings.TrimLeft(line, "\t")
+		depth := len(line) - len(name)
+		if depth > len(stack) {
+			return roots, Fatalf("unexpected snapshot tree indent: '%s'", line)
+		}
+		stack = stack[:depth]
+		snapshot := Snapshot{Name: name, Children: []*Snapshot{}}
+		if depth == 0 {
+			roots = append(roots, &snapshot)
+		} else {
+			parent := stack[depth-1]
+			snapshot.Parent = parent.Name
+			parent.Children = append(parent.Children, &snapshot)
+		}
+		stack = append(stack, &snapshot)
+	}
+	return roots, nil
+}
+
+func (v *vmctl) CreateSnapshot(ctx context.Context, vid, name string) (string, error) {
+	if v.debug {
+		log.Printf("CreateSnapshot(%s, %s)\n", vid, name)
+	}
+	vm, err := v.cli.GetVM(ctx, vid)
+	if err != nil {
+		return "", wrap(err)
+	}
+	if v.verbose {
+		fmt.Printf("[%s] Creating snapshot '%s'\n", vm.Name, name)
+	}
+	_, err = v.vmrun(ctx, &vm, "snapshot", name)
+	if err != nil {
+		return "", wrap(err)
+	}
+	return "snapshot created", nil
+}
+
+func (v *vmctl) ListSnapshots(ctx context.Context, vid string) ([]*Snapshot, error) {
+	if v.debug {
+		log.Printf("ListSnapshots(%s)\n", vid)
+	}
+	vm, err := v.cli.GetVM(ctx, vid)
+	if err != nil {
+		return []*Snapshot{}, wrap(err)
+	}
+	olines, err := v.vmrun(ctx, &vm, "listSnapshots", "showTree")
+	if err != nil {
+		return []*Snapshot{}, wrap(err)
+	}
+	snapshots, err := ParseSnapshotTree(olines)
+	if err != nil {
+		return []*Snapshot{}, wrap(err)
+	}
+	return snapshots, nil
+}
+
+func (v *vmctl) RevertSnapshot(ctx context.Context, vid, name string, options SnapshotOptions) (string, error) {
+	if v.debug {
+		log.Printf("RevertSnapshot(%s, %s, %+v)\n", vid, name, options)
+	}
+	vm, err := v.cli.GetVM(ctx, vid)
+	if err != nil {
+		return "", wrap(err)
+	}
+	state := ""
+	if options.Wait && !options.PowerOn {
+		state, err = v.snapshotPowerState(ctx, &vm, name)
+		if err != nil {
+			return "", wrap(err)
+		}
+	}
+	if v.verbose {
+		fmt.Printf("[%s] Reverting to snapshot '%s'\n", vm.Name, name)
+	}
+	_, err = v.vmrun(ctx, &vm, "revertToSnapshot", name)
+	if err != nil {
+		return "", wrap(err)
+	}
+	if v.verbose {
+		fmt.Printf("[%s] Revert request complete\n", vm.Name)
+	}
+	if options.PowerOn {
+		result, err := v.Start(ctx, vid, StartOptions{Background: options.Background, Wait: options.Wait}, IsoOptions{})
+		if err != nil {
+			return "", wrap(err)
+		}
+		return "reverted; " + result, nil
+	}
+	if options.Wait {
+		err = v.Wait(ctx, vid, state)
+		if err != nil {
+			return "", wrap(err)
+		}
+	}
+	return "reverted", nil
+}
+
+// return the power state recorded in a snapshot; the .vmsd file marks
+// snapshots of a running instance, which include its memory, with type 1
+func (v *vmctl) snapshotPowerState(ctx context.Context, vm *VM, name string) (string, error) {
+	data, err := v.ReadHostFile(ctx, vm, vm.Name+".vmsd")
+	if err != nil {
+		return "", wrap(err)
+	}
+	doc := ParseVMXDocument(data)
+	// vmrun accepts a snapshot path of the form parent/child
+	displayName := name[strings.LastIndex(name, "/")+1:]
+	for _, key := range doc.Keys() {
+		m := SNAPSHOT_NAME_KEY.FindStringSubmatch(key)
+		if m == nil {
+			continue
+		}
+		value, _ := doc.Get(key)
+		if value != displayName {
+			continue
+		}
+		snapshotType, _ := doc.Get(m[1] + ".type")
+		if snapshotType == "1" {
+			return "on", nil
+		}
+		return "off", nil
+	}
+	return "", wrapf("[%s] snapshot '%s' %w", vm.Name, name, ErrNotFound)
+}
+
+func (v *vmctl) DeleteSnapshot(ctx context.Context, vid, name string, options SnapshotOptions) (string, error) {
+	if v.debug {
+		log.Printf("DeleteSnapshot(%s, %s, %+v)\n", vid, name, options)
+	}
+	vm, err := v.cli.GetVM(ctx, vid)
+	if err != nil {
+		return "", wrap(err)
+	}
+	args := []string{name}
+	action := "Deleting snapshot"
+	if options.DeleteChildren {
+		args = append(args, "andDeleteChildren")
+		action = "Deleting snapshot and children"
+	}
+	if v.verbose {
+		fmt.Printf("[%s] %s '%s'\n", vm.Name, action, name)
+	}
+	_, err = v.vmrun(ctx, &vm, "deleteSnapshot", args...)
+	if err != nil {
+		return "", wrap(err)
+	}
+	return "snapshot deleted", nil
+}
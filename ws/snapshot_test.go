@@ -0,0 +1,82 @@
+package ws
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseSnapshotTree(t *testing.T) {
+
+	lines := []string{
+		"Total snapshots: 4",
+		"base",
+		"\tinstalled",
+		"\t\tconfigured",
+		"\tpatched",
+	}
+	snapshots, err := ParseSnapshotTree(lines)
+	require.Nil(t, err)
+	require.Len(t, snapshots, 1)
+	base := snapshots[0]
+	require.Equal(t, "base", base.Name)
+	require.Equal(t, "", base.Parent)
+	require.Len(t, base.Children, 2)
+	require.Equal(t, "installed", base.Children[0].Name)
+	require.Equal(t, "base", base.Children[0].Parent)
+	require.Equal(t, "configured", base.Children[0].Children[0].Name)
+	require.Equal(t, "installed", base.Children[0].Children[0].Parent)
+	require.Equal(t, "patched", base.Children[1].Name)
+	require.Equal(t, "base", base.Children[1].Parent)
+}
+
+func TestParseSnapshotTreeEmpty(t *testing.T) {
+	snapshots, err := ParseSnapshotTree([]string{"Total snapshots: 0"})
+	require.Nil(t, err)
+	require.Empty(t, snapshots)
+}
+
+func TestParseSnapshotTreeWindows(t *testing.T) {
+	lines := []string{
+		"Total snapshots: 2\r",
+		"one\r",
+		"two\r",
+	}
+	snapshots, err := ParseSnapshotTree(lines)
+	require.Nil(t, err)
+	require.Len(t, snapshots, 2)
+	require.Equal(t, "one", snapshots[0].Name)
+	require.Equal(t, "two", snapshots[1].Name)
+}
+
+func TestParseSnapshotTreeBadIndent(t *testing.T) {
+	_, err := ParseSnapshotTree([]string{"one", "\t\tthree"})
+	require.NotNil(t, err)
+}
+
+func TestRevertSnapshotWait(t *testing.T) {
+	ctx := t.Context()
+	v, host := newFakeController(t)
+	_, err := v.Create(ctx, "web1", *NewCreateOptions(), IsoOptions{})
+	require.Nil(t, err)
+	_, err = v.CreateSnapshot(ctx, "web1", "cold")
+	require.Nil(t, err)
+	_, err = v.Start(ctx, "web1", StartOptions{Background: true}, IsoOptions{})
+	require.Nil(t, err)
+	_, err = v.CreateSnapshot(ctx, "web1", "warm")
+	require.Nil(t, err)
+
+	// a snapshot of a running instance reverts to on, others to off
+	result, err := v.RevertSnapshot(ctx, "web1", "cold", SnapshotOptions{Wait: true})
+	require.Nil(t, err)
+	require.Equal(t, "reverted", result)
+	require.Equal(t, "off", host.PowerState("/vmware/web1/web1.vmx"))
+	state, err := v.snapshotPowerState(ctx, &VM{Name: "web1", Path: "/vmware/web1/web1.vmx"}, "warm")
+	require.Nil(t, err)
+	require.Equal(t, "on", state)
+	_, err = v.RevertSnapshot(ctx, "web1", "warm", SnapshotOptions{Wait: true})
+	require.Nil(t, err)
+	require.Equal(t, "on", host.PowerState("/vmware/web1/web1.vmx"))
+
+	_, err = v.RevertSnapshot(ctx, "web1", "missing", SnapshotOptions{Wait: true})
+	require.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,676 @@
+package ws
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is a declarative instance definition mapping onto CreateOptions and IsoOptions.
+// guest_os, disk, preallocated and single_file are only used when the instance is created.
+// mac is shorthand for a nics list configuring the MAC address of ethernet0.
+type Spec struct {
+	Name         string            `json:"name" yaml:"name"`
+	GuestOS      string            `json:"guest_os,omitempty" yaml:"guest_os,omitempty"`
+	Cpu          int               `json:"cpu,omitempty" yaml:"cpu,omitempty"`
+	Memory       string            `json:"memory,omitempty" yaml:"memory,omitempty"`
+	Disk         string            `json:"disk,omitempty" yaml:"disk,omitempty"`
+	Preallocated bool              `json:"preallocated,omitempty" yaml:"preallocated,omitempty"`
+	SingleFile   bool              `json:"single_file,omitempty" yaml:"single_file,omitempty"`
+	EFI          *bool             `json:"efi,omitempty" yaml:"efi,omitempty"`
+	TimeSync     *bool             `json:"time_sync,omitempty" yaml:"time_sync,omitempty"`
+	TimeZone     string            `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+	Clipboard    *bool             `json:"clipboard,omitempty" yaml:"clipboard,omitempty"`
+	MAC          string            `json:"mac,omitempty" yaml:"mac,omitempty"`
+	Nics         []SpecNIC         `json:"nics,omitempty" yaml:"nics,omitempty"`
+	ISO          *SpecISO          `json:"iso,omitempty" yaml:"iso,omitempty"`
+	Serial       *SpecSerial       `json:"serial,omitempty" yaml:"serial,omitempty"`
+	VNC          *SpecVNC          `json:"vnc,omitempty" yaml:"vnc,omitempty"`
+	Share        *SpecShare        `json:"share,omitempty" yaml:"share,omitempty"`
+	GuestInfo    map[string]string `json:"guestinfo,omitempty" yaml:"guestinfo,omitempty"`
+}
+
+// SpecNIC maps onto NICOptions for adapter ethernet<Index>; Remove deletes the
+// adapter, otherwise empty fields are neither compared nor changed.
+// Adapters not listed are left unchanged.
+type SpecNIC struct {
+	Index          int    `json:"index" yaml:"index"`
+	Remove         bool   `json:"remove,omitempty" yaml:"remove,omitempty"`
+	Connection     string `json:"connection,omitempty" yaml:"connection,omitempty"`
+	Device         string `json:"device,omitempty" yaml:"device,omitempty"`
+	MAC            string `json:"mac,omitempty" yaml:"mac,omitempty"`
+	StartConnected *bool  `json:"start_connected,omitempty" yaml:"start_connected,omitempty"`
+}
+
+// an empty File detaches the ISO
+type SpecISO struct {
+	File          string `json:"file" yaml:"file"`
+	BootConnected bool   `json:"boot_connected" yaml:"boot_connected"`
+	CA            string `json:"ca,omitempty" yaml:"ca,omitempty"`
+	Cert          string `json:"cert,omitempty" yaml:"cert,omitempty"`
+	Key           string `json:"key,omitempty" yaml:"key,omitempty"`
+}
+
+// an empty Pipe removes the serial port
+type SpecSerial struct {
+	Pipe   string `json:"pipe" yaml:"pipe"`
+	Client bool   `json:"client,omitempty" yaml:"client,omitempty"`
+	V2V    bool   `json:"v2v,omitempty" yaml:"v2v,omitempty"`
+}
+
+type SpecVNC struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	Port    int  `json:"port,omitempty" yaml:"port,omitempty"`
+}
+
+type SpecShare struct {
+	Enabled   bool   `json:"enabled" yaml:"enabled"`
+	HostPath  string `json:"host_path,omitempty" yaml:"host_path,omitempty"`
+	GuestPath string `json:"guest_path,omitempty" yaml:"guest_path,omitempty"`
+}
+
+type SpecChange struct {
+	Section string `json:"section"`
+	Have    string `json:"have"`
+	Want    string `json:"want"`
+}
+
+type SpecPlan struct {
+	Name    string       `json:"name"`
+	Create  bool         `json:"create"`
+	Changes []SpecChange `json:"changes"`
+}
+
+type ApplyOptions struct {
+	Wait bool
+}
+
+// sections compared against an existing instance, in output order
+var SpecSections = []string{"cpu", "memory", "efi", "time_sync", "timezone", "clipboard", "nic", "iso", "serial", "vnc", "share"}
+
+// parse a YAML or JSON spec document
+func ParseSpec(data []byte) (*Spec, error) {
+	var spec Spec
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err := decoder.Decode(&spec)
+	if err != nil {
+		return nil, Fatalf("failed parsing spec: %v", err)
+	}
+	if spec.Name == "" {
+		return nil, Fatalf("spec is missing name")
+	}
+	if spec.MAC != "" {
+		if spec.Nics != nil {
+			return nil, Fatalf("spec mac and nics are mutually exclusive")
+		}
+		spec.Nics = []SpecNIC{{Index: 0, MAC: spec.MAC}}
+		if strings.ToLower(spec.MAC) == "none" {
+			spec.Nics = []SpecNIC{{Index: 0, Remove: true}}
+		}
+		spec.MAC = ""
+	}
+	indexes := make(map[int]bool)
+	for _, nic := range spec.Nics {
+		if nic.Index < 0 || nic.Index >= MAX_NICS {
+			return nil, Fatalf("invalid spec NIC index: %d", nic.Index)
+		}
+		if indexes[nic.Index] {
+			return nil, Fatalf("duplicate spec NIC index: %d", nic.Index)
+		}
+		indexes[nic.Index] = true
+	}
+	sort.Slice(spec.Nics, func(i, j int) bool {
+		return spec.Nics[i].Index < spec.Nics[j].Index
+	})
+	return &spec, nil
+}
+
+func FormatSpec(spec *Spec) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	err := encoder.Encode(spec)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	err = encoder.Close()
+	if err != nil {
+		return nil, wrap(err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (n *SpecNIC) options() NICOptions {
+	if n.Remove {
+		return NICOptions{Index: n.Index, Remove: true}
+	}
+	options := NICOptions{Index: n.Index, Connection: n.Connection, Device: n.Device, MacAddress: n.MAC}
+	if n.StartConnected != nil {
+		options.ModifyStartConnected = true
+		options.StartConnected = *n.StartConnected
+	}
+	return options
+}
+
+// return the spec adapter for ethernet<index>, or nil if it is not listed
+func (s *Spec) nic(index int) *SpecNIC {
+	for i := range s.Nics {
+		if s.Nics[i].Index == index {
+			return &s.Nics[i]
+		}
+	}
+	return nil
+}
+
+// return the NIC options for the adapters listed in the spec, in index order
+func (s *Spec) NICOptions() []NICOptions {
+	nics := []NICOptions{}
+	for _, nic := range s.Nics {
+		nics = append(nics, nic.options())
+	}
+	return nics
+}
+
+// set the options for a spec section; unspecified sections are ignored
+func (s *Spec) setOptions(section string, options *CreateOptions, isoOptions *IsoOptions) {
+	switch section {
+	case "cpu":
+		if s.Cpu == 0 {
+			return
+		}
+		options.ModifyCpu = true
+		options.CpuCount = s.Cpu
+	case "memory":
+		if s.Memory == "" {
+			return
+		}
+		options.ModifyMemory = true
+		options.MemorySize = s.Memory
+	case "efi":
+		if s.EFI == nil {
+			return
+		}
+		options.ModifyEFI = true
+		options.EFIBoot = *s.EFI
+	case "time_sync":
+		if s.TimeSync == nil {
+			return
+		}
+		options.ModifyTimeSync = true
+		options.HostTimeSync = *s.TimeSync
+	case "timezone":
+		if s.TimeZone == "" {
+			return
+		}
+		options.ModifyTimeZone = true
+		options.GuestTimeZone = s.TimeZone
+	case "clipboard":
+		if s.Clipboard == nil {
+			return
+		}
+		options.ModifyClipboard = true
+		options.ClipboardEnabled = *s.Clipboard
+	case "iso":
+		if s.ISO == nil {
+			return
+		}
+		isoOptions.ModifyISO = true
+		isoOptions.IsoPresent = s.ISO.File != ""
+		isoOptions.IsoFile = s.ISO.File
+		isoOptions.IsoBootConnected = s.ISO.BootConnected
+		isoOptions.IsoCA = s.ISO.CA
+		isoOptions.IsoClientCert = s.ISO.Cert
+		isoOptions.IsoClientKey = s.ISO.Key
+	case "serial":
+		if s.Serial == nil {
+			return
+		}
+		options.ModifyTTY = true
+		options.SerialPipe = s.Serial.Pipe
+		options.SerialClient = s.Serial.Client
+		options.SerialV2V = s.Serial.V2V
+	case "vnc":
+		if s.VNC == nil {
+			return
+		}
+		options.ModifyVNC = true
+		options.VNCEnabled = s.VNC.Enabled
+		options.VNCPort = s.VNC.Port
+		if options.VNCPort == 0 {
+			options.VNCPort = 5900
+		}
+	case "share":
+		if s.Share == nil {
+			return
+		}
+		options.ModifyShare = true
+		options.FileShareEnabled = s.Share.Enabled
+		options.SharedHostPath = s.Share.HostPath
+		options.SharedGuestPath = s.Share.GuestPath
+	}
+}
+
+// return the options for creating the instance described by the spec
+func (s *Spec) CreateOptions() (*CreateOptions, *IsoOptions) {
+	options := NewCreateOptions()
+	isoOptions := IsoOptions{}
+	if s.GuestOS != "" {
+		options.GuestOS = s.GuestOS
+	}
+	if s.Disk != "" {
+		options.DiskSize = s.Disk
+	}
+	options.DiskPreallocated = s.Preallocated
+	options.DiskSingleFile = s.SingleFile
+	for _, section := range SpecSections {
+		s.setOptions(section, options, &isoOptions)
+	}
+	// Create configures ethernet0; the other adapters are added by Modify
+	nic := s.nic(0)
+	if nic != nil {
+		options.NIC = nic.options()
+	}
+	if len(s.GuestInfo) > 0 {
+		options.ModifyGuestInfo = true
+		options.GuestInfo = s.GuestInfo
+	}
+	return options, &isoOptions
+}
+
+// return the options modifying only the sections in changes; adapter
+// changes are returned by ModifyNICOptions
+func (s *Spec) ModifyOptions(changes []SpecChange) (*CreateOptions, *IsoOptions) {
+	options := CreateOptions{}
+	isoOptions := IsoOptions{}
+	for _, change := range changes {
+		if strings.HasPrefix(change.Section, "nic:") {
+			continue
+		}
+		key, isGuestInfo := strings.CutPrefix(change.Section, "guestinfo:")
+		if isGuestInfo {
+			if options.GuestInfo == nil {
+				options.GuestInfo = make(map[string]string)
+			}
+			options.ModifyGuestInfo = true
+			options.GuestInfo[key] = s.GuestInfo[key]
+			continue
+		}
+		s.setOptions(change.Section, &options, &isoOptions)
+	}
+	return &options, &isoOptions
+}
+
+// return the NIC options for the adapter sections in changes
+func (s *Spec) ModifyNICOptions(changes []SpecChange) []NICOptions {
+	nics := []NICOptions{}
+	for _, change := range changes {
+		index, isNIC := strings.CutPrefix(change.Section, "nic:")
+		if !isNIC {
+			continue
+		}
+		i, err := strconv.Atoi(index)
+		if err != nil {
+			continue
+		}
+		nic := s.nic(i)
+		if nic != nil {
+			options := nic.options()
+			// auto accepts the current address; new adapters default to a generated one
+			if strings.ToLower(options.MacAddress) == "auto" {
+				options.MacAddress = ""
+			}
+			nics = append(nics, options)
+		}
+	}
+	return nics
+}
+
+// return a connection in the form exported by ExportSpec, so differently
+// written connections to the same network compare equal
+func normalizeConnection(connection string) string {
+	connectionType, vnet, pvnID, err := parseNICConnection("linux", connection)
+	if err != nil {
+		return strings.ToLower(connection)
+	}
+	switch connectionType {
+	case "custom":
+		return "vmnet" + NIC_VNET_PATTERN.FindStringSubmatch(vnet)[1]
+	case "pvn":
+		return "segment:" + pvnID
+	}
+	return connectionType
+}
+
+// return the comparable value of adapter have, comparing only the fields set in want;
+// have is nil if the adapter is not present
+func nicValue(want, have *SpecNIC) string {
+	if have == nil || have.Remove {
+		return "none"
+	}
+	if want.Remove {
+		return "present"
+	}
+	fields := []string{}
+	if want.Connection != "" {
+		fields = append(fields, "connection="+normalizeConnection(have.Connection))
+	}
+	if want.Device != "" {
+		fields = append(fields, "device="+strings.ToLower(have.Device))
+	}
+	// auto accepts any address
+	if want.MAC != "" && strings.ToLower(want.MAC) != "auto" {
+		fields = append(fields, "mac="+strings.ToLower(have.MAC))
+	}
+	if want.StartConnected != nil {
+		fields = append(fields, "start_connected="+formatBool(have.StartConnected))
+	}
+	if len(fields) == 0 {
+		return "present"
+	}
+	return strings.Join(fields, " ")
+}
+
+func formatBool(value *bool) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatBool(*value)
+}
+
+func normalizeSpecPath(pathname string) string {
+	if pathname == "" {
+		return ""
+	}
+	normalized, err := PathNormalize(pathname)
+	if err != nil {
+		return pathname
+	}
+	return normalized
+}
+
+// return the comparable value of a spec section, or false if the section is not specified
+func (v *vmctl) specValue(s *Spec, section string) (string, bool) {
+	switch section {
+	case "cpu":
+		return strconv.Itoa(s.Cpu), s.Cpu != 0
+	case "memory":
+		size, err := SizeParse(s.Memory)
+		if err != nil {
+			return s.Memory, s.Memory != ""
+		}
+		return FormatSize(size), s.Memory != ""
+	case "efi":
+		return formatBool(s.EFI), s.EFI != nil
+	case "time_sync":
+		return formatBool(s.TimeSync), s.TimeSync != nil
+	case "timezone":
+		return s.TimeZone, s.TimeZone != ""
+	case "clipboard":
+		return formatBool(s.Clipboard), s.Clipboard != nil
+	case "iso":
+		if s.ISO == nil {
+			return "", false
+		}
+		if s.ISO.File == "" {
+			return "none", true
+		}
+		file := s.ISO.File
+		if strings.HasPrefix(file, "http:") || strings.HasPrefix(file, "https:") {
+			_, file = path.Split(file)
+		}
+		formatted, err := FormatIsoPathname(v.IsoPath, file)
+		if err == nil {
+			file = formatted
+		}
+		return fmt.Sprintf("%s boot_connected=%v", normalizeSpecPath(file), s.ISO.BootConnected), true
+	case "serial":
+		if s.Serial == nil {
+			return "", false
+		}
+		if s.Serial.Pipe == "" {
+			return "none", true
+		}
+		// compare the pipe name only; the instance pipe pathname is formatted for the host
+		return fmt.Sprintf("%s client=%v v2v=%v", path.Base(normalizeSpecPath(s.Serial.Pipe)), s.Serial.Client, s.Serial.V2V), true
+	case "vnc":
+		if s.VNC == nil {
+			return "", false
+		}
+		if !s.VNC.Enabled {
+			return "disabled", true
+		}
+		port := s.VNC.Port
+		if port == 0 {
+			port = 5900
+		}
+		return fmt.Sprintf("port=%d", port), true
+	case "share":
+		if s.Share == nil {
+			return "", false
+		}
+		if !s.Share.Enabled {
+			return "disabled", true
+		}
+		return fmt.Sprintf("host=%s guest=%s", normalizeSpecPath(s.Share.HostPath), s.Share.GuestPath), true
+	}
+	return "", false
+}
+
+// compare the desired spec against the current spec of an existing instance
+func (v *vmctl) DiffSpec(want, have *Spec) []SpecChange {
+	changes := []SpecChange{}
+	for _, section := range SpecSections {
+		if section == "nic" {
+			for _, nic := range want.Nics {
+				wantValue := nicValue(&nic, &nic)
+				haveValue := nicValue(&nic, have.nic(nic.Index))
+				if wantValue != haveValue {
+					changes = append(changes, SpecChange{Section: fmt.Sprintf("nic:%d", nic.Index), Have: haveValue, Want: wantValue})
+				}
+			}
+			continue
+		}
+		wantValue, ok := v.specValue(want, section)
+		if !ok {
+			continue
+		}
+		haveValue, _ := v.specValue(have, section)
+		if wantValue != haveValue {
+			changes = append(changes, SpecChange{Section: section, Have: haveValue, Want: wantValue})
+		}
+	}
+	keys := []string{}
+	for key := range want.GuestInfo {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		wantValue := want.GuestInfo[key]
+		haveValue := have.GuestInfo[GuestInfoKey(key)]
+		if wantValue != haveValue {
+			changes = append(changes, SpecChange{Section: "guestinfo:" + key, Have: haveValue, Want: wantValue})
+		}
+	}
+	return changes
+}
+
+// return a spec describing an existing instance
+func (v *vmctl) ExportSpec(ctx context.Context, vid string) (*Spec, error) {
+	if v.debug {
+		log.Printf("ExportSpec(%s)\n", vid)
+	}
+	vm, err := v.cli.GetVM(ctx, vid)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	err = v.queryVM(ctx, &vm, QueryTypeConfig)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	config, err := v.cli.GetParams(ctx, &vm)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	param := func(key string) string {
+		value, err := v.cli.GetString(config, key, false)
+		if err != nil {
+			return ""
+		}
+		return value
+	}
+	boolParam := func(key string) *bool {
+		value := param(key)
+		if value == "" {
+			return nil
+		}
+		b := strings.ToUpper(value) == "TRUE"
+		return &b
+	}
+
+	efi := param("firmware") == "efi"
+	spec := Spec{
+		Name:      vm.Name,
+		GuestOS:   param("guestOS"),
+		Cpu:       vm.CpuCount,
+		Memory:    vm.RamSize,
+		Disk:      vm.DiskSize,
+		EFI:       &efi,
+		TimeSync:  boolParam("tools.syncTime"),
+		TimeZone:  param("guestTimeZone"),
+		Clipboard: &vm.ClipboardEnabled,
+	}
+
+	spec.Nics = []SpecNIC{}
+	if len(vm.Nics) == 0 || vm.Nics[0].Index != 0 {
+		// keep Create from adding the default ethernet0
+		spec.Nics = append(spec.Nics, SpecNIC{Index: 0, Remove: true})
+	}
+	for _, nic := range vm.Nics {
+		specNIC := SpecNIC{
+			Index:          nic.Index,
+			Connection:     nic.Connection,
+			Device:         nic.Device,
+			MAC:            "auto",
+			StartConnected: &nic.StartConnected,
+		}
+		switch nic.Connection {
+		case "custom":
+			specNIC.Connection = nic.Network
+		case "segment":
+			specNIC.Connection = "segment:" + nic.Network
+		}
+		if nic.StaticMac {
+			specNIC.MAC = nic.MacAddress
+		}
+		spec.Nics = append(spec.Nics, specNIC)
+	}
+
+	if vm.IsoAttached {
+		spec.ISO = &SpecISO{File: vm.IsoFile, BootConnected: vm.IsoAttachOnStart}
+	} else {
+		spec.ISO = &SpecISO{}
+	}
+
+	spec.Serial = &SpecSerial{}
+	if vm.SerialAttached {
+		spec.Serial.Pipe = vm.SerialPipe
+		spec.Serial.Client = param("serial0.pipe.endPoint") == "client"
+		spec.Serial.V2V = strings.ToUpper(param("serial0.tryNoRxLoss")) == "FALSE"
+	}
+
+	spec.VNC = &SpecVNC{Enabled: vm.VncEnabled}
+	if vm.VncEnabled {
+		spec.VNC.Port = vm.VncPort
+		if spec.VNC.Port == 0 {
+			spec.VNC.Port = 5900
+		}
+	}
+
+	spec.Share = &SpecShare{Enabled: vm.FileShareEnabled && param("sharedFolder0.present") != ""}
+	if spec.Share.Enabled {
+		spec.Share.HostPath = normalizeSpecPath(param("sharedFolder0.hostPath"))
+		spec.Share.GuestPath = param("sharedFolder0.guestName")
+	}
+
+	guestInfo, err := v.readGuestInfoVMX(ctx, &vm)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if len(guestInfo) > 0 {
+		spec.GuestInfo = guestInfo
+	}
+	return &spec, nil
+}
+
+// return the changes required to bring the instance in line with the spec
+func (v *vmctl) PlanSpec(ctx context.Context, spec *Spec) (*SpecPlan, error) {
+	if v.debug {
+		log.Printf("PlanSpec(%s)\n", spec.Name)
+	}
+	plan := SpecPlan{Name: spec.Name, Changes: []SpecChange{}}
+	_, err := v.cli.GetVM(ctx, spec.Name)
+	if errors.Is(err, ErrNotFound) {
+		plan.Create = true
+		return &plan, nil
+	}
+	if err != nil {
+		return nil, wrap(err)
+	}
+	have, err := v.ExportSpec(ctx, spec.Name)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	plan.Changes = v.DiffSpec(spec, have)
+	return &plan, nil
+}
+
+// create the instance if it is missing, otherwise modify the sections that differ from the spec
+func (v *vmctl) ApplySpec(ctx context.Context, spec *Spec, options ApplyOptions) (*SpecPlan, error) {
+	if v.debug {
+		log.Printf("ApplySpec(%s, %+v)\n", spec.Name, options)
+	}
+	plan, err := v.PlanSpec(ctx, spec)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	nics := []NICOptions{}
+	if plan.Create {
+		createOptions, isoOptions := spec.CreateOptions()
+		createOptions.Wait = options.Wait
+		_, err := v.Create(ctx, spec.Name, *createOptions, *isoOptions)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		for _, nic := range spec.NICOptions() {
+			if nic.Index != 0 {
+				nics = append(nics, nic)
+			}
+		}
+	} else if len(plan.Changes) > 0 {
+		nics = spec.ModifyNICOptions(plan.Changes)
+		if len(nics) < len(plan.Changes) {
+			modifyOptions, isoOptions := spec.ModifyOptions(plan.Changes)
+			_, err = v.Modify(ctx, spec.Name, *modifyOptions, *isoOptions)
+			if err != nil {
+				return nil, wrap(err)
+			}
+		}
+	}
+	// CreateOptions has a single adapter, so each adapter is a separate Modify
+	for _, nic := range nics {
+		_, err := v.Modify(ctx, spec.Name, CreateOptions{ModifyNIC: true, NIC: nic}, IsoOptions{})
+		if err != nil {
+			return nil, wrap(err)
+		}
+	}
+	return plan, nil
+}
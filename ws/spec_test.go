@@ -0,0 +1,172 @@
+package ws
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const testSpecYAML = `
+name: test
+cpu: 2
+memory: 4G
+efi: true
+mac: auto
+vnc:
+  enabled: true
+  port: 5901
+guestinfo:
+  metadata: e30K
+  metadata.encoding: base64
+`
+
+func TestParseSpec(t *testing.T) {
+	spec, err := ParseSpec([]byte(testSpecYAML))
+	require.Nil(t, err)
+	require.Equal(t, "test", spec.Name)
+	require.Equal(t, 2, spec.Cpu)
+	require.True(t, *spec.EFI)
+	require.Nil(t, spec.TimeSync)
+	require.Equal(t, 5901, spec.VNC.Port)
+
+	jspec, err := ParseSpec([]byte(`{"name": "test", "cpu": 2, "serial": {"pipe": "/tmp/tty"}}`))
+	require.Nil(t, err)
+	require.Equal(t, "/tmp/tty", jspec.Serial.Pipe)
+
+	_, err = ParseSpec([]byte("name: test\ncpus: 2\n"))
+	require.NotNil(t, err)
+	_, err = ParseSpec([]byte("cpu: 2\n"))
+	require.NotNil(t, err)
+
+	data, err := FormatSpec(spec)
+	require.Nil(t, err)
+	rspec, err := ParseSpec(data)
+	require.Nil(t, err)
+	require.Equal(t, spec, rspec)
+}
+
+func TestDiffSpec(t *testing.T) {
+	initTestConfig(t)
+	v := vmctl{IsoPath: "/iso"}
+	want, err := ParseSpec([]byte(testSpecYAML))
+	require.Nil(t, err)
+	efi := true
+	have := Spec{
+		Name:      "test",
+		Cpu:       2,
+		Memory:    "2G",
+		EFI:       &efi,
+		Nics:      []SpecNIC{{Index: 0, Connection: "nat", MAC: "00:50:56:01:02:03"}},
+		VNC:       &SpecVNC{},
+		GuestInfo: map[string]string{"guestinfo.metadata": "e30K"},
+	}
+	changes := v.DiffSpec(want, &have)
+	sections := []string{}
+	for _, change := range changes {
+		sections = append(sections, change.Section)
+	}
+	require.Equal(t, []string{"memory", "vnc", "guestinfo:metadata.encoding"}, sections)
+
+	options, isoOptions := want.ModifyOptions(changes)
+	require.False(t, options.ModifyCpu)
+	require.False(t, options.ModifyNIC)
+	require.False(t, isoOptions.ModifyISO)
+	require.True(t, options.ModifyMemory)
+	require.Equal(t, "4G", options.MemorySize)
+	require.True(t, options.ModifyVNC)
+	require.Equal(t, 5901, options.VNCPort)
+	require.Equal(t, map[string]string{"metadata.encoding": "base64"}, options.GuestInfo)
+
+	have.Nics = nil
+	changes = v.DiffSpec(want, &have)
+	require.Equal(t, "nic:0", changes[1].Section)
+	require.Equal(t, "none", changes[1].Have)
+
+	// adapters are compared on the fields the spec sets; unlisted adapters are unchanged
+	connected := false
+	have.Nics = []SpecNIC{
+		{Index: 0, Connection: "nat", Device: "e1000", MAC: "auto"},
+		{Index: 1, Connection: "vmnet2", Device: "e1000", MAC: "auto"},
+		{Index: 2, Connection: "bridged", Device: "e1000", MAC: "auto"},
+	}
+	want.Memory = "2G"
+	want.VNC = &SpecVNC{}
+	want.GuestInfo = nil
+	want.Nics = []SpecNIC{
+		{Index: 0, MAC: "auto"},
+		{Index: 1, Connection: "VMnet3", Device: "vmxnet3"},
+		{Index: 3, Connection: "hostonly", StartConnected: &connected},
+	}
+	changes = v.DiffSpec(want, &have)
+	sections = []string{}
+	for _, change := range changes {
+		sections = append(sections, change.Section)
+	}
+	require.Equal(t, []string{"nic:1", "nic:3"}, sections)
+	require.Equal(t, "connection=vmnet2 device=e1000", changes[0].Have)
+	require.Equal(t, "connection=vmnet3 device=vmxnet3", changes[0].Want)
+	nics := want.ModifyNICOptions(changes)
+	require.Equal(t, []NICOptions{
+		{Index: 1, Connection: "VMnet3", Device: "vmxnet3"},
+		{Index: 3, Connection: "hostonly", ModifyStartConnected: true},
+	}, nics)
+}
+
+func TestParseSpecNics(t *testing.T) {
+	spec, err := ParseSpec([]byte("name: test\nmac: none\n"))
+	require.Nil(t, err)
+	require.Equal(t, "", spec.MAC)
+	require.Equal(t, []SpecNIC{{Index: 0, Remove: true}}, spec.Nics)
+
+	spec, err = ParseSpec([]byte("name: test\nnics:\n- index: 1\n  connection: nat\n- index: 0\n  mac: auto\n"))
+	require.Nil(t, err)
+	require.Equal(t, 0, spec.Nics[0].Index)
+	require.Equal(t, 1, spec.Nics[1].Index)
+	options, _ := spec.CreateOptions()
+	require.Equal(t, NICOptions{MacAddress: "auto"}, options.NIC)
+
+	_, err = ParseSpec([]byte("name: test\nmac: auto\nnics:\n- index: 0\n"))
+	require.NotNil(t, err)
+	_, err = ParseSpec([]byte("name: test\nnics:\n- index: 1\n- index: 1\n"))
+	require.NotNil(t, err)
+}
+
+func TestPlanSpec(t *testing.T) {
+	ctx := t.Context()
+	v, host := newFakeController(t)
+	spec, err := ParseSpec([]byte(testSpecYAML))
+	require.Nil(t, err)
+
+	plan, err := v.PlanSpec(ctx, spec)
+	require.Nil(t, err)
+	require.True(t, plan.Create)
+
+	// a host failure is an error, not a missing instance
+	host.Fail = wrapf("%w: connection reset", ErrTransport)
+	_, err = v.PlanSpec(ctx, spec)
+	require.ErrorIs(t, err, ErrTransport)
+	_, err = v.ApplySpec(ctx, spec, ApplyOptions{})
+	require.ErrorIs(t, err, ErrTransport)
+}
+
+func TestApplySpecNics(t *testing.T) {
+	ctx := t.Context()
+	v, _ := newFakeController(t)
+	spec, err := ParseSpec([]byte("name: test\nnics:\n- index: 0\n  connection: nat\n- index: 1\n  connection: vmnet2\n  mac: 00:50:56:01:02:03\n"))
+	require.Nil(t, err)
+	_, err = v.ApplySpec(ctx, spec, ApplyOptions{})
+	require.Nil(t, err)
+
+	exported, err := v.ExportSpec(ctx, "test")
+	require.Nil(t, err)
+	require.Len(t, exported.Nics, 2)
+	require.Equal(t, "nat", exported.Nics[0].Connection)
+	require.Equal(t, "auto", exported.Nics[0].MAC)
+	require.Equal(t, "vmnet2", exported.Nics[1].Connection)
+	require.Equal(t, "00:50:56:01:02:03", exported.Nics[1].MAC)
+
+	// the exported spec applies without changes
+	plan, err := v.PlanSpec(ctx, exported)
+	require.Nil(t, err)
+	require.False(t, plan.Create)
+	require.Empty(t, plan.Changes)
+}
@@ -0,0 +1,303 @@
+package ws
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const SSH_DIAL_TIMEOUT = 30 * time.Second
+
+// nativeSSHExecutor runs commands in sessions of a single ssh connection and
+// transfers files with SFTP over the same connection; it is selected with the
+// sshclient shell and does not read ~/.ssh/config
+type nativeSSHExecutor struct {
+	v      *vmctl
+	client *ssh.Client
+	sftp   *sftp.Client
+	agent  net.Conn
+}
+
+func newNativeSSHExecutor(ctx context.Context, v *vmctl) (*nativeSSHExecutor, error) {
+	e := nativeSSHExecutor{v: v}
+	auth, err := e.authMethods()
+	if err != nil {
+		return nil, wrap(err)
+	}
+	hostKeyCallback, err := sshHostKeyCallback()
+	if err != nil {
+		e.Close()
+		return nil, wrap(err)
+	}
+	config := ssh.ClientConfig{
+		User:            v.Username,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         SSH_DIAL_TIMEOUT,
+	}
+	address := sshAddress(v.Hostname)
+	if v.debug {
+		log.Printf("ssh dial %s@%s\n", v.Username, address)
+	}
+	dialer := net.Dialer{Timeout: SSH_DIAL_TIMEOUT}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		e.Close()
+		return nil, wrapf("%w: ssh connection to %s failed: %v", ErrTransport, address, err)
+	}
+	// abort the handshake if ctx is done before it completes
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	clientConn, channels, requests, err := ssh.NewClientConn(conn, address, &config)
+	if !stop() {
+		err = ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		e.Close()
+		return nil, wrapf("%w: ssh connection to %s failed: %v", ErrTransport, address, err)
+	}
+	e.client = ssh.NewClient(clientConn, channels, requests)
+	return &e, nil
+}
+
+// return host:port, adding the default ssh port if hostname has no port
+func sshAddress(hostname string) string {
+	_, _, err := net.SplitHostPort(hostname)
+	if err == nil {
+		return hostname
+	}
+	return net.JoinHostPort(hostname, "22")
+}
+
+// return public key authentication using ssh_key and the ssh agent if SSH_AUTH_SOCK is set
+func (e *nativeSSHExecutor) authMethods() ([]ssh.AuthMethod, error) {
+	methods := []ssh.AuthMethod{}
+	if e.v.KeyFile != "" {
+		data, err := os.ReadFile(e.v.KeyFile)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		signer, err := ssh.ParsePrivateKey(data)
+		if err != nil {
+			return nil, Fatalf("ssh_key %s: %v; use an ssh agent for passphrase protected keys", e.v.KeyFile, err)
+		}
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket != "" {
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			log.Printf("WARNING: ssh agent connection failed: %v\n", err)
+		} else {
+			e.agent = conn
+			methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+	if len(methods) == 0 {
+		return nil, Fatalf("ssh authentication requires ssh_key or an ssh agent")
+	}
+	return methods, nil
+}
+
+// return a host key callback verifying against the ssh_known_hosts files
+func sshHostKeyCallback() (ssh.HostKeyCallback, error) {
+	files := ViperGetStringSlice("ssh_known_hosts")
+	if len(files) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, wrap(err)
+		}
+		files = []string{filepath.Join(home, ".ssh", "known_hosts")}
+	}
+	callback, err := knownhosts.New(files...)
+	if err != nil {
+		return nil, Fatalf("failed reading ssh known_hosts: %v", err)
+	}
+	return callback, nil
+}
+
+// run command in a new session, writing stdout to w and returning stderr;
+// the remote command is killed if ctx is done before it exits
+func (e *nativeSSHExecutor) run(ctx context.Context, w io.Writer, command string) (string, error) {
+	session, err := e.client.NewSession()
+	if err != nil {
+		return "", wrapf("%w: ssh session failed: %v", ErrTransport, err)
+	}
+	defer session.Close()
+	var stderr bytes.Buffer
+	session.Stdout = w
+	session.Stderr = &stderr
+	stop := context.AfterFunc(ctx, func() {
+		session.Signal(ssh.SIGKILL)
+		session.Close()
+	})
+	err = session.Run(command)
+	if !stop() {
+		return stderr.String(), wrap(ctx.Err())
+	}
+	var exitErr *ssh.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return stderr.String(), wrapf("%w: ssh command '%s' failed: %v", ErrTransport, redact(ctx, command), err)
+	}
+	return stderr.String(), err
+}
+
+func (e *nativeSSHExecutor) Exec(ctx context.Context, command string, exitCode *int) ([]string, error) {
+	if e.v.debug {
+		log.Printf("ssh Exec('%s', %v)\n", redact(ctx, command), exitCode)
+	}
+	var stdout bytes.Buffer
+	stderr, err := e.run(ctx, &stdout, command)
+	olines := e.v.outputLines(stdout.String(), stderr)
+	switch exitErr := err.(type) {
+	case nil:
+		if exitCode != nil {
+			*exitCode = 0
+		}
+	case *ssh.ExitError:
+		if exitCode == nil {
+			return olines, wrapf("ssh command '%s' exited %d\n%s", redact(ctx, command), exitErr.ExitStatus(), commandOutput(olines, stderr))
+		}
+		*exitCode = exitErr.ExitStatus()
+		log.Printf("WARNING: ssh command '%s' exited %d\n%s", redact(ctx, command), *exitCode, stderr)
+	default:
+		return olines, wrap(err)
+	}
+	return olines, nil
+}
+
+func (e *nativeSSHExecutor) Spawn(ctx context.Context, command string, exitCode *int) error {
+	_, err := e.Exec(ctx, command, exitCode)
+	if err != nil {
+		return wrap(err)
+	}
+	return nil
+}
+
+func (e *nativeSSHExecutor) Stream(ctx context.Context, w io.Writer, command string) error {
+	if e.v.debug {
+		log.Printf("ssh Stream('%s')\n", redact(ctx, command))
+	}
+	stderr, err := e.run(ctx, w, command)
+	if err != nil {
+		return wrapf("ssh command '%s' failed: %w\n%s", redact(ctx, command), err, stderr)
+	}
+	return nil
+}
+
+// return the SFTP client, starting the subsystem on first use
+func (e *nativeSSHExecutor) sftpClient() (*sftp.Client, error) {
+	if e.sftp == nil {
+		client, err := sftp.NewClient(e.client)
+		if err != nil {
+			return nil, wrapf("%w: sftp subsystem failed: %v", ErrTransport, err)
+		}
+		e.sftp = client
+	}
+	return e.sftp, nil
+}
+
+// return the SFTP form of a host pathname; windows drive paths become /C:/dir/file
+func (e *nativeSSHExecutor) sftpPath(hostPath string) string {
+	if e.v.Remote != "windows" {
+		return hostPath
+	}
+	sftpPath := strings.ReplaceAll(hostPath, "\\", "/")
+	if len(sftpPath) > 1 && sftpPath[1] == ':' {
+		sftpPath = "/" + sftpPath
+	}
+	return sftpPath
+}
+
+func (e *nativeSSHExecutor) Upload(ctx context.Context, hostDest, localSource string) error {
+	client, err := e.sftpClient()
+	if err != nil {
+		return wrap(err)
+	}
+	src, err := os.Open(localSource)
+	if err != nil {
+		return wrap(err)
+	}
+	defer src.Close()
+	dst, err := client.Create(e.sftpPath(hostDest))
+	if err != nil {
+		return wrapf("sftp create %s failed: %w", hostDest, err)
+	}
+	stop := context.AfterFunc(ctx, func() { src.Close() })
+	_, err = dst.ReadFrom(src)
+	if !stop() {
+		dst.Close()
+		return wrap(ctx.Err())
+	}
+	if err != nil {
+		dst.Close()
+		return wrap(err)
+	}
+	err = dst.Close()
+	if err != nil {
+		return wrap(err)
+	}
+	return nil
+}
+
+func (e *nativeSSHExecutor) Download(ctx context.Context, localDest, hostSource string) error {
+	client, err := e.sftpClient()
+	if err != nil {
+		return wrap(err)
+	}
+	src, err := client.Open(e.sftpPath(hostSource))
+	if err != nil {
+		return wrapf("sftp open %s failed: %w", hostSource, err)
+	}
+	defer src.Close()
+	dst, err := os.Create(localDest)
+	if err != nil {
+		return wrap(err)
+	}
+	stop := context.AfterFunc(ctx, func() { src.Close() })
+	_, err = src.WriteTo(dst)
+	if !stop() {
+		dst.Close()
+		return wrap(ctx.Err())
+	}
+	if err != nil {
+		dst.Close()
+		return wrap(err)
+	}
+	err = dst.Close()
+	if err != nil {
+		return wrap(err)
+	}
+	return nil
+}
+
+func (e *nativeSSHExecutor) Close() error {
+	var err error
+	if e.sftp != nil {
+		err = e.sftp.Close()
+		e.sftp = nil
+	}
+	if e.client != nil {
+		closeErr := e.client.Close()
+		if err == nil {
+			err = closeErr
+		}
+		e.client = nil
+	}
+	if e.agent != nil {
+		e.agent.Close()
+		e.agent = nil
+	}
+	return err
+}
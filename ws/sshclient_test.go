@@ -0,0 +1,214 @@
+package ws
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// start an ssh server accepting clientKey, serving sh -c exec requests and
+// the sftp subsystem; return the listener address and the known_hosts file
+func startTestSSHServer(t *testing.T, clientKey ssh.PublicKey) (string, string) {
+	_, hostPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+	hostSigner, err := ssh.NewSignerFromKey(hostPrivate)
+	require.Nil(t, err)
+	config := ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(clientKey.Marshal()) {
+				return nil, Fatalf("unknown key")
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(hostSigner)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestSSH(conn, &config)
+		}
+	}()
+	address := listener.Addr().String()
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(address)}, hostSigner.PublicKey())
+	require.Nil(t, os.WriteFile(knownHosts, []byte(line+"\n"), 0600))
+	return address, knownHosts
+}
+
+func serveTestSSH(conn net.Conn, config *ssh.ServerConfig) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer channel.Close()
+			for request := range channelRequests {
+				payload := string(request.Payload[4:])
+				switch request.Type {
+				case "exec":
+					request.Reply(true, nil)
+					cmd := exec.Command("sh", "-c", payload)
+					cmd.Stdout = channel
+					cmd.Stderr = channel.Stderr()
+					cmd.Run()
+					status := make([]byte, 4)
+					binary.BigEndian.PutUint32(status, uint32(cmd.ProcessState.ExitCode()))
+					channel.SendRequest("exit-status", false, status)
+					return
+				case "subsystem":
+					if payload != "sftp" {
+						request.Reply(false, nil)
+						return
+					}
+					request.Reply(true, nil)
+					server, err := sftp.NewServer(channel)
+					if err != nil {
+						return
+					}
+					server.Serve()
+					return
+				default:
+					request.Reply(false, nil)
+				}
+			}
+		}()
+	}
+}
+
+func newTestSSHController(t *testing.T) *vmctl {
+	ctx := t.Context()
+	initTestConfig(t)
+	t.Setenv("SSH_AUTH_SOCK", "")
+	_, clientPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+	block, err := ssh.MarshalPrivateKey(clientPrivate, "")
+	require.Nil(t, err)
+	keyFile := filepath.Join(t.TempDir(), "id_ed25519")
+	require.Nil(t, os.WriteFile(keyFile, pem.EncodeToMemory(block), 0600))
+	signer, err := ssh.NewSignerFromKey(clientPrivate)
+	require.Nil(t, err)
+	address, knownHosts := startTestSSHServer(t, signer.PublicKey())
+	ViperSet("ssh_known_hosts", []string{knownHosts})
+	t.Cleanup(func() { ViperSet("ssh_known_hosts", []string{}) })
+	v := &vmctl{
+		Hostname: address,
+		Username: "vmware",
+		KeyFile:  keyFile,
+		Shell:    "sshclient",
+		Local:    "linux",
+	}
+	executor, err := newExecutor(ctx, v, v.Shell, nil)
+	require.Nil(t, err)
+	v.executor = executor
+	t.Cleanup(func() { v.Close() })
+	return v
+}
+
+func TestNewExecutorShell(t *testing.T) {
+	ctx := t.Context()
+	v := &vmctl{Local: "linux"}
+	executor, err := newExecutor(ctx, v, "ssh", nil)
+	require.Nil(t, err)
+	require.IsType(t, &sshExecutor{}, executor)
+	_, err = newExecutor(ctx, v, "openssh", nil)
+	require.NotNil(t, err)
+}
+
+func TestSSHAddress(t *testing.T) {
+	require.Equal(t, "host:22", sshAddress("host"))
+	require.Equal(t, "host:2222", sshAddress("host:2222"))
+	require.Equal(t, "10.0.0.1:22", sshAddress("10.0.0.1"))
+}
+
+func TestSSHSftpPath(t *testing.T) {
+	e := nativeSSHExecutor{v: &vmctl{Remote: "windows"}}
+	require.Equal(t, "/C:/vmware/web1/web1.vmx", e.sftpPath("C:\\vmware\\web1\\web1.vmx"))
+	e.v.Remote = "linux"
+	require.Equal(t, "/vmware/web1/web1.vmx", e.sftpPath("/vmware/web1/web1.vmx"))
+}
+
+func TestSSHExecutor(t *testing.T) {
+	ctx := t.Context()
+	v := newTestSSHController(t)
+	remote, err := v.detectRemoteOS(ctx)
+	require.Nil(t, err)
+	require.Equal(t, "linux", remote)
+	v.Remote = remote
+
+	olines, err := v.RemoteExec(ctx, "echo one; echo two", nil)
+	require.Nil(t, err)
+	require.Equal(t, []string{"one", "two"}, olines)
+
+	_, err = v.RemoteExec(ctx, "exit 3", nil)
+	require.NotNil(t, err)
+	var exitCode int
+	_, err = v.RemoteExec(ctx, "exit 3", &exitCode)
+	require.Nil(t, err)
+	require.Equal(t, 3, exitCode)
+
+	dir := t.TempDir()
+	localFile := filepath.Join(dir, "upload.txt")
+	require.Nil(t, os.WriteFile(localFile, []byte("uploaded"), 0600))
+	hostFile := filepath.Join(dir, "host.txt")
+	require.Nil(t, v.executor.Upload(ctx, hostFile, localFile))
+	olines, err = v.RemoteExec(ctx, "cat "+hostFile, nil)
+	require.Nil(t, err)
+	require.Equal(t, []string{"uploaded"}, olines)
+
+	downloaded := filepath.Join(dir, "download.txt")
+	require.Nil(t, v.executor.Download(ctx, downloaded, hostFile))
+	data, err := os.ReadFile(downloaded)
+	require.Nil(t, err)
+	require.Equal(t, "uploaded", string(data))
+}
+
+func TestSSHCancel(t *testing.T) {
+	v := newTestSSHController(t)
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := v.RemoteExec(ctx, "sleep 5", nil)
+	require.NotNil(t, err)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+
+	// Stream keeps the cause so an interrupted backup exits 130
+	ctx, cancel = context.WithCancel(t.Context())
+	cancel()
+	err = v.executor.(streamExecutor).Stream(ctx, io.Discard, "sleep 5")
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestSSHUnknownHost(t *testing.T) {
+	ctx := t.Context()
+	v := newTestSSHController(t)
+	ViperSet("ssh_known_hosts", []string{filepath.Join(t.TempDir(), "empty")})
+	require.Nil(t, os.WriteFile(ViperGetStringSlice("ssh_known_hosts")[0], []byte{}, 0600))
+	_, err := newNativeSSHExecutor(ctx, v)
+	require.ErrorIs(t, err, ErrTransport)
+}
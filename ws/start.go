@@ -1,8 +1,11 @@
 package ws
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 )
 
 type StartOptions struct {
@@ -14,11 +17,13 @@ type StartOptions struct {
 }
 
 type StopOptions struct {
-	PowerOff bool
-	Wait     bool
+	PowerOff     bool
+	Wait         bool
+	Escalate     bool
+	GraceSeconds int64
 }
 
-func (v *vmctl) Start(vid string, options StartOptions, isoOptions IsoOptions) (string, error) {
+func (v *vmctl) Start(ctx context.Context, vid string, options StartOptions, isoOptions IsoOptions) (string, error) {
 	if v.debug {
 		log.Printf("Start(%s, options, isoOptions)\noptions: %s\nisoOptions: %s\n",
 			vid,
@@ -26,24 +31,36 @@ func (v *vmctl) Start(vid string, options StartOptions, isoOptions IsoOptions) (
 			FormatJSON(isoOptions),
 		)
 	}
-	vm, err := v.cli.GetVM(vid)
+	vm, err := v.cli.GetVM(ctx, vid)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
-	ok, err := v.checkPowerState(&vm, "start", "on")
+	ok, err := v.checkPowerState(ctx, &vm, "start", "on")
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 	if ok {
 		return "already started", nil
 	}
 
+	// a paused instance is resumed with unpause; vmrun start resumes a suspended instance
+	var resume bool
+	switch vm.PowerState {
+	case "paused":
+		return v.Unpause(ctx, vid, StopOptions{Wait: options.Wait})
+	case "suspended":
+		if isoOptions.ModifyISO {
+			return "", wrapf("[%s] cannot modify ISO: %w", vm.Name, &ErrPowerState{Want: "off", Have: vm.PowerState})
+		}
+		resume = true
+	}
+
 	var savedBootConnected bool
 	if isoOptions.ModifyISO {
 		var currentIsoOptions IsoOptions
-		err := v.cli.GetIsoOptions(&vm, &currentIsoOptions)
+		err := v.cli.GetIsoOptions(ctx, &vm, &currentIsoOptions)
 		if err != nil {
-			return "", Fatal(err)
+			return "", wrap(err)
 		}
 
 		savedBootConnected = currentIsoOptions.IsoBootConnected
@@ -74,15 +91,15 @@ func (v *vmctl) Start(vid string, options StartOptions, isoOptions IsoOptions) (
 			}
 			log.Println(msg)
 		}
-		_, err = v.Modify(vid, CreateOptions{}, isoOptions)
+		_, err = v.Modify(ctx, vid, CreateOptions{}, isoOptions)
 		if err != nil {
-			return "", Fatal(err)
+			return "", wrap(err)
 		}
 	}
 
 	path, err := PathnameFormat(v.Remote, vm.Path)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 	command := ""
 	var visibility string
@@ -110,28 +127,32 @@ func (v *vmctl) Start(vid string, options StartOptions, isoOptions IsoOptions) (
 	}
 
 	if options.ModifyStretch {
-		err = v.setStretch(&vm, options.StretchEnabled)
+		err = v.setStretch(ctx, &vm, options.StretchEnabled)
 		if err != nil {
-			return "", Fatal(err)
+			return "", wrap(err)
 		}
 	}
 
+	action := "start"
+	if resume {
+		action = "resume"
+	}
 	if v.verbose {
-		fmt.Printf("[%s] Requesting %s start\n", vm.Name, visibility)
+		fmt.Printf("[%s] Requesting %s %s\n", vm.Name, visibility, action)
 	}
 
-	err = v.RemoteSpawn(command, nil)
+	err = v.RemoteSpawn(ctx, command, nil)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 	if v.verbose {
-		fmt.Printf("[%s] Start request complete\n", vm.Name)
+		fmt.Printf("[%s] %s request complete\n", vm.Name, action)
 	}
 
 	if options.Wait {
-		err := v.Wait(vid, "on")
+		err := v.Wait(ctx, vid, "on")
 		if err != nil {
-			return "", Fatal(err)
+			return "", wrap(err)
 		}
 
 		if isoOptions.ModifyISO {
@@ -141,62 +162,211 @@ func (v *vmctl) Start(vid string, options StartOptions, isoOptions IsoOptions) (
 					fmt.Println(msg)
 				}
 				log.Println(msg)
-				err := v.cli.SetIsoStartConnected(&vm, savedBootConnected)
+				err := v.cli.SetIsoStartConnected(ctx, &vm, savedBootConnected)
 				if err != nil {
-					return "", Fatal(err)
+					return "", wrap(err)
 				}
 			}
 		}
 
+		if resume {
+			return "resumed", nil
+		}
 		return "started", nil
 	}
-	return "start pending", nil
+	return action + " pending", nil
 }
 
-func (v *vmctl) Stop(vid string, options StopOptions) (string, error) {
+func (v *vmctl) Stop(ctx context.Context, vid string, options StopOptions) (string, error) {
 	if v.debug {
 		log.Printf("Stop(%s, %+v)\n", vid, options)
 	}
-	vm, err := v.cli.GetVM(vid)
+	vm, err := v.cli.GetVM(ctx, vid)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 
-	ok, err := v.checkPowerState(&vm, "stop", "off")
+	ok, err := v.checkPowerState(ctx, &vm, "stop", "off")
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 	if ok {
 		return "already stopped", nil
 	}
-	path, err := PathnameFormat(v.Remote, vm.Path)
+
+	if options.Escalate && !options.PowerOff {
+		grace := options.GraceSeconds
+		if grace == 0 {
+			grace = v.GraceSeconds
+		}
+		// a soft stop fails immediately if VMware Tools is not running in the guest
+		msg := fmt.Sprintf("[%s] shutdown not complete after %d seconds, escalating to forced power down", vm.Name, grace)
+		err := v.stopRequest(ctx, &vm, false)
+		if err != nil {
+			msg = fmt.Sprintf("[%s] shutdown request failed, escalating to forced power down: %v", vm.Name, err)
+		} else {
+			ok, err := v.waitPowerState(ctx, vid, "off", grace)
+			if err != nil {
+				return "", wrap(err)
+			}
+			if ok {
+				return "stopped", nil
+			}
+		}
+		if v.verbose {
+			fmt.Println(msg)
+		}
+		log.Println(msg)
+		options.PowerOff = true
+	}
+
+	err = v.stopRequest(ctx, &vm, options.PowerOff)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
-	// FIXME: may need -vp PASSWORD here for encrypted instances
-	command := "vmrun -T ws stop " + path
+
+	if options.Wait {
+		err := v.Wait(ctx, vid, "off")
+		if err != nil {
+			return "", wrap(err)
+		}
+		return "stopped", nil
+	}
+	return "stop pending", nil
+}
+
+func (v *vmctl) stopRequest(ctx context.Context, vm *VM, hard bool) error {
+	mode := "soft"
 	action := "shutdown"
-	if options.PowerOff {
+	if hard {
+		mode = "hard"
 		action = "forced power down"
 	}
 	if v.verbose {
 		fmt.Printf("[%s] Requesting %s\n", vm.Name, action)
 	}
+	_, err := v.vmrun(ctx, vm, "stop", mode)
+	if err != nil {
+		return wrap(err)
+	}
+	if v.verbose {
+		fmt.Printf("[%s] %s request complete\n", vm.Name, action)
+	}
+	return nil
+}
 
-	_, err = v.RemoteExec(command, nil)
+// options.PowerOff requests a hard reset; options.Escalate falls back to a hard reset if the soft reset fails;
+// the power state does not change across a reset, so options.Wait awaits the guest restarting VMware Tools
+func (v *vmctl) Reboot(ctx context.Context, vid string, options StopOptions) (string, error) {
+	if v.debug {
+		log.Printf("Reboot(%s, %+v)\n", vid, options)
+	}
+	vm, err := v.cli.GetVM(ctx, vid)
+	if err != nil {
+		return "", wrap(err)
+	}
+	err = v.requirePowerState(ctx, &vm, "on", "reboot")
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
+	}
+	if options.Wait {
+		err = v.requireToolsRunning(ctx, &vm)
+		if err != nil {
+			return "", wrap(err)
+		}
 	}
 
+	mode := "soft"
+	action := "reboot"
+	if options.PowerOff {
+		mode = "hard"
+		action = "forced reset"
+	}
+	if v.verbose {
+		fmt.Printf("[%s] Requesting %s\n", vm.Name, action)
+	}
+	_, err = v.vmrun(ctx, &vm, "reset", mode)
+	if err != nil {
+		if !options.Escalate || options.PowerOff {
+			return "", wrap(err)
+		}
+		msg := fmt.Sprintf("[%s] soft reset failed, escalating to forced reset: %v", vm.Name, err)
+		if v.verbose {
+			fmt.Println(msg)
+		}
+		log.Println(msg)
+		action = "forced reset"
+		_, err = v.vmrun(ctx, &vm, "reset", "hard")
+		if err != nil {
+			return "", wrap(err)
+		}
+	}
 	if v.verbose {
 		fmt.Printf("[%s] %s request complete\n", vm.Name, action)
 	}
 	if options.Wait {
-		err := v.Wait(vid, "off")
+		err := v.waitGuestReboot(ctx, &vm)
 		if err != nil {
-			return "", Fatal(err)
+			return "", wrap(err)
+		}
+		return "rebooted", nil
+	}
+	return "reboot pending", nil
+}
+
+// return the vmrun checkToolsState result: running, installed, not installed or unknown
+func (v *vmctl) toolsState(ctx context.Context, vm *VM) (string, error) {
+	olines, err := v.vmrun(ctx, vm, "checkToolsState")
+	if err != nil {
+		return "", wrap(err)
+	}
+	if len(olines) == 0 {
+		return "unknown", nil
+	}
+	return strings.TrimSpace(olines[len(olines)-1]), nil
+}
+
+// a reboot can only be awaited when VMware Tools reports the guest going down and coming back
+func (v *vmctl) requireToolsRunning(ctx context.Context, vm *VM) error {
+	state, err := v.toolsState(ctx, vm)
+	if err != nil {
+		return wrap(err)
+	}
+	if state != "running" {
+		return wrapf("[%s] cannot await reboot: VMware Tools is %s", vm.Name, state)
+	}
+	return nil
+}
+
+// poll until VMware Tools stops and then runs again in the guest
+func (v *vmctl) waitGuestReboot(ctx context.Context, vm *VM) error {
+	if v.verbose {
+		fmt.Printf("[%s] Awaiting guest reboot\n", vm.Name)
+	}
+	start := time.Now()
+	interval := time.Duration(v.IntervalSeconds) * time.Second
+	timeout := time.Duration(v.TimeoutSeconds) * time.Second
+	down := false
+	for {
+		state, err := v.toolsState(ctx, vm)
+		if err != nil {
+			return wrap(err)
+		}
+		switch {
+		case state != "running":
+			down = true
+		case down:
+			if v.verbose {
+				fmt.Printf("[%s] Detected guest running\n", vm.Name)
+			}
+			return nil
+		}
+		if v.TimeoutSeconds != 0 && time.Since(start) > timeout {
+			return wrapf("[%s] %w awaiting guest reboot", vm.Name, ErrTimeout)
+		}
+		err = sleepContext(ctx, interval)
+		if err != nil {
+			return wrap(err)
 		}
-		return "stopped", nil
 	}
-	return "stop pending", nil
 }
@@ -0,0 +1,81 @@
+package ws
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestStartWaitStop(t *testing.T) {
+	ctx := t.Context()
+	v, host := newFakeController(t)
+	_, err := v.Create(ctx, "web1", *NewCreateOptions(), IsoOptions{})
+	require.Nil(t, err)
+
+	result, err := v.Start(ctx, "web1", StartOptions{Background: true, Wait: true}, IsoOptions{})
+	require.Nil(t, err)
+	require.Equal(t, "started", result)
+	require.Equal(t, "on", host.PowerState("/vmware/web1/web1.vmx"))
+	require.Contains(t, host.Commands, "vmrun -T ws start /vmware/web1/web1.vmx nogui")
+
+	result, err = v.Start(ctx, "web1", StartOptions{Background: true}, IsoOptions{})
+	require.Nil(t, err)
+	require.Equal(t, "already started", result)
+
+	_, err = v.Modify(ctx, "web1", CreateOptions{ModifyCpu: true, CpuCount: 4}, IsoOptions{})
+	require.NotNil(t, err)
+	require.NotNil(t, v.Destroy(ctx, "web1", DestroyOptions{}))
+
+	require.Nil(t, v.Wait(ctx, "web1", "running"))
+	result, err = v.Stop(ctx, "web1", StopOptions{Wait: true})
+	require.Nil(t, err)
+	require.Equal(t, "stopped", result)
+	require.Equal(t, "off", host.PowerState("/vmware/web1/web1.vmx"))
+
+	_, err = v.Start(ctx, "web1", StartOptions{Background: true}, IsoOptions{})
+	require.Nil(t, err)
+	require.Nil(t, v.Destroy(ctx, "web1", DestroyOptions{Force: true}))
+	require.False(t, IsDir(host.local("/vmware/web1")))
+}
+
+func TestRebootWait(t *testing.T) {
+	ctx := t.Context()
+	v, host := newFakeController(t)
+	_, err := v.Create(ctx, "web1", *NewCreateOptions(), IsoOptions{})
+	require.Nil(t, err)
+	_, err = v.Start(ctx, "web1", StartOptions{Background: true}, IsoOptions{})
+	require.Nil(t, err)
+
+	result, err := v.Reboot(ctx, "web1", StopOptions{Wait: true})
+	require.Nil(t, err)
+	require.Equal(t, "rebooted", result)
+	require.Contains(t, host.Commands, "vmrun -T ws reset /vmware/web1/web1.vmx soft")
+	// the wait saw the guest go down and come back
+	require.Equal(t, 0, host.rebooting["/vmware/web1/web1.vmx"])
+
+	// without VMware Tools the reboot cannot be awaited, so it is not requested
+	host.NoTools = true
+	host.Commands = nil
+	_, err = v.Reboot(ctx, "web1", StopOptions{Wait: true, PowerOff: true})
+	require.NotNil(t, err)
+	require.NotContains(t, host.Commands, "vmrun -T ws reset /vmware/web1/web1.vmx hard")
+}
+
+func TestWaitCancel(t *testing.T) {
+	ctx := t.Context()
+	v, _ := newFakeController(t)
+	_, err := v.Create(ctx, "web1", *NewCreateOptions(), IsoOptions{})
+	require.Nil(t, err)
+
+	// with no timeout, Wait polls until the context is done
+	v.TimeoutSeconds = 0
+	v.IntervalSeconds = 1
+	ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = v.Wait(ctx, "web1", "running")
+	require.NotNil(t, err)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+}
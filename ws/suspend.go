@@ -0,0 +1,76 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// options.PowerOff requests a hard suspend without notifying the guest
+func (v *vmctl) Suspend(ctx context.Context, vid string, options StopOptions) (string, error) {
+	if v.debug {
+		log.Printf("Suspend(%s, %+v)\n", vid, options)
+	}
+	mode := "soft"
+	if options.PowerOff {
+		mode = "hard"
+	}
+	return v.powerRequest(ctx, vid, "suspend", "suspended", "suspended", options.Wait, mode)
+}
+
+func (v *vmctl) Pause(ctx context.Context, vid string, options StopOptions) (string, error) {
+	if v.debug {
+		log.Printf("Pause(%s, %+v)\n", vid, options)
+	}
+	return v.powerRequest(ctx, vid, "pause", "paused", "paused", options.Wait)
+}
+
+func (v *vmctl) Unpause(ctx context.Context, vid string, options StopOptions) (string, error) {
+	if v.debug {
+		log.Printf("Unpause(%s, %+v)\n", vid, options)
+	}
+	return v.powerRequest(ctx, vid, "unpause", "on", "unpaused", options.Wait)
+}
+
+// send a vmrun power command and optionally wait for the resulting power state
+func (v *vmctl) powerRequest(ctx context.Context, vid, command, state, result string, wait bool, args ...string) (string, error) {
+	vm, err := v.cli.GetVM(ctx, vid)
+	if err != nil {
+		return "", wrap(err)
+	}
+	ok, err := v.checkPowerState(ctx, &vm, command, state)
+	if err != nil {
+		return "", wrap(err)
+	}
+	if ok {
+		return "already " + state, nil
+	}
+	switch command {
+	case "suspend", "pause":
+		if vm.PowerState != "on" {
+			return "", wrapf("[%s] cannot %s: %w", vm.Name, command, &ErrPowerState{Want: "on", Have: vm.PowerState})
+		}
+	case "unpause":
+		if vm.PowerState != "paused" {
+			return "", wrapf("[%s] cannot %s: %w", vm.Name, command, &ErrPowerState{Want: "paused", Have: vm.PowerState})
+		}
+	}
+	if v.verbose {
+		fmt.Printf("[%s] Requesting %s\n", vm.Name, command)
+	}
+	_, err = v.vmrun(ctx, &vm, command, args...)
+	if err != nil {
+		return "", wrap(err)
+	}
+	if v.verbose {
+		fmt.Printf("[%s] %s request complete\n", vm.Name, command)
+	}
+	if wait {
+		err := v.Wait(ctx, vid, state)
+		if err != nil {
+			return "", wrap(err)
+		}
+		return result, nil
+	}
+	return command + " pending", nil
+}
@@ -1,6 +1,7 @@
 package ws
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -25,32 +26,38 @@ func NewCliClient(v *vmctl) *vmcli {
 	return &c
 }
 
-func (c *vmcli) exec(vm *VM, command string, result any) error {
+func (c *vmcli) exec(ctx context.Context, vm *VM, command string, result any) error {
 	hostPath, err := PathnameFormat(c.v.Remote, vm.Path)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
-	olines, err := c.v.RemoteExec(fmt.Sprintf("vmcli %s %s", command, hostPath), nil)
+	olines, err := c.v.RemoteExec(ctx, fmt.Sprintf("vmcli %s %s", command, hostPath), nil)
 	if err != nil {
-		return Fatal(err)
+		switch {
+		case ENCRYPTED_VM_ERROR.MatchString(err.Error()):
+			return wrapf("[%s] %w: %w", vm.Name, ErrEncrypted, err)
+		case NONEXISTENT_VM_ERROR.MatchString(err.Error()):
+			return wrapf("[%s] VMX file %w: %w", vm.Name, ErrNotFound, err)
+		}
+		return wrap(err)
 	}
 	if result != nil {
 		stdout := strings.Join(olines, "\n")
 		err = json.Unmarshal([]byte(stdout), result)
 		if err != nil {
-			return Fatal(err)
+			return wrap(err)
 		}
 	}
 	return nil
 }
 
-func (c *vmcli) execCommand(name, command string, lines int) error {
+func (c *vmcli) execCommand(ctx context.Context, name, command string, lines int) error {
 	if c.v.debug {
 		fmt.Printf("[%s] %s\n", name, command)
 	}
-	olines, err := c.v.RemoteExec(command, nil)
+	olines, err := c.v.RemoteExec(ctx, command, nil)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	var count int
 	if c.v.verbose && len(olines) > 0 {
@@ -67,13 +74,13 @@ func (c *vmcli) execCommand(name, command string, lines int) error {
 	return nil
 }
 
-func (c *vmcli) GetVIDs() ([]*VID, error) {
+func (c *vmcli) GetVIDs(ctx context.Context) ([]*VID, error) {
 	c.Reset()
 	vids := []*VID{}
 	for _, rootPath := range c.v.Roots {
-		err := c.getPathVIDs(rootPath)
+		err := c.getPathVIDs(ctx, rootPath)
 		if err != nil {
-			return vids, Fatal(err)
+			return vids, wrap(err)
 		}
 	}
 	for _, vid := range c.ByPath {
@@ -82,7 +89,7 @@ func (c *vmcli) GetVIDs() ([]*VID, error) {
 	return vids, nil
 }
 
-func (c *vmcli) getPathVIDs(vmPath string) error {
+func (c *vmcli) getPathVIDs(ctx context.Context, vmPath string) error {
 	hostPath, err := PathnameFormat(c.v.Remote, vmPath)
 	if err != nil {
 		return nil
@@ -91,21 +98,21 @@ func (c *vmcli) getPathVIDs(vmPath string) error {
 	switch c.v.Remote {
 	case "windows":
 		command := "dir /B /AD " + hostPath
-		dirs, err := c.v.RemoteExec(command, nil)
+		dirs, err := c.v.RemoteExec(ctx, command, nil)
 		if err != nil {
-			return Fatal(err)
+			return wrap(err)
 		}
 		for _, dir := range dirs {
 			dir = strings.TrimSpace(dir)
 			if dir != "" {
 				normalDir, err := PathNormalize(dir)
 				if err != nil {
-					return Fatal(err)
+					return wrap(err)
 				}
 				vmxFile := path.Join(vmPath, normalDir, normalDir+".vmx")
-				exists, err := c.windowsFileExists(vmxFile)
+				exists, err := c.windowsFileExists(ctx, vmxFile)
 				if err != nil {
-					return Fatal(err)
+					return wrap(err)
 				}
 				if exists {
 					files[path.Join(vmPath, normalDir, normalDir+".vmx")] = true
@@ -114,9 +121,9 @@ func (c *vmcli) getPathVIDs(vmPath string) error {
 		}
 	default:
 		command := fmt.Sprintf("find %s -maxdepth 2 -type f -name '*.vmx'", hostPath)
-		lines, err := c.v.RemoteExec(command, nil)
+		lines, err := c.v.RemoteExec(ctx, command, nil)
 		if err != nil {
-			return Fatal(err)
+			return wrap(err)
 		}
 		for _, line := range lines {
 			files[line] = true
@@ -125,21 +132,21 @@ func (c *vmcli) getPathVIDs(vmPath string) error {
 	for file, _ := range files {
 		_, err := c.newVID(file)
 		if err != nil {
-			return Fatal(err)
+			return wrap(err)
 		}
 	}
 	return nil
 }
 
-func (c *vmcli) windowsFileExists(pathname string) (bool, error) {
+func (c *vmcli) windowsFileExists(ctx context.Context, pathname string) (bool, error) {
 	hostPath, err := PathFormat(c.v.Remote, pathname)
 	if err != nil {
-		return false, Fatal(err)
+		return false, wrap(err)
 	}
 	var exitCode int
-	_, err = c.v.RemoteExec("dir >NUL 2>NUL "+hostPath, &exitCode)
+	_, err = c.v.RemoteExec(ctx, "dir >NUL 2>NUL "+hostPath, &exitCode)
 	if err != nil {
-		return false, Fatal(err)
+		return false, wrap(err)
 	}
 	if exitCode == 0 {
 		return true, nil
@@ -152,11 +159,11 @@ func (c *vmcli) newVID(pathname string) (*VID, error) {
 	//log.Printf("newVID %s\n", pathname)
 	vmxPath, err := PathNormalize(pathname)
 	if err != nil {
-		return nil, Fatal(err)
+		return nil, wrap(err)
 	}
 	name, err := PathToName(vmxPath)
 	if err != nil {
-		return nil, Fatal(err)
+		return nil, wrap(err)
 	}
 	vid := VID{
 		Name: name,
@@ -180,12 +187,12 @@ func (c *vmcli) Reset() {
 }
 
 // search for a VM by Name or Id
-func (c *vmcli) IsVM(vid string) (bool, error) {
+func (c *vmcli) IsVM(ctx context.Context, vid string) (bool, error) {
 	if len(c.ById) == 0 {
 		// refresh ID index
-		_, err := c.GetVIDs()
+		_, err := c.GetVIDs(ctx)
 		if err != nil {
-			return false, Fatal(err)
+			return false, wrap(err)
 		}
 	}
 
@@ -204,10 +211,10 @@ func (c *vmcli) IsVM(vid string) (bool, error) {
 }
 
 // return VM ID by Name or ID; error if neither is found
-func (c *vmcli) GetId(vid string) (string, error) {
-	ok, err := c.IsVM(vid)
+func (c *vmcli) GetId(ctx context.Context, vid string) (string, error) {
+	ok, err := c.IsVM(ctx, vid)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 	if ok {
 		_, ok = c.ById[vid]
@@ -223,13 +230,13 @@ func (c *vmcli) GetId(vid string) (string, error) {
 		}
 		return "", Fatalf("IsVM(%s) is true, but vid not in ById or ByName", vid)
 	}
-	return "", Fatalf("VM not found: %s", vid)
+	return "", wrapf("VM %w: %s", ErrNotFound, vid)
 }
 
-func (c *vmcli) GetVM(vid string) (VM, error) {
-	id, err := c.GetId(vid)
+func (c *vmcli) GetVM(ctx context.Context, vid string) (VM, error) {
+	id, err := c.GetId(ctx, vid)
 	if err != nil {
-		return VM{}, Fatal(err)
+		return VM{}, wrap(err)
 	}
 	v, ok := c.ById[id]
 	if !ok {
@@ -239,67 +246,67 @@ func (c *vmcli) GetVM(vid string) (VM, error) {
 	return vm, nil
 }
 
-func (c *vmcli) GetConfig(vm *VM) error {
+func (c *vmcli) GetConfig(ctx context.Context, vm *VM) error {
 
-	config, err := c.GetParams(vm)
+	config, err := c.GetParams(ctx, vm)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	vm.CpuCount, err = c.GetInt(config, "numvcpus", true)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	vm.RamSize, err = c.GetSize(config, "memsize", true)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	vm.IsoFile, err = c.GetPath(config, "ide1:0.fileName", false)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	vm.IsoAttached, err = c.GetBool(config, "ide1:0.present", false)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	vm.IsoAttachOnStart, err = c.GetBool(config, "ide1:0.startConnected", false)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
-	err = c.GetMacAddress(vm, config)
+	err = c.GetNics(ctx, vm, config)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	vm.SerialAttached, err = c.GetBool(config, "serial0.present", false)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	vm.SerialPipe, err = c.GetPath(config, "serial0.fileName", false)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	vm.VncEnabled, err = c.GetBool(config, "RemoteDisplay.vnc.enabled", false)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	vm.VncPort, err = c.GetInt(config, "RemoteDisplay.vnc.port", false)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	copyDisabled, err := c.GetBool(config, "isolation.tools.copy.disable", false)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	pasteDisabled, err := c.GetBool(config, "isolation.tools.paste.disable", false)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	dndDisabled, err := c.GetBool(config, "isolation.tools.dnd.disable", false)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	shareDisabled, err := c.GetBool(config, "isolation.tools.hgfs.disable", false)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	vm.FileShareEnabled = !shareDisabled
 	vm.ClipboardEnabled = true
@@ -310,53 +317,53 @@ func (c *vmcli) GetConfig(vm *VM) error {
 	return nil
 }
 
-func (c *vmcli) GetParam(vm *VM, name string) (string, error) {
-	config, err := c.GetParams(vm)
+func (c *vmcli) GetParam(ctx context.Context, vm *VM, name string) (string, error) {
+	config, err := c.GetParams(ctx, vm)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 	value, err := value(config, name)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 	ret := fmt.Sprintf("%v", value)
 	return strings.Trim(ret, `"`), nil
 }
 
-func (c *vmcli) SetParam(vm *VM, name, value string) error {
+func (c *vmcli) SetParam(ctx context.Context, vm *VM, name, value string) error {
 	command := fmt.Sprintf("configParams SetEntry %s %s", name, value)
-	err := c.exec(vm, command, nil)
+	err := c.exec(ctx, vm, command, nil)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	return nil
 }
 
-func (c *vmcli) QueryPowerState(vm *VM) error {
+func (c *vmcli) QueryPowerState(ctx context.Context, vm *VM) error {
 	if c.debug {
 		log.Printf("[%s] QueryPowerState\n", vm.Name)
 	}
 	var state struct{ PowerState string }
-	err := c.exec(vm, "power query -f json", &state)
+	err := c.exec(ctx, vm, "power query -f json", &state)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	vm.PowerState = state.PowerState
 	vm.Running = state.PowerState != "off"
 	return nil
 }
 
-func (c *vmcli) GetParams(vm *VM) (*VMConfig, error) {
+func (c *vmcli) GetParams(ctx context.Context, vm *VM) (*VMConfig, error) {
 	if c.debug {
 		log.Printf("[%s] GetParams\n", vm.Name)
 	}
 	var params VMConfig
-	err := c.exec(vm, "configParams query -f json", &params)
+	err := c.exec(ctx, vm, "configParams query -f json", &params)
 	if err != nil {
 		if checkEncryptedError(vm, err) {
 			return &VMConfig{}, nil
 		}
-		return nil, Fatal(err)
+		return nil, wrap(err)
 	}
 	return &params, nil
 }
@@ -373,7 +380,7 @@ func (c *vmcli) GetSize(config *VMConfig, key string, required bool) (string, er
 	value, err := value(config, key)
 	if err != nil {
 		if required {
-			return "", Fatal(err)
+			return "", wrap(err)
 		}
 		return "", nil
 	}
@@ -384,7 +391,7 @@ func (c *vmcli) GetSize(config *VMConfig, key string, required bool) (string, er
 	case string:
 		s, err := strconv.ParseInt(value.(string), 10, 64)
 		if err != nil {
-			return "", Fatal(err)
+			return "", wrap(err)
 		}
 		size = s
 	default:
@@ -397,7 +404,7 @@ func (c *vmcli) GetInt(config *VMConfig, key string, required bool) (int, error)
 	value, err := value(config, key)
 	if err != nil {
 		if required {
-			return 0, Fatal(err)
+			return 0, wrap(err)
 		}
 		return 0, nil
 	}
@@ -407,7 +414,7 @@ func (c *vmcli) GetInt(config *VMConfig, key string, required bool) (int, error)
 	case string:
 		ivalue, err := strconv.Atoi(value.(string))
 		if err != nil {
-			return 0, Fatal(err)
+			return 0, wrap(err)
 		}
 		return ivalue, nil
 	default:
@@ -419,7 +426,7 @@ func (c *vmcli) GetString(config *VMConfig, key string, required bool) (string,
 	value, err := value(config, key)
 	if err != nil {
 		if required {
-			return "", Fatal(err)
+			return "", wrap(err)
 		}
 		return "", nil
 	}
@@ -436,11 +443,11 @@ func (c *vmcli) GetString(config *VMConfig, key string, required bool) (string,
 func (c *vmcli) GetPath(config *VMConfig, key string, required bool) (string, error) {
 	value, err := c.GetString(config, key, required)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 	normalized, err := PathNormalize(value)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 	return normalized, nil
 }
@@ -449,7 +456,7 @@ func (c *vmcli) GetBool(config *VMConfig, key string, required bool) (bool, erro
 	value, err := value(config, key)
 	if err != nil {
 		if required {
-			return false, Fatal(err)
+			return false, wrap(err)
 		}
 		return false, nil
 	}
@@ -472,85 +479,86 @@ func (c *vmcli) GetBool(config *VMConfig, key string, required bool) (bool, erro
 	}
 }
 
-func (c *vmcli) GetMacAddress(vm *VM, config *VMConfig) error {
+func (c *vmcli) GetNics(ctx context.Context, vm *VM, config *VMConfig) error {
 	if config == nil {
-		c, err := c.GetParams(vm)
+		c, err := c.GetParams(ctx, vm)
 		if err != nil {
-			return Fatal(err)
+			return wrap(err)
 		}
 		config = c
 	}
-	key := "ethernet0.addressType"
-	addressType, err := c.GetString(config, key, false)
-	if err != nil {
-		return Fatal(err)
-	}
-	if addressType == "" {
-		vm.MacAddress = ""
-		return nil
-	}
-	key = "ethernet0.generatedAddress"
-	if addressType == "static" {
-		key = "ethernet0.address"
+	var err error
+	get := func(key string) string {
+		if err != nil {
+			return ""
+		}
+		var value string
+		value, err = c.GetString(config, key, false)
+		return value
 	}
-	addr, err := c.GetString(config, key, false)
-	if err != nil {
-		return Fatal(err)
+	vm.Nics = []VMNic{}
+	for i := 0; i < MAX_NICS; i++ {
+		nic, ok := parseNIC(i, get)
+		if err != nil {
+			return wrap(err)
+		}
+		if ok {
+			vm.Nics = append(vm.Nics, nic)
+		}
 	}
-	vm.MacAddress = addr
 	return nil
 }
 
-func (c *vmcli) GetIsoOptions(vm *VM, options *IsoOptions) error {
-	config, err := c.GetParams(vm)
+func (c *vmcli) GetIsoOptions(ctx context.Context, vm *VM, options *IsoOptions) error {
+	config, err := c.GetParams(ctx, vm)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	options.ModifyISO = true
 	options.IsoPresent, err = c.GetBool(config, "ide1:0.present", false)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	options.IsoFile, err = c.GetPath(config, "ide1:0.fileName", false)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	options.IsoBootConnected, err = c.GetBool(config, "ide1:0.startConnected", false)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	return nil
 }
 
-func (c *vmcli) GetIsoStartConnected(vm *VM) (bool, error) {
-	config, err := c.GetParams(vm)
+func (c *vmcli) GetIsoStartConnected(ctx context.Context, vm *VM) (bool, error) {
+	config, err := c.GetParams(ctx, vm)
 	if err != nil {
-		return false, Fatal(err)
+		return false, wrap(err)
 	}
 	connected, err := c.GetBool(config, "ide1:0.startConnected", false)
 	if err != nil {
-		return false, Fatal(err)
+		return false, wrap(err)
 	}
 	return connected, nil
 }
 
-func (c *vmcli) SetIsoStartConnected(vm *VM, connected bool) error {
+func (c *vmcli) SetIsoStartConnected(ctx context.Context, vm *VM, connected bool) error {
 	label := "ide1:0"
 	command := fmt.Sprintf("disk setStartConnected %s %v", label, connected)
-	err := c.exec(vm, command, nil)
+	err := c.exec(ctx, vm, command, nil)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	return nil
 }
 
-func (c *vmcli) SetIsoOptions(vm *VM, options *IsoOptions) error {
+func (c *vmcli) SetIsoOptions(ctx context.Context, vm *VM, options *IsoOptions) error {
 	label := "ide1:0"
 
 	command := fmt.Sprintf("disk setPresent %s %v", label, options.IsoPresent)
-	err := c.exec(vm, command, nil)
+	err := c.exec(ctx, vm, command, nil)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 
 	if !options.IsoPresent {
@@ -559,24 +567,24 @@ func (c *vmcli) SetIsoOptions(vm *VM, options *IsoOptions) error {
 
 	hostPath, err := PathnameFormat(c.v.Remote, options.IsoFile)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 
 	command = fmt.Sprintf("disk setBackingInfo %s cdrom_image %s false", label, hostPath)
-	err = c.exec(vm, command, nil)
+	err = c.exec(ctx, vm, command, nil)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 
 	command = fmt.Sprintf("disk setStartConnected %s %v", label, options.IsoBootConnected)
-	err = c.exec(vm, command, nil)
+	err = c.exec(ctx, vm, command, nil)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	return nil
 }
 
-func (c *vmcli) Create(name, guestOS string) (*VM, error) {
+func (c *vmcli) Create(ctx context.Context, name, guestOS string) (*VM, error) {
 	if c.debug {
 		log.Printf("Create(%s, %s)\n", name, guestOS)
 	}
@@ -584,36 +592,36 @@ func (c *vmcli) Create(name, guestOS string) (*VM, error) {
 	// make a VID, which will fail if the instance exists
 	vid, err := c.newVID(path.Join(c.v.Roots[0], name, name+".vmx"))
 	if err != nil {
-		return nil, Fatal(err)
+		return nil, wrap(err)
 	}
 
 	guestFlag, guestValue, err := GuestOsParams(guestOS)
 	if err != nil {
-		return nil, Fatal(err)
+		return nil, wrap(err)
 	}
 
 	// create a directory for the new instance
 	dir, _ := path.Split(vid.Path)
 	hostPath, err := PathFormat(c.v.Remote, dir)
 	if err != nil {
-		return nil, Fatal(err)
+		return nil, wrap(err)
 	}
 
 	mkdirCommand := "mkdir " + hostPath
-	_, err = c.v.RemoteExec(mkdirCommand, nil)
+	_, err = c.v.RemoteExec(ctx, mkdirCommand, nil)
 	if err != nil {
-		return nil, Fatal(err)
+		return nil, wrap(err)
 	}
 
 	// use vmcli to create the VM instance
-	err = c.execCommand(name, fmt.Sprintf("vmcli VM Create -n %s -d %s %s %s", name, hostPath, guestFlag, guestValue), 1)
+	err = c.execCommand(ctx, name, fmt.Sprintf("vmcli VM Create -n %s -d %s %s %s", name, hostPath, guestFlag, guestValue), 1)
 	if err != nil {
-		return nil, Fatal(err)
+		return nil, wrap(err)
 	}
 
-	vm, err := c.GetVM(name)
+	vm, err := c.GetVM(ctx, name)
 	if err != nil {
-		return nil, Fatal(err)
+		return nil, wrap(err)
 	}
 	return &vm, nil
 }
@@ -630,47 +638,59 @@ func (c *vmcli) diskPathnames(vm *VM, diskName string) (string, string, error) {
 	diskPathname := path.Join(vmxPath, diskName)
 	hostPathname, err := PathnameFormat(c.v.Remote, diskPathname)
 	if err != nil {
-		return "", "", Fatal(err)
+		return "", "", wrap(err)
 	}
 	return diskPathname, hostPathname, nil
 }
 
-func (c *vmcli) CreateDisk(vm *VM, diskName, size string, singleFile, preallocated bool) error {
-	err := c.DeleteDisk(vm, diskName)
+func (c *vmcli) CreateDisk(ctx context.Context, vm *VM, diskName, size, adapter string, singleFile, preallocated bool) error {
+	err := c.DeleteDisk(ctx, vm, diskName)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	_, hostPathname, err := c.diskPathnames(vm, diskName)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
-	adapter := "lsilogic"
 	diskType := ParseDiskType(singleFile, preallocated)
-	err = c.execCommand(vm.Name, fmt.Sprintf("vmcli Disk Create -f %s -a %s -s %s -t %d", hostPathname, adapter, size, int(diskType)), 0)
+	err = c.execCommand(ctx, vm.Name, fmt.Sprintf("vmcli Disk Create -f %s -a %s -s %s -t %d", hostQuote(c.v.Remote, hostPathname), adapter, size, int(diskType)), 0)
+	if err != nil {
+		return wrap(err)
+	}
+	return nil
+}
+
+// run vmware-vdiskmanager; disk arguments are host pathnames
+func (c *vmcli) DiskManager(ctx context.Context, vm *VM, args ...string) error {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = hostQuote(c.v.Remote, arg)
+	}
+	err := c.execCommand(ctx, vm.Name, "vmware-vdiskmanager "+strings.Join(quoted, " "), 0)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	return nil
 }
 
 // DANGER, WILL ROBINSON! - delete the instance's virtual disk file
-func (c *vmcli) DeleteDisk(vm *VM, diskName string) error {
+func (c *vmcli) DeleteDisk(ctx context.Context, vm *VM, diskName string) error {
 
 	_, hostPathname, err := c.diskPathnames(vm, diskName)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 
 	var command string
 	switch c.v.Remote {
 	case "windows":
-		command = "del " + hostPathname
+		command = "del " + hostQuote(c.v.Remote, hostPathname)
 	default:
-		command = "rm " + hostPathname
+		command = "rm " + hostQuote(c.v.Remote, hostPathname)
 	}
-	err = c.execCommand(vm.Name, command, 0)
+	err = c.execCommand(ctx, vm.Name, command, 0)
 	if err != nil {
-		return Fatal(err)
+		return wrap(err)
 	}
 	return nil
 }
@@ -0,0 +1,515 @@
+package ws
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+)
+
+// hosted sparse extent reader and writer, as described in the VMware Virtual Disk Format 1.1 specification
+
+const VMDK_SECTOR_SIZE = 512
+const VMDK_SPARSE_MAGIC = 0x564d444b
+const VMDK_GRAIN_SECTORS = 128
+const VMDK_GTES_PER_GT = 512
+
+const (
+	VMDKMonolithicSparse = "monolithicSparse"
+	VMDKStreamOptimized  = "streamOptimized"
+)
+
+const vmdkGDAtEnd = 0xffffffffffffffff
+const vmdkDescriptorSectors = 20
+
+const (
+	vmdkFlagNewlineTest = 1 << 0
+	vmdkFlagRedundantGT = 1 << 1
+	vmdkFlagCompressed  = 1 << 16
+	vmdkFlagMarkers     = 1 << 17
+)
+
+const (
+	vmdkMarkerEOS = iota
+	vmdkMarkerGT
+	vmdkMarkerGD
+	vmdkMarkerFooter
+)
+
+const vmdkCompressDeflate = 1
+
+type vmdkSparseHeader struct {
+	MagicNumber        uint32
+	Version            uint32
+	Flags              uint32
+	Capacity           uint64
+	GrainSize          uint64
+	DescriptorOffset   uint64
+	DescriptorSize     uint64
+	NumGTEsPerGT       uint32
+	RGDOffset          uint64
+	GDOffset           uint64
+	OverHead           uint64
+	UncleanShutdown    uint8
+	SingleEndLineChar  uint8
+	NonEndLineChar     uint8
+	DoubleEndLineChar1 uint8
+	DoubleEndLineChar2 uint8
+	CompressAlgorithm  uint16
+	Pad                [433]uint8
+}
+
+type vmdkMarker struct {
+	Value uint64
+	Size  uint32
+	Type  uint32
+	Pad   [496]uint8
+}
+
+type VMDKOptions struct {
+	Format     string // monolithicSparse or streamOptimized
+	ExtentName string // filename written in the descriptor extent description
+	Adapter    string // descriptor ddb.adapterType; default lsilogic
+	Capacity   int64  // minimum capacity in bytes; the source size is used if larger
+}
+
+func vmdkSectors(size int64) int64 {
+	return (size + VMDK_SECTOR_SIZE - 1) / VMDK_SECTOR_SIZE
+}
+
+func vmdkAlign(sector, alignment int64) int64 {
+	return (sector + alignment - 1) / alignment * alignment
+}
+
+func isZero(data []byte) bool {
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// return a VMDK descriptor for a single sparse extent
+func VMDKDescriptor(createType, extentName, adapter string, capacitySectors int64) string {
+	if adapter == "" {
+		adapter = "lsilogic"
+	}
+	heads, sectors, maxCylinders := int64(255), int64(63), int64(65535)
+	if adapter == "ide" {
+		heads, maxCylinders = 16, 16383
+	}
+	cylinders := max(min(capacitySectors/(heads*sectors), maxCylinders), 1)
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Disk DescriptorFile\n")
+	fmt.Fprintf(&b, "version=1\n")
+	fmt.Fprintf(&b, "encoding=\"UTF-8\"\n")
+	fmt.Fprintf(&b, "CID=%08x\n", rand.Uint32())
+	fmt.Fprintf(&b, "parentCID=ffffffff\n")
+	fmt.Fprintf(&b, "createType=\"%s\"\n", createType)
+	fmt.Fprintf(&b, "\n# Extent description\n")
+	fmt.Fprintf(&b, "RW %d SPARSE \"%s\"\n", capacitySectors, extentName)
+	fmt.Fprintf(&b, "\n# The Disk Data Base\n#DDB\n\n")
+	fmt.Fprintf(&b, "ddb.adapterType = \"%s\"\n", adapter)
+	fmt.Fprintf(&b, "ddb.geometry.cylinders = \"%d\"\n", cylinders)
+	fmt.Fprintf(&b, "ddb.geometry.heads = \"%d\"\n", heads)
+	fmt.Fprintf(&b, "ddb.geometry.sectors = \"%d\"\n", sectors)
+	fmt.Fprintf(&b, "ddb.virtualHWVersion = \"4\"\n")
+	return b.String()
+}
+
+type vmdkWriter struct {
+	w      io.WriterAt
+	offset int64
+}
+
+func (w *vmdkWriter) write(data []byte) error {
+	_, err := w.w.WriteAt(data, w.offset)
+	if err != nil {
+		return wrap(err)
+	}
+	w.offset += int64(len(data))
+	return nil
+}
+
+func (w *vmdkWriter) writeStruct(value any) error {
+	var buf bytes.Buffer
+	err := binary.Write(&buf, binary.LittleEndian, value)
+	if err != nil {
+		return wrap(err)
+	}
+	return w.write(buf.Bytes())
+}
+
+// pad to the next sector boundary
+func (w *vmdkWriter) pad() error {
+	pad := vmdkAlign(w.offset, VMDK_SECTOR_SIZE) - w.offset
+	if pad == 0 {
+		return nil
+	}
+	return w.write(make([]byte, pad))
+}
+
+func uint32Table(entries []uint32, sectors int64) []byte {
+	data := make([]byte, sectors*VMDK_SECTOR_SIZE)
+	for i, entry := range entries {
+		binary.LittleEndian.PutUint32(data[i*4:], entry)
+	}
+	return data
+}
+
+// write a sparse VMDK containing size bytes read from src, returning the length of the VMDK
+func WriteVMDK(w io.WriterAt, src io.ReaderAt, size int64, options VMDKOptions) (int64, error) {
+	stream := false
+	switch options.Format {
+	case "", VMDKMonolithicSparse:
+	case VMDKStreamOptimized:
+		stream = true
+	default:
+		return 0, Fatalf("unsupported VMDK format: '%s'", options.Format)
+	}
+	if options.ExtentName == "" {
+		return 0, Fatalf("missing VMDK extent name")
+	}
+	capacity := vmdkSectors(max(size, options.Capacity))
+	grainBytes := int64(VMDK_GRAIN_SECTORS * VMDK_SECTOR_SIZE)
+	numGrains := (capacity + VMDK_GRAIN_SECTORS - 1) / VMDK_GRAIN_SECTORS
+	numGTs := (numGrains + VMDK_GTES_PER_GT - 1) / VMDK_GTES_PER_GT
+	gdSectors := vmdkSectors(numGTs * 4)
+	gtSectors := vmdkSectors(VMDK_GTES_PER_GT * 4)
+
+	createType := VMDKMonolithicSparse
+	if stream {
+		createType = VMDKStreamOptimized
+	}
+	descriptor := []byte(VMDKDescriptor(createType, options.ExtentName, options.Adapter, capacity))
+	descriptorSectors := max(vmdkSectors(int64(len(descriptor))), vmdkDescriptorSectors)
+
+	header := vmdkSparseHeader{
+		MagicNumber:        VMDK_SPARSE_MAGIC,
+		Version:            1,
+		Flags:              vmdkFlagNewlineTest | vmdkFlagRedundantGT,
+		Capacity:           uint64(capacity),
+		GrainSize:          VMDK_GRAIN_SECTORS,
+		DescriptorOffset:   1,
+		DescriptorSize:     uint64(descriptorSectors),
+		NumGTEsPerGT:       VMDK_GTES_PER_GT,
+		SingleEndLineChar:  '\n',
+		NonEndLineChar:     ' ',
+		DoubleEndLineChar1: '\r',
+		DoubleEndLineChar2: '\n',
+	}
+	// monolithicSparse metadata: redundant GD and GTs, then GD and GTs, then grains
+	rgdOffset := 1 + descriptorSectors
+	rgtOffset := rgdOffset + gdSectors
+	gdOffset := rgtOffset + numGTs*gtSectors
+	gtOffset := gdOffset + gdSectors
+	if stream {
+		header.Version = 3
+		header.Flags = vmdkFlagNewlineTest | vmdkFlagCompressed | vmdkFlagMarkers
+		header.GDOffset = vmdkGDAtEnd
+		header.CompressAlgorithm = vmdkCompressDeflate
+		header.OverHead = uint64(vmdkAlign(1+descriptorSectors, VMDK_GRAIN_SECTORS))
+	} else {
+		header.RGDOffset = uint64(rgdOffset)
+		header.GDOffset = uint64(gdOffset)
+		header.OverHead = uint64(vmdkAlign(gtOffset+numGTs*gtSectors, VMDK_GRAIN_SECTORS))
+	}
+
+	out := vmdkWriter{w: w}
+	err := out.writeStruct(&header)
+	if err != nil {
+		return 0, wrap(err)
+	}
+	err = out.write(descriptor)
+	if err != nil {
+		return 0, wrap(err)
+	}
+	out.offset = int64(header.OverHead) * VMDK_SECTOR_SIZE
+
+	gtes := make([]uint32, numGTs*VMDK_GTES_PER_GT)
+	grain := make([]byte, grainBytes)
+	for i := int64(0); i < numGrains; i++ {
+		clear(grain)
+		start := i * grainBytes
+		if start < size {
+			n, err := src.ReadAt(grain[:min(grainBytes, size-start)], start)
+			if err != nil && !(err == io.EOF && int64(n) == min(grainBytes, size-start)) {
+				return 0, wrap(err)
+			}
+		}
+		if isZero(grain) {
+			continue
+		}
+		sector := out.offset / VMDK_SECTOR_SIZE
+		if sector > 0xffffffff {
+			return 0, Fatalf("VMDK sparse extent too large")
+		}
+		gtes[i] = uint32(sector)
+		if !stream {
+			err = out.write(grain)
+			if err != nil {
+				return 0, wrap(err)
+			}
+			continue
+		}
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		_, err = zw.Write(grain)
+		if err != nil {
+			return 0, wrap(err)
+		}
+		err = zw.Close()
+		if err != nil {
+			return 0, wrap(err)
+		}
+		marker := make([]byte, 12)
+		binary.LittleEndian.PutUint64(marker[0:8], uint64(i*VMDK_GRAIN_SECTORS))
+		binary.LittleEndian.PutUint32(marker[8:12], uint32(compressed.Len()))
+		err = out.write(append(marker, compressed.Bytes()...))
+		if err != nil {
+			return 0, wrap(err)
+		}
+		err = out.pad()
+		if err != nil {
+			return 0, wrap(err)
+		}
+	}
+
+	gd := make([]uint32, numGTs)
+	if !stream {
+		rgd := make([]uint32, numGTs)
+		for i := int64(0); i < numGTs; i++ {
+			table := uint32Table(gtes[i*VMDK_GTES_PER_GT:(i+1)*VMDK_GTES_PER_GT], gtSectors)
+			rgd[i] = uint32(rgtOffset + i*gtSectors)
+			gd[i] = uint32(gtOffset + i*gtSectors)
+			_, err = w.WriteAt(table, int64(rgd[i])*VMDK_SECTOR_SIZE)
+			if err != nil {
+				return 0, wrap(err)
+			}
+			_, err = w.WriteAt(table, int64(gd[i])*VMDK_SECTOR_SIZE)
+			if err != nil {
+				return 0, wrap(err)
+			}
+		}
+		_, err = w.WriteAt(uint32Table(rgd, gdSectors), rgdOffset*VMDK_SECTOR_SIZE)
+		if err != nil {
+			return 0, wrap(err)
+		}
+		_, err = w.WriteAt(uint32Table(gd, gdSectors), gdOffset*VMDK_SECTOR_SIZE)
+		if err != nil {
+			return 0, wrap(err)
+		}
+		return out.offset, nil
+	}
+
+	// streamOptimized metadata follows the grains; empty grain tables are omitted
+	for i := int64(0); i < numGTs; i++ {
+		entries := gtes[i*VMDK_GTES_PER_GT : (i+1)*VMDK_GTES_PER_GT]
+		table := uint32Table(entries, gtSectors)
+		if isZero(table) {
+			continue
+		}
+		err = out.writeStruct(&vmdkMarker{Value: uint64(gtSectors), Type: vmdkMarkerGT})
+		if err != nil {
+			return 0, wrap(err)
+		}
+		gd[i] = uint32(out.offset / VMDK_SECTOR_SIZE)
+		err = out.write(table)
+		if err != nil {
+			return 0, wrap(err)
+		}
+	}
+	err = out.writeStruct(&vmdkMarker{Value: uint64(gdSectors), Type: vmdkMarkerGD})
+	if err != nil {
+		return 0, wrap(err)
+	}
+	footer := header
+	footer.GDOffset = uint64(out.offset / VMDK_SECTOR_SIZE)
+	err = out.write(uint32Table(gd, gdSectors))
+	if err != nil {
+		return 0, wrap(err)
+	}
+	err = out.writeStruct(&vmdkMarker{Value: 1, Type: vmdkMarkerFooter})
+	if err != nil {
+		return 0, wrap(err)
+	}
+	err = out.writeStruct(&footer)
+	if err != nil {
+		return 0, wrap(err)
+	}
+	err = out.writeStruct(&vmdkMarker{Type: vmdkMarkerEOS})
+	if err != nil {
+		return 0, wrap(err)
+	}
+	return out.offset, nil
+}
+
+// VMDKSparseReader reads the virtual disk contents of a hosted sparse extent
+type VMDKSparseReader struct {
+	r          io.ReaderAt
+	header     vmdkSparseHeader
+	gd         []uint32
+	gts        map[uint32][]uint32
+	grainBytes int64
+	grainIndex int64
+	grain      []byte
+}
+
+func readVMDKSparseHeader(r io.ReaderAt, offset int64) (*vmdkSparseHeader, error) {
+	data := make([]byte, VMDK_SECTOR_SIZE)
+	_, err := r.ReadAt(data, offset)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	var header vmdkSparseHeader
+	err = binary.Read(bytes.NewReader(data), binary.LittleEndian, &header)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if header.MagicNumber != VMDK_SPARSE_MAGIC {
+		return nil, Fatalf("not a VMDK sparse extent")
+	}
+	return &header, nil
+}
+
+func OpenVMDKSparse(r io.ReaderAt, size int64) (*VMDKSparseReader, error) {
+	header, err := readVMDKSparseHeader(r, 0)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if header.GDOffset == vmdkGDAtEnd {
+		// streamOptimized footer precedes the end-of-stream marker
+		header, err = readVMDKSparseHeader(r, size-2*VMDK_SECTOR_SIZE)
+		if err != nil {
+			return nil, wrap(err)
+		}
+	}
+	if header.GrainSize == 0 || header.NumGTEsPerGT == 0 {
+		return nil, Fatalf("invalid VMDK sparse header")
+	}
+	if header.Flags&vmdkFlagCompressed != 0 && header.CompressAlgorithm != vmdkCompressDeflate {
+		return nil, Fatalf("unsupported VMDK compression: %d", header.CompressAlgorithm)
+	}
+	reader := VMDKSparseReader{
+		r:          r,
+		header:     *header,
+		gts:        make(map[uint32][]uint32),
+		grainBytes: int64(header.GrainSize) * VMDK_SECTOR_SIZE,
+		grainIndex: -1,
+	}
+	numGrains := (int64(header.Capacity) + int64(header.GrainSize) - 1) / int64(header.GrainSize)
+	numGTs := (numGrains + int64(header.NumGTEsPerGT) - 1) / int64(header.NumGTEsPerGT)
+	reader.gd, err = readUint32Table(r, int64(header.GDOffset)*VMDK_SECTOR_SIZE, numGTs)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	return &reader, nil
+}
+
+func readUint32Table(r io.ReaderAt, offset, count int64) ([]uint32, error) {
+	data := make([]byte, count*4)
+	_, err := r.ReadAt(data, offset)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	table := make([]uint32, count)
+	for i := range table {
+		table[i] = binary.LittleEndian.Uint32(data[i*4:])
+	}
+	return table, nil
+}
+
+func (v *VMDKSparseReader) Size() int64 {
+	return int64(v.header.Capacity) * VMDK_SECTOR_SIZE
+}
+
+func (v *VMDKSparseReader) Descriptor() ([]byte, error) {
+	data := make([]byte, v.header.DescriptorSize*VMDK_SECTOR_SIZE)
+	_, err := v.r.ReadAt(data, int64(v.header.DescriptorOffset)*VMDK_SECTOR_SIZE)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	return bytes.TrimRight(data, "\x00"), nil
+}
+
+// read grain index into the grain buffer
+func (v *VMDKSparseReader) readGrain(index int64) error {
+	if index == v.grainIndex {
+		return nil
+	}
+	v.grainIndex = -1
+	if v.grain == nil {
+		v.grain = make([]byte, v.grainBytes)
+	}
+	clear(v.grain)
+	gtIndex := index / int64(v.header.NumGTEsPerGT)
+	gtOffset := v.gd[gtIndex]
+	if gtOffset == 0 {
+		v.grainIndex = index
+		return nil
+	}
+	gt, ok := v.gts[gtOffset]
+	if !ok {
+		var err error
+		gt, err = readUint32Table(v.r, int64(gtOffset)*VMDK_SECTOR_SIZE, int64(v.header.NumGTEsPerGT))
+		if err != nil {
+			return wrap(err)
+		}
+		v.gts[gtOffset] = gt
+	}
+	sector := gt[index%int64(v.header.NumGTEsPerGT)]
+	// entry 1 marks a zeroed grain
+	if sector > 1 {
+		offset := int64(sector) * VMDK_SECTOR_SIZE
+		if v.header.Flags&vmdkFlagCompressed == 0 {
+			_, err := v.r.ReadAt(v.grain, offset)
+			if err != nil && err != io.EOF {
+				return wrap(err)
+			}
+		} else {
+			marker := make([]byte, 12)
+			_, err := v.r.ReadAt(marker, offset)
+			if err != nil {
+				return wrap(err)
+			}
+			compressed := make([]byte, binary.LittleEndian.Uint32(marker[8:12]))
+			_, err = v.r.ReadAt(compressed, offset+12)
+			if err != nil {
+				return wrap(err)
+			}
+			zr, err := zlib.NewReader(bytes.NewReader(compressed))
+			if err != nil {
+				return wrap(err)
+			}
+			_, err = io.ReadFull(zr, v.grain)
+			if err != nil && err != io.ErrUnexpectedEOF {
+				return wrap(err)
+			}
+		}
+	}
+	v.grainIndex = index
+	return nil
+}
+
+func (v *VMDKSparseReader) ReadAt(p []byte, off int64) (int, error) {
+	size := v.Size()
+	count := 0
+	for count < len(p) {
+		if off >= size {
+			return count, io.EOF
+		}
+		err := v.readGrain(off / v.grainBytes)
+		if err != nil {
+			return count, wrap(err)
+		}
+		grainStart := off / v.grainBytes * v.grainBytes
+		n := copy(p[count:], v.grain[off-grainStart:min(v.grainBytes, size-grainStart)])
+		count += n
+		off += int64(n)
+	}
+	return count, nil
+}
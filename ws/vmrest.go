@@ -0,0 +1,1107 @@
+package ws
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const VMREST_DEFAULT_URL = "http://127.0.0.1:8697"
+const VMREST_MEDIA_TYPE = "application/vnd.vmware.vmw.rest-v1+json"
+
+// vmrest NIC types; custom adapters also name the vmnet
+var VMREST_NIC_TYPES = map[string]bool{
+	"bridged":  true,
+	"nat":      true,
+	"hostonly": true,
+	"custom":   true,
+}
+
+var VMREST_POWER_STATES = map[string]string{
+	"poweredOn":  "on",
+	"poweredOff": "off",
+	"suspended":  "suspended",
+	"paused":     "paused",
+}
+
+var VMREST_ISO_PATTERN = regexp.MustCompile(`(?i)\.iso$`)
+
+// vmrest shared folder flags
+const VMREST_SHARE_WRITE_ACCESS = 4
+
+type vmrestID struct {
+	ID   string `json:"id,omitzero"`
+	Path string `json:"path,omitzero"`
+}
+
+type vmrestRegistration struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+type vmrestCpuRam struct {
+	Processors int `json:"processors"`
+	Memory     int `json:"memory"`
+}
+
+type vmrestNIC struct {
+	Index      int    `json:"index,omitzero"`
+	Type       string `json:"type"`
+	Vmnet      string `json:"vmnet,omitzero"`
+	MacAddress string `json:"macAddress,omitzero"`
+}
+
+type vmrestNICList struct {
+	Num  int         `json:"num"`
+	Nics []vmrestNIC `json:"nics"`
+}
+
+type vmrestSharedFolder struct {
+	FolderID string `json:"folder_id"`
+	HostPath string `json:"host_path"`
+	Flags    int    `json:"flags"`
+}
+
+type vmrestParam struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type vmrestIP struct {
+	IP string `json:"ip"`
+}
+
+// vmrest is a Controller using the Workstation vmrest API for inventory,
+// power, CPU/RAM, NIC and shared folder operations.  Operations the API does
+// not support are passed to the embedded CLI controller, so those require
+// the instance to be in a vmware_roots directory.  Instances found in
+// vmware_roots are added to the vmrest inventory on first use.
+type vmrest struct {
+	*vmctl
+	URL string
+	api *vmrestClient
+}
+
+// the vmrest.url, vmrest.username and vmrest.password config values select the
+// API server; vmrest.cert, vmrest.key and vmrest.ca configure client TLS
+func newVMRestController(v *vmctl, prefix string) (*vmrest, error) {
+	ViperSetDefault(prefix+"vmrest.url", VMREST_DEFAULT_URL)
+	baseURL := strings.TrimRight(ViperGetString(prefix+"vmrest.url"), "/")
+	headers := map[string]string{
+		"Accept":       VMREST_MEDIA_TYPE,
+		"Content-Type": VMREST_MEDIA_TYPE,
+	}
+	username := ViperGetString(prefix + "vmrest.username")
+	if username != "" {
+		credentials := username + ":" + ViperGetString(prefix+"vmrest.password")
+		headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+	}
+	api, err := newVMRestClient(
+		prefix+"vmrest.",
+		baseURL+"/api",
+		ViperGetString(prefix+"vmrest.cert"),
+		ViperGetString(prefix+"vmrest.key"),
+		ViperGetString(prefix+"vmrest.ca"),
+		headers,
+	)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	r := vmrest{vmctl: v, URL: baseURL, api: api}
+	return &r, nil
+}
+
+func (r *vmrest) Close() error {
+	r.api.Close()
+	return r.vmctl.Close()
+}
+
+// send an API request; the request is canceled if ctx is done
+func (r *vmrest) request(ctx context.Context, method, path string, request, response any) error {
+	err := r.api.Do(ctx, method, path, request, response)
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return wrap(ctx.Err())
+	}
+	if isTransportError(err) {
+		return wrapf("%w: %w", ErrTransport, err)
+	}
+	return wrap(err)
+}
+
+func vmrestPath(vm *VM, elements ...string) string {
+	path := "/vms/" + url.PathEscape(vm.Id)
+	for _, element := range elements {
+		path += "/" + url.PathEscape(element)
+	}
+	return path
+}
+
+// return the vmrest inventory
+func (r *vmrest) inventory(ctx context.Context) ([]VM, error) {
+	var response VmRestGetVmsResponse
+	err := r.request(ctx, "GET", "/vms", nil, &response)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	vms := []VM{}
+	for _, item := range response {
+		path, err := PathNormalize(item.Path)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		name, err := PathToName(path)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		vms = append(vms, VM{Id: item.ID, Path: path, Name: name})
+	}
+	return vms, nil
+}
+
+// return the VM for a vmrest ID, name or pathname; an instance found in
+// vmware_roots but missing from the inventory is registered; as with the
+// CLI controller, IDs take precedence and names are case sensitive
+func (r *vmrest) Get(ctx context.Context, vid string) (VM, error) {
+	if r.debug {
+		log.Printf("vmrest Get(%s)\n", vid)
+	}
+	vms, err := r.inventory(ctx)
+	if err != nil {
+		return VM{}, wrap(err)
+	}
+	for _, vm := range vms {
+		if vid == vm.Id {
+			return vm, nil
+		}
+	}
+	for _, vm := range vms {
+		if vid == vm.Name || vid == vm.Path {
+			return vm, nil
+		}
+	}
+	vm, err := r.vmctl.Get(ctx, vid)
+	if err != nil {
+		return VM{}, wrap(err)
+	}
+	return r.register(ctx, &vm)
+}
+
+func (r *vmrest) register(ctx context.Context, vm *VM) (VM, error) {
+	hostPath, err := PathnameFormat(r.Remote, vm.Path)
+	if err != nil {
+		return VM{}, wrap(err)
+	}
+	if r.verbose {
+		fmt.Printf("[%s] Registering %s\n", vm.Name, hostPath)
+	}
+	request := vmrestRegistration{Name: vm.Name, Path: hostPath}
+	var response vmrestID
+	err = r.request(ctx, "POST", "/vms/registration", &request, &response)
+	if err != nil {
+		return VM{}, wrapf("[%s] vmrest registration failed: %w", vm.Name, err)
+	}
+	return VM{Id: response.ID, Path: vm.Path, Name: vm.Name}, nil
+}
+
+func (r *vmrest) Show(ctx context.Context, name string, options ShowOptions) (*[]VMState, error) {
+	if r.debug {
+		log.Printf("vmrest Show(%s, %+v)\n", name, options)
+	}
+	vms, err := r.inventory(ctx)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	states := []VMState{}
+	for _, vm := range vms {
+		if !showNameMatch(name, vm.Name) {
+			continue
+		}
+		if options.Running {
+			err := r.queryPowerState(ctx, &vm)
+			if err != nil {
+				return nil, wrap(err)
+			}
+			if !vm.Running {
+				continue
+			}
+		}
+		if options.Detail {
+			state, err := r.GetState(ctx, vm.Id)
+			if err != nil {
+				return nil, wrap(err)
+			}
+			states = append(states, *state)
+		} else {
+			states = append(states, VMState{Name: vm.Name})
+		}
+	}
+	return &states, nil
+}
+
+func (r *vmrest) GetState(ctx context.Context, vid string) (*VMState, error) {
+	vm, err := r.Get(ctx, vid)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	err = r.queryVM(ctx, &vm, QueryTypeState)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	state := VMState{
+		Name:       vm.Name,
+		Path:       vm.Path,
+		Id:         vm.Id,
+		Nics:       vm.Nics,
+		IpAddress:  vm.IpAddress,
+		PowerState: vm.PowerState,
+	}
+	return &state, nil
+}
+
+func (r *vmrest) queryVM(ctx context.Context, vm *VM, queryType QueryType) error {
+	if r.debug {
+		log.Printf("vmrest queryVM(%s, %d)\n", vm.Name, queryType)
+	}
+	if queryType == QueryTypeConfig || queryType == QueryTypeAll {
+		err := r.getConfig(ctx, vm)
+		if err != nil {
+			return wrap(err)
+		}
+		_, _, err = r.getDisks(ctx, vm)
+		if err != nil {
+			return wrap(err)
+		}
+	}
+	if queryType == QueryTypeState || queryType == QueryTypeAll {
+		err := r.queryPowerState(ctx, vm)
+		if err != nil {
+			return wrap(err)
+		}
+		err = r.getNics(ctx, vm)
+		if err != nil {
+			return wrap(err)
+		}
+		err = r.getIpAddress(ctx, vm)
+		if err != nil {
+			return wrap(err)
+		}
+	}
+	return nil
+}
+
+// set the VM configuration fields from the vmrest restrictions
+func (r *vmrest) getConfig(ctx context.Context, vm *VM) error {
+	var response VmRestGetVmRestrictionsResponse
+	err := r.request(ctx, "GET", vmrestPath(vm, "restrictions"), nil, &response)
+	if err != nil {
+		return wrap(err)
+	}
+	vm.CpuCount = response.Cpu.Processors
+	vm.RamSize = FormatSize(int64(response.Memory) * MB)
+	vm.IsoFile = ""
+	vm.IsoAttached = false
+	vm.IsoAttachOnStart = false
+	for _, device := range response.CddvdList.Devices {
+		if VMREST_ISO_PATTERN.MatchString(device.DevicePath) {
+			vm.IsoFile, err = PathNormalize(device.DevicePath)
+			if err != nil {
+				return wrap(err)
+			}
+			vm.IsoAttached = true
+			vm.IsoAttachOnStart = device.StartConnected
+			break
+		}
+	}
+	vm.SerialAttached = len(response.SerialPortList.Devices) > 0
+	vm.SerialPipe = ""
+	if vm.SerialAttached {
+		vm.SerialPipe, err = PathNormalize(response.SerialPortList.Devices[0].DevicePath)
+		if err != nil {
+			return wrap(err)
+		}
+	}
+	vm.VncEnabled = response.RemoteVnc.VncEnabled
+	vm.VncPort = response.RemoteVnc.VncPort
+	isolation := response.GuestIsolation
+	vm.FileShareEnabled = !isolation.HgfsDisabled
+	vm.ClipboardEnabled = !(isolation.CopyDisabled && isolation.PasteDisabled && isolation.DndDisabled)
+	vm.Nics = []VMNic{}
+	for _, nic := range response.NicList.Nics {
+		vm.Nics = append(vm.Nics, vmrestNICState(vmrestNIC{
+			Index:      nic.Index,
+			Type:       nic.Type,
+			Vmnet:      nic.Vmnet,
+			MacAddress: nic.MacAddress,
+		}))
+	}
+	return nil
+}
+
+func (r *vmrest) queryPowerState(ctx context.Context, vm *VM) error {
+	if r.debug {
+		log.Printf("[%s] vmrest queryPowerState\n", vm.Name)
+	}
+	var response VmRestGetPowerStateResponse
+	err := r.request(ctx, "GET", vmrestPath(vm, "power"), nil, &response)
+	if err != nil {
+		return wrap(err)
+	}
+	state, ok := VMREST_POWER_STATES[response.PowerState]
+	if !ok {
+		return Fatalf("[%s] unexpected vmrest power state: '%s'", vm.Name, response.PowerState)
+	}
+	vm.PowerState = state
+	vm.Running = state != "off"
+	return nil
+}
+
+func (r *vmrest) requirePowerState(ctx context.Context, vm *VM, state, action string) error {
+	err := r.queryPowerState(ctx, vm)
+	if err != nil {
+		return wrap(err)
+	}
+	if vm.PowerState != state {
+		return wrapf("[%s] cannot %s: %w", vm.Name, action, &ErrPowerState{Want: state, Have: vm.PowerState})
+	}
+	return nil
+}
+
+// send a vmrest power operation: on, off, shutdown, suspend, pause, unpause or reset
+func (r *vmrest) powerOperation(ctx context.Context, vm *VM, operation string) error {
+	if r.verbose {
+		fmt.Printf("[%s] Requesting %s\n", vm.Name, operation)
+	}
+	body := []byte(operation)
+	var response VmRestGetPowerStateResponse
+	err := r.request(ctx, "PUT", vmrestPath(vm, "power"), &body, &response)
+	if err != nil {
+		return wrapf("[%s] vmrest power %s failed: %w", vm.Name, operation, err)
+	}
+	if r.verbose {
+		fmt.Printf("[%s] %s request complete\n", vm.Name, operation)
+	}
+	return nil
+}
+
+func (r *vmrest) Wait(ctx context.Context, vid, state string) error {
+	if r.debug {
+		log.Printf("vmrest Wait(%s, %s)\n", vid, state)
+	}
+	vm, err := r.Get(ctx, vid)
+	if err != nil {
+		return wrap(err)
+	}
+	state = waitStateName(state)
+	ok, err := r.waitPowerState(ctx, &vm, state, r.TimeoutSeconds)
+	if err != nil {
+		return wrap(err)
+	}
+	if !ok {
+		return wrapf("[%s] %w awaiting power state %s", vm.Name, ErrTimeout, state)
+	}
+	return nil
+}
+
+// poll until the power state matches; return false if timeoutSeconds elapses first
+func (r *vmrest) waitPowerState(ctx context.Context, vm *VM, state string, timeoutSeconds int64) (bool, error) {
+	err := r.validatePowerState(state)
+	if err != nil {
+		return false, wrap(err)
+	}
+	if r.verbose {
+		fmt.Printf("[%s] Awaiting power state: %s\n", vm.Name, state)
+	}
+	start := time.Now()
+	interval := time.Duration(r.IntervalSeconds) * time.Second
+	timeout := time.Duration(timeoutSeconds) * time.Second
+	for {
+		err := r.queryPowerState(ctx, vm)
+		if err != nil {
+			return false, wrap(err)
+		}
+		if vm.PowerState == state {
+			if r.verbose {
+				fmt.Printf("[%s] Detected power %s\n", vm.Name, state)
+			}
+			return true, nil
+		}
+		if timeoutSeconds != 0 && time.Since(start) > timeout {
+			return false, nil
+		}
+		err = sleepContext(ctx, interval)
+		if err != nil {
+			return false, wrap(err)
+		}
+	}
+}
+
+// GUI, stretch and ISO start options use the CLI controller
+func (r *vmrest) Start(ctx context.Context, vid string, options StartOptions, isoOptions IsoOptions) (string, error) {
+	if r.debug {
+		log.Printf("vmrest Start(%s, %+v, %+v)\n", vid, options, isoOptions)
+	}
+	vm, err := r.Get(ctx, vid)
+	if err != nil {
+		return "", wrap(err)
+	}
+	if !options.Background || options.FullScreen || options.ModifyStretch || isoOptions.ModifyISO {
+		return r.vmctl.Start(ctx, vm.Name, options, isoOptions)
+	}
+	err = r.queryPowerState(ctx, &vm)
+	if err != nil {
+		return "", wrap(err)
+	}
+	action := "start"
+	result := "started"
+	switch vm.PowerState {
+	case "on":
+		return "already started", nil
+	case "paused":
+		return r.Unpause(ctx, vm.Id, StopOptions{Wait: options.Wait})
+	case "suspended":
+		action = "resume"
+		result = "resumed"
+	}
+	err = r.powerOperation(ctx, &vm, "on")
+	if err != nil {
+		return "", wrap(err)
+	}
+	if options.Wait {
+		err := r.Wait(ctx, vm.Id, "on")
+		if err != nil {
+			return "", wrap(err)
+		}
+		return result, nil
+	}
+	return action + " pending", nil
+}
+
+func (r *vmrest) Stop(ctx context.Context, vid string, options StopOptions) (string, error) {
+	if r.debug {
+		log.Printf("vmrest Stop(%s, %+v)\n", vid, options)
+	}
+	vm, err := r.Get(ctx, vid)
+	if err != nil {
+		return "", wrap(err)
+	}
+	err = r.queryPowerState(ctx, &vm)
+	if err != nil {
+		return "", wrap(err)
+	}
+	if vm.PowerState == "off" {
+		return "already stopped", nil
+	}
+
+	if options.Escalate && !options.PowerOff {
+		grace := options.GraceSeconds
+		if grace == 0 {
+			grace = r.GraceSeconds
+		}
+		msg := fmt.Sprintf("[%s] shutdown not complete after %d seconds, escalating to forced power down", vm.Name, grace)
+		err := r.powerOperation(ctx, &vm, "shutdown")
+		if err != nil {
+			msg = fmt.Sprintf("[%s] shutdown request failed, escalating to forced power down: %v", vm.Name, err)
+		} else {
+			ok, err := r.waitPowerState(ctx, &vm, "off", grace)
+			if err != nil {
+				return "", wrap(err)
+			}
+			if ok {
+				return "stopped", nil
+			}
+		}
+		if r.verbose {
+			fmt.Println(msg)
+		}
+		log.Println(msg)
+		options.PowerOff = true
+	}
+
+	operation := "shutdown"
+	if options.PowerOff {
+		operation = "off"
+	}
+	err = r.powerOperation(ctx, &vm, operation)
+	if err != nil {
+		return "", wrap(err)
+	}
+	if options.Wait {
+		err := r.Wait(ctx, vm.Id, "off")
+		if err != nil {
+			return "", wrap(err)
+		}
+		return "stopped", nil
+	}
+	return "stop pending", nil
+}
+
+// a hard suspend uses the CLI controller
+func (r *vmrest) Suspend(ctx context.Context, vid string, options StopOptions) (string, error) {
+	if r.debug {
+		log.Printf("vmrest Suspend(%s, %+v)\n", vid, options)
+	}
+	if options.PowerOff {
+		vm, err := r.Get(ctx, vid)
+		if err != nil {
+			return "", wrap(err)
+		}
+		return r.vmctl.Suspend(ctx, vm.Name, options)
+	}
+	return r.powerRequest(ctx, vid, "suspend", "suspended", "suspended", options.Wait)
+}
+
+func (r *vmrest) Pause(ctx context.Context, vid string, options StopOptions) (string, error) {
+	if r.debug {
+		log.Printf("vmrest Pause(%s, %+v)\n", vid, options)
+	}
+	return r.powerRequest(ctx, vid, "pause", "paused", "paused", options.Wait)
+}
+
+func (r *vmrest) Unpause(ctx context.Context, vid string, options StopOptions) (string, error) {
+	if r.debug {
+		log.Printf("vmrest Unpause(%s, %+v)\n", vid, options)
+	}
+	return r.powerRequest(ctx, vid, "unpause", "on", "unpaused", options.Wait)
+}
+
+// send a vmrest power operation and optionally wait for the resulting power state
+func (r *vmrest) powerRequest(ctx context.Context, vid, operation, state, result string, wait bool) (string, error) {
+	vm, err := r.Get(ctx, vid)
+	if err != nil {
+		return "", wrap(err)
+	}
+	err = r.queryPowerState(ctx, &vm)
+	if err != nil {
+		return "", wrap(err)
+	}
+	if vm.PowerState == state {
+		return "already " + state, nil
+	}
+	switch operation {
+	case "suspend", "pause":
+		if vm.PowerState != "on" {
+			return "", wrapf("[%s] cannot %s: %w", vm.Name, operation, &ErrPowerState{Want: "on", Have: vm.PowerState})
+		}
+	case "unpause":
+		if vm.PowerState != "paused" {
+			return "", wrapf("[%s] cannot %s: %w", vm.Name, operation, &ErrPowerState{Want: "paused", Have: vm.PowerState})
+		}
+	}
+	err = r.powerOperation(ctx, &vm, operation)
+	if err != nil {
+		return "", wrap(err)
+	}
+	if wait {
+		err := r.Wait(ctx, vm.Id, state)
+		if err != nil {
+			return "", wrap(err)
+		}
+		return result, nil
+	}
+	return operation + " pending", nil
+}
+
+// vmrest only supports a hard reset; a soft reboot uses the CLI controller
+func (r *vmrest) Reboot(ctx context.Context, vid string, options StopOptions) (string, error) {
+	if r.debug {
+		log.Printf("vmrest Reboot(%s, %+v)\n", vid, options)
+	}
+	vm, err := r.Get(ctx, vid)
+	if err != nil {
+		return "", wrap(err)
+	}
+	if !options.PowerOff {
+		return r.vmctl.Reboot(ctx, vm.Name, options)
+	}
+	err = r.requirePowerState(ctx, &vm, "on", "reboot")
+	if err != nil {
+		return "", wrap(err)
+	}
+	if options.Wait {
+		err = r.requireToolsRunning(ctx, &vm)
+		if err != nil {
+			return "", wrap(err)
+		}
+	}
+	err = r.powerOperation(ctx, &vm, "reset")
+	if err != nil {
+		return "", wrap(err)
+	}
+	if options.Wait {
+		err := r.waitGuestReboot(ctx, &vm)
+		if err != nil {
+			return "", wrap(err)
+		}
+		return "rebooted", nil
+	}
+	return "reboot pending", nil
+}
+
+// delete the instance and its files with vmrest
+func (r *vmrest) Destroy(ctx context.Context, vid string, options DestroyOptions) error {
+	if r.debug {
+		log.Printf("vmrest Destroy(%s, %+v)\n", vid, options)
+	}
+	vm, err := r.Get(ctx, vid)
+	if err != nil {
+		return wrap(err)
+	}
+	err = r.queryPowerState(ctx, &vm)
+	if err != nil {
+		return wrap(err)
+	}
+	if vm.PowerState != "off" {
+		if !options.Force {
+			return wrapf("[%s] --kill required: %w", vm.Name, &ErrPowerState{Want: "off", Have: vm.PowerState})
+		}
+		_, err := r.Stop(ctx, vm.Id, StopOptions{PowerOff: true, Wait: true})
+		if err != nil {
+			return wrap(err)
+		}
+	}
+	var response any
+	err = r.request(ctx, "DELETE", vmrestPath(&vm), nil, &response)
+	if err != nil {
+		return wrapf("[%s] vmrest delete failed: %w", vm.Name, err)
+	}
+	r.cli.Reset()
+	return nil
+}
+
+// return the VMNic for a vmrest NIC; vmrest NIC index N is adapter ethernet<N-1>
+func vmrestNICState(nic vmrestNIC) VMNic {
+	state := VMNic{
+		Index:      nic.Index - 1,
+		Connection: strings.ToLower(nic.Type),
+		MacAddress: nic.MacAddress,
+	}
+	if state.Connection == "custom" {
+		m := NIC_VNET_PATTERN.FindStringSubmatch(nic.Vmnet)
+		if m != nil {
+			state.Network = "vmnet" + m[1]
+		}
+	}
+	return state
+}
+
+func (r *vmrest) listNICs(ctx context.Context, vm *VM) ([]vmrestNIC, error) {
+	var response vmrestNICList
+	err := r.request(ctx, "GET", vmrestPath(vm, "nic"), nil, &response)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	return response.Nics, nil
+}
+
+func (r *vmrest) getNics(ctx context.Context, vm *VM) error {
+	nics, err := r.listNICs(ctx, vm)
+	if err != nil {
+		return wrap(err)
+	}
+	vm.Nics = []VMNic{}
+	for _, nic := range nics {
+		vm.Nics = append(vm.Nics, vmrestNICState(nic))
+	}
+	return nil
+}
+
+// the guest IP address is only available while VMware Tools is running
+func (r *vmrest) getIpAddress(ctx context.Context, vm *VM) error {
+	vm.IpAddress = ""
+	if vm.Running {
+		var response vmrestIP
+		err := r.request(ctx, "GET", vmrestPath(vm, "ip"), nil, &response)
+		if err != nil {
+			if r.debug {
+				log.Printf("[%s] vmrest ip query failed: %v\n", vm.Name, err)
+			}
+		} else {
+			vm.IpAddress = response.IP
+		}
+	}
+	if len(vm.Nics) == 1 {
+		vm.Nics[0].IpAddress = vm.IpAddress
+	}
+	return nil
+}
+
+func (r *vmrest) GetProperty(ctx context.Context, vid, property string) (string, error) {
+	if r.verbose {
+		log.Printf("vmrest GetProperty(%s, %s)\n", vid, property)
+	}
+	vm, err := r.Get(ctx, vid)
+	if err != nil {
+		return "", wrap(err)
+	}
+	switch strings.ToLower(property) {
+	case "power", "powerstate":
+		err := r.queryPowerState(ctx, &vm)
+		if err != nil {
+			return "", wrap(err)
+		}
+		return vm.PowerState, nil
+
+	case "ip", "ipaddress", "ipaddr":
+		err := r.queryVM(ctx, &vm, QueryTypeState)
+		if err != nil {
+			return "", wrap(err)
+		}
+		return vm.IpAddress, nil
+
+	case "mac", "macaddr", "macaddress":
+		err := r.getNics(ctx, &vm)
+		if err != nil {
+			return "", wrap(err)
+		}
+		if len(vm.Nics) == 0 {
+			return "", nil
+		}
+		return vm.Nics[0].MacAddress, nil
+
+	case "nic", "nics":
+		err := r.queryVM(ctx, &vm, QueryTypeState)
+		if err != nil {
+			return "", wrap(err)
+		}
+		return FormatJSON(vm.Nics), nil
+
+	case "state":
+		state, err := r.GetState(ctx, vm.Id)
+		if err != nil {
+			return "", wrap(err)
+		}
+		return FormatJSON(state), nil
+
+	case "config":
+		err := r.queryVM(ctx, &vm, QueryTypeConfig)
+		if err != nil {
+			return "", wrap(err)
+		}
+		return FormatJSON(&vm), nil
+
+	case "all", "detail", "":
+		err := r.queryVM(ctx, &vm, QueryTypeAll)
+		if err != nil {
+			return "", wrap(err)
+		}
+		return FormatJSON(&vm), nil
+
+	case "vmx", "disk", "disks", "diskinfo", "disksize", "disksizemb", "diskcapacity":
+		return r.vmctl.GetProperty(ctx, vm.Name, property)
+	}
+
+	key, ok := r.vmkey[strings.ToLower(property)]
+	if ok {
+		err := r.queryVM(ctx, &vm, QueryTypeAll)
+		if err != nil {
+			return "", wrap(err)
+		}
+		vmap, err := r.toMap(&vm)
+		if err != nil {
+			return "", wrap(err)
+		}
+		data, err := json.Marshal(vmap[key])
+		if err != nil {
+			return "", wrap(err)
+		}
+		return string(data), nil
+	}
+
+	var response vmrestParam
+	err = r.request(ctx, "GET", vmrestPath(&vm, "params", property), nil, &response)
+	if err != nil {
+		return "", wrap(err)
+	}
+	return response.Value, nil
+}
+
+// CpuCount, RamSize and VMX keys are set with vmrest; other VM properties use the CLI controller
+func (r *vmrest) SetProperty(ctx context.Context, vid, property, value string) error {
+	if r.debug {
+		log.Printf("vmrest SetProperty(%s, %s, %s)\n", vid, property, value)
+	}
+	vm, err := r.Get(ctx, vid)
+	if err != nil {
+		return wrap(err)
+	}
+	key, ok := r.vmkey[strings.ToLower(property)]
+	if property == "vmx" || (ok && key != "CpuCount" && key != "RamSize") {
+		return r.vmctl.SetProperty(ctx, vm.Name, property, value)
+	}
+	if r.verbose {
+		fmt.Printf("[%s] setting %s=%s\n", vm.Name, property, value)
+	}
+	if ok {
+		err := r.requirePowerState(ctx, &vm, "off", fmt.Sprintf("modify '%s'", key))
+		if err != nil {
+			return wrap(err)
+		}
+		var cpuCount int
+		var memorySize string
+		if key == "CpuCount" {
+			_, err := fmt.Sscanf(value, "%d", &cpuCount)
+			if err != nil {
+				return Fatalf("invalid CpuCount: '%s'", value)
+			}
+		} else {
+			memorySize = value
+		}
+		err = r.setCpuRam(ctx, &vm, cpuCount, memorySize)
+		if err != nil {
+			return wrap(err)
+		}
+		return nil
+	}
+	request := vmrestParam{Name: property, Value: value}
+	var response any
+	err = r.request(ctx, "PUT", vmrestPath(&vm, "params"), &request, &response)
+	if err != nil {
+		return wrap(err)
+	}
+	return nil
+}
+
+// update the processor count and memory size; zero values are unchanged
+func (r *vmrest) setCpuRam(ctx context.Context, vm *VM, cpuCount int, memorySize string) error {
+	var current VmRestGetVmCpuRamResponse
+	err := r.request(ctx, "GET", vmrestPath(vm), nil, &current)
+	if err != nil {
+		return wrap(err)
+	}
+	request := vmrestCpuRam{Processors: current.Cpu.Processors, Memory: current.Memory}
+	if cpuCount != 0 {
+		request.Processors = cpuCount
+	}
+	if memorySize != "" {
+		size, err := SizeParse(memorySize)
+		if err != nil {
+			return wrap(err)
+		}
+		request.Memory = int(size / MB)
+	}
+	var response VmRestGetVmCpuRamResponse
+	err = r.request(ctx, "PUT", vmrestPath(vm), &request, &response)
+	if err != nil {
+		return wrap(err)
+	}
+	return nil
+}
+
+// return true if vmrest supports all of the requested changes
+func vmrestModifiable(options *CreateOptions, isoOptions *IsoOptions) bool {
+	if isoOptions.ModifyISO {
+		return false
+	}
+	unsupported := []bool{
+		options.ModifyName,
+		options.ModifyGuestOS,
+		options.ModifyDisk,
+		options.ModifyTimeSync,
+		options.ModifyTimeZone,
+		options.ModifyClipboard,
+		options.ModifyTTY,
+		options.ModifyVNC,
+		options.ModifyEFI,
+		options.ModifyFloppy,
+		options.ModifyUSB,
+		options.ModifyGuestInfo,
+		options.ModifySeedISO,
+	}
+	for _, modify := range unsupported {
+		if modify {
+			return false
+		}
+	}
+	if options.ModifyNIC && !options.NIC.Remove {
+		nic := options.NIC
+		if nic.Device != "" || nic.MacAddress != "" || nic.ModifyStartConnected {
+			return false
+		}
+		if nic.Connection != "" {
+			_, err := vmrestNICRequest(nic.Connection)
+			if err != nil {
+				return false
+			}
+		}
+	}
+	return options.ModifyCpu || options.ModifyMemory || options.ModifyNIC || options.ModifyShare
+}
+
+// return the vmrest NIC for a connection; LAN segments are not supported
+func vmrestNICRequest(connection string) (vmrestNIC, error) {
+	lower := strings.ToLower(connection)
+	if VMREST_NIC_TYPES[lower] && lower != "custom" {
+		return vmrestNIC{Type: lower}, nil
+	}
+	m := NIC_VNET_PATTERN.FindStringSubmatch(connection)
+	if m != nil {
+		return vmrestNIC{Type: "custom", Vmnet: "vmnet" + m[1]}, nil
+	}
+	return vmrestNIC{}, Fatalf("unsupported vmrest NIC connection: '%s'", connection)
+}
+
+// CPU, memory, NIC connection and shared folder changes are made with
+// vmrest; a request including any other change uses the CLI controller
+func (r *vmrest) Modify(ctx context.Context, vid string, options CreateOptions, isoOptions IsoOptions) (*[]string, error) {
+	if r.debug {
+		log.Printf("vmrest Modify(%s, %+v, %+v)\n", vid, options, isoOptions)
+	}
+	vm, err := r.Get(ctx, vid)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if !vmrestModifiable(&options, &isoOptions) {
+		return r.vmctl.Modify(ctx, vm.Name, options, isoOptions)
+	}
+	err = r.requirePowerState(ctx, &vm, "off", "modify the instance")
+	if err != nil {
+		return nil, wrap(err)
+	}
+	actions := []string{}
+	if options.ModifyCpu || options.ModifyMemory {
+		var cpuCount int
+		var memorySize string
+		if options.ModifyCpu {
+			cpuCount = options.CpuCount
+		}
+		if options.ModifyMemory {
+			memorySize = options.MemorySize
+		}
+		err := r.setCpuRam(ctx, &vm, cpuCount, memorySize)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		if options.ModifyCpu {
+			actions = append(actions, fmt.Sprintf("Set cpu count %d", cpuCount))
+		}
+		if options.ModifyMemory {
+			size, err := SizeParse(memorySize)
+			if err != nil {
+				return nil, wrap(err)
+			}
+			actions = append(actions, fmt.Sprintf("Set memory size %s", FormatSize(size)))
+		}
+	}
+	if options.ModifyNIC {
+		action, err := r.setNIC(ctx, &vm, options.NIC)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		actions = append(actions, action)
+	}
+	if options.ModifyShare {
+		action, err := r.setFileShare(ctx, &vm, options.FileShareEnabled, options.SharedHostPath, options.SharedGuestPath)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		actions = append(actions, action)
+	}
+	return &actions, nil
+}
+
+func (r *vmrest) setNIC(ctx context.Context, vm *VM, options NICOptions) (string, error) {
+	if options.Index < 0 || options.Index >= MAX_NICS {
+		return "", Fatalf("invalid NIC index: %d", options.Index)
+	}
+	key := NICKey(options.Index)
+	index := fmt.Sprintf("%d", options.Index+1)
+	nics, err := r.listNICs(ctx, vm)
+	if err != nil {
+		return "", wrap(err)
+	}
+	var present bool
+	for _, nic := range nics {
+		if nic.Index == options.Index+1 {
+			present = true
+		}
+	}
+	var response any
+	if options.Remove {
+		if present {
+			err := r.request(ctx, "DELETE", vmrestPath(vm, "nic", index), nil, &response)
+			if err != nil {
+				return "", wrap(err)
+			}
+		}
+		return fmt.Sprintf("Removed %s", key), nil
+	}
+	connection := options.Connection
+	if connection == "" {
+		if present {
+			return fmt.Sprintf("%s unchanged", key), nil
+		}
+		// VMware default
+		connection = "bridged"
+	}
+	request, err := vmrestNICRequest(connection)
+	if err != nil {
+		return "", wrap(err)
+	}
+	if present {
+		err = r.request(ctx, "PUT", vmrestPath(vm, "nic", index), &request, &response)
+		if err != nil {
+			return "", wrap(err)
+		}
+		return fmt.Sprintf("Set %s connection: %s", key, connection), nil
+	}
+	err = r.request(ctx, "POST", vmrestPath(vm, "nic"), &request, &response)
+	if err != nil {
+		return "", wrap(err)
+	}
+	return fmt.Sprintf("Added %s; Set %s connection: %s", key, key, connection), nil
+}
+
+// replace the shared folders with one read/write folder, or remove them all
+func (r *vmrest) setFileShare(ctx context.Context, vm *VM, enable bool, hostPath, guestPath string) (string, error) {
+	if enable {
+		formatted, err := PathnameFormat(r.Remote, hostPath)
+		if err != nil {
+			return "", wrap(err)
+		}
+		hostPath = formatted
+		if hostPath == "" {
+			return "", Fatalf("missing filesystem share host path")
+		}
+		if guestPath == "" {
+			return "", Fatalf("missing filesystem share guest path")
+		}
+	}
+	var folders []vmrestSharedFolder
+	err := r.request(ctx, "GET", vmrestPath(vm, "sharedfolders"), nil, &folders)
+	if err != nil {
+		return "", wrap(err)
+	}
+	var response any
+	for _, folder := range folders {
+		err := r.request(ctx, "DELETE", vmrestPath(vm, "sharedfolders", folder.FolderID), nil, &response)
+		if err != nil {
+			return "", wrap(err)
+		}
+	}
+	hgfs := vmrestParam{Name: "isolation.tools.hgfs.disable", Value: "TRUE"}
+	if enable {
+		hgfs.Value = "FALSE"
+	}
+	err = r.request(ctx, "PUT", vmrestPath(vm, "params"), &hgfs, &response)
+	if err != nil {
+		return "", wrap(err)
+	}
+	if !enable {
+		return "Disabled filesystem share", nil
+	}
+	request := vmrestSharedFolder{FolderID: guestPath, HostPath: hostPath, Flags: VMREST_SHARE_WRITE_ACCESS}
+	err = r.request(ctx, "POST", vmrestPath(vm, "sharedfolders"), &request, &response)
+	if err != nil {
+		return "", wrap(err)
+	}
+	return fmt.Sprintf("Enabled filesystem share: host=%s guest=%s", hostPath, guestPath), nil
+}
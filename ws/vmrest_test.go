@@ -0,0 +1,427 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type fakeVMRestVM struct {
+	ID      string
+	Path    string
+	CPU     int
+	Memory  int
+	Power   string
+	IP      string
+	Nics    []vmrestNIC
+	Folders []vmrestSharedFolder
+	Params  map[string]string
+}
+
+// fakeVMRest is an httptest stand-in for the Workstation vmrest API
+type fakeVMRest struct {
+	t        *testing.T
+	vms      []*fakeVMRestVM
+	Requests []string
+}
+
+func newFakeVMRestController(t *testing.T) (*vmrest, *fakeVMRest, *fakeHost) {
+	v, host := newFakeController(t)
+	api := &fakeVMRest{t: t}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	ViperSet("vmrest.url", server.URL)
+	ViperSet("vmrest.username", "vmrest")
+	ViperSet("vmrest.password", "secret")
+	r, err := newVMRestController(v, "")
+	require.Nil(t, err)
+	return r, api, host
+}
+
+func (f *fakeVMRest) vm(id string) *fakeVMRestVM {
+	for _, vm := range f.vms {
+		if vm.ID == id {
+			return vm
+		}
+	}
+	return nil
+}
+
+func (f *fakeVMRest) reply(w http.ResponseWriter, status int, response any) {
+	w.Header().Set("Content-Type", VMREST_MEDIA_TYPE)
+	w.WriteHeader(status)
+	if response != nil {
+		require.Nil(f.t, json.NewEncoder(w).Encode(response))
+	}
+}
+
+func (f *fakeVMRest) fail(w http.ResponseWriter, status int, message string) {
+	f.reply(w, status, map[string]any{"code": status, "message": message})
+}
+
+func (f *fakeVMRest) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	username, password, ok := req.BasicAuth()
+	if !ok || username != "vmrest" || password != "secret" {
+		f.fail(w, http.StatusUnauthorized, "Authentication failed")
+		return
+	}
+	if req.Header.Get("Accept") != VMREST_MEDIA_TYPE {
+		f.fail(w, http.StatusNotAcceptable, "unsupported media type")
+		return
+	}
+	body, err := io.ReadAll(req.Body)
+	require.Nil(f.t, err)
+	decode := func(request any) {
+		require.Nil(f.t, json.Unmarshal(body, request))
+	}
+	f.Requests = append(f.Requests, req.Method+" "+req.URL.Path)
+
+	elements := strings.Split(strings.TrimPrefix(req.URL.Path, "/api/vms"), "/")[1:]
+	if len(elements) == 0 {
+		list := []vmrestID{}
+		for _, vm := range f.vms {
+			list = append(list, vmrestID{ID: vm.ID, Path: vm.Path})
+		}
+		f.reply(w, http.StatusOK, list)
+		return
+	}
+	if elements[0] == "registration" {
+		var request vmrestRegistration
+		decode(&request)
+		vm := fakeVMRestVM{
+			ID:     fmt.Sprintf("VM%d", len(f.vms)+1),
+			Path:   request.Path,
+			CPU:    1,
+			Memory: 1024,
+			Power:  "poweredOff",
+			Nics:   []vmrestNIC{{Index: 1, Type: "nat", Vmnet: "vmnet8", MacAddress: "00:0c:29:00:00:01"}},
+			Params: make(map[string]string),
+		}
+		f.vms = append(f.vms, &vm)
+		f.reply(w, http.StatusCreated, vmrestID{ID: vm.ID, Path: vm.Path})
+		return
+	}
+	vm := f.vm(elements[0])
+	if vm == nil {
+		f.fail(w, http.StatusNotFound, "The virtual machine is not found")
+		return
+	}
+	cpuRam := func() VmRestGetVmCpuRamResponse {
+		var response VmRestGetVmCpuRamResponse
+		response.ID = vm.ID
+		response.Cpu.Processors = vm.CPU
+		response.Memory = vm.Memory
+		return response
+	}
+	resource := strings.Join(elements[1:], "/")
+	if len(elements) > 2 {
+		resource = elements[1] + "/ID"
+	}
+	switch req.Method + " " + resource {
+	case "GET ":
+		f.reply(w, http.StatusOK, cpuRam())
+	case "PUT ":
+		if vm.Power != "poweredOff" {
+			f.fail(w, http.StatusConflict, "The virtual machine is powered on")
+			return
+		}
+		var request vmrestCpuRam
+		decode(&request)
+		vm.CPU = request.Processors
+		vm.Memory = request.Memory
+		f.reply(w, http.StatusOK, cpuRam())
+	case "DELETE ":
+		for i, item := range f.vms {
+			if item == vm {
+				f.vms = append(f.vms[:i], f.vms[i+1:]...)
+			}
+		}
+		f.reply(w, http.StatusNoContent, nil)
+	case "GET power":
+		f.reply(w, http.StatusOK, VmRestGetPowerStateResponse{PowerState: vm.Power})
+	case "PUT power":
+		states := map[string]string{
+			"on":       "poweredOn",
+			"off":      "poweredOff",
+			"shutdown": "poweredOff",
+			"suspend":  "suspended",
+			"pause":    "paused",
+			"unpause":  "poweredOn",
+			"reset":    "poweredOn",
+		}
+		state, ok := states[string(body)]
+		if !ok {
+			f.fail(w, http.StatusBadRequest, "invalid power operation")
+			return
+		}
+		vm.Power = state
+		f.reply(w, http.StatusOK, VmRestGetPowerStateResponse{PowerState: vm.Power})
+	case "GET ip":
+		if vm.IP == "" || vm.Power != "poweredOn" {
+			f.fail(w, http.StatusInternalServerError, "Unable to get the IP address")
+			return
+		}
+		f.reply(w, http.StatusOK, vmrestIP{IP: vm.IP})
+	case "GET restrictions":
+		var response VmRestGetVmRestrictionsResponse
+		response.ID = vm.ID
+		response.Cpu.Processors = vm.CPU
+		response.Memory = vm.Memory
+		response.GuestIsolation.HgfsDisabled = vm.Params["isolation.tools.hgfs.disable"] != "FALSE"
+		f.reply(w, http.StatusOK, response)
+	case "GET nic":
+		f.reply(w, http.StatusOK, vmrestNICList{Num: len(vm.Nics), Nics: vm.Nics})
+	case "POST nic":
+		var request vmrestNIC
+		decode(&request)
+		request.Index = len(vm.Nics) + 1
+		vm.Nics = append(vm.Nics, request)
+		f.reply(w, http.StatusCreated, request)
+	case "PUT nic/ID", "DELETE nic/ID":
+		index, err := strconv.Atoi(elements[2])
+		require.Nil(f.t, err)
+		for i, nic := range vm.Nics {
+			if nic.Index == index {
+				if req.Method == "DELETE" {
+					vm.Nics = append(vm.Nics[:i], vm.Nics[i+1:]...)
+					f.reply(w, http.StatusNoContent, nil)
+					return
+				}
+				var request vmrestNIC
+				decode(&request)
+				vm.Nics[i].Type = request.Type
+				vm.Nics[i].Vmnet = request.Vmnet
+				f.reply(w, http.StatusOK, vm.Nics[i])
+				return
+			}
+		}
+		f.fail(w, http.StatusNotFound, "NIC not found")
+	case "GET sharedfolders":
+		f.reply(w, http.StatusOK, vm.Folders)
+	case "POST sharedfolders":
+		var request vmrestSharedFolder
+		decode(&request)
+		vm.Folders = append(vm.Folders, request)
+		f.reply(w, http.StatusCreated, vm.Folders)
+	case "DELETE sharedfolders/ID":
+		for i, folder := range vm.Folders {
+			if folder.FolderID == elements[2] {
+				vm.Folders = append(vm.Folders[:i], vm.Folders[i+1:]...)
+			}
+		}
+		f.reply(w, http.StatusNoContent, nil)
+	case "GET params/ID":
+		f.reply(w, http.StatusOK, vmrestParam{Name: elements[2], Value: vm.Params[elements[2]]})
+	case "PUT params":
+		var request vmrestParam
+		decode(&request)
+		vm.Params[request.Name] = request.Value
+		f.reply(w, http.StatusOK, request)
+	default:
+		f.fail(w, http.StatusNotFound, "unsupported request")
+	}
+}
+
+func TestVMRestInventory(t *testing.T) {
+	ctx := t.Context()
+	r, api, _ := newFakeVMRestController(t)
+	_, err := r.Create(ctx, "web1", *NewCreateOptions(), IsoOptions{})
+	require.Nil(t, err)
+
+	vms, err := r.Show(ctx, "", ShowOptions{})
+	require.Nil(t, err)
+	require.Empty(t, *vms)
+
+	// an instance in vmware_roots is registered on first use
+	vm, err := r.Get(ctx, "web1")
+	require.Nil(t, err)
+	require.Equal(t, "VM1", vm.Id)
+	require.Equal(t, "/vmware/web1/web1.vmx", vm.Path)
+	require.Contains(t, api.Requests, "POST /api/vms/registration")
+
+	vm, err = r.Get(ctx, "VM1")
+	require.Nil(t, err)
+	require.Equal(t, "web1", vm.Name)
+
+	vms, err = r.Show(ctx, "", ShowOptions{Detail: true})
+	require.Nil(t, err)
+	require.Len(t, *vms, 1)
+	state := (*vms)[0]
+	require.Equal(t, "off", state.PowerState)
+	require.Len(t, state.Nics, 1)
+	require.Equal(t, 0, state.Nics[0].Index)
+	require.Equal(t, "nat", state.Nics[0].Connection)
+
+	_, err = r.Get(ctx, "missing")
+	require.NotNil(t, err)
+
+	// like the CLI controller, Get matches names exactly and Show ignores case
+	_, err = r.Get(ctx, "WEB1")
+	require.ErrorIs(t, err, ErrNotFound)
+	vms, err = r.Show(ctx, "WEB1", ShowOptions{})
+	require.Nil(t, err)
+	require.Len(t, *vms, 1)
+}
+
+func TestVMRestTransportError(t *testing.T) {
+	ctx := t.Context()
+	r, _, _ := newFakeVMRestController(t)
+
+	// an API error is not a transport error
+	err := r.request(ctx, "GET", "/vms/VM9", nil, nil)
+	require.NotNil(t, err)
+	require.False(t, errors.Is(err, ErrTransport))
+
+	// a refused connection is
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	r.api.URL = "http://" + listener.Addr().String() + "/api"
+	require.Nil(t, listener.Close())
+	_, err = r.Get(ctx, "web1")
+	require.ErrorIs(t, err, ErrTransport)
+}
+
+func TestClientTLSConfig(t *testing.T) {
+	config, err := clientTLSConfig("", "", "")
+	require.Nil(t, err)
+	require.Nil(t, config)
+	_, err = clientTLSConfig("client.pem", "", "ca.pem")
+	require.ErrorContains(t, err, "incomplete TLS config")
+	_, err = clientTLSConfig("missing.pem", "missing.key", "ca.pem")
+	require.ErrorContains(t, err, "failed loading client certificate pair")
+}
+
+func TestVMRestPower(t *testing.T) {
+	ctx := t.Context()
+	r, api, host := newFakeVMRestController(t)
+	_, err := r.Create(ctx, "web1", *NewCreateOptions(), IsoOptions{})
+	require.Nil(t, err)
+
+	result, err := r.Start(ctx, "web1", StartOptions{Background: true, Wait: true}, IsoOptions{})
+	require.Nil(t, err)
+	require.Equal(t, "started", result)
+	require.Equal(t, "poweredOn", api.vms[0].Power)
+	// vmrest started the instance, not vmrun
+	require.Equal(t, "off", host.PowerState("/vmware/web1/web1.vmx"))
+
+	result, err = r.Start(ctx, "web1", StartOptions{Background: true}, IsoOptions{})
+	require.Nil(t, err)
+	require.Equal(t, "already started", result)
+
+	api.vms[0].IP = "10.0.0.5"
+	value, err := r.GetProperty(ctx, "web1", "ip")
+	require.Nil(t, err)
+	require.Equal(t, "10.0.0.5", value)
+	value, err = r.GetProperty(ctx, "web1", "power")
+	require.Nil(t, err)
+	require.Equal(t, "on", value)
+
+	result, err = r.Pause(ctx, "web1", StopOptions{Wait: true})
+	require.Nil(t, err)
+	require.Equal(t, "paused", result)
+	result, err = r.Start(ctx, "web1", StartOptions{Background: true, Wait: true}, IsoOptions{})
+	require.Nil(t, err)
+	require.Equal(t, "unpaused", result)
+
+	result, err = r.Suspend(ctx, "web1", StopOptions{Wait: true})
+	require.Nil(t, err)
+	require.Equal(t, "suspended", result)
+	result, err = r.Start(ctx, "web1", StartOptions{Background: true, Wait: true}, IsoOptions{})
+	require.Nil(t, err)
+	require.Equal(t, "resumed", result)
+
+	require.NotNil(t, r.Destroy(ctx, "web1", DestroyOptions{}))
+	result, err = r.Stop(ctx, "web1", StopOptions{Wait: true})
+	require.Nil(t, err)
+	require.Equal(t, "stopped", result)
+	require.Equal(t, "poweredOff", api.vms[0].Power)
+
+	require.Nil(t, r.Destroy(ctx, "web1", DestroyOptions{}))
+	require.Empty(t, api.vms)
+}
+
+func TestVMRestModify(t *testing.T) {
+	ctx := t.Context()
+	r, api, host := newFakeVMRestController(t)
+	_, err := r.Create(ctx, "web1", *NewCreateOptions(), IsoOptions{})
+	require.Nil(t, err)
+	_, err = r.Get(ctx, "web1")
+	require.Nil(t, err)
+
+	options := CreateOptions{
+		ModifyCpu:        true,
+		CpuCount:         4,
+		ModifyMemory:     true,
+		MemorySize:       "4G",
+		ModifyNIC:        true,
+		NIC:              NICOptions{Index: 1, Connection: "vmnet2"},
+		ModifyShare:      true,
+		FileShareEnabled: true,
+		SharedHostPath:   "/srv/share",
+		SharedGuestPath:  "share",
+	}
+	actions, err := r.Modify(ctx, "web1", options, IsoOptions{})
+	require.Nil(t, err)
+	require.Equal(t, []string{
+		"Set cpu count 4",
+		"Set memory size 4G",
+		"Added ethernet1; Set ethernet1 connection: vmnet2",
+		"Enabled filesystem share: host=/srv/share guest=share",
+	}, *actions)
+	vm := api.vms[0]
+	require.Equal(t, 4, vm.CPU)
+	require.Equal(t, 4096, vm.Memory)
+	require.Equal(t, vmrestNIC{Index: 2, Type: "custom", Vmnet: "vmnet2"}, vm.Nics[1])
+	require.Equal(t, []vmrestSharedFolder{{FolderID: "share", HostPath: "/srv/share", Flags: VMREST_SHARE_WRITE_ACCESS}}, vm.Folders)
+
+	config, err := r.GetProperty(ctx, "web1", "CpuCount")
+	require.Nil(t, err)
+	require.Equal(t, "4", config)
+	config, err = r.GetProperty(ctx, "web1", "FileShareEnabled")
+	require.Nil(t, err)
+	require.Equal(t, "true", config)
+
+	require.Nil(t, r.SetProperty(ctx, "web1", "RamSize", "2G"))
+	require.Equal(t, 2048, vm.Memory)
+	require.Nil(t, r.SetProperty(ctx, "web1", "annotation", "test"))
+	value, err := r.GetProperty(ctx, "web1", "annotation")
+	require.Nil(t, err)
+	require.Equal(t, "test", value)
+
+	actions, err = r.Modify(ctx, "web1", CreateOptions{ModifyNIC: true, NIC: NICOptions{Index: 1, Remove: true}, ModifyShare: true}, IsoOptions{})
+	require.Nil(t, err)
+	require.Equal(t, []string{"Removed ethernet1", "Disabled filesystem share"}, *actions)
+	require.Len(t, vm.Nics, 1)
+	require.Empty(t, vm.Folders)
+
+	// changes vmrest does not support are made by the CLI controller
+	_, err = r.Modify(ctx, "web1", CreateOptions{ModifyVNC: true, VNCEnabled: true, VNCPort: 5901}, IsoOptions{})
+	require.Nil(t, err)
+	doc, err := host.readVMX("/vmware/web1/web1.vmx")
+	require.Nil(t, err)
+	enabled, _ := doc.Get("RemoteDisplay.vnc.enabled")
+	require.Equal(t, "TRUE", enabled)
+
+	api.vms[0].Power = "poweredOn"
+	_, err = r.Modify(ctx, "web1", CreateOptions{ModifyCpu: true, CpuCount: 2}, IsoOptions{})
+	require.NotNil(t, err)
+}
+
+func TestVMRestModifiable(t *testing.T) {
+	require.True(t, vmrestModifiable(&CreateOptions{ModifyCpu: true}, &IsoOptions{}))
+	require.False(t, vmrestModifiable(&CreateOptions{ModifyCpu: true}, &IsoOptions{ModifyISO: true}))
+	require.False(t, vmrestModifiable(&CreateOptions{ModifyCpu: true, ModifyEFI: true}, &IsoOptions{}))
+	require.True(t, vmrestModifiable(&CreateOptions{ModifyNIC: true, NIC: NICOptions{Connection: "hostonly"}}, &IsoOptions{}))
+	require.False(t, vmrestModifiable(&CreateOptions{ModifyNIC: true, NIC: NICOptions{Connection: "segment:lab"}}, &IsoOptions{}))
+	require.False(t, vmrestModifiable(&CreateOptions{ModifyNIC: true, NIC: NICOptions{Device: "vmxnet3"}}, &IsoOptions{}))
+	require.True(t, vmrestModifiable(&CreateOptions{ModifyNIC: true, NIC: NICOptions{Remove: true, Device: "vmxnet3"}}, &IsoOptions{}))
+	require.False(t, vmrestModifiable(&CreateOptions{}, &IsoOptions{}))
+}
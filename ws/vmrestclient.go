@@ -0,0 +1,146 @@
+package ws
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+const VMREST_IDLE_CONN_TIMEOUT = 5
+const VMREST_DISABLE_KEEPALIVES = false
+
+// vmrestClient sends vmrest API requests with the caller's context; unlike
+// APIClient it returns the net/http errors, so transport failures can be
+// told apart from API errors
+type vmrestClient struct {
+	client  *http.Client
+	URL     string
+	Headers map[string]string
+	debug   bool
+}
+
+// takes the NewAPIClient arguments and reads the same PREFIXapi_client
+// settings, so vmrest configuration is unchanged
+func newVMRestClient(prefix, url, certFile, keyFile, caFile string, headers map[string]string) (*vmrestClient, error) {
+	ViperSetDefault(prefix+"api_client.idle_conn_timeout", VMREST_IDLE_CONN_TIMEOUT)
+	ViperSetDefault(prefix+"api_client.disable_keepalives", VMREST_DISABLE_KEEPALIVES)
+	tlsConfig, err := clientTLSConfig(certFile, keyFile, caFile)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	transport := http.Transport{
+		IdleConnTimeout:   time.Duration(ViperGetInt64(prefix+"api_client.idle_conn_timeout")) * time.Second,
+		DisableKeepAlives: ViperGetBool(prefix + "api_client.disable_keepalives"),
+		TLSClientConfig:   tlsConfig,
+	}
+	c := vmrestClient{
+		client:  &http.Client{Transport: &transport},
+		URL:     url,
+		Headers: headers,
+		debug:   ViperGetBool("debug"),
+	}
+	return &c, nil
+}
+
+// return the client TLS config for certFile, keyFile and caFile, or nil if
+// none are set; pathnames may contain environment variables
+func clientTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" && caFile == "" {
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" || caFile == "" {
+		return nil, Fatalf("incomplete TLS config: cert=%s key=%s ca=%s", certFile, keyFile, caFile)
+	}
+	cert, err := tls.LoadX509KeyPair(os.ExpandEnv(certFile), os.ExpandEnv(keyFile))
+	if err != nil {
+		return nil, wrapf("failed loading client certificate pair: %w", err)
+	}
+	caCert, err := os.ReadFile(os.ExpandEnv(caFile))
+	if err != nil {
+		return nil, wrapf("failed loading certificate authority file: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		return nil, wrapf("failed opening system certificate pool: %w", err)
+	}
+	pool.AppendCertsFromPEM(caCert)
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+	}, nil
+}
+
+func (c *vmrestClient) Close() {
+	c.client.CloseIdleConnections()
+}
+
+// send request as the JSON body, or as is if it is a *[]byte, and decode the
+// JSON response into response
+func (c *vmrestClient) Do(ctx context.Context, method, path string, request, response any) error {
+	if c.debug {
+		log.Printf("vmrest %s %s\n", method, path)
+	}
+	var body io.Reader
+	switch raw := request.(type) {
+	case nil:
+	case *[]byte:
+		body = bytes.NewReader(*raw)
+	default:
+		data, err := json.Marshal(request)
+		if err != nil {
+			return wrapf("failed marshalling JSON body for %s request: %w", method, err)
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.URL+path, body)
+	if err != nil {
+		return wrap(err)
+	}
+	for key, value := range c.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return wrapf("vmrest %s %s failed: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return wrapf("vmrest %s %s failed reading response: %w", method, path, err)
+	}
+	if c.debug {
+		log.Printf("vmrest %s %s: %s (%d bytes)\n", method, path, resp.Status, len(data))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail := ""
+		if len(data) > 0 {
+			detail = "\n" + string(data)
+		}
+		return Fatalf("vmrest %s %s: %s%s", method, path, resp.Status, detail)
+	}
+	if len(data) > 0 && response != nil {
+		err = json.Unmarshal(data, response)
+		if err != nil {
+			return wrapf("failed decoding JSON response: %w", err)
+		}
+	}
+	return nil
+}
+
+// http.Client reports connection failures as *url.Error; reading the
+// response body reports them as net.Error
+func isTransportError(err error) bool {
+	var urlErr *url.Error
+	var netErr net.Error
+	return errors.As(err, &urlErr) || errors.As(err, &netErr)
+}
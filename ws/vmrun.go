@@ -0,0 +1,50 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// run 'vmrun -T ws COMMAND VMX_PATH [ARGS...]' on the host, returning stdout lines
+func (v *vmctl) vmrun(ctx context.Context, vm *VM, command string, args ...string) ([]string, error) {
+	return v.vmrunExec(ctx, vm, nil, command, nil, args...)
+}
+
+// note: if exitCode is nil, vmrun exit != 0 is an error, otherwise the exit code will be set;
+// the vmx path and args are quoted for the host shell by vmrunExec
+func (v *vmctl) vmrunExec(ctx context.Context, vm *VM, auth *GuestAuth, command string, exitCode *int, args ...string) ([]string, error) {
+	if v.debug {
+		log.Printf("vmrun(%s, %s, %v)\n", vm.Name, command, args)
+	}
+	path, err := PathnameFormat(v.Remote, vm.Path)
+	if err != nil {
+		return []string{}, wrap(err)
+	}
+	// FIXME: may need -vp PASSWORD here for encrypted instances
+	line := "vmrun -T ws"
+	if auth != nil {
+		password := hostQuote(v.Remote, auth.Password)
+		// keep the guest password out of logs and error messages
+		ctx = withSecret(ctx, auth.Password, password)
+		line += fmt.Sprintf(" -gu %s -gp %s", hostQuote(v.Remote, auth.Username), password)
+	}
+	line += fmt.Sprintf(" %s %s", command, hostQuote(v.Remote, path))
+	for _, arg := range args {
+		line += " " + hostQuote(v.Remote, arg)
+	}
+	var code int
+	olines, err := v.RemoteExec(ctx, line, &code)
+	if err != nil {
+		return olines, wrap(err)
+	}
+	if exitCode != nil {
+		*exitCode = code
+		return olines, nil
+	}
+	if code != 0 {
+		return olines, Fatalf("[%s] vmrun %s failed: %s", vm.Name, command, strings.Join(olines, "; "))
+	}
+	return olines, nil
+}
@@ -0,0 +1,265 @@
+package ws
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// VMXDocument is an order-preserving model of a VMX configuration file.
+// Keys are matched case-insensitively; unmodified lines are written back unchanged.
+type VMXDocument struct {
+	entries []*vmxEntry
+	crlf    bool
+}
+
+type vmxEntry struct {
+	raw     string // original line; comments, blank and unparsed lines have no key
+	key     string
+	value   string // unescaped value
+	quoted  bool
+	deleted bool
+	dirty   bool
+}
+
+func ParseVMXDocument(data []byte) *VMXDocument {
+	doc := VMXDocument{crlf: strings.Contains(string(data), "\r\n")}
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.TrimRight(text, "\n")
+	if text == "" {
+		return &doc
+	}
+	for _, line := range strings.Split(text, "\n") {
+		doc.entries = append(doc.entries, parseVMXLine(line))
+	}
+	return &doc
+}
+
+func parseVMXLine(line string) *vmxEntry {
+	entry := vmxEntry{raw: line}
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return &entry
+	}
+	key, value, ok := strings.Cut(trimmed, "=")
+	if !ok {
+		return &entry
+	}
+	entry.key = strings.TrimSpace(key)
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, `"`) {
+		entry.quoted = true
+		value = value[1:]
+		end := strings.Index(value, `"`)
+		if end >= 0 {
+			value = value[:end]
+		}
+	} else if comment := strings.Index(value, "#"); comment >= 0 {
+		value = strings.TrimSpace(value[:comment])
+	}
+	entry.value = VMXUnescape(value)
+	return &entry
+}
+
+// replace VMware '|XX' hex escapes with the bytes they encode
+func VMXUnescape(value string) string {
+	if !strings.Contains(value, "|") {
+		return value
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '|' && i+2 < len(value) {
+			c, err := strconv.ParseUint(value[i+1:i+3], 16, 8)
+			if err == nil {
+				b.WriteByte(byte(c))
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String()
+}
+
+// escape characters which may not appear in a quoted VMX value as '|XX'
+func VMXEscape(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c < 0x20, c == 0x7f, c == '"', c == '|', c == '#':
+			fmt.Fprintf(&b, "|%02X", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func (e *vmxEntry) String() string {
+	if e.key == "" || !e.dirty {
+		return e.raw
+	}
+	return fmt.Sprintf(`%s = "%s"`, e.key, VMXEscape(e.value))
+}
+
+func (d *VMXDocument) Bytes() []byte {
+	lines := []string{}
+	for _, entry := range d.entries {
+		if !entry.deleted {
+			lines = append(lines, entry.String())
+		}
+	}
+	eol := "\n"
+	if d.crlf {
+		eol = "\r\n"
+	}
+	if len(lines) == 0 {
+		return []byte{}
+	}
+	return []byte(strings.Join(lines, eol) + eol)
+}
+
+func (d *VMXDocument) find(key string) *vmxEntry {
+	for _, entry := range d.entries {
+		if !entry.deleted && entry.key != "" && strings.EqualFold(entry.key, key) {
+			return entry
+		}
+	}
+	return nil
+}
+
+// return the keys in document order
+func (d *VMXDocument) Keys() []string {
+	keys := []string{}
+	for _, entry := range d.entries {
+		if !entry.deleted && entry.key != "" {
+			keys = append(keys, entry.key)
+		}
+	}
+	return keys
+}
+
+func (d *VMXDocument) Has(key string) bool {
+	return d.find(key) != nil
+}
+
+func (d *VMXDocument) Get(key string) (string, bool) {
+	entry := d.find(key)
+	if entry == nil {
+		return "", false
+	}
+	return entry.value, true
+}
+
+// return the value of a TRUE/FALSE key; missing keys return defaultValue
+func (d *VMXDocument) GetBool(key string, defaultValue bool) bool {
+	value, ok := d.Get(key)
+	if !ok {
+		return defaultValue
+	}
+	return strings.EqualFold(value, "TRUE")
+}
+
+func (d *VMXDocument) GetInt(key string) (int, bool, error) {
+	value, ok := d.Get(key)
+	if !ok {
+		return 0, false, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, true, Fatalf("failed parsing VMX %s: '%s'", key, value)
+	}
+	return i, true, nil
+}
+
+// update key in place, or append it if it is not present
+func (d *VMXDocument) Set(key, value string) {
+	entry := d.find(key)
+	if entry == nil {
+		d.entries = append(d.entries, &vmxEntry{key: key, value: value, quoted: true, dirty: true})
+		return
+	}
+	if entry.value != value || !entry.quoted {
+		entry.value = value
+		entry.quoted = true
+		entry.dirty = true
+	}
+}
+
+func (d *VMXDocument) SetBool(key string, value bool) {
+	if value {
+		d.Set(key, "TRUE")
+	} else {
+		d.Set(key, "FALSE")
+	}
+}
+
+func (d *VMXDocument) SetInt(key string, value int) {
+	d.Set(key, strconv.Itoa(value))
+}
+
+// delete key, returning true if it was present
+func (d *VMXDocument) Delete(key string) bool {
+	deleted := false
+	for _, entry := range d.entries {
+		if !entry.deleted && entry.key != "" && strings.EqualFold(entry.key, key) {
+			entry.deleted = true
+			deleted = true
+		}
+	}
+	return deleted
+}
+
+// delete a device or key group: 'name', 'name.*' and 'name:*' keys
+func (d *VMXDocument) DeleteTree(name string) int {
+	return d.DeleteFunc(func(key string) bool {
+		return VMXKeyInTree(key, name)
+	})
+}
+
+// delete the keys for which match returns true, returning the count
+func (d *VMXDocument) DeleteFunc(match func(string) bool) int {
+	count := 0
+	for _, entry := range d.entries {
+		if !entry.deleted && entry.key != "" && match(entry.key) {
+			entry.deleted = true
+			count++
+		}
+	}
+	return count
+}
+
+// return the keys and values in the tree 'name', sorted by key
+func (d *VMXDocument) Tree(name string) map[string]string {
+	values := make(map[string]string)
+	for _, entry := range d.entries {
+		if !entry.deleted && entry.key != "" && VMXKeyInTree(entry.key, name) {
+			values[entry.key] = entry.value
+		}
+	}
+	return values
+}
+
+// return true if key is name, or is under name followed by '.' or ':'
+func VMXKeyInTree(key, name string) bool {
+	if len(key) < len(name) || !strings.EqualFold(key[:len(name)], name) {
+		return false
+	}
+	if len(key) == len(name) {
+		return true
+	}
+	next := key[len(name)]
+	return next == '.' || next == ':'
+}
+
+// return the sorted keys of a map returned by Tree
+func SortedKeys(values map[string]string) []string {
+	keys := []string{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
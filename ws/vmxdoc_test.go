@@ -0,0 +1,94 @@
+package ws
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readGolden(t *testing.T, name string) []byte {
+	data, err := os.ReadFile(filepath.Join("testdata", "vmxdoc", name))
+	require.Nil(t, err)
+	return data
+}
+
+func TestVMXDocumentRoundTrip(t *testing.T) {
+	input := readGolden(t, "input.vmx")
+	doc := ParseVMXDocument(input)
+	require.Equal(t, string(input), string(doc.Bytes()))
+
+	crlf := []byte("a = \"1\"\r\n# comment\r\nb = \"2\"\r\n")
+	require.Equal(t, crlf, ParseVMXDocument(crlf).Bytes())
+}
+
+func TestVMXDocumentAccessors(t *testing.T) {
+	doc := ParseVMXDocument(readGolden(t, "input.vmx"))
+
+	encoding, ok := doc.Get(".encoding")
+	require.True(t, ok)
+	require.Equal(t, "UTF-8", encoding)
+
+	annotation, ok := doc.Get("annotation")
+	require.True(t, ok)
+	require.Equal(t, "line one\nline two | pipe \"quoted\"", annotation)
+
+	value, ok := doc.Get("DISPLAYNAME")
+	require.True(t, ok)
+	require.Equal(t, "test", value)
+
+	require.True(t, doc.GetBool("unquoted", false))
+	require.True(t, doc.GetBool("missing", true))
+	cpus, ok, err := doc.GetInt("numvcpus")
+	require.Nil(t, err)
+	require.True(t, ok)
+	require.Equal(t, 1, cpus)
+
+	require.Equal(t, 3, doc.DeleteTree("nvme0"))
+	require.True(t, doc.Has("nvme01.present"))
+	require.False(t, doc.Has("nvme0:0.fileName"))
+
+	require.True(t, doc.Delete("memsize"))
+	require.False(t, doc.Delete("memsize"))
+}
+
+func TestVMXEscape(t *testing.T) {
+	require.Equal(t, "a|22b|7Cc|23d|0A", VMXEscape("a\"b|c#d\n"))
+	require.Equal(t, "a\"b|c#d\n", VMXUnescape("a|22b|7Cc|23d|0A"))
+	require.Equal(t, "|zz|", VMXUnescape("|zz|"))
+}
+
+func TestVMXSetGolden(t *testing.T) {
+	initTestConfig(t)
+	vmx, err := InitVMX("linux", "test", readGolden(t, "input.vmx"))
+	require.Nil(t, err)
+
+	_, err = vmx.SetName("renamed")
+	require.Nil(t, err)
+	_, err = vmx.SetCpu(4)
+	require.Nil(t, err)
+	_, err = vmx.SetMemory("4G")
+	require.Nil(t, err)
+	_, err = vmx.SetDisk("renamed.vmdk")
+	require.Nil(t, err)
+	_, err = vmx.SetEthernet(NICOptions{MacAddress: "00:50:56:01:02:03"})
+	require.Nil(t, err)
+	_, err = vmx.SetClipboard(false)
+	require.Nil(t, err)
+	_, err = vmx.SetFileShare(false, "", "")
+	require.Nil(t, err)
+	_, err = vmx.SetEFI(true)
+	require.Nil(t, err)
+	_, err = vmx.SetVNC(true, 5901)
+	require.Nil(t, err)
+	_, err = vmx.SetGuestInfo(map[string]string{"motd": `say "hi"`})
+	require.Nil(t, err)
+
+	data, err := vmx.Read()
+	require.Nil(t, err)
+	if os.Getenv("UPDATE_GOLDEN") != "" {
+		err = os.WriteFile(filepath.Join("testdata", "vmxdoc", "expected.vmx"), data, 0644)
+		require.Nil(t, err)
+	}
+	require.Equal(t, string(readGolden(t, "expected.vmx")), string(data))
+}
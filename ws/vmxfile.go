@@ -1,16 +1,14 @@
 package ws
 
 import (
+	"crypto/rand"
 	"fmt"
 	"log"
 	"regexp"
 	"strings"
 )
 
-var DISPLAY_NAME = regexp.MustCompile(`^displayName = "([^"]+)"`)
 var MAC_PATTERN = regexp.MustCompile(`^([[:xdigit:]]{2}:){5}[[:xdigit:]]{2}$`)
-var ISO_FILENAME_PATTERN = regexp.MustCompile(`^ide1:0\.fileName = "([^"]*)"`)
-var ISO_PRESENT_PATTERN = regexp.MustCompile(`^ide1:0\.present = "([^"]*)"`)
 var USB_ID_PATTERN = regexp.MustCompile(`^[0-9a-fA-F]{4}:[0-9a-fA-F]{4}$`)
 
 // OS names generated using the error message from this command:
@@ -36,7 +34,7 @@ type VMX struct {
 	name    string
 	hostOS  string
 	macros  map[string]string
-	lines   []string
+	doc     *VMXDocument
 	debug   bool
 	verbose bool
 }
@@ -45,6 +43,7 @@ func newVMX(os, name string) VMX {
 	vmx := VMX{
 		name:    name,
 		hostOS:  os,
+		doc:     ParseVMXDocument([]byte{}),
 		debug:   ViperGetBool("debug"),
 		verbose: ViperGetBool("verbose"),
 	}
@@ -55,7 +54,7 @@ func InitVMX(os, name string, data []byte) (*VMX, error) {
 	vmx := newVMX(os, name)
 	err := vmx.Write(data)
 	if err != nil {
-		return nil, Fatal(err)
+		return nil, wrap(err)
 	}
 	return &vmx, nil
 }
@@ -88,7 +87,7 @@ func (v *VMX) Configure(options *CreateOptions, isoOptions *IsoOptions) ([]strin
 	if options.ModifyName {
 		action, err := v.SetName(options.Name)
 		if err != nil {
-			return actions, Fatal(err)
+			return actions, wrap(err)
 		}
 		actions = append(actions, action)
 	}
@@ -96,7 +95,7 @@ func (v *VMX) Configure(options *CreateOptions, isoOptions *IsoOptions) ([]strin
 	if options.ModifyCpu {
 		action, err := v.SetCpu(options.CpuCount)
 		if err != nil {
-			return actions, Fatal(err)
+			return actions, wrap(err)
 		}
 		actions = append(actions, action)
 	}
@@ -105,7 +104,7 @@ func (v *VMX) Configure(options *CreateOptions, isoOptions *IsoOptions) ([]strin
 		action, err := v.SetMemory(options.MemorySize)
 
 		if err != nil {
-			return actions, Fatal(err)
+			return actions, wrap(err)
 		}
 		actions = append(actions, action)
 	}
@@ -113,7 +112,7 @@ func (v *VMX) Configure(options *CreateOptions, isoOptions *IsoOptions) ([]strin
 	if options.ModifyDisk {
 		action, err := v.SetDisk(options.DiskName)
 		if err != nil {
-			return actions, Fatal(err)
+			return actions, wrap(err)
 		}
 		actions = append(actions, action)
 	}
@@ -121,7 +120,7 @@ func (v *VMX) Configure(options *CreateOptions, isoOptions *IsoOptions) ([]strin
 	if options.ModifyFloppy {
 		action, err := v.SetFloppy(false)
 		if err != nil {
-			return actions, Fatal(err)
+			return actions, wrap(err)
 		}
 		actions = append(actions, action)
 	}
@@ -129,7 +128,7 @@ func (v *VMX) Configure(options *CreateOptions, isoOptions *IsoOptions) ([]strin
 	if options.ModifyEFI {
 		action, err := v.SetEFI(options.EFIBoot)
 		if err != nil {
-			return actions, Fatal(err)
+			return actions, wrap(err)
 		}
 		actions = append(actions, action)
 	}
@@ -137,7 +136,7 @@ func (v *VMX) Configure(options *CreateOptions, isoOptions *IsoOptions) ([]strin
 	if isoOptions.ModifyISO {
 		action, err := v.SetISO(isoOptions)
 		if err != nil {
-			return actions, Fatal(err)
+			return actions, wrap(err)
 		}
 		actions = append(actions, action)
 	}
@@ -145,15 +144,15 @@ func (v *VMX) Configure(options *CreateOptions, isoOptions *IsoOptions) ([]strin
 	if options.ModifyUSB {
 		action, err := v.SetUSB(options)
 		if err != nil {
-			return actions, Fatal(err)
+			return actions, wrap(err)
 		}
 		actions = append(actions, action)
 	}
 
 	if options.ModifyNIC {
-		action, err := v.SetEthernet(options.MacAddress)
+		action, err := v.SetEthernet(options.NIC)
 		if err != nil {
-			return actions, Fatal(err)
+			return actions, wrap(err)
 		}
 		actions = append(actions, action)
 	}
@@ -161,7 +160,7 @@ func (v *VMX) Configure(options *CreateOptions, isoOptions *IsoOptions) ([]strin
 	if options.ModifyTTY {
 		action, err := v.SetSerial(options.SerialPipe, options.SerialClient, options.SerialV2V)
 		if err != nil {
-			return actions, Fatal(err)
+			return actions, wrap(err)
 		}
 		actions = append(actions, action)
 	}
@@ -169,7 +168,7 @@ func (v *VMX) Configure(options *CreateOptions, isoOptions *IsoOptions) ([]strin
 	if options.ModifyVNC {
 		action, err := v.SetVNC(options.VNCEnabled, options.VNCPort)
 		if err != nil {
-			return actions, Fatal(err)
+			return actions, wrap(err)
 		}
 		actions = append(actions, action)
 	}
@@ -177,7 +176,7 @@ func (v *VMX) Configure(options *CreateOptions, isoOptions *IsoOptions) ([]strin
 	if options.ModifyClipboard {
 		action, err := v.SetClipboard(options.ClipboardEnabled)
 		if err != nil {
-			return actions, Fatal(err)
+			return actions, wrap(err)
 		}
 		actions = append(actions, action)
 	}
@@ -185,7 +184,7 @@ func (v *VMX) Configure(options *CreateOptions, isoOptions *IsoOptions) ([]strin
 	if options.ModifyShare {
 		action, err := v.SetFileShare(options.FileShareEnabled, options.SharedHostPath, options.SharedGuestPath)
 		if err != nil {
-			return actions, Fatal(err)
+			return actions, wrap(err)
 		}
 		actions = append(actions, action)
 	}
@@ -193,7 +192,7 @@ func (v *VMX) Configure(options *CreateOptions, isoOptions *IsoOptions) ([]strin
 	if options.ModifyTimeSync {
 		action, err := v.SetTimeSync(options.HostTimeSync)
 		if err != nil {
-			return actions, Fatal(err)
+			return actions, wrap(err)
 		}
 		actions = append(actions, action)
 	}
@@ -201,7 +200,23 @@ func (v *VMX) Configure(options *CreateOptions, isoOptions *IsoOptions) ([]strin
 	if options.ModifyTimeZone {
 		action, err := v.SetGuestTimeZone(options.GuestTimeZone)
 		if err != nil {
-			return actions, Fatal(err)
+			return actions, wrap(err)
+		}
+		actions = append(actions, action)
+	}
+
+	if options.ModifySeedISO {
+		action, err := v.SetSeedISO(options.SeedISOFile)
+		if err != nil {
+			return actions, wrap(err)
+		}
+		actions = append(actions, action)
+	}
+
+	if options.ModifyGuestInfo {
+		action, err := v.SetGuestInfo(options.GuestInfo)
+		if err != nil {
+			return actions, wrap(err)
 		}
 		actions = append(actions, action)
 	}
@@ -210,18 +225,21 @@ func (v *VMX) Configure(options *CreateOptions, isoOptions *IsoOptions) ([]strin
 }
 
 func (v *VMX) Write(data []byte) error {
-	v.lines = strings.Split(strings.TrimSpace(string(data)), "\n")
-	for _, line := range v.lines {
-		match := DISPLAY_NAME.FindStringSubmatch(line)
-		if len(match) == 2 {
-			v.name = match[1]
-		}
+	v.doc = ParseVMXDocument(data)
+	name, ok := v.doc.Get("displayName")
+	if ok && name != "" {
+		v.name = name
 	}
 	return nil
 }
 
 func (v *VMX) Read() ([]byte, error) {
-	return []byte(strings.Join(v.lines, "\n")), nil
+	return v.doc.Bytes(), nil
+}
+
+// return the parsed VMX document
+func (v *VMX) Document() *VMXDocument {
+	return v.doc
 }
 
 func (v *VMX) GetConfig(key string) string {
@@ -243,26 +261,12 @@ func (v *VMX) GetConfig(key string) string {
 	return value
 }
 
-func (v *VMX) removePrefix(prefix string) {
-	lines := []string{}
-	for _, line := range v.lines {
-		if !strings.HasPrefix(line, prefix) {
-			lines = append(lines, line)
-		}
-	}
-	v.lines = lines
-}
-
-func (v *VMX) addLine(line string) {
-	v.lines = append(v.lines, line)
-}
-
 func (v *VMX) SetName(name string) (string, error) {
 	if v.debug {
 		log.Printf("SetName(%s)\n", name)
 	}
-	v.removePrefix("displayName ")
-	v.addLine(fmt.Sprintf(`displayName = "%s"`, name))
+	v.doc.Set("displayName", name)
+	v.name = name
 	return "Set display name " + name, nil
 }
 
@@ -270,8 +274,7 @@ func (v *VMX) SetCpu(cpuCount int) (string, error) {
 	if v.debug {
 		log.Printf("SetCpuCount(%d)\n", cpuCount)
 	}
-	v.removePrefix("numvcpus ")
-	v.addLine(fmt.Sprintf(`numvcpus = "%d"`, cpuCount))
+	v.doc.SetInt("numvcpus", cpuCount)
 
 	return fmt.Sprintf("Set cpu count %d", cpuCount), nil
 }
@@ -280,13 +283,12 @@ func (v *VMX) SetMemory(memorySize string) (string, error) {
 	if v.debug {
 		log.Printf("SetMemory(%s)\n", memorySize)
 	}
-	v.removePrefix("memsize =")
-	v.removePrefix("memory.maxsize =")
 	size, err := SizeParse(memorySize)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
-	v.addLine(fmt.Sprintf(`memsize = "%d"`, size/MB))
+	v.doc.Set("memsize", fmt.Sprintf("%d", size/MB))
+	v.doc.Delete("memory.maxsize")
 
 	return fmt.Sprintf("Set memory size %s", FormatSize(size)), nil
 }
@@ -295,26 +297,25 @@ func (v *VMX) SetDisk(diskName string) (string, error) {
 	if v.debug {
 		log.Printf("SetDisk(%s)\n", diskName)
 	}
-	action := "Removed NVME disk"
-	v.removePrefix("nvme0")
-	if diskName != "" {
-		v.addLine(`nvme0.present = "TRUE"`)
-		v.addLine(fmt.Sprintf(`nvme0:0.fileName = "%s"`, diskName))
-		v.addLine(`nvme0:0.present = "TRUE"`)
-		action = "Set NVME disk " + diskName
+	if diskName == "" {
+		v.doc.DeleteTree("nvme0")
+		return "Removed NVME disk", nil
 	}
-	return action, nil
+	v.doc.SetBool("nvme0.present", true)
+	v.doc.Set("nvme0:0.fileName", diskName)
+	v.doc.SetBool("nvme0:0.present", true)
+	return "Set NVME disk " + diskName, nil
 }
 
 func (v *VMX) SetFloppy(enabled bool) (string, error) {
 	if v.debug {
 		log.Printf("SetFloppy(%v)\n", enabled)
 	}
-	v.removePrefix("floppy0")
 	if enabled {
 		return "", Fatalf("unsupported: floppy enable: '%v'", enabled)
 	}
-	v.lines = append(v.lines, `floppy0.present = "FALSE"`)
+	v.doc.DeleteTree("floppy0")
+	v.doc.SetBool("floppy0.present", false)
 	return "Disabled floppy device", nil
 }
 
@@ -322,11 +323,11 @@ func (v *VMX) SetGuestTimeZone(zone string) (string, error) {
 	if v.debug {
 		log.Printf("SetGuestTimeZone('%s')\n", zone)
 	}
-	v.removePrefix("guestTimeZone")
 	if zone == "" {
+		v.doc.Delete("guestTimeZone")
 		return "Removed guest time zone", nil
 	}
-	v.lines = append(v.lines, fmt.Sprintf(`guestTimeZone = "%s"`, zone))
+	v.doc.Set("guestTimeZone", zone)
 	return fmt.Sprintf("Set guest time zone: '%s'", zone), nil
 }
 
@@ -334,11 +335,11 @@ func (v *VMX) SetEFI(efi bool) (string, error) {
 	if v.debug {
 		log.Printf("SetEFI(%v)\n", efi)
 	}
-	v.removePrefix("firmware =")
 	if efi {
-		v.addLine(`firmware = "efi"`)
+		v.doc.Set("firmware", "efi")
 		return "Set EFI firmware", nil
 	}
+	v.doc.Delete("firmware")
 	return "Set BIOS firmware", nil
 }
 
@@ -349,91 +350,90 @@ func (v *VMX) SetISO(options *IsoOptions) (string, error) {
 	}
 
 	if options.ModifyBootConnected {
-		for _, line := range v.lines {
-			m := ISO_FILENAME_PATTERN.FindStringSubmatch(line)
-			if len(m) == 2 {
-				options.IsoFile = m[1]
-			}
-			m = ISO_PRESENT_PATTERN.FindStringSubmatch(line)
-			if len(m) == 2 {
-				options.IsoPresent = m[1] == "TRUE"
-			}
-		}
+		options.IsoFile, _ = v.doc.Get("ide1:0.fileName")
+		options.IsoPresent = v.doc.GetBool("ide1:0.present", false)
 		//log.Printf("ModifyBootConected: %+v\n", *options)
 	}
 
-	v.removePrefix("ide1:0.")
 	if !options.IsoPresent {
-		v.addLine(`ide1:0.present = "FALSE"`)
+		v.doc.DeleteTree("ide1:0")
+		v.doc.SetBool("ide1:0.present", false)
 		return "Removed boot ISO", nil
 	}
-	v.addLine(`ide1:0.present = "TRUE"`)
-	v.addLine(`ide1:0.deviceType = "cdrom-image"`)
 
 	normalized, err := PathNormalize(options.IsoFile)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 	hostPath, err := PathFormat(v.hostOS, normalized)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
-	v.addLine(`ide1:0.fileName = "` + hostPath + `"`)
-	var atBoot string
+	v.doc.SetBool("ide1:0.present", true)
+	v.doc.Set("ide1:0.deviceType", "cdrom-image")
+	v.doc.Set("ide1:0.fileName", hostPath)
+	v.doc.SetBool("ide1:0.startConnected", options.IsoBootConnected)
+	atBoot := "disconnected"
 	if options.IsoBootConnected {
-		v.addLine(`ide1:0.startConnected = "TRUE"`)
 		atBoot = "connected"
-	} else {
-		v.addLine(`ide1:0.startConnected = "FALSE"`)
-		atBoot = "disconnected"
 	}
 	return fmt.Sprintf("Set boot ISO '%s' [%s]", normalized, atBoot), nil
 }
 
-// FIXME: more NIC options could be modified
-func (v *VMX) SetEthernet(mac string) (string, error) {
+// attach filename as a second CD-ROM on ide1:1; an empty filename removes the device
+func (v *VMX) SetSeedISO(filename string) (string, error) {
 	if v.debug {
-		log.Printf("SetEthernet(%s)\n", mac)
+		log.Printf("SetSeedISO(%s)\n", filename)
 	}
-	v.removePrefix("ethernet0.")
-	if mac == "" {
-		v.addLine(`ethernet0.present = "FALSE"`)
-		return "Removed ethernet device", nil
+	if filename == "" {
+		v.doc.DeleteTree("ide1:1")
+		return "Removed seed ISO", nil
 	}
+	v.doc.SetBool("ide1:1.present", true)
+	v.doc.Set("ide1:1.deviceType", "cdrom-image")
+	v.doc.Set("ide1:1.fileName", filename)
+	v.doc.SetBool("ide1:1.startConnected", true)
+	return fmt.Sprintf("Set seed ISO '%s'", filename), nil
+}
 
-	v.addLine(`ethernet0.present = "TRUE"`)
-	v.addLine(`ethernet0.virtualDev = "e1000"`)
-	if mac == "auto" {
-		v.addLine(`ethernet0.addressType = "generated"`)
-		return "Set auto-generated MAC address", nil
+// generate a new random BIOS UUID; the location UUID is regenerated by VMware
+func (v *VMX) SetUUID() (string, error) {
+	if v.debug {
+		log.Println("SetUUID()")
 	}
-
-	if MAC_PATTERN.MatchString(mac) {
-		v.addLine(`ethernet0.address = "` + mac + `"`)
-		v.addLine(`ethernet0.addressType = "static"`)
-		return fmt.Sprintf("Set MAC address: %s", mac), nil
+	var b [16]byte
+	_, err := rand.Read(b[:])
+	if err != nil {
+		return "", wrap(err)
 	}
+	uuid := FormatVMXUUID(b)
+	v.doc.Set("uuid.bios", uuid)
+	v.doc.Delete("uuid.location")
+	return fmt.Sprintf("Set BIOS UUID %s", uuid), nil
+}
 
-	return "", Fatalf("invalid MAC address: '%s'", mac)
-
+// format 16 bytes as a VMX uuid value: "xx xx xx xx xx xx xx xx-xx xx xx xx xx xx xx xx"
+func FormatVMXUUID(b [16]byte) string {
+	parts := make([]string, 16)
+	for i, c := range b {
+		parts[i] = fmt.Sprintf("%02x", c)
+	}
+	return strings.Join(parts[:8], " ") + "-" + strings.Join(parts[8:], " ")
 }
 
 func (v *VMX) SetSerial(pipe string, isClient, isV2V bool) (string, error) {
 	if v.debug {
 		log.Printf("SetSerial(%s, %v, %v)\n", pipe, isClient, isV2V)
 	}
-	v.removePrefix("serial0.")
 	if pipe == "" {
-		v.addLine(`serial0.present = "FALSE"`)
+		v.doc.DeleteTree("serial0")
+		v.doc.SetBool("serial0.present", false)
 		return "Removed serial device", nil
 	}
 
-	v.addLine(`serial0.present = "TRUE"`)
-	v.addLine(`serial0.fileType = "pipe"`)
-
 	normalized, err := PathNormalize(pipe)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 	hostPipe := normalized
 
@@ -456,21 +456,26 @@ func (v *VMX) SetSerial(pipe string, isClient, isV2V bool) (string, error) {
 		hostPipe = strings.ReplaceAll(normalized, "/", "\\")
 	}
 
+	v.doc.SetBool("serial0.present", true)
+	v.doc.Set("serial0.fileType", "pipe")
+
 	var ttyMode string
 	if isV2V {
 		ttyMode = "v2v"
-		v.addLine(`serial0.tryNoRxLoss = "FALSE"`)
+		v.doc.SetBool("serial0.tryNoRxLoss", false)
 	} else {
 		ttyMode = "app"
-		v.addLine(`serial0.tryNoRxLoss = "TRUE"`)
+		v.doc.SetBool("serial0.tryNoRxLoss", true)
 	}
 
-	v.addLine(`serial0.fileName = "` + hostPipe + `"`)
+	v.doc.Set("serial0.fileName", hostPipe)
 
 	ttyEnd := "server"
 	if isClient {
-		v.addLine(`serial0.pipe.endPoint = "client"`)
+		v.doc.Set("serial0.pipe.endPoint", "client")
 		ttyEnd = "client"
+	} else {
+		v.doc.Delete("serial0.pipe.endPoint")
 	}
 
 	return fmt.Sprintf("Set tty %s %s pipe %s", ttyMode, ttyEnd, hostPipe), nil
@@ -481,14 +486,16 @@ func (v *VMX) SetVNC(enabled bool, port int) (string, error) {
 	if v.debug {
 		log.Printf("SetVNC(%v, %d)\n", enabled, port)
 	}
-	v.removePrefix("RemoteDisplay.vnc.")
 	if !enabled {
-		v.addLine(`RemoteDisplay.vnc.enabled = "FALSE"`)
+		v.doc.DeleteTree("RemoteDisplay.vnc")
+		v.doc.SetBool("RemoteDisplay.vnc.enabled", false)
 		return "Disabled VNC", nil
 	}
-	v.addLine(`RemoteDisplay.vnc.enabled = "TRUE"`)
+	v.doc.SetBool("RemoteDisplay.vnc.enabled", true)
 	if port != 5900 {
-		v.addLine(fmt.Sprintf(`RemoteDisplay.vnc.port = "%d"`, port))
+		v.doc.SetInt("RemoteDisplay.vnc.port", port)
+	} else {
+		v.doc.Delete("RemoteDisplay.vnc.port")
 	}
 	return fmt.Sprintf("Enabled VNC on port %d", port), nil
 }
@@ -497,19 +504,13 @@ func (v *VMX) SetClipboard(enable bool) (string, error) {
 	if v.debug {
 		log.Printf("SetClipboard(%v)\n", enable)
 	}
-	v.removePrefix("isolation.tools.copy")
-	v.removePrefix("isolation.tools.pase")
-	v.removePrefix("isolation.tools.dnd")
-
-	value := "TRUE"
 	action := "Disabled clipboard"
 	if enable {
-		value = "FALSE"
 		action = "Enabled clipboard"
 	}
-	v.addLine(fmt.Sprintf(`isolation.tools.copy.disable = "%s"`, value))
-	v.addLine(fmt.Sprintf(`isolation.tools.paste.disable = "%s"`, value))
-	v.addLine(fmt.Sprintf(`isolation.tools.dnd.disable = "%s"`, value))
+	v.doc.SetBool("isolation.tools.copy.disable", !enable)
+	v.doc.SetBool("isolation.tools.paste.disable", !enable)
+	v.doc.SetBool("isolation.tools.dnd.disable", !enable)
 	return action, nil
 }
 
@@ -529,16 +530,17 @@ func (v *VMX) SetFileShare(enable bool, hostPath, guestPath string) (string, err
 		log.Printf("SetFileShare(%v, %s, %s)\n", enable, hostPath, guestPath)
 	}
 
-	v.removePrefix("sharedFolder")
-	v.removePrefix("isolation.tools.hgfs.")
 	if !enable {
-		v.addLine(`isolation.tools.hgfs.disable = "TRUE"`)
+		v.doc.DeleteFunc(func(key string) bool {
+			return strings.HasPrefix(strings.ToLower(key), "sharedfolder")
+		})
+		v.doc.SetBool("isolation.tools.hgfs.disable", true)
 		return "Disabled filesystem share", nil
 	}
 
 	formatted, err := PathnameFormat(v.hostOS, hostPath)
 	if err != nil {
-		return "", Fatal(err)
+		return "", wrap(err)
 	}
 	hostPath = formatted
 
@@ -550,15 +552,16 @@ func (v *VMX) SetFileShare(enable bool, hostPath, guestPath string) (string, err
 		return "", Fatalf("missing filesystem share guest path")
 	}
 
-	v.addLine(`isolation.tools.hgfs.disable = "FALSE"`)
-	v.addLine(`sharedFolder0.present = "TRUE"`)
-	v.addLine(`sharedFolder0.enabled = "TRUE"`)
-	v.addLine(`sharedFolder0.readAccess = "TRUE"`)
-	v.addLine(`sharedFolder0.writeAccess = "TRUE"`)
-	v.addLine(fmt.Sprintf(`sharedFolder0.guestName = "%s"`, guestPath))
-	v.addLine(fmt.Sprintf(`sharedFolder0.hostPath = "%s"`, hostPath))
-	v.addLine(`sharedFolder0.expiration = "never"`)
-	v.addLine(`sharedFolder0.maxNum = "1"`)
+	v.doc.SetBool("isolation.tools.hgfs.disable", false)
+	v.doc.SetBool("sharedFolder0.present", true)
+	v.doc.SetBool("sharedFolder0.enabled", true)
+	v.doc.SetBool("sharedFolder0.readAccess", true)
+	v.doc.SetBool("sharedFolder0.writeAccess", true)
+	v.doc.Set("sharedFolder0.guestName", guestPath)
+	v.doc.Set("sharedFolder0.hostPath", hostPath)
+	v.doc.Set("sharedFolder0.expiration", "never")
+	v.doc.Delete("sharedFolder0.maxNum")
+	v.doc.Set("sharedFolder.maxNum", "1")
 	return fmt.Sprintf("Enabled filesystem share: host=%s guest=%s", hostPath, guestPath), nil
 }
 
@@ -567,18 +570,17 @@ func (v *VMX) SetTimeSync(enable bool) (string, error) {
 		log.Printf("SetTimeSync(%v)\n", enable)
 	}
 
-	v.removePrefix("tools.syncTime")
-	v.removePrefix("time.synchronize")
 	if !enable {
-		v.addLine(`tools.syncTime = "FALSE"`)
+		v.doc.DeleteTree("time.synchronize")
+		v.doc.SetBool("tools.syncTime", false)
 		return "Disabled host time sync", nil
 	}
-	v.addLine(`tools.syncTime = "TRUE"`)
-	v.addLine(`time.synchronize.continue = "TRUE"`)
-	v.addLine(`time.synchronize.restore = "TRUE"`)
-	v.addLine(`time.synchronize.resume.disk = "TRUE"`)
-	v.addLine(`time.synchronize.shrink = "TRUE"`)
-	v.addLine(`time.synchronize.tools.startup = "TRUE"`)
+	v.doc.SetBool("tools.syncTime", true)
+	v.doc.SetBool("time.synchronize.continue", true)
+	v.doc.SetBool("time.synchronize.restore", true)
+	v.doc.SetBool("time.synchronize.resume.disk", true)
+	v.doc.SetBool("time.synchronize.shrink", true)
+	v.doc.SetBool("time.synchronize.tools.startup", true)
 	return "Enabled host time sync", nil
 }
 
@@ -587,35 +589,39 @@ func (v *VMX) SetUSB(options *CreateOptions) (string, error) {
 		log.Printf("SetUSB: %s\n", FormatJSON(*options))
 	}
 
-	v.removePrefix("usb.generic.allow")
-	v.removePrefix("usb.autoConnect")
-	v.removePrefix("usb.quirks")
-
 	if options.AllowHID {
-		v.addLine(`usb.generic.allowHID = "TRUE"`)
+		v.doc.SetBool("usb.generic.allowHID", true)
+	} else {
+		v.doc.Delete("usb.generic.allowHID")
 	}
 	if options.AllowCCID {
-		v.addLine(`usb.generic.allowCCID = "TRUE"`)
+		v.doc.SetBool("usb.generic.allowCCID", true)
+	} else {
+		v.doc.Delete("usb.generic.allowCCID")
 	}
 
 	devices := map[string]string{
 		"device0": options.Device0,
 		"device1": options.Device1,
 	}
-	for label, ids := range devices {
-
-		if ids != "" {
-			if !USB_ID_PATTERN.MatchString(ids) {
-				return "", Fatalf("unexpected format: USB %s: %s", label, ids)
-			}
-			vid, pid, ok := strings.Cut(ids, ":")
-			if !ok {
-				return "", Fatalf("failed parsing USB %s: %s", label, ids)
-			}
-			v.addLine(fmt.Sprintf(`usb.quirks.%s = "0x%s:0x%s allow"`, label, vid, pid))
-			v.addLine(fmt.Sprintf(`usb.autoConnect.%s = "vid:%s pid:%s autoclean:0"`, label, vid, pid))
-
+	for _, label := range SortedKeys(devices) {
+		ids := devices[label]
+		quirksKey := "usb.quirks." + label
+		autoConnectKey := "usb.autoConnect." + label
+		if ids == "" {
+			v.doc.Delete(quirksKey)
+			v.doc.Delete(autoConnectKey)
+			continue
+		}
+		if !USB_ID_PATTERN.MatchString(ids) {
+			return "", Fatalf("unexpected format: USB %s: %s", label, ids)
+		}
+		vid, pid, ok := strings.Cut(ids, ":")
+		if !ok {
+			return "", Fatalf("failed parsing USB %s: %s", label, ids)
 		}
+		v.doc.Set(quirksKey, fmt.Sprintf("0x%s:0x%s allow", vid, pid))
+		v.doc.Set(autoConnectKey, fmt.Sprintf("vid:%s pid:%s autoclean:0", vid, pid))
 	}
 	return "Configured USB devices", nil
 }
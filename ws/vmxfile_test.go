@@ -0,0 +1,11 @@
+package ws
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestFormatVMXUUID(t *testing.T) {
+	b := [16]byte{0x56, 0x4d, 0x9a, 0x1b, 0x2c, 0x3d, 0x4e, 0x5f, 0x60, 0x71, 0x82, 0x93, 0xa4, 0xb5, 0xc6, 0xd7}
+	require.Equal(t, "56 4d 9a 1b 2c 3d 4e 5f-60 71 82 93 a4 b5 c6 d7", FormatVMXUUID(b))
+}